@@ -8,4 +8,16 @@ go get github.com/sirupsen/logrus
 go get github.com/lestrrat/go-file-rotatelogs
 go get golang.org/x/crypto/ssh/terminal
 go get github.com/o3labs/neo-utils/neoutils
-go get github.com/stripe/stripe-go
\ No newline at end of file
+go get github.com/stripe/stripe-go
+go get go.opentelemetry.io/otel/trace
+go get github.com/prometheus/client_golang/prometheus
+go get github.com/segmentio/kafka-go
+go get github.com/getsentry/sentry-go
+go get gopkg.in/yaml.v2
+go get github.com/BurntSushi/toml
+go get github.com/fsnotify/fsnotify
+go get google.golang.org/grpc
+go get cloud.google.com/go/logging
+go get go.uber.org/zap
+go get github.com/klauspost/compress/zstd
+go get cloud.google.com/go/storage
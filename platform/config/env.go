@@ -39,6 +39,9 @@ type config struct {
 			KeyPath   string `json:"keyPath"`
 		} `json:"cloudfront"`
 	} `json:"aws"`
+	Log struct {
+		Formatter string `json:"formatter"`
+	} `json:"log"`
 	NEO struct {
 		CoZEndpoint             string `json:"cozEndpoint"`
 		SmartContractScriptHash string `json:"smartContractScriptHash"`
@@ -0,0 +1,79 @@
+package errors
+
+import (
+	"errors"
+	"fmt"
+)
+
+// StructuredError is an error with a machine readable code, e.g.
+// "card_declined", the error it wraps and key/value fields describing it.
+// The log formatters write the code and fields as fields of their own:
+//
+//	err := errors.Wrap(err, "card_declined", "Failed to charge").With("charge", id)
+//	log.Error(err) // error="Failed to charge: ..." error.code=card_declined error.charge=ch_1
+type StructuredError struct {
+	Code    string
+	Message string
+	Cause   error
+	Fields  map[string]interface{}
+}
+
+// Newf returns an error with code and a formatted message.
+func Newf(code string, format string, a ...interface{}) *StructuredError {
+	return &StructuredError{Code: code, Message: fmt.Sprintf(format, a...)}
+}
+
+// Wrap returns an error with code and message wrapping cause.
+func Wrap(cause error, code string, message string) *StructuredError {
+	return &StructuredError{Code: code, Message: message, Cause: cause}
+}
+
+// With returns a copy of the error with the field key set to value.
+func (e *StructuredError) With(key string, value interface{}) *StructuredError {
+	c := *e
+	c.Fields = make(map[string]interface{}, len(e.Fields)+1)
+	for k, v := range e.Fields {
+		c.Fields[k] = v
+	}
+	c.Fields[key] = value
+	return &c
+}
+
+func (e *StructuredError) Error() string {
+	if e.Cause == nil {
+		return e.Message
+	}
+	if e.Message == "" {
+		return e.Cause.Error()
+	}
+	return e.Message + ": " + e.Cause.Error()
+}
+
+func (e *StructuredError) Unwrap() error {
+	return e.Cause
+}
+
+// ErrorCode and ErrorFields are what the log formatters look for.
+func (e *StructuredError) ErrorCode() string {
+	return e.Code
+}
+
+func (e *StructuredError) ErrorFields() map[string]interface{} {
+	return e.Fields
+}
+
+// CodeOf returns the code of the outermost StructuredError in err's chain,
+// or an empty string.
+func CodeOf(err error) string {
+	var e *StructuredError
+	for err != nil {
+		if !errors.As(err, &e) {
+			return ""
+		}
+		if e.Code != "" {
+			return e.Code
+		}
+		err = e.Cause
+	}
+	return ""
+}
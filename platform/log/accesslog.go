@@ -0,0 +1,231 @@
+package log
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	logrus "github.com/sirupsen/logrus"
+)
+
+// AccessLogLayout is the line format of an AccessLogFormatter.
+type AccessLogLayout int
+
+const (
+	// AccessLogCombined is the Apache/NCSA combined log format:
+	//
+	//	127.0.0.1 - - [26/Feb/2018:10:04:05 +0000] "GET /charges?limit=10 HTTP/1.1" 200 2326 "-" "curl/7.58.0"
+	AccessLogCombined AccessLogLayout = iota
+
+	// AccessLogW3C is the W3C extended log file format, starting with
+	// #Version and #Fields directives:
+	//
+	//	2018-02-26 10:04:05 127.0.0.1 GET /charges limit=10 200 2326 0.012 curl/7.58.0 -
+	AccessLogW3C
+)
+
+const (
+	accessLogTimeFormat = "02/Jan/2006:15:04:05 -0700"
+	w3cFields           = "date time c-ip cs-method cs-uri-stem cs-uri-query sc-status sc-bytes time-taken cs(User-Agent) cs(Referer)"
+)
+
+// AccessLogKeys are the fields an AccessLogFormatter reads, the ones
+// httplog logs by default.
+type AccessLogKeys struct {
+	Method    string
+	Path      string
+	Query     string
+	Proto     string
+	Status    string
+	Bytes     string
+	Latency   string
+	RemoteIP  string
+	Referer   string
+	UserAgent string
+	User      string
+}
+
+var DefaultAccessLogKeys = AccessLogKeys{
+	Method:    "method",
+	Path:      "path",
+	Query:     "query",
+	Proto:     "proto",
+	Status:    "status",
+	Bytes:     "bytes",
+	Latency:   "latency",
+	RemoteIP:  "remote_ip",
+	Referer:   "referer",
+	UserAgent: "user_agent",
+	User:      "user_id",
+}
+
+// AccessLogFormatter writes the requests logged by httplog in the formats
+// GoAccess, AWStats and other web analytics tools read. Entries without a
+// method field, i.e. anything but requests, are dropped, so give it an
+// output of its own on the http channel. The W3C directives are written
+// before the first entry, not again when a RotatingFileWriter rotates.
+type AccessLogFormatter struct {
+	Layout AccessLogLayout
+
+	// Keys overrides the field names. Empty names keep the default.
+	Keys AccessLogKeys
+
+	header sync.Once
+}
+
+// Format renders a single log entry
+func (f *AccessLogFormatter) Format(entry *logrus.Entry) ([]byte, error) {
+	keys := f.Keys.withDefaults()
+	if _, ok := entry.Data[keys.Method]; !ok {
+		return nil, nil
+	}
+
+	b := &bytes.Buffer{}
+	switch f.Layout {
+	case AccessLogCombined:
+		b.WriteString(accessField(entry.Data, keys.RemoteIP))
+		b.WriteString(" - ")
+		b.WriteString(accessField(entry.Data, keys.User))
+		b.WriteString(" [")
+		b.WriteString(entryTime(entry).Format(accessLogTimeFormat))
+		b.WriteString("] ")
+
+		uri := accessValue(entry.Data, keys.Path)
+		if q := accessValue(entry.Data, keys.Query); q != "" {
+			uri += "?" + q
+		}
+		request := accessValue(entry.Data, keys.Method) + " " + uri
+		if proto := accessValue(entry.Data, keys.Proto); proto != "" {
+			request += " " + proto
+		}
+		appendAccessQuoted(b, request)
+		b.WriteByte(' ')
+		b.WriteString(accessField(entry.Data, keys.Status))
+		b.WriteByte(' ')
+		if n := accessValue(entry.Data, keys.Bytes); n != "" && n != "0" {
+			b.WriteString(n)
+		} else {
+			b.WriteByte('-')
+		}
+		b.WriteByte(' ')
+		appendAccessQuoted(b, accessField(entry.Data, keys.Referer))
+		b.WriteByte(' ')
+		appendAccessQuoted(b, accessField(entry.Data, keys.UserAgent))
+
+	case AccessLogW3C:
+		f.header.Do(func() {
+			fmt.Fprintf(b, "#Version: 1.0\n#Date: %s\n#Fields: %s\n", entry.Time.UTC().Format("2006-01-02 15:04:05"), w3cFields)
+		})
+		t := entry.Time.UTC()
+		took := "-"
+		if d, ok := accessDuration(entry.Data[keys.Latency]); ok {
+			took = strconv.FormatFloat(d.Seconds(), 'f', 3, 64)
+		}
+		for i, v := range []string{
+			t.Format("2006-01-02"),
+			t.Format("15:04:05"),
+			accessField(entry.Data, keys.RemoteIP),
+			accessField(entry.Data, keys.Method),
+			accessField(entry.Data, keys.Path),
+			accessField(entry.Data, keys.Query),
+			accessField(entry.Data, keys.Status),
+			accessField(entry.Data, keys.Bytes),
+			took,
+			accessField(entry.Data, keys.UserAgent),
+			accessField(entry.Data, keys.Referer),
+		} {
+			if i > 0 {
+				b.WriteByte(' ')
+			}
+			// W3C fields are space separated and can't be quoted
+			b.WriteString(strings.Map(w3cRune, v))
+		}
+
+	default:
+		return nil, fmt.Errorf("log: unknown access log layout %d", f.Layout)
+	}
+	b.WriteByte('\n')
+	return b.Bytes(), nil
+}
+
+func (k AccessLogKeys) withDefaults() AccessLogKeys {
+	set := func(name *string, def string) {
+		if *name == "" {
+			*name = def
+		}
+	}
+	set(&k.Method, DefaultAccessLogKeys.Method)
+	set(&k.Path, DefaultAccessLogKeys.Path)
+	set(&k.Query, DefaultAccessLogKeys.Query)
+	set(&k.Proto, DefaultAccessLogKeys.Proto)
+	set(&k.Status, DefaultAccessLogKeys.Status)
+	set(&k.Bytes, DefaultAccessLogKeys.Bytes)
+	set(&k.Latency, DefaultAccessLogKeys.Latency)
+	set(&k.RemoteIP, DefaultAccessLogKeys.RemoteIP)
+	set(&k.Referer, DefaultAccessLogKeys.Referer)
+	set(&k.UserAgent, DefaultAccessLogKeys.UserAgent)
+	set(&k.User, DefaultAccessLogKeys.User)
+	return k
+}
+
+// accessValue returns the field key as a string, empty when it is missing.
+func accessValue(data logrus.Fields, key string) string {
+	v, ok := data[key]
+	if !ok || v == nil {
+		return ""
+	}
+	return fmt.Sprint(resolveLazy(v))
+}
+
+// accessField is accessValue with "-" for missing fields, as access logs
+// write them.
+func accessField(data logrus.Fields, key string) string {
+	if v := accessValue(data, key); v != "" {
+		return v
+	}
+	return "-"
+}
+
+func accessDuration(v interface{}) (time.Duration, bool) {
+	switch v := resolveLazy(v).(type) {
+	case time.Duration:
+		return v, true
+	case Duration:
+		return time.Duration(v), true
+	case string:
+		d, err := time.ParseDuration(v)
+		return d, err == nil
+	}
+	return 0, false
+}
+
+// appendAccessQuoted quotes s the way Apache does, escaping quotes,
+// backslashes and control characters.
+func appendAccessQuoted(b *bytes.Buffer, s string) {
+	b.WriteByte('"')
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '"' || c == '\\':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case c < 0x20 || c == 0x7f:
+			fmt.Fprintf(b, "\\x%02x", c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	b.WriteByte('"')
+}
+
+func w3cRune(r rune) rune {
+	if r == ' ' {
+		return '+'
+	}
+	if r < 0x20 || r == 0x7f {
+		return -1
+	}
+	return r
+}
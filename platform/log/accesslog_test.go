@@ -0,0 +1,48 @@
+package log
+
+import (
+	"testing"
+	"time"
+
+	logrus "github.com/sirupsen/logrus"
+)
+
+func TestAccessLogFormatter(t *testing.T) {
+	entry := &logrus.Entry{
+		Time:  time.Date(2018, 2, 26, 10, 4, 5, 0, time.UTC),
+		Level: logrus.InfoLevel,
+		Data: logrus.Fields{
+			"method": "GET", "path": "/charges", "query": "limit=10", "proto": "HTTP/1.1", "status": 200, "bytes": 2326,
+			"latency": "12.5ms", "remote_ip": "127.0.0.1", "user_agent": `Mozilla/5.0 "X"`, "request_id": "r1",
+		},
+	}
+
+	b, err := (&AccessLogFormatter{}).Format(entry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `127.0.0.1 - - [26/Feb/2018:10:04:05 +0000] "GET /charges?limit=10 HTTP/1.1" 200 2326 "-" "Mozilla/5.0 \"X\""` + "\n"
+	if string(b) != expected {
+		t.Errorf("expected %q got %q", expected, b)
+	}
+
+	f := &AccessLogFormatter{Layout: AccessLogW3C}
+	b, _ = f.Format(entry)
+	expected = "#Version: 1.0\n#Date: 2018-02-26 10:04:05\n#Fields: " + w3cFields + "\n" +
+		`2018-02-26 10:04:05 127.0.0.1 GET /charges limit=10 200 2326 0.013 Mozilla/5.0+"X" -` + "\n"
+	if string(b) != expected {
+		t.Errorf("expected %q got %q", expected, b)
+	}
+	delete(entry.Data, "query")
+	entry.Data["bytes"] = 0
+	b, _ = f.Format(entry)
+	expected = `2018-02-26 10:04:05 127.0.0.1 GET /charges - 200 0 0.013 Mozilla/5.0+"X" -` + "\n"
+	if string(b) != expected {
+		t.Errorf("expected the directives once, got %q", b)
+	}
+
+	b, _ = f.Format(&logrus.Entry{Message: "not a request", Data: logrus.Fields{}})
+	if len(b) != 0 {
+		t.Errorf("expected other entries dropped, got %q", b)
+	}
+}
@@ -0,0 +1,128 @@
+package log
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/o3labs/openpoint/platform/errors"
+	"github.com/o3labs/openpoint/platform/models"
+)
+
+// LevelHandler reports and changes log levels at runtime, e.g. mounted at
+// /debug/loglevel.
+//
+// GET returns the global level, the level of every channel and the
+// SetLevelFor overrides. PUT takes a JSON body such as
+//
+//	{"level": "debug"}                             global level
+//	{"channel": "payments", "level": "debug"}      one channel
+//	{"pattern": "platform/*", "level": "debug"}    SetLevelFor override
+//	{"pattern": "platform/*", "level": ""}         ResetLevelFor
+type LevelHandler struct {
+	// Token required as "Authorization: Bearer <token>". Empty means the
+	// handler is open, so only leave it empty behind an internal listener.
+	Token string
+}
+
+type levelStatus struct {
+	Level    string            `json:"level"`
+	Channels map[string]string `json:"channels"`
+	Rules    map[string]string `json:"rules"`
+}
+
+type levelChange struct {
+	Level   string `json:"level"`
+	Channel string `json:"channel"`
+	Pattern string `json:"pattern"`
+}
+
+func (h *LevelHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !h.authorized(r) {
+		errors.Unauthorized().Write(w)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut:
+		change := levelChange{}
+		if err := json.NewDecoder(r.Body).Decode(&change); err != nil {
+			errors.BadRequest("%v", err).Write(w)
+			return
+		}
+		if err := applyLevelChange(change); err != nil {
+			err.Write(w)
+			return
+		}
+		Warn("Log level changed %+v", change)
+	default:
+		errors.NewError(http.StatusMethodNotAllowed, "Use GET or PUT", http.StatusText(http.StatusMethodNotAllowed)).Write(w)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	json.NewEncoder(w).Encode(models.NewSuccessResponse(currentLevels()))
+}
+
+func (h *LevelHandler) authorized(r *http.Request) bool {
+	return BearerAuthorized(r, h.Token)
+}
+
+// BearerAuthorized reports whether r carries token as a bearer token. An
+// empty token lets every request through.
+func BearerAuthorized(r *http.Request, token string) bool {
+	if token == "" {
+		return true
+	}
+	given := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return subtle.ConstantTimeCompare([]byte(given), []byte(token)) == 1
+}
+
+func applyLevelChange(change levelChange) *errors.ErrorModel {
+	if change.Pattern != "" && change.Level == "" {
+		ResetLevelFor(change.Pattern)
+		return nil
+	}
+	level, err := ParseLevel(change.Level)
+	if err != nil {
+		e := errors.BadRequest("%v", err)
+		return &e
+	}
+
+	switch {
+	case change.Pattern != "":
+		if err := SetLevelFor(change.Pattern, level); err != nil {
+			e := errors.BadRequest("Invalid pattern %v", err)
+			return &e
+		}
+	case change.Channel != "":
+		l, ok := lookupChannel(change.Channel)
+		if !ok {
+			e := errors.RecordNotFound("Channel %v not found", change.Channel)
+			return &e
+		}
+		l.SetLevel(level)
+	default:
+		SetLevel(level)
+	}
+	return nil
+}
+
+func currentLevels() levelStatus {
+	status := levelStatus{
+		Level:    GetLevel().String(),
+		Channels: map[string]string{},
+		Rules:    map[string]string{},
+	}
+	for _, name := range Channels() {
+		if l, ok := lookupChannel(name); ok {
+			status.Channels[name] = l.GetLevel().String()
+		}
+	}
+	for pattern, level := range LevelRules() {
+		status.Rules[pattern] = level.String()
+	}
+	return status
+}
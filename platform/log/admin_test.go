@@ -0,0 +1,68 @@
+package log
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	logrus "github.com/sirupsen/logrus"
+)
+
+func TestLevelHandler(t *testing.T) {
+	h := &LevelHandler{Token: "secret"}
+	Channel("admin.payments")
+	defer SetLevel(logrus.InfoLevel)
+	defer ResetLevelFor("platform/*")
+
+	serve := func(method, body string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, "/debug/loglevel", strings.NewReader(body))
+		r.Header.Set("Authorization", "Bearer secret")
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/debug/loglevel", nil))
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("expected the token to be required, got %v", w.Code)
+	}
+
+	for _, tt := range []struct {
+		method, body string
+		code         int
+	}{
+		{"PUT", `{"level": "debug"}`, http.StatusOK},
+		{"PUT", `{"channel": "admin.payments", "level": "warning"}`, http.StatusOK},
+		{"PUT", `{"pattern": "platform/*", "level": "trace"}`, http.StatusOK},
+		{"PUT", `{"channel": "admin.missing", "level": "debug"}`, http.StatusNotFound},
+		{"PUT", `{"level": "loud"}`, http.StatusBadRequest},
+		{"PUT", `{"level":`, http.StatusBadRequest},
+		{"POST", `{"level": "debug"}`, http.StatusMethodNotAllowed},
+	} {
+		if w := serve(tt.method, tt.body); w.Code != tt.code {
+			t.Errorf("%v %v: expected %v got %v %s", tt.method, tt.body, tt.code, w.Code, w.Body)
+		}
+	}
+
+	w = serve("GET", "")
+	out := struct {
+		Result struct {
+			Data levelStatus `json:"data"`
+		} `json:"result"`
+	}{}
+	if err := json.Unmarshal(w.Body.Bytes(), &out); err != nil {
+		t.Fatalf("%v %s", err, w.Body)
+	}
+	status := out.Result.Data
+	if status.Level != "debug" || status.Channels["admin.payments"] != "warning" || status.Rules["platform/*"] != "trace" {
+		t.Errorf("expected the changed levels, got %+v", status)
+	}
+
+	serve("PUT", `{"pattern": "platform/*", "level": ""}`)
+	if _, ok := LevelRules()["platform/*"]; ok {
+		t.Error("expected an empty level to reset the pattern")
+	}
+}
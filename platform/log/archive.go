@@ -0,0 +1,251 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"cloud.google.com/go/storage"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	"github.com/o3labs/openpoint/platform/config"
+)
+
+const (
+	defaultArchiveMaxRetries   = 5
+	defaultArchiveRetryBackoff = time.Second
+	defaultArchiveTimeout      = time.Minute
+	archiveTimeFormat          = "20060102T150405.000Z"
+)
+
+// ObjectStore puts log segments to object storage, see S3Store and GCSStore
+type ObjectStore interface {
+	Put(ctx context.Context, key string, body []byte) error
+}
+
+// S3Store puts objects to an S3 bucket
+type S3Store struct {
+	Bucket string
+
+	// StorageClass of the objects, e.g. STANDARD_IA. Defaults to the
+	// bucket's.
+	StorageClass string
+
+	Client s3iface.S3API
+}
+
+// NewS3Store returns a store putting to bucket with the AWS config of the
+// platform.
+func NewS3Store(bucket string) *S3Store {
+	return &S3Store{
+		Bucket: bucket,
+		Client: s3.New(session.New(config.AWSConfig())),
+	}
+}
+
+// Put uploads body to key
+func (s *S3Store) Put(ctx context.Context, key string, body []byte) error {
+	in := &s3.PutObjectInput{
+		Bucket:      aws.String(s.Bucket),
+		Key:         aws.String(key),
+		Body:        bytes.NewReader(body),
+		ContentType: aws.String(archiveContentType(key)),
+	}
+	if s.StorageClass != "" {
+		in.StorageClass = aws.String(s.StorageClass)
+	}
+	_, err := s.Client.PutObjectWithContext(ctx, in)
+	return err
+}
+
+// GCSStore puts objects to a Cloud Storage bucket
+type GCSStore struct {
+	Bucket string
+	Client *storage.Client
+}
+
+// NewGCSStore returns a store putting to bucket with the application default
+// credentials.
+func NewGCSStore(ctx context.Context, bucket string) (*GCSStore, error) {
+	client, err := storage.NewClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &GCSStore{Bucket: bucket, Client: client}, nil
+}
+
+// Put uploads body to key
+func (s *GCSStore) Put(ctx context.Context, key string, body []byte) error {
+	w := s.Client.Bucket(s.Bucket).Object(key).NewWriter(ctx)
+	w.ContentType = archiveContentType(key)
+	if _, err := w.Write(body); err != nil {
+		w.Close()
+		return err
+	}
+	return w.Close()
+}
+
+// archiveContentType returns the type of the segment named key. Compressed
+// segments aren't marked with a content encoding, so they are downloaded as
+// they were archived.
+func archiveContentType(key string) string {
+	switch {
+	case strings.HasSuffix(key, ".gz"):
+		return "application/gzip"
+	case strings.HasSuffix(key, ".zst"):
+		return "application/zstd"
+	}
+	return "text/plain; charset=utf-8"
+}
+
+// Archiver uploads log segments to object storage under keys partitioned by
+// the hour they were archived in, e.g.
+//
+//	logs/api/dt=2026-10-16/hour=13/web-1-20261016T130405.123Z-000042.log.gz
+//
+// so query engines like Athena or BigQuery prune partitions on dt and hour,
+// and lifecycle rules can expire or transition everything under the prefix.
+// Keys of a process sort in the order the segments were written.
+//
+// Every Write is uploaded as one segment, so write through a BatchWriter,
+// which writes a compressed batch at a time. Use UploadFile, or the Archive
+// field of RotatingFileWriter, for rotated files.
+type Archiver struct {
+	Store ObjectStore
+
+	// Prefix of the keys, without a trailing slash
+	Prefix string
+
+	// Name of the process in the keys. Defaults to the hostname.
+	Name string
+
+	// Extension of the segments written with Write. Defaults to .log.gz.
+	Extension string
+
+	// MaxRetries of a failed upload, backing off from RetryBackoff. Default
+	// to 5 and 1s.
+	MaxRetries   int
+	RetryBackoff time.Duration
+
+	// Timeout of an upload attempt. Defaults to 1m.
+	Timeout time.Duration
+
+	seq uint64
+}
+
+// NewArchiver returns an archiver uploading to store under prefix.
+func NewArchiver(store ObjectStore, prefix string) *Archiver {
+	name, _ := os.Hostname()
+	if name == "" {
+		name = "log"
+	}
+	return &Archiver{
+		Store:        store,
+		Prefix:       strings.TrimSuffix(prefix, "/"),
+		Name:         name,
+		Extension:    ".log.gz",
+		MaxRetries:   defaultArchiveMaxRetries,
+		RetryBackoff: defaultArchiveRetryBackoff,
+		Timeout:      defaultArchiveTimeout,
+	}
+}
+
+// OpenArchiver returns an archiver for a URL such as s3://bucket/prefix or
+// gs://bucket/prefix.
+func OpenArchiver(rawurl string) (*Archiver, error) {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return nil, err
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("log: archive %q without a bucket", rawurl)
+	}
+	var store ObjectStore
+	switch u.Scheme {
+	case "s3":
+		store = NewS3Store(u.Host)
+	case "gs":
+		if store, err = NewGCSStore(context.Background(), u.Host); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("log: unknown archive %q", rawurl)
+	}
+	return NewArchiver(store, strings.TrimPrefix(u.Path, "/")), nil
+}
+
+// Write uploads p as a segment.
+func (a *Archiver) Write(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	ext := a.Extension
+	if ext == "" {
+		ext = ".log.gz"
+	}
+	if err := a.put(a.key(now(), ext), p); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// UploadFile uploads the file at filename as a segment and removes it once
+// uploaded. The segment keeps the .gz suffix of a compressed file.
+func (a *Archiver) UploadFile(filename string) error {
+	body, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+	ext := ".log"
+	if strings.HasSuffix(filename, ".gz") {
+		ext = ".log.gz"
+	}
+	if err := a.put(a.key(now(), ext), body); err != nil {
+		return err
+	}
+	return os.Remove(filename)
+}
+
+// key returns the key of the next segment archived at t.
+func (a *Archiver) key(t time.Time, ext string) string {
+	t = t.UTC()
+	seq := atomic.AddUint64(&a.seq, 1)
+	name := fmt.Sprintf("%s-%s-%06d%s", a.Name, t.Format(archiveTimeFormat), seq, ext)
+	return path.Join(a.Prefix, "dt="+t.Format("2006-01-02"), "hour="+t.Format("15"), name)
+}
+
+// put uploads body to key, retrying failed uploads.
+func (a *Archiver) put(key string, body []byte) error {
+	timeout := a.Timeout
+	if timeout <= 0 {
+		timeout = defaultArchiveTimeout
+	}
+	backoff := a.RetryBackoff
+	if backoff <= 0 {
+		backoff = defaultArchiveRetryBackoff
+	}
+	var err error
+	for attempt := 0; ; attempt++ {
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		err = a.Store.Put(ctx, key, body)
+		cancel()
+		if err == nil || attempt >= a.MaxRetries {
+			break
+		}
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+	if err != nil {
+		return fmt.Errorf("log: archive %s: %v", key, err)
+	}
+	return nil
+}
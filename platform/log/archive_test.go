@@ -0,0 +1,132 @@
+package log
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memoryStore keeps the objects put to it, failing the first fail puts
+type memoryStore struct {
+	mu      sync.Mutex
+	fail    int
+	objects map[string]string
+}
+
+func (s *memoryStore) Put(ctx context.Context, key string, body []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.fail > 0 {
+		s.fail--
+		return errors.New("503 slow down")
+	}
+	s.objects[key] = string(body)
+	return nil
+}
+
+func TestArchiver(t *testing.T) {
+	SetClock(&fixedClock{t: time.Date(2026, 10, 16, 15, 4, 5, 0, time.FixedZone("CEST", 7200))})
+	defer SetClock(nil)
+
+	store := &memoryStore{fail: 1, objects: map[string]string{}}
+	a := NewArchiver(store, "logs/api/")
+	a.Name = "web-1"
+	a.RetryBackoff = time.Millisecond
+	if _, err := a.Write([]byte("batch")); err != nil {
+		t.Fatal(err)
+	}
+	key := "logs/api/dt=2026-10-16/hour=13/web-1-20261016T130405.000Z-000001.log.gz"
+	if store.objects[key] != "batch" {
+		t.Errorf("expected the segment to be retried and put to %s, got %v", key, store.objects)
+	}
+
+	store.fail = 2
+	a.MaxRetries = 1
+	if _, err := a.Write([]byte("batch")); err == nil {
+		t.Errorf("expected an error once the retries are exhausted")
+	}
+
+	dir, err := ioutil.TempDir("", "archive")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "api.log.2026-10-16T15-04-05.000.gz")
+	if err := ioutil.WriteFile(filename, []byte("rotated"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.UploadFile(filename); err != nil {
+		t.Fatal(err)
+	}
+	if store.objects["logs/api/dt=2026-10-16/hour=13/web-1-20261016T130405.000Z-000003.log.gz"] != "rotated" {
+		t.Errorf("expected the rotated file to be put, got %v", store.objects)
+	}
+	if _, err := os.Stat(filename); !os.IsNotExist(err) {
+		t.Errorf("expected the uploaded file to be removed")
+	}
+
+	if _, err := OpenArchiver("ftp://bucket/logs"); err == nil {
+		t.Errorf("expected an error for an unknown archive")
+	}
+}
+
+// blockingStore puts objects once release is closed
+type blockingStore struct {
+	memoryStore
+	release chan struct{}
+}
+
+func (s *blockingStore) Put(ctx context.Context, key string, body []byte) error {
+	select {
+	case <-s.release:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	return s.memoryStore.Put(ctx, key, body)
+}
+
+func TestRotatingFileWriterArchive(t *testing.T) {
+	dir, err := ioutil.TempDir("", "archive")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	store := &blockingStore{memoryStore: memoryStore{objects: map[string]string{}}, release: make(chan struct{})}
+	w := &RotatingFileWriter{Filename: filepath.Join(dir, "app.log"), MaxBackups: 1}
+	w.Archive = NewArchiver(store, "logs")
+	w.Write([]byte("first\n"))
+	w.Rotate()
+	w.Write([]byte("second\n"))
+	w.Rotate()
+
+	// uploads don't hold the writer or the shutdown
+	written := make(chan struct{})
+	go func() {
+		w.Write([]byte("third\n"))
+		close(written)
+	}()
+	select {
+	case <-written:
+	case <-time.After(time.Second):
+		t.Fatal("expected writes not to wait for the upload")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := Shutdown(ctx); err == nil {
+		t.Error("expected the shutdown to give up on the pending uploads")
+	}
+
+	close(store.release)
+	w.Close()
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	if len(store.objects) != 2 {
+		t.Errorf("expected both backups uploaded rather than removed, got %v", store.objects)
+	}
+}
@@ -0,0 +1,112 @@
+package log
+
+import (
+	"errors"
+	"io"
+	"sync"
+)
+
+var ErrWriterClosed = errors.New("log: writer closed")
+
+type asyncItem struct {
+	b       []byte
+	flushed chan error
+}
+
+// AsyncWriter queues formatted entries in a bounded buffer and writes them to
+// the underlying writer on a background goroutine, so logging does not block
+// on slow disks or networks. Write only blocks once the buffer is full.
+type AsyncWriter struct {
+	w     io.Writer
+	items chan asyncItem
+	done  chan struct{}
+
+	mu     sync.RWMutex
+	closed bool
+	err    error
+
+	registration sinkRegistration
+}
+
+// NewAsyncWriter starts an AsyncWriter around w holding up to size entries.
+func NewAsyncWriter(w io.Writer, size int) *AsyncWriter {
+	a := &AsyncWriter{
+		w:     w,
+		items: make(chan asyncItem, size),
+		done:  make(chan struct{}),
+	}
+	a.registration.register(a, SinkQueue)
+	go a.run()
+	return a
+}
+
+func (a *AsyncWriter) run() {
+	defer close(a.done)
+	var err error
+	for item := range a.items {
+		if item.flushed != nil {
+			item.flushed <- err
+			err = nil
+			continue
+		}
+		if _, e := a.w.Write(item.b); e != nil && err == nil {
+			err = e
+		}
+	}
+	a.err = err
+}
+
+// Write queues a copy of p. The formatter's buffer is reused by the caller
+// once Write returns, so p itself can't be kept.
+func (a *AsyncWriter) Write(p []byte) (int, error) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	if a.closed {
+		return 0, ErrWriterClosed
+	}
+	b := make([]byte, len(p))
+	copy(b, p)
+	a.items <- asyncItem{b: b}
+	return len(p), nil
+}
+
+// Flush blocks until every entry queued before the call has been written and
+// returns the first write error since the previous flush.
+func (a *AsyncWriter) Flush() error {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	if a.closed {
+		return ErrWriterClosed
+	}
+	flushed := make(chan error, 1)
+	a.items <- asyncItem{flushed: flushed}
+	return <-flushed
+}
+
+// Close drains the queue, stops the background goroutine and closes the
+// underlying writer if it is an io.Closer.
+func (a *AsyncWriter) Close() error {
+	a.registration.release()
+	a.mu.Lock()
+	if a.closed {
+		a.mu.Unlock()
+		return ErrWriterClosed
+	}
+	a.closed = true
+	close(a.items)
+	a.mu.Unlock()
+
+	<-a.done
+	err := a.err
+	if c, ok := a.w.(io.Closer); ok {
+		if cerr := c.Close(); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+// QueueLen returns the number of entries waiting to be written.
+func (a *AsyncWriter) QueueLen() int {
+	return len(a.items)
+}
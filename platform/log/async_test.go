@@ -0,0 +1,118 @@
+package log
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+// closeCollectWriter is a collectWriter recording its Close
+type closeCollectWriter struct {
+	collectWriter
+	closed bool
+}
+
+func (w *closeCollectWriter) Close() error {
+	w.closed = true
+	return nil
+}
+
+func TestAsyncWriter(t *testing.T) {
+	w := &closeCollectWriter{}
+	a := NewAsyncWriter(w, 8)
+	for i := 0; i < 100; i++ {
+		fmt.Fprintf(a, "level=info msg=%d\n", i)
+	}
+	if err := a.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	w.mu.Lock()
+	flushed := len(w.lines)
+	w.mu.Unlock()
+	if flushed != 100 {
+		t.Errorf("expected Flush to write the 100 queued entries, got %d", flushed)
+	}
+
+	for i := 100; i < 200; i++ {
+		fmt.Fprintf(a, "level=info msg=%d\n", i)
+	}
+	if err := a.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if len(w.lines) != 200 || w.lines[199] != "level=info msg=199\n" {
+		t.Errorf("expected Close to write the 200 entries in order, got %d", len(w.lines))
+	}
+	if !w.closed {
+		t.Error("expected Close to close the underlying writer")
+	}
+}
+
+func TestAsyncWriterCopies(t *testing.T) {
+	w := &collectWriter{}
+	a := NewAsyncWriter(w, 8)
+	b := []byte("level=info msg=first\n")
+	a.Write(b)
+	copy(b, "level=info msg=reuse\n")
+	a.Close()
+	if strings.Join(w.lines, "") != "level=info msg=first\n" {
+		t.Errorf("expected the entry as written, got %q", w.lines)
+	}
+}
+
+func TestAsyncWriterFlushError(t *testing.T) {
+	a := NewAsyncWriter(failingWriter{}, 8)
+	a.Write([]byte("level=info msg=lost\n"))
+	if err := a.Flush(); err == nil || err.Error() != "disk full" {
+		t.Errorf("expected the write error, got %v", err)
+	}
+	if err := a.Flush(); err != nil {
+		t.Errorf("expected no error since the previous Flush, got %v", err)
+	}
+	a.Close()
+}
+
+func TestAsyncWriterFull(t *testing.T) {
+	w := blockedWriter{release: make(chan struct{})}
+	a := NewAsyncWriter(w, 1)
+	dropped := Dropped()
+
+	written := make(chan struct{})
+	go func() {
+		// one being written, one queued and one waiting for room
+		for i := 0; i < 3; i++ {
+			fmt.Fprintf(a, "level=info msg=%d\n", i)
+		}
+		close(written)
+	}()
+	select {
+	case <-written:
+		t.Fatal("expected Write to wait for room in a full queue")
+	case <-time.After(50 * time.Millisecond):
+	}
+	if Dropped() != dropped {
+		t.Errorf("expected a full queue not to drop entries, %d were", Dropped()-dropped)
+	}
+
+	close(w.release)
+	<-written
+	if err := a.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAsyncWriterClosed(t *testing.T) {
+	a := NewAsyncWriter(&collectWriter{}, 8)
+	if err := a.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.Write([]byte("level=info msg=late\n")); err != ErrWriterClosed {
+		t.Errorf("expected ErrWriterClosed from Write, got %v", err)
+	}
+	if err := a.Flush(); err != ErrWriterClosed {
+		t.Errorf("expected ErrWriterClosed from Flush, got %v", err)
+	}
+	if err := a.Close(); err != ErrWriterClosed {
+		t.Errorf("expected ErrWriterClosed from a second Close, got %v", err)
+	}
+}
@@ -0,0 +1,291 @@
+package log
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	logrus "github.com/sirupsen/logrus"
+)
+
+// AuditChannel is the channel audit records are also logged on.
+const AuditChannel = "audit"
+
+// AuditLog writes compliance-sensitive actions to an append-only file, one
+// JSON record per line. Every record carries the hash of the previous one and
+// its own hash over both, an HMAC-SHA256 when a key is given, so editing,
+// removing or reordering records breaks the chain VerifyAuditLog checks.
+// Removing records from the end can only be detected by comparing the last
+// hash with one kept elsewhere.
+type AuditLog struct {
+	key     []byte
+	channel *Logger
+
+	mu   sync.Mutex
+	file *os.File
+	seq  uint64
+	prev string
+
+	// size of the file up to the last complete record, and the error that
+	// left the file unusable when a failed write couldn't be rolled back
+	size   int64
+	broken error
+}
+
+// auditRecord is the line format of an AuditLog. Hash covers the record
+// marshaled without it.
+type auditRecord struct {
+	Seq    uint64          `json:"seq"`
+	Time   string          `json:"time"`
+	Action string          `json:"action"`
+	Fields json.RawMessage `json:"fields,omitempty"`
+	Prev   string          `json:"prev"`
+	Hash   string          `json:"hash,omitempty"`
+}
+
+// OpenAuditLog opens the audit file at path, continuing the chain of the
+// records it already holds. key signs the records, without it they are
+// chained with plain SHA-256, which only detects accidental changes.
+//
+// A last record without its newline was torn by a crash during Record,
+// which never returned for it, and is removed.
+func OpenAuditLog(path string, key []byte) (*AuditLog, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+	a := &AuditLog{key: key, channel: Channel(AuditChannel), file: file}
+
+	torn, err := a.truncateTorn()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	if torn > 0 {
+		reportf("Removed the incomplete last audit record of %v, %d bytes", path, torn)
+	}
+	line, err := lastLine(file)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	if len(line) > 0 {
+		rec := auditRecord{}
+		if err := json.Unmarshal(line, &rec); err != nil {
+			file.Close()
+			return nil, fmt.Errorf("log: last audit record of %v is invalid, %v", path, err)
+		}
+		a.seq = rec.Seq
+		a.prev = rec.Hash
+	}
+	return a, nil
+}
+
+// Record appends action with fields to the audit file and logs it on the
+// audit channel. Unlike logging it returns the error, so callers can refuse
+// an action that can't be audited.
+func (a *AuditLog) Record(action string, fields logrus.Fields) error {
+	values := make(map[string]interface{}, len(fields))
+	for k, v := range fields {
+		if err, ok := v.(error); ok {
+			v = err.Error()
+		}
+		values[k] = v
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.broken != nil {
+		return fmt.Errorf("log: audit log unusable after a failed write, %v", a.broken)
+	}
+
+	rec := auditRecord{
+		Seq:    a.seq + 1,
+		Time:   now().UTC().Format(time.RFC3339Nano),
+		Action: action,
+		Prev:   a.prev,
+	}
+	if len(values) > 0 {
+		b, err := json.Marshal(values)
+		if err != nil {
+			return fmt.Errorf("Failed to marshal fields to JSON, %v", err)
+		}
+		rec.Fields = b
+	}
+	line, err := a.seal(&rec)
+	if err != nil {
+		return err
+	}
+	if _, err := a.file.Write(line); err != nil {
+		a.rollback()
+		return err
+	}
+	if err := a.file.Sync(); err != nil {
+		a.rollback()
+		return err
+	}
+	a.seq = rec.Seq
+	a.prev = rec.Hash
+	a.size += int64(len(line))
+
+	a.channel.WithFields(fields).WithField("audit_seq", rec.Seq).Info(action)
+	return nil
+}
+
+// seal sets the hash of rec and returns its line.
+func (a *AuditLog) seal(rec *auditRecord) ([]byte, error) {
+	rec.Hash = ""
+	body, err := json.Marshal(rec)
+	if err != nil {
+		return nil, err
+	}
+	rec.Hash = auditHash(a.key, body)
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return nil, err
+	}
+	return append(line, '\n'), nil
+}
+
+// rollback truncates what a failed Record wrote, so the file ends with a
+// complete record, and marks the log unusable when it can't.
+func (a *AuditLog) rollback() {
+	if err := a.file.Truncate(a.size); err != nil {
+		a.broken = err
+	}
+}
+
+// truncateTorn removes a last record without its newline and sets size,
+// returning the number of bytes removed.
+func (a *AuditLog) truncateTorn() (int64, error) {
+	info, err := a.file.Stat()
+	if err != nil {
+		return 0, err
+	}
+	size := info.Size()
+	end := size
+	b := make([]byte, 4096)
+	for end > 0 {
+		n := int64(len(b))
+		if end < n {
+			n = end
+		}
+		if _, err := a.file.ReadAt(b[:n], end-n); err != nil {
+			return 0, err
+		}
+		if i := bytes.LastIndexByte(b[:n], '\n'); i >= 0 {
+			end = end - n + int64(i) + 1
+			break
+		}
+		end -= n
+	}
+	a.size = end
+	if end == size {
+		return 0, nil
+	}
+	return size - end, a.file.Truncate(end)
+}
+
+func (a *AuditLog) Close() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.file.Close()
+}
+
+func auditHash(key, body []byte) string {
+	var h hash.Hash
+	if len(key) > 0 {
+		h = hmac.New(sha256.New, key)
+	} else {
+		h = sha256.New()
+	}
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// VerifyAuditLog checks the chain of the records read from r with key and
+// returns the hash of the last record, to compare with one kept elsewhere.
+// The error names the first record that doesn't verify.
+func VerifyAuditLog(r io.Reader, key []byte) (string, error) {
+	a := &AuditLog{key: key}
+	br := bufio.NewReader(r)
+	for line := 1; ; line++ {
+		b, err := br.ReadBytes('\n')
+		if err == io.EOF {
+			if len(b) > 0 {
+				return a.prev, fmt.Errorf("log: audit line %d is incomplete", line)
+			}
+			return a.prev, nil
+		}
+		if err != nil {
+			return a.prev, err
+		}
+
+		rec := auditRecord{}
+		if err := json.Unmarshal(b, &rec); err != nil {
+			return a.prev, fmt.Errorf("log: audit line %d is invalid, %v", line, err)
+		}
+		if rec.Seq != a.seq+1 {
+			return a.prev, fmt.Errorf("log: audit line %d has sequence %d, expected %d", line, rec.Seq, a.seq+1)
+		}
+		if rec.Prev != a.prev {
+			return a.prev, fmt.Errorf("log: audit record %d doesn't follow record %d", rec.Seq, a.seq)
+		}
+		given := rec.Hash
+		if _, err := a.seal(&rec); err != nil {
+			return a.prev, err
+		}
+		if !hmac.Equal([]byte(given), []byte(rec.Hash)) {
+			return a.prev, fmt.Errorf("log: audit record %d was modified", rec.Seq)
+		}
+		a.seq = rec.Seq
+		a.prev = rec.Hash
+	}
+}
+
+// lastLine returns the last line of file without its newline, reading
+// backwards so large files aren't read whole.
+func lastLine(file *os.File) ([]byte, error) {
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	end := info.Size()
+	for end > 0 {
+		b := []byte{0}
+		if _, err := file.ReadAt(b, end-1); err != nil {
+			return nil, err
+		}
+		if b[0] != '\n' {
+			break
+		}
+		end--
+	}
+
+	line := []byte{}
+	chunk := make([]byte, 4096)
+	for pos := end; pos > 0; {
+		n := int64(len(chunk))
+		if pos < n {
+			n = pos
+		}
+		pos -= n
+		if _, err := file.ReadAt(chunk[:n], pos); err != nil {
+			return nil, err
+		}
+		if i := bytes.LastIndexByte(chunk[:n], '\n'); i >= 0 {
+			return append(append([]byte{}, chunk[i+1:n]...), line...), nil
+		}
+		line = append(append([]byte{}, chunk[:n]...), line...)
+	}
+	return line, nil
+}
@@ -0,0 +1,131 @@
+package log
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	logrus "github.com/sirupsen/logrus"
+)
+
+func TestAuditLog(t *testing.T) {
+	dir, err := ioutil.TempDir("", "audit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "audit.log")
+	key := []byte("secret")
+
+	a, err := OpenAuditLog(path, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Record("user.login", logrus.Fields{"user_id": 7}); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Record("refund.issued", logrus.Fields{"amount": 1000, "note": "<tag> & more"}); err != nil {
+		t.Fatal(err)
+	}
+	a.Close()
+
+	// reopening continues the chain
+	a, err = OpenAuditLog(path, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Record("user.logout", nil); err != nil {
+		t.Fatal(err)
+	}
+	a.Close()
+
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	last, err := VerifyAuditLog(bytes.NewReader(b), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if last == "" || !strings.Contains(string(b), last) {
+		t.Errorf("expected the hash of the last record, got %q", last)
+	}
+
+	if _, err := VerifyAuditLog(bytes.NewReader(b), []byte("other")); err == nil {
+		t.Error("expected records signed with another key to fail")
+	}
+
+	tampered := bytes.Replace(b, []byte(`"amount":1000`), []byte(`"amount":9000`), 1)
+	if _, err := VerifyAuditLog(bytes.NewReader(tampered), key); err == nil || !strings.Contains(err.Error(), "record 2 was modified") {
+		t.Errorf("expected record 2 to be reported, got %v", err)
+	}
+
+	lines := bytes.SplitAfter(b, []byte("\n"))
+	removed := append(append([]byte{}, lines[0]...), lines[2]...)
+	if _, err := VerifyAuditLog(bytes.NewReader(removed), key); err == nil {
+		t.Error("expected a removed record to break the chain")
+	}
+}
+
+func TestAuditLogTornRecord(t *testing.T) {
+	dir, err := ioutil.TempDir("", "audit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "audit.log")
+	key := []byte("secret")
+
+	a, err := OpenAuditLog(path, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Record("user.login", logrus.Fields{"user_id": 7}); err != nil {
+		t.Fatal(err)
+	}
+	a.Close()
+
+	// a crash in the middle of a write
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Write([]byte(`{"seq":2,"time":"2017-07-14T02:40:00Z","act`))
+	f.Close()
+
+	a, err = OpenAuditLog(path, key)
+	if err != nil {
+		t.Fatalf("expected the torn record to be removed, got %v", err)
+	}
+	if err := a.Record("user.logout", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// a write that fails and can't be rolled back leaves the log unusable
+	file := a.file
+	if a.file, err = os.Open(path); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Record("refund.issued", nil); err == nil {
+		t.Fatal("expected the failed write to be returned")
+	}
+	if err := a.Record("refund.issued", nil); err == nil || !strings.Contains(err.Error(), "unusable") {
+		t.Errorf("expected the log to be unusable, got %v", err)
+	}
+	a.Close()
+	file.Close()
+
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := VerifyAuditLog(bytes.NewReader(b), key); err != nil {
+		t.Errorf("expected the chain to verify, got %v", err)
+	}
+	if n := bytes.Count(b, []byte("\n")); n != 2 {
+		t.Errorf("expected 2 records, got %d", n)
+	}
+}
@@ -0,0 +1,249 @@
+package log
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+const (
+	defaultBatchSize     = 1000
+	defaultBatchInterval = 10 * time.Second
+	batchQueueSize       = 4
+)
+
+// Compression of the batches of a BatchWriter
+type Compression int
+
+const (
+	CompressGzip Compression = iota
+	CompressZstd
+)
+
+// ParseCompression parses gzip or zstd.
+func ParseCompression(s string) (Compression, error) {
+	switch strings.ToLower(s) {
+	case "", "gzip":
+		return CompressGzip, nil
+	case "zstd":
+		return CompressZstd, nil
+	}
+	return 0, fmt.Errorf("log: unknown compression %q", s)
+}
+
+// extension is the file suffix of a batch
+func (c Compression) extension() string {
+	if c == CompressZstd {
+		return ".zst"
+	}
+	return ".gz"
+}
+
+// BatchWriter collects entries, one per line as the JSON and logfmt
+// formatters write them, and writes them to the underlying writer in
+// compressed batches of Size entries, or what was collected when Interval
+// passes. Verbose debug logs compress to a fraction of their size.
+//
+// Every batch is a complete gzip member or zstd frame written with a single
+// Write, so a file of batches decompresses as one stream with zcat or
+// zstdcat, and a writer putting an object to storage per Write gets objects
+// that decompress on their own. Batches are compressed and written on a
+// background goroutine, Write only blocks once batchQueueSize batches are
+// waiting for a slow writer.
+type BatchWriter struct {
+	w           io.Writer
+	compression Compression
+	size        int
+
+	mu     sync.Mutex
+	buf    bytes.Buffer
+	n      int
+	closed bool
+
+	batches chan batch
+	queued  int64
+	stop    chan struct{}
+	done    chan struct{}
+
+	registration sinkRegistration
+}
+
+// batch is n entries for the background goroutine to write, flushed gets
+// the error of the write when set
+type batch struct {
+	p       []byte
+	n       int
+	flushed chan error
+}
+
+// NewBatchWriter starts a BatchWriter around w. size defaults to 1000
+// entries and interval to 10s.
+func NewBatchWriter(w io.Writer, compression Compression, size int, interval time.Duration) *BatchWriter {
+	if size <= 0 {
+		size = defaultBatchSize
+	}
+	if interval <= 0 {
+		interval = defaultBatchInterval
+	}
+	b := &BatchWriter{
+		w:           w,
+		compression: compression,
+		size:        size,
+		batches:     make(chan batch, batchQueueSize),
+		stop:        make(chan struct{}),
+		done:        make(chan struct{}),
+	}
+	b.registration.register(b, SinkQueue)
+	go b.run()
+	go b.tick(interval)
+	return b
+}
+
+func (b *BatchWriter) run() {
+	defer close(b.done)
+	for bt := range b.batches {
+		err := b.write(bt)
+		atomic.AddInt64(&b.queued, -int64(bt.n))
+		if bt.flushed != nil {
+			bt.flushed <- err
+		} else if err != nil {
+			reportf("Failed to write log batch because %+v", err)
+		}
+	}
+}
+
+// tick queues what was collected every interval
+func (b *BatchWriter) tick(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			b.mu.Lock()
+			if !b.closed && b.n > 0 {
+				b.batches <- b.take(nil)
+			}
+			b.mu.Unlock()
+		case <-b.stop:
+			return
+		}
+	}
+}
+
+// take returns the entries collected as a batch and starts a new one,
+// called with mu held.
+func (b *BatchWriter) take(flushed chan error) batch {
+	bt := batch{p: b.buf.Bytes(), n: b.n, flushed: flushed}
+	b.buf = bytes.Buffer{}
+	b.n = 0
+	atomic.AddInt64(&b.queued, int64(bt.n))
+	return bt
+}
+
+// Write adds p to the batch and queues the batch once it is full. It
+// returns ErrWriterClosed after Close.
+func (b *BatchWriter) Write(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return 0, ErrWriterClosed
+	}
+	b.buf.Write(p)
+	if p[len(p)-1] != '\n' {
+		b.buf.WriteByte('\n')
+	}
+	b.n++
+	if b.n >= b.size {
+		b.batches <- b.take(nil)
+	}
+	return len(p), nil
+}
+
+// Flush compresses and writes the entries collected so far, after the
+// batches queued before.
+func (b *BatchWriter) Flush() error {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return ErrWriterClosed
+	}
+	flushed := make(chan error, 1)
+	b.batches <- b.take(flushed)
+	b.mu.Unlock()
+	return <-flushed
+}
+
+func (b *BatchWriter) write(bt batch) error {
+	if bt.n == 0 {
+		return nil
+	}
+	compressed := &bytes.Buffer{}
+	var zw io.WriteCloser
+	switch b.compression {
+	case CompressZstd:
+		enc, err := zstd.NewWriter(compressed)
+		if err != nil {
+			recordDropped(uint64(bt.n))
+			return err
+		}
+		zw = enc
+	default:
+		zw = gzip.NewWriter(compressed)
+	}
+	if _, err := zw.Write(bt.p); err != nil {
+		recordDropped(uint64(bt.n))
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		recordDropped(uint64(bt.n))
+		return err
+	}
+	if _, err := b.w.Write(compressed.Bytes()); err != nil {
+		recordDropped(uint64(bt.n))
+		return err
+	}
+	return nil
+}
+
+// QueueLen returns the number of entries waiting to be written.
+func (b *BatchWriter) QueueLen() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.n + int(atomic.LoadInt64(&b.queued))
+}
+
+// Close writes the last batch, stops the background goroutines and closes
+// the underlying writer if it is an io.Closer.
+func (b *BatchWriter) Close() error {
+	b.registration.release()
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return ErrWriterClosed
+	}
+	b.closed = true
+	flushed := make(chan error, 1)
+	b.batches <- b.take(flushed)
+	close(b.batches)
+	b.mu.Unlock()
+
+	close(b.stop)
+	err := <-flushed
+	<-b.done
+	if c, ok := b.w.(io.Closer); ok {
+		if cerr := c.Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
@@ -0,0 +1,107 @@
+package log
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBatchWriter(t *testing.T) {
+	w := &collectWriter{}
+	b := NewBatchWriter(w, CompressGzip, 2, time.Hour)
+	for _, line := range []string{`{"msg":"cache miss"}` + "\n", `{"msg":"charge succeeded"}`, `{"msg":"retrying"}` + "\n"} {
+		if _, err := b.Write([]byte(line)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	deadline := time.Now().Add(time.Second)
+	for b.QueueLen() > 1 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	w.mu.Lock()
+	written := len(w.lines)
+	w.mu.Unlock()
+	if written != 1 || b.QueueLen() != 1 {
+		t.Errorf("expected a full batch to be written, got %d batches and %d queued", written, b.QueueLen())
+	}
+	if err := b.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if len(w.lines) != 2 {
+		t.Fatalf("expected Close to write the last batch, got %d batches", len(w.lines))
+	}
+
+	r, err := gzip.NewReader(bytes.NewBufferString(strings.Join(w.lines, "")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `{"msg":"cache miss"}` + "\n" + `{"msg":"charge succeeded"}` + "\n" + `{"msg":"retrying"}` + "\n"
+	if string(out) != expected {
+		t.Errorf("expected %q got %q", expected, out)
+	}
+	if err := b.Close(); err != ErrWriterClosed {
+		t.Errorf("expected ErrWriterClosed, got %v", err)
+	}
+	if _, err := b.Write([]byte("late\n")); err != ErrWriterClosed {
+		t.Errorf("expected writes after Close to fail with ErrWriterClosed, got %v", err)
+	}
+}
+
+// blockedWriter blocks every Write until release is closed
+type blockedWriter struct {
+	release chan struct{}
+}
+
+func (w blockedWriter) Write(p []byte) (int, error) {
+	<-w.release
+	return len(p), nil
+}
+
+func TestBatchWriterDoesNotWriteInline(t *testing.T) {
+	w := blockedWriter{release: make(chan struct{})}
+	b := NewBatchWriter(w, CompressGzip, 1, time.Hour)
+
+	written := make(chan struct{})
+	go func() {
+		b.Write([]byte("level=info msg=first\n"))
+		b.Write([]byte("level=info msg=second\n"))
+		close(written)
+	}()
+	select {
+	case <-written:
+	case <-time.After(time.Second):
+		t.Fatal("expected Write not to wait for the underlying writer")
+	}
+	close(w.release)
+	if err := b.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestBatchWriterInterval(t *testing.T) {
+	w := &collectWriter{}
+	b := NewBatchWriter(w, CompressGzip, 100, 10*time.Millisecond)
+	defer b.Close()
+	b.Write([]byte("level=info msg=\"charge succeeded\"\n"))
+
+	deadline := time.Now().Add(time.Second)
+	for b.QueueLen() > 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if len(w.lines) != 1 {
+		t.Errorf("expected the interval to write the batch, got %d batches", len(w.lines))
+	}
+
+	if _, err := ParseCompression("lz4"); err == nil {
+		t.Errorf("expected an error for an unknown compression")
+	}
+}
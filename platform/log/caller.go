@@ -0,0 +1,117 @@
+package log
+
+import (
+	"fmt"
+	"reflect"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+var (
+	logPackage    = reflect.TypeOf(ChannelTextFormatter{}).PkgPath()
+	logrusPackage = "github.com/sirupsen/logrus"
+)
+
+// callerFrame returns the first frame outside logrus and this package, then
+// skips skip more frames for callers that wrap the log functions themselves.
+func callerFrame(skip int) (runtime.Frame, bool) {
+	pc := make([]uintptr, 32)
+	n := runtime.Callers(2, pc)
+	frames := runtime.CallersFrames(pc[:n])
+
+	found := false
+	for {
+		frame, more := frames.Next()
+		if found || !isLogFrame(frame) {
+			found = true
+			if skip <= 0 {
+				return frame, true
+			}
+			skip--
+		}
+		if !more {
+			return runtime.Frame{}, false
+		}
+	}
+}
+
+// callerPackages caches the package callerPackage finds at each return
+// address, "" when it is inside logrus or this package.
+var callerPackages sync.Map
+
+// callerPackage returns the package of the first frame outside logrus and
+// this package, like callerFrame, symbolizing each return address once.
+func callerPackage() (string, bool) {
+	var pc [32]uintptr
+	n := runtime.Callers(2, pc[:])
+	for _, p := range pc[:n] {
+		pkg, ok := callerPackages.Load(p)
+		if !ok {
+			pkg = framePackage(p)
+			callerPackages.Store(p, pkg)
+		}
+		if pkg != "" {
+			return pkg.(string), true
+		}
+	}
+	return "", false
+}
+
+// framePackage returns the package of the first frame at pc outside logrus
+// and this package, there are several when calls were inlined.
+func framePackage(pc uintptr) string {
+	frames := runtime.CallersFrames([]uintptr{pc})
+	for {
+		frame, more := frames.Next()
+		if !isLogFrame(frame) {
+			return funcPackage(frame.Function)
+		}
+		if !more {
+			return ""
+		}
+	}
+}
+
+func isLogFrame(frame runtime.Frame) bool {
+	pkg := funcPackage(frame.Function)
+	if strings.HasPrefix(pkg, logrusPackage) {
+		return true
+	}
+	return pkg == logPackage && !strings.HasSuffix(frame.File, "_test.go")
+}
+
+// funcPackage returns the import path of a function name as reported by
+// runtime, e.g. "github.com/o3labs/openpoint/platform/log.(*Logger).Info".
+func funcPackage(function string) string {
+	slash := strings.LastIndex(function, "/")
+	if i := strings.Index(function[slash+1:], "."); i >= 0 {
+		return function[:slash+1+i]
+	}
+	return function
+}
+
+// trimCallerFile strips the first matching prefix from file. Without
+// prefixes the path is trimmed up to the GOPATH src directory.
+func trimCallerFile(file string, prefixes []string) string {
+	for _, p := range prefixes {
+		if strings.HasPrefix(file, p) {
+			return strings.TrimPrefix(strings.TrimPrefix(file, p), "/")
+		}
+	}
+	if len(prefixes) == 0 {
+		if i := strings.LastIndex(file, "/src/"); i >= 0 {
+			return file[i+len("/src/"):]
+		}
+	}
+	return file
+}
+
+// formatCaller returns "file:line" and the function name without its import path.
+func formatCaller(frame runtime.Frame, prefixes []string) (string, string) {
+	function := frame.Function
+	if i := strings.LastIndex(function, "/"); i >= 0 {
+		function = function[i+1:]
+	}
+	return fmt.Sprintf("%s:%d", trimCallerFile(frame.File, prefixes), frame.Line), function
+}
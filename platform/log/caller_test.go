@@ -0,0 +1,19 @@
+package log
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestReportCaller(t *testing.T) {
+	b := &bytes.Buffer{}
+	l := Channel("caller")
+	l.SetOutput(b)
+	l.SetFormatter(&ChannelTextFormatter{DisableTimestamp: true, ReportCaller: true})
+
+	l.Info("hello")
+	if !strings.Contains(b.String(), "caller_test.go:") || !strings.Contains(b.String(), "func=log.TestReportCaller") {
+		t.Errorf("expected caller of the test, got %q", b.String())
+	}
+}
@@ -23,7 +23,11 @@ type ChannelLogger struct {
 
 func Init(path string) {
 
-	logrus.SetFormatter(&ChannelTextFormatter{TimestampFormat: "2006-01-02 15:04:05", FullTimestamp: true})
+	if config.Env.Log.Formatter == "dev" {
+		logrus.SetFormatter(&DevFormatter{})
+	} else {
+		logrus.SetFormatter(&ChannelTextFormatter{TimestampFormat: "2006-01-02 15:04:05", FullTimestamp: true})
+	}
 
 	if config.Env.Name != config.LocalEnv {
 
@@ -35,14 +39,14 @@ func Init(path string) {
 		)
 
 		if err != nil {
-			Errorf("Failed to log to file because %+v", err)
+			reportf("Failed to log to file because %+v", err)
 			return
 		}
 
 		Info("Started log to file")
 
 		logrus.SetOutput(writer)
-		logrus.SetLevel(logrus.WarnLevel)
+		SetLevel(logrus.WarnLevel)
 		logrus.SetFormatter(&ChannelJSONFormatter{})
 
 	}
@@ -50,47 +54,90 @@ func Init(path string) {
 }
 
 func Info(format string, args ...interface{}) {
-	logrus.WithFields(logrus.Fields{}).Info(fmt.Sprintf(format, args...))
+	if !packageEnabled(logrus.InfoLevel) {
+		return
+	}
+	stamp(logrus.WithFields(logrus.Fields{})).Info(fmt.Sprintf(format, args...))
 }
 
 func Warn(format string, args ...interface{}) {
-	logrus.WithFields(logrus.Fields{}).Warn(fmt.Sprintf(format, args...))
+	if !packageEnabled(logrus.WarnLevel) {
+		return
+	}
+	stamp(logrus.WithFields(logrus.Fields{})).Warn(fmt.Sprintf(format, args...))
 }
 
 func Error(err error) {
-	logrus.WithFields(logrus.Fields{"error": err}).Error()
+	if !packageEnabled(logrus.ErrorLevel) {
+		return
+	}
+	stamp(logrus.WithFields(logrus.Fields{"error": err})).Error()
 }
 
 func Errorf(format string, args ...interface{}) {
-	logrus.WithFields(logrus.Fields{"error": fmt.Sprintf(format, args...)}).Error()
+	if !packageEnabled(logrus.ErrorLevel) {
+		return
+	}
+	stamp(logrus.WithFields(logrus.Fields{"error": fmt.Sprintf(format, args...)})).Error()
 }
 
 func ErrorHttpRequest(status int, duration time.Duration, request *http.Request) {
+	if !packageEnabled(logrus.ErrorLevel) {
+		return
+	}
 	// https://golang.org/src/net/http/request.go
 	headerErr := ""
 	header, err := json.Marshal(request.Header)
 	if err == nil {
 		headerErr = string(header)
 	}
-	logrus.WithFields(logrus.Fields{"error": fmt.Sprintf("%v", status), "duration": fmt.Sprintf("%v", duration), "method": request.Method, "requestURI": request.RequestURI, "header": headerErr}).Error()
+	stamp(logrus.WithFields(logrus.Fields{"error": fmt.Sprintf("%v", status), "duration": fmt.Sprintf("%v", duration), "method": request.Method, "requestURI": request.RequestURI, "header": headerErr})).Error()
 }
 
 func DebugMessage(format string, args ...interface{}) {
-	logrus.WithFields(logrus.Fields{"dubug": fmt.Sprintf(format, args...)}).Debug()
+	if !packageEnabled(logrus.DebugLevel) {
+		return
+	}
+	stamp(logrus.WithFields(logrus.Fields{"dubug": fmt.Sprintf(format, args...)})).Debug()
+}
+
+// Trace logs wire-level detail, such as request and response bodies, below
+// Debug. Enable it for a single package or channel with SetLevelFor so Debug
+// users aren't flooded.
+func Trace(format string, args ...interface{}) {
+	if !packageEnabled(logrus.TraceLevel) {
+		return
+	}
+	stamp(logrus.WithFields(logrus.Fields{})).Trace(fmt.Sprintf(format, args...))
+}
+
+// TraceEnabled reports whether Trace logs for the calling package, to skip
+// building a dump nobody will see.
+func TraceEnabled() bool {
+	return packageEnabled(logrus.TraceLevel)
 }
 
 func Panicf(format string, args ...interface{}) {
-	logrus.WithFields(logrus.Fields{"error": fmt.Sprintf(format, args...)}).Error()
+	if !packageEnabled(logrus.ErrorLevel) {
+		return
+	}
+	stamp(logrus.WithFields(logrus.Fields{"error": fmt.Sprintf(format, args...)})).Error()
 }
 
 func Panic(err error) {
+	if !packageEnabled(logrus.ErrorLevel) {
+		return
+	}
 	// logrus panic Exit(1)
-	logrus.WithFields(logrus.Fields{"panic": err}).Error()
+	stamp(logrus.WithFields(logrus.Fields{"panic": err})).Error()
 }
 
 func Fatal(err error) {
+	if !packageEnabled(logrus.ErrorLevel) {
+		return
+	}
 	// logrus fatal Exit(1)
-	logrus.WithFields(logrus.Fields{"fatal": err}).Error()
+	stamp(logrus.WithFields(logrus.Fields{"fatal": err})).Error()
 }
 
 func Printf(format string, args ...interface{}) {
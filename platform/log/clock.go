@@ -0,0 +1,98 @@
+package log
+
+import (
+	"sync/atomic"
+	"time"
+
+	logrus "github.com/sirupsen/logrus"
+)
+
+// Clock tells the time to the log package: the time of the entries logged
+// through it, the rotation of files and the rate limits. Tests replace it
+// with SetClock to get deterministic output, see logtest.Clock.
+type Clock interface {
+	Now() time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+// SystemClock is the clock of the machine, used by default
+var SystemClock Clock = systemClock{}
+
+// clockState holds the clock and zone in an atomic.Value, which wants the
+// same concrete type on every store
+type clockState struct {
+	clock    Clock
+	location *time.Location
+}
+
+var currentClock atomic.Value
+
+func init() {
+	currentClock.Store(clockState{clock: SystemClock})
+}
+
+// SetClock replaces the clock of the package, nil restores SystemClock.
+func SetClock(c Clock) {
+	if c == nil {
+		c = SystemClock
+	}
+	s := currentClock.Load().(clockState)
+	s.clock = c
+	currentClock.Store(s)
+}
+
+// SetTimeLocation makes the formatters write timestamps in loc, such as
+// time.UTC, instead of the zone of the entry time, the local zone unless
+// the entry was given a time. nil restores the zone of the entry time.
+func SetTimeLocation(loc *time.Location) {
+	s := currentClock.Load().(clockState)
+	s.location = loc
+	currentClock.Store(s)
+}
+
+// now returns the time of the package clock.
+func now() time.Time {
+	return currentClock.Load().(clockState).clock.Now()
+}
+
+// entryTime returns the time of entry in the zone set by SetTimeLocation.
+func entryTime(entry *logrus.Entry) time.Time {
+	if loc := currentClock.Load().(clockState).location; loc != nil {
+		return entry.Time.In(loc)
+	}
+	return entry.Time
+}
+
+// stamp gives entry the time of the package clock when it isn't the
+// system clock, which logrus uses when the entry has no time.
+func stamp(entry *logrus.Entry) *logrus.Entry {
+	if c := currentClock.Load().(clockState).clock; c != SystemClock {
+		return entry.WithTime(c.Now())
+	}
+	return entry
+}
+
+// ClockHook stamps entries with the time of Clock, for loggers whose
+// entries aren't logged through this package.
+type ClockHook struct {
+	// Clock defaults to the clock of the package, see SetClock
+	Clock Clock
+}
+
+func (h *ClockHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (h *ClockHook) Fire(entry *logrus.Entry) error {
+	if h.Clock != nil {
+		entry.Time = h.Clock.Now()
+	} else {
+		entry.Time = now()
+	}
+	return nil
+}
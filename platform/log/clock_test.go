@@ -0,0 +1,89 @@
+package log
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	logrus "github.com/sirupsen/logrus"
+)
+
+type fixedClock struct {
+	t time.Time
+}
+
+func (c *fixedClock) Now() time.Time {
+	return c.t
+}
+
+func TestClock(t *testing.T) {
+	clock := &fixedClock{t: time.Date(2024, 3, 1, 12, 0, 0, 0, time.FixedZone("CET", 3600))}
+	SetClock(clock)
+	defer SetClock(nil)
+
+	b := &bytes.Buffer{}
+	c := Channel("clock")
+	c.SetOutput(b)
+	c.SetFormatter(&LogfmtFormatter{})
+	c.SetLevel(logrus.InfoLevel)
+
+	c.Info("tick")
+	expected := `time=2024-03-01T12:00:00+01:00 level=info msg=tick channel=clock` + "\n"
+	if b.String() != expected {
+		t.Errorf("expected %q got %q", expected, b.String())
+	}
+
+	SetTimeLocation(time.UTC)
+	defer SetTimeLocation(nil)
+	b.Reset()
+	c.Info("tock")
+	expected = `time=2024-03-01T11:00:00Z level=info msg=tock channel=clock` + "\n"
+	if b.String() != expected {
+		t.Errorf("expected %q got %q", expected, b.String())
+	}
+
+	// entries of other loggers get the time of the clock from the hook
+	l := logrus.New()
+	l.Out = b
+	l.Formatter = &LogfmtFormatter{}
+	l.AddHook(&ClockHook{})
+	b.Reset()
+	l.Info("hook")
+	expected = `time=2024-03-01T11:00:00Z level=info msg=hook` + "\n"
+	if b.String() != expected {
+		t.Errorf("expected %q got %q", expected, b.String())
+	}
+}
+
+func TestClockRotation(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rotate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	clock := &fixedClock{t: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)}
+	SetClock(clock)
+	defer SetClock(nil)
+
+	w := &RotatingFileWriter{Filename: filepath.Join(dir, "app.log"), MaxAge: 24 * time.Hour}
+	defer w.Close()
+	w.Write([]byte("first\n"))
+	clock.t = clock.t.Add(23 * time.Hour)
+	w.Write([]byte("second\n"))
+	if backups, _ := w.backups(); len(backups) != 0 {
+		t.Errorf("expected no rotation before MaxAge, got %v", backups)
+	}
+	clock.t = clock.t.Add(time.Hour)
+	w.Write([]byte("third\n"))
+	backups, err := w.backups()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(backups) != 1 || filepath.Base(backups[0]) != "app.log."+clock.t.Format(backupTimeFormat) {
+		t.Errorf("expected a backup named after the clock, got %v", backups)
+	}
+}
@@ -0,0 +1,294 @@
+package log
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs/cloudwatchlogsiface"
+	"github.com/o3labs/openpoint/platform/config"
+	logrus "github.com/sirupsen/logrus"
+)
+
+const (
+	// PutLogEvents limits, every event counts 26 bytes on top of its message
+	cloudWatchMaxBatchBytes  = 1048576
+	cloudWatchMaxBatchEvents = 10000
+	cloudWatchEventOverhead  = 26
+	cloudWatchMaxEventBytes  = 262144 - cloudWatchEventOverhead
+
+	// the events of a batch can't span more than 24 hours
+	cloudWatchMaxBatchSpan = 24 * time.Hour
+
+	defaultCloudWatchBatchWait  = 5 * time.Second
+	defaultCloudWatchMaxRetries = 5
+	cloudWatchMinBackoff        = 500 * time.Millisecond
+)
+
+// CloudWatchHook batches entries and sends them to an AWS CloudWatch Logs
+// stream, creating the group and stream when they don't exist, so Lambda and
+// ECS deployments don't need a log shipping sidecar.
+type CloudWatchHook struct {
+	Group  string
+	Stream string
+
+	// Formatter renders the event message. Defaults to ChannelJSONFormatter.
+	Formatter logrus.Formatter
+
+	BatchWait  time.Duration
+	MaxRetries int
+
+	Client cloudwatchlogsiface.CloudWatchLogsAPI
+
+	entries chan *cloudwatchlogs.InputLogEvent
+	done    chan struct{}
+	once    sync.Once
+
+	mu     sync.RWMutex
+	closed bool
+
+	// token is the sequence token of the next PutLogEvents, only used by the
+	// run goroutine
+	token *string
+
+	registration sinkRegistration
+}
+
+// NewCloudWatchHook returns a hook sending to stream in group with the AWS
+// config of the platform. BatchWait and MaxRetries may be changed until the
+// first entry is fired. Call Close on shutdown to send what is still batched.
+func NewCloudWatchHook(group, stream string) (*CloudWatchHook, error) {
+	return newCloudWatchHook(cloudwatchlogs.New(session.New(config.AWSConfig())), group, stream)
+}
+
+func newCloudWatchHook(client cloudwatchlogsiface.CloudWatchLogsAPI, group, stream string) (*CloudWatchHook, error) {
+	h := &CloudWatchHook{
+		Group:      group,
+		Stream:     stream,
+		Formatter:  &ChannelJSONFormatter{},
+		BatchWait:  defaultCloudWatchBatchWait,
+		MaxRetries: defaultCloudWatchMaxRetries,
+		Client:     client,
+		entries:    make(chan *cloudwatchlogs.InputLogEvent, cloudWatchMaxBatchEvents),
+		done:       make(chan struct{}),
+	}
+	if err := h.createStream(); err != nil {
+		return nil, err
+	}
+	h.registration.register(h, SinkQueue)
+	return h, nil
+}
+
+func (h *CloudWatchHook) createStream() error {
+	_, err := h.Client.CreateLogGroup(&cloudwatchlogs.CreateLogGroupInput{
+		LogGroupName: aws.String(h.Group),
+	})
+	if err != nil && !isAWSErrorCode(err, cloudwatchlogs.ErrCodeResourceAlreadyExistsException) {
+		return err
+	}
+	_, err = h.Client.CreateLogStream(&cloudwatchlogs.CreateLogStreamInput{
+		LogGroupName:  aws.String(h.Group),
+		LogStreamName: aws.String(h.Stream),
+	})
+	if err == nil {
+		return nil
+	}
+	if !isAWSErrorCode(err, cloudwatchlogs.ErrCodeResourceAlreadyExistsException) {
+		return err
+	}
+
+	// the stream exists, continue from its sequence token
+	out, err := h.Client.DescribeLogStreams(&cloudwatchlogs.DescribeLogStreamsInput{
+		LogGroupName:        aws.String(h.Group),
+		LogStreamNamePrefix: aws.String(h.Stream),
+	})
+	if err != nil {
+		return err
+	}
+	for _, s := range out.LogStreams {
+		if aws.StringValue(s.LogStreamName) == h.Stream {
+			h.token = s.UploadSequenceToken
+		}
+	}
+	return nil
+}
+
+func (h *CloudWatchHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (h *CloudWatchHook) Fire(entry *logrus.Entry) error {
+	b, err := h.Formatter.Format(entry)
+	if err != nil {
+		return err
+	}
+	message := strings.TrimRight(string(b), "\n")
+	if message == "" {
+		return nil
+	}
+	if len(message) > cloudWatchMaxEventBytes {
+		message = strings.ToValidUTF8(message[:cloudWatchMaxEventBytes], "")
+	}
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	if h.closed {
+		// still attached to the logger after Close
+		recordDropped(1)
+		return nil
+	}
+	h.start()
+	select {
+	case h.entries <- &cloudwatchlogs.InputLogEvent{
+		Message:   aws.String(message),
+		Timestamp: aws.Int64(entry.Time.UnixNano() / int64(time.Millisecond)),
+	}:
+	default:
+		recordDropped(1)
+	}
+	return nil
+}
+
+// Close sends the pending batch and stops the hook. Entries fired after
+// Close are dropped.
+func (h *CloudWatchHook) Close() error {
+	h.registration.release()
+	h.mu.Lock()
+	if !h.closed {
+		h.closed = true
+		close(h.entries)
+	}
+	h.mu.Unlock()
+	h.start()
+	<-h.done
+	return nil
+}
+
+// start runs the batching goroutine once, after the settings are final.
+func (h *CloudWatchHook) start() {
+	h.once.Do(func() {
+		go h.run()
+	})
+}
+
+func (h *CloudWatchHook) run() {
+	defer close(h.done)
+
+	ticker := time.NewTicker(h.BatchWait)
+	defer ticker.Stop()
+
+	batch := []*cloudwatchlogs.InputLogEvent{}
+	size := 0
+	push := func() {
+		if len(batch) == 0 {
+			return
+		}
+		for _, events := range cloudWatchSpans(batch) {
+			if err := h.push(events); err != nil {
+				recordDropped(uint64(len(events)))
+				reportf("Failed to send %d entries to CloudWatch because %+v", len(events), err)
+			}
+		}
+		batch = []*cloudwatchlogs.InputLogEvent{}
+		size = 0
+	}
+
+	for {
+		select {
+		case e, ok := <-h.entries:
+			if !ok {
+				push()
+				return
+			}
+			eventSize := len(*e.Message) + cloudWatchEventOverhead
+			if size+eventSize > cloudWatchMaxBatchBytes || len(batch) >= cloudWatchMaxBatchEvents {
+				push()
+			}
+			batch = append(batch, e)
+			size += eventSize
+		case <-ticker.C:
+			push()
+		}
+	}
+}
+
+// cloudWatchSpans sorts batch in chronological order, as CloudWatch
+// requires, and splits it into batches spanning at most 24 hours.
+func cloudWatchSpans(batch []*cloudwatchlogs.InputLogEvent) [][]*cloudwatchlogs.InputLogEvent {
+	sort.SliceStable(batch, func(i, j int) bool {
+		return *batch[i].Timestamp < *batch[j].Timestamp
+	})
+	maxSpan := int64(cloudWatchMaxBatchSpan / time.Millisecond)
+	var spans [][]*cloudwatchlogs.InputLogEvent
+	start := 0
+	for i, e := range batch {
+		if *e.Timestamp-*batch[start].Timestamp >= maxSpan {
+			spans = append(spans, batch[start:i])
+			start = i
+		}
+	}
+	return append(spans, batch[start:])
+}
+
+// push sends a sorted batch, retrying with exponential backoff on
+// throttling and unavailability and with the expected token after a
+// sequence token mismatch.
+func (h *CloudWatchHook) push(batch []*cloudwatchlogs.InputLogEvent) error {
+	backoff := cloudWatchMinBackoff
+	for attempt := 0; ; attempt++ {
+		out, err := h.Client.PutLogEvents(&cloudwatchlogs.PutLogEventsInput{
+			LogGroupName:  aws.String(h.Group),
+			LogStreamName: aws.String(h.Stream),
+			LogEvents:     batch,
+			SequenceToken: h.token,
+		})
+		if err == nil {
+			h.token = out.NextSequenceToken
+			return nil
+		}
+
+		switch e := err.(type) {
+		case *cloudwatchlogs.InvalidSequenceTokenException:
+			h.token = e.ExpectedSequenceToken
+			if attempt < h.MaxRetries {
+				continue
+			}
+		case *cloudwatchlogs.DataAlreadyAcceptedException:
+			h.token = e.ExpectedSequenceToken
+			return nil
+		}
+		if !isCloudWatchRetryable(err) || attempt >= h.MaxRetries {
+			return err
+		}
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+func isCloudWatchRetryable(err error) bool {
+	aerr, ok := err.(awserr.Error)
+	if !ok {
+		// network errors
+		return true
+	}
+	switch aerr.Code() {
+	case "ThrottlingException", cloudwatchlogs.ErrCodeServiceUnavailableException, "RequestError":
+		return true
+	}
+	return false
+}
+
+func isAWSErrorCode(err error, code string) bool {
+	aerr, ok := err.(awserr.Error)
+	return ok && aerr.Code() == code
+}
+
+// QueueLen returns the number of entries waiting to be written.
+func (h *CloudWatchHook) QueueLen() int {
+	return len(h.entries)
+}
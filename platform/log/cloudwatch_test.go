@@ -0,0 +1,138 @@
+package log
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs/cloudwatchlogsiface"
+	logrus "github.com/sirupsen/logrus"
+)
+
+type fakeCloudWatch struct {
+	cloudwatchlogsiface.CloudWatchLogsAPI
+
+	mu   sync.Mutex
+	puts []*cloudwatchlogs.PutLogEventsInput
+	// errs are returned by the first PutLogEvents calls
+	errs []error
+}
+
+func (c *fakeCloudWatch) CreateLogGroup(*cloudwatchlogs.CreateLogGroupInput) (*cloudwatchlogs.CreateLogGroupOutput, error) {
+	return &cloudwatchlogs.CreateLogGroupOutput{}, nil
+}
+
+func (c *fakeCloudWatch) CreateLogStream(*cloudwatchlogs.CreateLogStreamInput) (*cloudwatchlogs.CreateLogStreamOutput, error) {
+	return &cloudwatchlogs.CreateLogStreamOutput{}, nil
+}
+
+func (c *fakeCloudWatch) PutLogEvents(in *cloudwatchlogs.PutLogEventsInput) (*cloudwatchlogs.PutLogEventsOutput, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.puts = append(c.puts, in)
+	if len(c.errs) > 0 {
+		err := c.errs[0]
+		c.errs = c.errs[1:]
+		return nil, err
+	}
+	return &cloudwatchlogs.PutLogEventsOutput{NextSequenceToken: aws.String("next")}, nil
+}
+
+func cloudWatchEntry(t time.Time, msg string) *logrus.Entry {
+	entry := logrus.NewEntry(logrus.StandardLogger())
+	entry.Level = logrus.InfoLevel
+	entry.Time = t
+	entry.Message = msg
+	return entry
+}
+
+func TestCloudWatchHook(t *testing.T) {
+	client := &fakeCloudWatch{}
+	h, err := newCloudWatchHook(client, "openpoint", "api")
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2017, 7, 14, 2, 40, 0, 0, time.UTC)
+	h.Fire(cloudWatchEntry(start.Add(time.Second), "second"))
+	h.Fire(cloudWatchEntry(start, "first"))
+	if err := h.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(client.puts) != 1 {
+		t.Fatalf("expected 1 PutLogEvents, got %d", len(client.puts))
+	}
+	events := client.puts[0].LogEvents
+	if len(events) != 2 || *events[0].Timestamp > *events[1].Timestamp {
+		t.Fatalf("expected 2 events in chronological order, got %v", events)
+	}
+	if aws.StringValue(h.token) != "next" {
+		t.Errorf("expected the next sequence token to be kept, got %q", aws.StringValue(h.token))
+	}
+}
+
+func TestCloudWatchHookSplitsBatchesOver24h(t *testing.T) {
+	client := &fakeCloudWatch{}
+	h, err := newCloudWatchHook(client, "openpoint", "api")
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2017, 7, 14, 2, 40, 0, 0, time.UTC)
+	h.Fire(cloudWatchEntry(start, "first"))
+	h.Fire(cloudWatchEntry(start.Add(time.Hour), "second"))
+	h.Fire(cloudWatchEntry(start.Add(25*time.Hour), "third"))
+	h.Close()
+
+	if len(client.puts) != 2 {
+		t.Fatalf("expected 2 PutLogEvents, got %d", len(client.puts))
+	}
+	if len(client.puts[0].LogEvents) != 2 || len(client.puts[1].LogEvents) != 1 {
+		t.Errorf("expected batches of 2 and 1 events, got %d and %d", len(client.puts[0].LogEvents), len(client.puts[1].LogEvents))
+	}
+}
+
+func TestCloudWatchHookFireAfterClose(t *testing.T) {
+	h, err := newCloudWatchHook(&fakeCloudWatch{}, "openpoint", "api")
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.Close()
+	dropped := Dropped()
+	if err := h.Fire(cloudWatchEntry(time.Now(), "late")); err != nil {
+		t.Fatal(err)
+	}
+	if Dropped() != dropped+1 {
+		t.Errorf("expected the entry to be dropped")
+	}
+}
+
+func TestCloudWatchHookRetries(t *testing.T) {
+	client := &fakeCloudWatch{errs: []error{awserr.New("ThrottlingException", "rate exceeded", nil)}}
+	h, err := newCloudWatchHook(client, "openpoint", "api")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// set after the constructor, before the first entry
+	h.BatchWait = 10 * time.Millisecond
+	h.MaxRetries = 1
+	defer h.Close()
+
+	started := time.Now()
+	h.Fire(cloudWatchEntry(started, "first"))
+	puts := 0
+	for puts < 2 && time.Since(started) < defaultCloudWatchBatchWait/2 {
+		time.Sleep(time.Millisecond)
+		client.mu.Lock()
+		puts = len(client.puts)
+		client.mu.Unlock()
+	}
+	if puts != 2 {
+		t.Fatalf("expected the throttled batch sent again before the default BatchWait, got %d PutLogEvents", puts)
+	}
+	if wait := time.Since(started); wait < cloudWatchMinBackoff {
+		t.Errorf("expected the retry after %v, got %v", cloudWatchMinBackoff, wait)
+	}
+}
@@ -0,0 +1,76 @@
+// Command logdecrypt writes the entries of log files encrypted by an
+// EncryptingWriter, such as file outputs with a keyFile, to stdout:
+//
+//	logdecrypt -keys /etc/openpoint/log.keys /var/log/openpoint.log | logview
+//	logdecrypt -keys /etc/openpoint/log.keys /var/log/openpoint.log.2018-02-26T10-04-05.000.gz
+//
+// Compressed backups are decompressed first. Reads stdin when no file is
+// given.
+package main
+
+import (
+	"compress/gzip"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/o3labs/openpoint/platform/log"
+)
+
+func main() {
+	keyFile := flag.String("keys", "", "file with the keys, see log.LoadEncryptionKeys")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: logdecrypt -keys file [file...]\n\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if *keyFile == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+	keys, err := log.LoadEncryptionKeys(*keyFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "logdecrypt: %v\n", err)
+		os.Exit(2)
+	}
+
+	files := flag.Args()
+	if len(files) == 0 {
+		if err := log.DecryptLogs(os.Stdout, os.Stdin, keys); err != nil {
+			fmt.Fprintf(os.Stderr, "logdecrypt: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	status := 0
+	for _, file := range files {
+		if err := decryptFile(file, keys); err != nil {
+			fmt.Fprintf(os.Stderr, "logdecrypt: %s: %v\n", file, err)
+			status = 1
+		}
+	}
+	os.Exit(status)
+}
+
+func decryptFile(file string, keys []log.EncryptionKey) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	if strings.HasSuffix(file, ".gz") {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return err
+		}
+		defer gz.Close()
+		r = gz
+	}
+	return log.DecryptLogs(os.Stdout, r, keys)
+}
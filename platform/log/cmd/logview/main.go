@@ -0,0 +1,253 @@
+// Command logview renders NDJSON logs, such as the output of the json
+// formatter in production, the way the text formatter prints them on a
+// terminal:
+//
+//	logview -level warn -match 'channel=payments' /var/log/openpoint.log
+//	kubectl logs -f api | logview -fields request_id,status
+//	logview -f /var/log/openpoint.log
+//
+// Lines that aren't JSON objects are printed as they are.
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ssh/terminal"
+
+	"github.com/o3labs/openpoint/platform/log"
+	"github.com/o3labs/openpoint/platform/log/logquery"
+	logrus "github.com/sirupsen/logrus"
+)
+
+const pollInterval = 250 * time.Millisecond
+
+func main() {
+	level := flag.String("level", "trace", "most verbose level shown")
+	match := flag.String("match", "", "only show entries matching this expression, e.g. 'channel=payments AND level>=warn AND since=1h'")
+	fields := flag.String("fields", "", "comma separated fields to show, all by default")
+	follow := flag.Bool("f", false, "keep reading the files as they grow")
+	flag.BoolVar(follow, "follow", false, "same as -f")
+	color := flag.String("color", "auto", "auto, always or never")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: logview [flags] [file...]\n\nReads stdin when no file is given.\n\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	v, err := newViewer(os.Stdout, *level, *match, *fields, *color)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "logview: %v\n", err)
+		os.Exit(2)
+	}
+
+	files := flag.Args()
+	if len(files) == 0 {
+		if err := v.copy(os.Stdin); err != nil {
+			fmt.Fprintf(os.Stderr, "logview: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if *follow {
+		wg := sync.WaitGroup{}
+		for _, file := range files {
+			wg.Add(1)
+			go func(file string) {
+				defer wg.Done()
+				if err := v.follow(file); err != nil {
+					fmt.Fprintf(os.Stderr, "logview: %v\n", err)
+				}
+			}(file)
+		}
+		wg.Wait()
+		return
+	}
+
+	status := 0
+	for _, file := range files {
+		f, err := os.Open(file)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "logview: %v\n", err)
+			status = 1
+			continue
+		}
+		err = v.copy(f)
+		f.Close()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "logview: %v\n", err)
+			status = 1
+		}
+	}
+	os.Exit(status)
+}
+
+type viewer struct {
+	out       io.Writer
+	formatter *log.ChannelTextFormatter
+	level     logrus.Level
+	query     *logquery.Query
+	fields    map[string]bool
+
+	mu sync.Mutex
+}
+
+func newViewer(out io.Writer, level, match, fields, color string) (*viewer, error) {
+	v := &viewer{
+		out:       out,
+		formatter: &log.ChannelTextFormatter{FullTimestamp: true, TimestampFormat: "2006-01-02 15:04:05.000"},
+	}
+
+	var err error
+	if v.level, err = log.ParseLevel(level); err != nil {
+		return nil, err
+	}
+	if match != "" {
+		if v.query, err = logquery.Parse(match); err != nil {
+			return nil, err
+		}
+	}
+	if fields != "" {
+		v.fields = map[string]bool{}
+		for _, f := range strings.Split(fields, ",") {
+			v.fields[strings.TrimSpace(f)] = true
+		}
+	}
+
+	switch color {
+	case "always":
+		v.formatter.ForceColors = true
+	case "never":
+		v.formatter.DisableColors = true
+	case "auto":
+		if f, ok := out.(*os.File); ok && terminal.IsTerminal(int(f.Fd())) && os.Getenv("NO_COLOR") == "" {
+			v.formatter.ForceColors = true
+		} else {
+			v.formatter.DisableColors = true
+		}
+	default:
+		return nil, fmt.Errorf("unknown color mode %q", color)
+	}
+	return v, nil
+}
+
+// copy renders every line of r.
+func (v *viewer) copy(r io.Reader) error {
+	br := bufio.NewReader(r)
+	for {
+		line, err := br.ReadBytes('\n')
+		if len(line) > 0 {
+			if werr := v.line(line); werr != nil {
+				return werr
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// follow renders the lines of file as they are appended, starting over when
+// the file is truncated or replaced by a rotation.
+func (v *viewer) follow(file string) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer func() { f.Close() }()
+
+	br := bufio.NewReader(f)
+	var partial []byte
+	for {
+		line, err := br.ReadBytes('\n')
+		partial = append(partial, line...)
+		if err == nil {
+			if err := v.line(partial); err != nil {
+				return err
+			}
+			partial = partial[:0]
+			continue
+		}
+		if err != io.EOF {
+			return err
+		}
+
+		time.Sleep(pollInterval)
+		current, err := f.Stat()
+		if err != nil {
+			return err
+		}
+		offset, err := f.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return err
+		}
+		latest, err := os.Stat(file)
+		switch {
+		case err == nil && !os.SameFile(current, latest):
+			// rotated, read what is left of the old file first
+			if offset < current.Size() {
+				continue
+			}
+			next, err := os.Open(file)
+			if err != nil {
+				continue
+			}
+			f.Close()
+			f = next
+		case err == nil && latest.Size() < offset:
+			// truncated
+			if _, err := f.Seek(0, io.SeekStart); err != nil {
+				return err
+			}
+		default:
+			continue
+		}
+		br.Reset(f)
+		partial = partial[:0]
+	}
+}
+
+// line renders one line if it passes the filters.
+func (v *viewer) line(line []byte) error {
+	line = bytes.TrimRight(line, "\r\n")
+	if len(bytes.TrimSpace(line)) == 0 {
+		return nil
+	}
+
+	var b []byte
+	entry, ok := logquery.ParseEntry(line)
+	if !ok {
+		b = append(line, '\n')
+	} else {
+		if entry.Level > v.level || (v.query != nil && !v.query.Matches(entry)) {
+			return nil
+		}
+		if v.fields != nil {
+			for k := range entry.Data {
+				if !v.fields[k] && k != log.LevelKey {
+					delete(entry.Data, k)
+				}
+			}
+		}
+		var err error
+		if b, err = v.formatter.Format(entry); err != nil {
+			return err
+		}
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	_, err := v.out.Write(b)
+	return err
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestViewer(t *testing.T) {
+	out := &bytes.Buffer{}
+	v, err := newViewer(out, "info", "channel=payments", "channel,amount", "never")
+	if err != nil {
+		t.Fatal(err)
+	}
+	in := strings.Join([]string{
+		`{"date":"2018-02-26T10:04:05Z","level":"info","message":"charge","channel":"payments","amount":1000,"card":"visa"}`,
+		`{"date":"2018-02-26T10:04:05Z","level":"debug","message":"too verbose","channel":"payments"}`,
+		`{"time":"2018-02-26T10:04:06Z","level":"info","msg":"other channel","channel":"http"}`,
+		`{"time":"2018-02-26T10:04:07Z","level":"audit","msg":"refund","channel":"payments"}`,
+		`panic: not json`,
+		``,
+	}, "\n")
+	if err := v.copy(strings.NewReader(in)); err != nil {
+		t.Fatal(err)
+	}
+	expected := `time="2018-02-26 10:04:05.000" level=info msg=charge amount=1000 channel=payments` + "\n" +
+		`time="2018-02-26 10:04:07.000" level=audit msg=refund channel=payments` + "\n" +
+		"panic: not json\n"
+	if out.String() != expected {
+		t.Errorf("expected %q got %q", expected, out.String())
+	}
+
+	for _, args := range [][]string{{"loud", "", "", "never"}, {"info", "a=b AND", "", "never"}, {"info", "", "", "sometimes"}} {
+		if _, err := newViewer(out, args[0], args[1], args[2], args[3]); err == nil {
+			t.Errorf("expected an error for %q", args)
+		}
+	}
+}
+
+func TestViewerFollow(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logview")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "app.log")
+	ioutil.WriteFile(file, []byte(`{"level":"info","msg":"first"}`+"\n"), 0644)
+
+	out := &syncBuffer{}
+	v, _ := newViewer(out, "trace", "", "", "never")
+	v.formatter.DisableTimestamp = true
+	go v.follow(file)
+
+	f, _ := os.OpenFile(file, os.O_APPEND|os.O_WRONLY, 0644)
+	f.WriteString(`{"level":"warning","msg":"sec`)
+	time.Sleep(2 * pollInterval)
+	f.WriteString(`ond"}` + "\n")
+	f.Close()
+
+	// rotated
+	os.Rename(file, file+".1")
+	ioutil.WriteFile(file, []byte(`{"level":"error","msg":"third"}`+"\n"), 0644)
+
+	expected := "level=info msg=first\nlevel=warning msg=second\nlevel=error msg=third\n"
+	deadline := time.Now().Add(5 * time.Second)
+	for out.String() != expected && time.Now().Before(deadline) {
+		time.Sleep(pollInterval)
+	}
+	if out.String() != expected {
+		t.Errorf("expected %q got %q", expected, out.String())
+	}
+}
+
+type syncBuffer struct {
+	mu sync.Mutex
+	b  bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.b.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.b.String()
+}
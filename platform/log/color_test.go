@@ -0,0 +1,52 @@
+package log
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"testing"
+
+	logrus "github.com/sirupsen/logrus"
+)
+
+func TestColorEnvironment(t *testing.T) {
+	format := func(w io.Writer) string {
+		l := logrus.New()
+		l.Out = w
+		entry := logrus.NewEntry(l).WithField("user", 7)
+		entry.Level, entry.Message = logrus.InfoLevel, "ready"
+		b, err := (&ChannelTextFormatter{DisableTimestamp: true, MessageWidth: MessageWidthNone}).Format(entry)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(b)
+	}
+	b := &bytes.Buffer{}
+	plain := "level=info msg=ready user=7\n"
+	colored := "\x1b[36mINFO\x1b[0m ready \x1b[36muser\x1b[0m=7\n"
+
+	if s := format(b); s != plain {
+		t.Errorf("expected no colors on a buffer, got %q", s)
+	}
+	if s := format(WriterHint{Writer: b, Terminal: true}); s != colored {
+		t.Errorf("expected colors with the writer hint, got %q", s)
+	}
+
+	os.Setenv("CLICOLOR_FORCE", "1")
+	defer os.Unsetenv("CLICOLOR_FORCE")
+	os.Setenv("CLICOLOR", "0")
+	defer os.Unsetenv("CLICOLOR")
+	if s := format(b); s != colored {
+		t.Errorf("expected CLICOLOR_FORCE to turn colors on, got %q", s)
+	}
+	os.Setenv("NO_COLOR", "1")
+	defer os.Unsetenv("NO_COLOR")
+	if s := format(b); s != plain {
+		t.Errorf("expected NO_COLOR to win, got %q", s)
+	}
+	os.Unsetenv("NO_COLOR")
+	os.Unsetenv("CLICOLOR_FORCE")
+	if s := format(WriterHint{Writer: b, Terminal: true}); s != plain {
+		t.Errorf("expected CLICOLOR=0 to turn colors off, got %q", s)
+	}
+}
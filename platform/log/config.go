@@ -0,0 +1,950 @@
+package log
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	logrus "github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v2"
+)
+
+// Config declares the whole logging setup of a service: levels, channels and
+// where each of them writes to. Load it with LoadConfig and apply it with
+// Build instead of wiring formatters and writers by hand.
+//
+//	level: warning
+//	levels:
+//	  platform/db: debug
+//	outputs:
+//	  - type: stderr
+//	    formatter: text
+//	  - type: file
+//	    path: /var/log/openpoint.log
+//	    formatter: json
+//	    maxSize: 104857600
+//	    maxBackups: 5
+//	  - name: alerts
+//	    type: syslog
+//	    address: alerts.internal:514
+//	routes:
+//	  - channel=payments AND level>=warn -> alerts
+//	  - field:tenant=acme -> file:/var/log/acme.log
+//	channels:
+//	  payments:
+//	    level: info
+type Config struct {
+	// Level of the package level functions and of channels without one.
+	// Defaults to info.
+	Level string `json:"level" yaml:"level" toml:"level"`
+
+	// Levels maps SetLevelFor patterns to levels
+	Levels map[string]string `json:"levels" yaml:"levels" toml:"levels"`
+
+	// Formatter of outputs that don't set one. Defaults to text.
+	Formatter string `json:"formatter" yaml:"formatter" toml:"formatter"`
+
+	// TimestampFormat of every formatter that takes one
+	TimestampFormat string `json:"timestampFormat" yaml:"timestampFormat" toml:"timestampFormat"`
+
+	// Color of the text and dev formatters: auto, the default, colors
+	// terminals, always and never override it.
+	Color string `json:"color" yaml:"color" toml:"color"`
+
+	// ReportCaller adds the caller to entries of the text, dev and
+	// cloudlogging formatters.
+	ReportCaller bool `json:"reportCaller" yaml:"reportCaller" toml:"reportCaller"`
+
+	// Outputs of the standard logger and of channels that don't set their
+	// own. Defaults to stderr.
+	Outputs []OutputConfig `json:"outputs" yaml:"outputs" toml:"outputs"`
+
+	// Routes send the entries matching an expression to an output, see
+	// ParseRoute. The target is the Name of one of Outputs, which then only
+	// writes the entries of its routes, or an output of its own written as
+	// type or type:path, e.g. stdout, file:/var/log/acme.log or
+	// slack:https://hooks.slack.com/services/... Routes apply
+	// to the standard logger and channels without outputs of their own.
+	Routes []string `json:"routes" yaml:"routes" toml:"routes"`
+
+	// Channels set the level and outputs of channels by name. Children such
+	// as http.client inherit what they don't set from http, see Logger.
+	Channels map[string]ChannelConfig `json:"channels" yaml:"channels" toml:"channels"`
+
+	// Redact masks fields and patterns in every entry, see RedactHook
+	Redact *RedactConfig `json:"redact" yaml:"redact" toml:"redact"`
+
+	// Truncate limits the length of messages and field values, see
+	// TruncateHook
+	Truncate *TruncateConfig `json:"truncate" yaml:"truncate" toml:"truncate"`
+
+	// Suppress drops or downgrades known noise, see SuppressionPolicy
+	Suppress []SuppressConfig `json:"suppress" yaml:"suppress" toml:"suppress"`
+}
+
+type ChannelConfig struct {
+	Level   string         `json:"level" yaml:"level" toml:"level"`
+	Outputs []OutputConfig `json:"outputs" yaml:"outputs" toml:"outputs"`
+}
+
+type RedactConfig struct {
+	Fields []string `json:"fields" yaml:"fields" toml:"fields"`
+
+	// Patterns are regular expressions
+	Patterns []string `json:"patterns" yaml:"patterns" toml:"patterns"`
+
+	Placeholder string `json:"placeholder" yaml:"placeholder" toml:"placeholder"`
+}
+
+// SuppressConfig is a SuppressRule:
+//
+//	suppress:
+//	  - name: billing-maintenance
+//	    match: field:dependency=billing AND msg~timeout
+//	    windows: ["02:00-03:00 UTC"]
+//	    downgrade: debug
+type SuppressConfig struct {
+	Name  string `json:"name" yaml:"name" toml:"name"`
+	Match string `json:"match" yaml:"match" toml:"match"`
+
+	// Windows are written as "15:04-15:04 Zone", see ParseTimeWindow
+	Windows []string `json:"windows" yaml:"windows" toml:"windows"`
+
+	// Downgrade is the level suppressed entries are logged at. Empty drops
+	// them.
+	Downgrade string `json:"downgrade" yaml:"downgrade" toml:"downgrade"`
+}
+
+type TruncateConfig struct {
+	MaxMessage int `json:"maxMessage" yaml:"maxMessage" toml:"maxMessage"`
+	MaxField   int `json:"maxField" yaml:"maxField" toml:"maxField"`
+}
+
+// OutputConfig is one destination of a logger.
+type OutputConfig struct {
+	// Name routes refer to the output by
+	Name string `json:"name" yaml:"name" toml:"name"`
+
+	// Type is one of stdout, stderr, split, file, archive, syslog, gelf,
+	// net, journald, slack, teams or an output registered with
+	// RegisterSinkPlugin. split writes entries at SplitLevel or more severe
+	// to stderr and the others to stdout, see SplitWriter. slack and teams
+	// post to the webhook at Address, see WebhookHook.
+	Type string `json:"type" yaml:"type" toml:"type"`
+
+	// Formatter is one of text, json, logfmt, dev, ecs, gelf, syslog,
+	// cloudlogging, journald, template, combined or w3c, the last two being
+	// access logs, see AccessLogFormatter, or a formatter registered with
+	// RegisterFormatterPlugin. Defaults to journald for journald outputs.
+	Formatter string `json:"formatter" yaml:"formatter" toml:"formatter"`
+
+	// Template is the layout of the template formatter, see
+	// TemplateFormatter.
+	Template string `json:"template" yaml:"template" toml:"template"`
+
+	// FieldMap renames the time, level and msg keys of the text, json and
+	// logfmt formatters, e.g. {time: "@timestamp"}, see FieldMap
+	FieldMap map[string]string `json:"fieldMap" yaml:"fieldMap" toml:"fieldMap"`
+
+	// Escaping of the values of the text and logfmt formatters, one of go,
+	// json, minimal or raw, see Escaping
+	Escaping string `json:"escaping" yaml:"escaping" toml:"escaping"`
+
+	// Level is the most verbose level written to this output. Defaults to
+	// everything the logger lets through.
+	Level string `json:"level" yaml:"level" toml:"level"`
+
+	// Match selects the entries written to this output, see ParseMatcher
+	Match string `json:"match" yaml:"match" toml:"match"`
+
+	// Path, MaxSize, MaxAge, MaxBackups and Compress configure file outputs,
+	// see RotatingFileWriter. MaxAge is a duration such as "24h". File
+	// outputs are reopened by ReopenFiles, e.g. on SIGHUP with HandleSignals.
+	Path       string `json:"path" yaml:"path" toml:"path"`
+	MaxSize    int64  `json:"maxSize" yaml:"maxSize" toml:"maxSize"`
+	MaxAge     string `json:"maxAge" yaml:"maxAge" toml:"maxAge"`
+	MaxBackups int    `json:"maxBackups" yaml:"maxBackups" toml:"maxBackups"`
+	Compress   bool   `json:"compress" yaml:"compress" toml:"compress"`
+
+	// Archive uploads the rotated files of a file output to object storage,
+	// and is where archive outputs upload their batches, e.g.
+	// s3://bucket/logs/api or gs://bucket/logs/api, see Archiver. Archive
+	// outputs are batched with gzip unless Batch says otherwise.
+	Archive string `json:"archive" yaml:"archive" toml:"archive"`
+
+	// Retention, RetentionSize and CompressAfter keep the rotated files of a
+	// file output within an age, such as "720h", and a size in bytes,
+	// gzipping them once older than CompressAfter, see RetentionManager.
+	Retention     string `json:"retention" yaml:"retention" toml:"retention"`
+	RetentionSize int64  `json:"retentionSize" yaml:"retentionSize" toml:"retentionSize"`
+	CompressAfter string `json:"compressAfter" yaml:"compressAfter" toml:"compressAfter"`
+
+	// KeyFile encrypts a file output with the last key of the file, see
+	// LoadEncryptionKeys and EncryptingWriter
+	KeyFile string `json:"keyFile" yaml:"keyFile" toml:"keyFile"`
+
+	// Network and Address of syslog, gelf and net outputs, Address being the
+	// webhook URL of slack and teams outputs
+	Network string `json:"network" yaml:"network" toml:"network"`
+	Address string `json:"address" yaml:"address" toml:"address"`
+
+	// Framing and TLS configure net outputs, see NetWriter. Framing is
+	// newline, length or octet-counted.
+	Framing string `json:"framing" yaml:"framing" toml:"framing"`
+	TLS     bool   `json:"tls" yaml:"tls" toml:"tls"`
+
+	// SplitLevel of split outputs. Defaults to warning.
+	SplitLevel string `json:"splitLevel" yaml:"splitLevel" toml:"splitLevel"`
+
+	// Batch compresses the entries of the output in batches of BatchSize
+	// entries or what was written within BatchInterval, such as "30s", with
+	// gzip or zstd, see BatchWriter. Use it with the json or logfmt formatter.
+	Batch         string `json:"batch" yaml:"batch" toml:"batch"`
+	BatchSize     int    `json:"batchSize" yaml:"batchSize" toml:"batchSize"`
+	BatchInterval string `json:"batchInterval" yaml:"batchInterval" toml:"batchInterval"`
+
+	// Async writes through an AsyncWriter holding BufferSize entries. Split
+	// outputs can't be async or batched.
+	Async      bool `json:"async" yaml:"async" toml:"async"`
+	BufferSize int  `json:"bufferSize" yaml:"bufferSize" toml:"bufferSize"`
+
+	// Options are the settings of plugin outputs and formatters, see
+	// RegisterSinkPlugin
+	Options map[string]string `json:"options" yaml:"options" toml:"options"`
+}
+
+const defaultAsyncBufferSize = 1024
+
+// LoadConfig reads a .json, .yaml, .yml or .toml file and applies the
+// environment overrides, see Config.ApplyEnv.
+func LoadConfig(file string) (*Config, error) {
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	c := &Config{}
+	switch ext := strings.ToLower(filepath.Ext(file)); ext {
+	case ".json":
+		err = json.Unmarshal(b, c)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(b, c)
+	case ".toml":
+		_, err = toml.Decode(string(b), c)
+	default:
+		return nil, fmt.Errorf("log: unknown config format %q", ext)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("log: failed to parse %v, %v", file, err)
+	}
+	c.ApplyEnv()
+	return c, nil
+}
+
+// ApplyEnv overrides the config with LOG_LEVEL, LOG_FORMATTER and
+// LOG_LEVEL_<CHANNEL>, where <CHANNEL> is the channel name in upper case
+// with anything but letters and digits replaced by underscores.
+func (c *Config) ApplyEnv() {
+	if v := os.Getenv("LOG_LEVEL"); v != "" {
+		c.Level = v
+	}
+	if v := os.Getenv("LOG_FORMATTER"); v != "" {
+		c.Formatter = v
+	}
+	for name, ch := range c.Channels {
+		if v := os.Getenv("LOG_LEVEL_" + envName(name)); v != "" {
+			ch.Level = v
+			c.Channels[name] = ch
+		}
+	}
+}
+
+func envName(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9'):
+			return r
+		}
+		return '_'
+	}, name)
+}
+
+// Build applies the config: it sets the levels and replaces the outputs of
+// the standard logger and of the configured channels. Hooks added outside
+// the config are kept. The returned Closer flushes and closes the files and
+// connections the config opened; close it once the pipeline is replaced or
+// on shutdown.
+func (c *Config) Build() (io.Closer, error) {
+	global := logrus.InfoLevel
+	if c.Level != "" {
+		l, err := ParseLevel(c.Level)
+		if err != nil {
+			return nil, err
+		}
+		global = l
+	}
+	rules := make(map[string]logrus.Level, len(c.Levels))
+	for pattern, level := range c.Levels {
+		l, err := ParseLevel(level)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, err
+		}
+		rules[pattern] = l
+	}
+
+	var redact *RedactHook
+	if c.Redact != nil {
+		redact = &RedactHook{Fields: c.Redact.Fields, Placeholder: c.Redact.Placeholder}
+		for _, pattern := range c.Redact.Patterns {
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				return nil, err
+			}
+			redact.Patterns = append(redact.Patterns, re)
+		}
+	}
+
+	var truncate *TruncateHook
+	if c.Truncate != nil {
+		truncate = NewTruncateHook(c.Truncate.MaxMessage, c.Truncate.MaxField)
+	}
+
+	policy, err := c.suppressionPolicy()
+	if err != nil {
+		return nil, err
+	}
+	var middleware Middleware
+	if policy != nil {
+		middleware = policy.Middleware()
+	}
+
+	outputs, err := c.routedOutputs()
+	if err != nil {
+		return nil, err
+	}
+	p := &pipeline{}
+	destinations, err := c.buildOutputs(p, outputs)
+	if err != nil {
+		p.Close()
+		return nil, err
+	}
+	std := &loggerOutputs{destinations: destinations, redact: redact, truncate: truncate, middleware: middleware, pipeline: p}
+	channelOutputs := make(map[string]*loggerOutputs, len(c.Channels))
+	channelLevels := make(map[string]logrus.Level, len(c.Channels))
+	for name, ch := range c.Channels {
+		if len(ch.Outputs) > 0 {
+			destinations, err := c.buildOutputs(p, ch.Outputs)
+			if err != nil {
+				p.Close()
+				return nil, err
+			}
+			channelOutputs[name] = &loggerOutputs{destinations: destinations, redact: redact, truncate: truncate, middleware: middleware, pipeline: p}
+		}
+		if ch.Level != "" {
+			if channelLevels[name], err = ParseLevel(ch.Level); err != nil {
+				p.Close()
+				return nil, err
+			}
+		}
+	}
+
+	// everything is valid and opened, swap it in
+	levelsMu.Lock()
+	globalLevel = global
+	levelRules = rules
+	packageLevels = &sync.Map{}
+	levelsMu.Unlock()
+
+	outputsMu.Lock()
+	stdOutputs = std
+	channelConfigOutputs = channelOutputs
+	configPolicy = policy
+	outputsMu.Unlock()
+	setOutputs(logrus.StandardLogger(), std)
+
+	// channels without outputs of their own write to those of their nearest
+	// configured ancestor, those of the standard logger otherwise, and the
+	// configured ones without a level inherit it
+	for name := range c.Channels {
+		Channel(name)
+	}
+	// channels the previous Build configured and this one doesn't go back
+	// to inheriting their level
+	levelsMu.Lock()
+	previous := configChannels
+	configChannels = make(map[string]bool, len(c.Channels))
+	for name := range c.Channels {
+		configChannels[name] = true
+	}
+	levelsMu.Unlock()
+	for _, name := range Channels() {
+		l, _ := lookupChannel(name)
+		setOutputs(l.logger, configOutputsFor(name))
+		_, configured := c.Channels[name]
+		if !configured && !previous[name] {
+			continue
+		}
+		levelsMu.Lock()
+		l.level, l.levelSet = channelLevels[name]
+		levelsMu.Unlock()
+	}
+	applyLevels()
+	return p, nil
+}
+
+// suppressionPolicy returns the policy of Suppress, nil without rules.
+func (c *Config) suppressionPolicy() (*SuppressionPolicy, error) {
+	if len(c.Suppress) == 0 {
+		return nil, nil
+	}
+	policy := &SuppressionPolicy{}
+	for i, s := range c.Suppress {
+		rule := &SuppressRule{Name: s.Name}
+		if rule.Name == "" {
+			rule.Name = fmt.Sprintf("suppress-%d", i+1)
+		}
+		if s.Match != "" {
+			m, err := ParseMatcher(s.Match)
+			if err != nil {
+				return nil, err
+			}
+			rule.Match = m
+		}
+		for _, window := range s.Windows {
+			w, err := ParseTimeWindow(window)
+			if err != nil {
+				return nil, err
+			}
+			rule.Windows = append(rule.Windows, w)
+		}
+		if s.Downgrade != "" {
+			l, err := ParseLevel(s.Downgrade)
+			if err != nil {
+				return nil, err
+			}
+			rule.Downgrade = &l
+		}
+		policy.Rules = append(policy.Rules, rule)
+	}
+	return policy, nil
+}
+
+// routedOutputs returns Outputs with Routes applied, restricting the named
+// outputs to the entries of their routes and adding the outputs routes
+// declare inline.
+func (c *Config) routedOutputs() ([]OutputConfig, error) {
+	outputs := append([]OutputConfig(nil), c.Outputs...)
+	if len(outputs) == 0 && len(c.Routes) > 0 {
+		outputs = []OutputConfig{{Type: "stderr"}}
+	}
+	routes := map[int][]string{}
+	for _, rule := range c.Routes {
+		expr, target, err := splitRoute(rule)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := ParseMatcher(expr); err != nil {
+			return nil, err
+		}
+		i := -1
+		for j, o := range outputs {
+			if o.Name == target {
+				i = j
+				break
+			}
+		}
+		if i < 0 {
+			o := OutputConfig{Name: target, Type: target}
+			if j := strings.IndexByte(target, ':'); j >= 0 {
+				o.Type = target[:j]
+				if o.Type == "file" {
+					o.Path = target[j+1:]
+				} else {
+					o.Address = target[j+1:]
+				}
+			}
+			outputs = append(outputs, o)
+			i = len(outputs) - 1
+		}
+		routes[i] = append(routes[i], "("+expr+")")
+	}
+	for i, exprs := range routes {
+		match := strings.Join(exprs, " OR ")
+		if outputs[i].Match != "" {
+			match = "(" + outputs[i].Match + ") AND (" + match + ")"
+		}
+		outputs[i].Match = match
+	}
+	return outputs, nil
+}
+
+func (c *Config) buildOutputs(p *pipeline, outputs []OutputConfig) ([]*Destination, error) {
+	if len(outputs) == 0 {
+		outputs = []OutputConfig{{Type: "stderr"}}
+	}
+	destinations := make([]*Destination, 0, len(outputs))
+	for _, o := range outputs {
+		d, err := c.buildOutput(p, o)
+		if err != nil {
+			return nil, err
+		}
+		destinations = append(destinations, d)
+	}
+	return destinations, nil
+}
+
+func (c *Config) buildOutput(p *pipeline, o OutputConfig) (*Destination, error) {
+	d := &Destination{Level: logrus.TraceLevel}
+	if o.Level != "" {
+		l, err := ParseLevel(o.Level)
+		if err != nil {
+			return nil, err
+		}
+		d.Level = l
+	}
+	if o.Match != "" {
+		m, err := ParseMatcher(o.Match)
+		if err != nil {
+			return nil, err
+		}
+		d.Match = m
+	}
+
+	name := o.Formatter
+	if name == "" && o.Type == "journald" {
+		name = "journald"
+	}
+	if name == "" {
+		name = c.Formatter
+	}
+	fieldMap, err := ParseFieldMap(o.FieldMap)
+	if err != nil {
+		return nil, err
+	}
+	escaping, err := ParseEscaping(o.Escaping)
+	if err != nil {
+		return nil, err
+	}
+	forceColors, disableColors := false, false
+	switch c.Color {
+	case "", "auto":
+	case "always":
+		forceColors = true
+	case "never":
+		disableColors = true
+	default:
+		return nil, fmt.Errorf("log: unknown color %q", c.Color)
+	}
+	switch name {
+	case "", "text":
+		d.Formatter = &ChannelTextFormatter{TimestampFormat: c.TimestampFormat, FullTimestamp: true, FieldMap: fieldMap, Escaping: escaping, ForceColors: forceColors, DisableColors: disableColors, ReportCaller: c.ReportCaller}
+	case "json":
+		d.Formatter = &ChannelJSONFormatter{TimestampFormat: c.TimestampFormat, FieldMap: fieldMap}
+	case "logfmt":
+		d.Formatter = &LogfmtFormatter{TimestampFormat: c.TimestampFormat, FieldMap: fieldMap, Escaping: escaping}
+	case "dev":
+		d.Formatter = &DevFormatter{TimestampFormat: c.TimestampFormat, ForceColors: forceColors, DisableColors: disableColors, ReportCaller: c.ReportCaller}
+	case "ecs":
+		d.Formatter = &ECSFormatter{}
+	case "gelf":
+		d.Formatter = &GELFFormatter{}
+	case "syslog":
+		d.Formatter = &SyslogFormatter{}
+	case "cloudlogging":
+		d.Formatter = &CloudLoggingFormatter{ProjectID: os.Getenv("GOOGLE_CLOUD_PROJECT"), ReportCaller: c.ReportCaller}
+	case "journald":
+		d.Formatter = &JournaldFormatter{}
+	case "combined":
+		d.Formatter = &AccessLogFormatter{Layout: AccessLogCombined}
+	case "w3c":
+		d.Formatter = &AccessLogFormatter{Layout: AccessLogW3C}
+	case "template":
+		t, err := NewTemplateFormatter(o.Template)
+		if err != nil {
+			return nil, err
+		}
+		if c.TimestampFormat != "" {
+			t.TimestampFormat = c.TimestampFormat
+		}
+		d.Formatter = t
+	default:
+		plugin, ok := formatterPlugin(name)
+		if !ok {
+			return nil, fmt.Errorf("log: unknown formatter %q", name)
+		}
+		f, err := plugin.NewFormatter(o)
+		if err != nil {
+			return nil, err
+		}
+		d.Formatter = f
+	}
+
+	switch o.Type {
+	case "slack", "teams":
+		if o.Async || o.Batch != "" {
+			return nil, fmt.Errorf("log: %s outputs can't be async or batched", o.Type)
+		}
+		if o.Address == "" {
+			return nil, fmt.Errorf("log: %s output without an address", o.Type)
+		}
+		kind := WebhookSlack
+		if o.Type == "teams" {
+			kind = WebhookTeams
+		}
+		h := newWebhookHook(o.Address, kind)
+		// the level and match of the output select the entries
+		h.Level = logrus.TraceLevel
+		d.Hook = h
+		return d, nil
+	}
+
+	var w io.Writer
+	switch o.Type {
+	case "stdout":
+		w = stdWriter{os.Stdout}
+	case "", "stderr":
+		w = stdWriter{os.Stderr}
+	case "split":
+		if o.Async || o.Batch != "" {
+			return nil, fmt.Errorf("log: split outputs can't be async or batched")
+		}
+		level := logrus.WarnLevel
+		if o.SplitLevel != "" {
+			l, err := ParseLevel(o.SplitLevel)
+			if err != nil {
+				return nil, err
+			}
+			level = l
+		}
+		w = NewSplitWriter(level, stdWriter{os.Stdout}, stdWriter{os.Stderr})
+	case "file":
+		if o.Path == "" {
+			return nil, fmt.Errorf("log: file output without a path")
+		}
+		f := &RotatingFileWriter{Filename: o.Path, MaxSize: o.MaxSize, MaxBackups: o.MaxBackups, Compress: o.Compress}
+		if o.MaxAge != "" {
+			age, err := time.ParseDuration(o.MaxAge)
+			if err != nil {
+				return nil, err
+			}
+			f.MaxAge = age
+		}
+		if o.Archive != "" {
+			a, err := OpenArchiver(o.Archive)
+			if err != nil {
+				return nil, err
+			}
+			f.Archive = a
+		}
+		if o.Retention != "" || o.RetentionSize > 0 || o.CompressAfter != "" {
+			m := &RetentionManager{Pattern: o.Path + ".*", MaxTotalSize: o.RetentionSize}
+			if o.Retention != "" {
+				age, err := time.ParseDuration(o.Retention)
+				if err != nil {
+					return nil, err
+				}
+				m.MaxAge = age
+			}
+			if o.CompressAfter != "" {
+				age, err := time.ParseDuration(o.CompressAfter)
+				if err != nil {
+					return nil, err
+				}
+				m.CompressAfter = age
+			}
+			m.Start(defaultRetentionInterval)
+			p.closers = append(p.closers, m)
+		}
+		p.unregister = append(p.unregister, RegisterReopener(f))
+		w = f
+		if o.KeyFile != "" {
+			keys, err := LoadEncryptionKeys(o.KeyFile)
+			if err != nil {
+				return nil, err
+			}
+			if w, err = NewEncryptingWriter(f, keys[len(keys)-1]); err != nil {
+				return nil, err
+			}
+		}
+	case "archive":
+		if o.Archive == "" {
+			return nil, fmt.Errorf("log: archive output without an archive")
+		}
+		if o.Batch == "" {
+			o.Batch = "gzip"
+		}
+		compression, err := ParseCompression(o.Batch)
+		if err != nil {
+			return nil, err
+		}
+		a, err := OpenArchiver(o.Archive)
+		if err != nil {
+			return nil, err
+		}
+		a.Extension = ".log" + compression.extension()
+		w = a
+	case "syslog":
+		network := o.Network
+		if network == "" {
+			network = "udp"
+		}
+		s, err := NewSyslogWriter(network, o.Address)
+		if err != nil {
+			return nil, err
+		}
+		w = s
+	case "gelf":
+		g, err := NewGELFWriter(o.Address)
+		if err != nil {
+			return nil, err
+		}
+		w = g
+	case "net":
+		network := o.Network
+		if network == "" {
+			network = "tcp"
+		}
+		framing, err := ParseFraming(o.Framing)
+		if err != nil {
+			return nil, err
+		}
+		n := NewNetWriter(network, o.Address, framing)
+		if o.TLS {
+			host, _, err := net.SplitHostPort(o.Address)
+			if err != nil {
+				return nil, err
+			}
+			n.TLS = &tls.Config{ServerName: host}
+		}
+		w = n
+	case "journald":
+		j, err := NewJournaldWriter()
+		if err != nil {
+			return nil, err
+		}
+		w = j
+	default:
+		plugin, ok := sinkPlugin(o.Type)
+		if !ok {
+			return nil, fmt.Errorf("log: unknown output %q", o.Type)
+		}
+		s, err := plugin.NewSink(o)
+		if err != nil {
+			return nil, err
+		}
+		w = s
+	}
+
+	if o.Batch != "" {
+		compression, err := ParseCompression(o.Batch)
+		if err != nil {
+			return nil, err
+		}
+		var interval time.Duration
+		if o.BatchInterval != "" {
+			if interval, err = time.ParseDuration(o.BatchInterval); err != nil {
+				return nil, err
+			}
+		}
+		w = NewBatchWriter(w, compression, o.BatchSize, interval)
+	}
+	if o.Async {
+		size := o.BufferSize
+		if size <= 0 {
+			size = defaultAsyncBufferSize
+		}
+		w = NewAsyncWriter(w, size)
+	}
+	if closer, ok := w.(io.Closer); ok {
+		p.closers = append(p.closers, closer)
+	}
+	d.Writer = w
+	return d, nil
+}
+
+// stdWriter hides the Close of os.Stdout and os.Stderr from the pipeline and
+// from AsyncWriter.
+type stdWriter struct {
+	io.Writer
+}
+
+// loggerOutputs is what Build sets up on a logger
+type loggerOutputs struct {
+	destinations []*Destination
+	redact       *RedactHook
+	truncate     *TruncateHook
+	middleware   Middleware
+
+	// pipeline owns the destinations, nil when they aren't closed
+	pipeline *pipeline
+}
+
+// configHook, configRedactHook and configTruncateHook are the hooks Build
+// installs, told apart from hooks added by other code when they are
+// replaced.
+type configHook struct {
+	*MultiHook
+	pipeline *pipeline
+}
+
+// Fire writes entry unless its outputs were closed, hooks are fired outside
+// the lock of the logger and entries may still come through the hooks of a
+// config that has been replaced.
+func (h configHook) Fire(entry *logrus.Entry) error {
+	if h.pipeline == nil {
+		return h.MultiHook.Fire(entry)
+	}
+	if !h.pipeline.enter() {
+		recordDropped(1)
+		return nil
+	}
+	defer h.pipeline.firing.Done()
+	return h.MultiHook.Fire(entry)
+}
+
+type configRedactHook struct {
+	*RedactHook
+}
+
+type configTruncateHook struct {
+	*TruncateHook
+}
+
+var (
+	outputsMu sync.Mutex
+
+	// stdOutputs are the outputs of the standard logger set by the last
+	// Build, which new channels start with
+	stdOutputs *loggerOutputs
+
+	// channelConfigOutputs are the outputs of the channels configured with
+	// outputs of their own by the last Build
+	channelConfigOutputs map[string]*loggerOutputs
+
+	// configPolicy is the SuppressionPolicy of the last Build
+	configPolicy *SuppressionPolicy
+
+	// configChannels are the channels listed by the last Build, guarded by
+	// levelsMu
+	configChannels map[string]bool
+)
+
+// configOutputsFor returns the outputs of the last Build for the channel
+// name: its own, those of its nearest ancestor with outputs or those of the
+// standard logger. Nil before any Build.
+func configOutputsFor(name string) *loggerOutputs {
+	outputsMu.Lock()
+	defer outputsMu.Unlock()
+	for n, ok := name, true; ok; n, ok = parentName(n) {
+		if o, configured := channelConfigOutputs[n]; configured {
+			return o
+		}
+	}
+	return stdOutputs
+}
+
+// setOutputs writes logger to o's destinations, directly when there is a
+// single one logging everything, through a configHook otherwise. The redact
+// hook goes before every other hook so none of them sees unmasked values,
+// then the truncate hook so they see short ones.
+func setOutputs(logger *logrus.Logger, o *loggerOutputs) {
+	outputsMu.Lock()
+	defer outputsMu.Unlock()
+
+	hooks := make(logrus.LevelHooks)
+	if o.redact != nil {
+		hooks.Add(configRedactHook{o.redact})
+	}
+	if o.truncate != nil {
+		hooks.Add(configTruncateHook{o.truncate})
+	}
+	for level, levelHooks := range logger.Hooks {
+		for _, h := range levelHooks {
+			switch h.(type) {
+			case configHook, configRedactHook, configTruncateHook:
+			default:
+				hooks[level] = append(hooks[level], h)
+			}
+		}
+	}
+
+	destinations := o.destinations
+	if len(destinations) == 1 && destinations[0].Level == logrus.TraceLevel && destinations[0].Match == nil && destinations[0].Hook == nil && o.middleware == nil {
+		logger.SetFormatter(destinations[0].Formatter)
+		logger.SetOutput(destinations[0].Writer)
+	} else {
+		hooks.Add(configHook{&MultiHook{Destinations: destinations, Middleware: o.middleware}, o.pipeline})
+		logger.SetFormatter(discardFormatter{})
+		logger.SetOutput(ioutil.Discard)
+	}
+	logger.ReplaceHooks(hooks)
+}
+
+// discardFormatter skips formatting for loggers that only write through
+// hooks.
+type discardFormatter struct{}
+
+func (discardFormatter) Format(*logrus.Entry) ([]byte, error) {
+	return nil, nil
+}
+
+// pipeline holds what a Build opened.
+type pipeline struct {
+	closers []io.Closer
+
+	// unregister removes its files from ReopenFiles
+	unregister []func()
+
+	mu     sync.Mutex
+	closed bool
+
+	// firing counts the entries being written by configHooks
+	firing sync.WaitGroup
+}
+
+// enter reports whether the outputs are still open and, if so, holds off
+// Close until firing is done.
+func (p *pipeline) enter() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return false
+	}
+	p.firing.Add(1)
+	return true
+}
+
+// Close waits for the entries being written, closes every output and returns
+// the first error. Entries coming after are dropped.
+func (p *pipeline) Close() error {
+	p.mu.Lock()
+	p.closed = true
+	p.mu.Unlock()
+	p.firing.Wait()
+
+	for _, unregister := range p.unregister {
+		unregister()
+	}
+	var firstErr error
+	for _, c := range p.closers {
+		if err := c.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
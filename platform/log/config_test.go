@@ -0,0 +1,86 @@
+package log
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	logrus "github.com/sirupsen/logrus"
+)
+
+// resetConfig undoes Build on the standard logger
+func resetConfig() {
+	outputsMu.Lock()
+	stdOutputs = nil
+	channelConfigOutputs = nil
+	outputsMu.Unlock()
+	setOutputs(logrus.StandardLogger(), &loggerOutputs{destinations: []*Destination{{Writer: os.Stderr, Formatter: &ChannelTextFormatter{}, Level: logrus.TraceLevel}}})
+	SetLevel(logrus.InfoLevel)
+}
+
+func TestConfigBuild(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logconfig")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "log.json")
+	out := filepath.Join(dir, "billing.log")
+	ioutil.WriteFile(file, []byte(`{
+		"level": "warning",
+		"outputs": [{"type": "stderr", "level": "error"}],
+		"channels": {
+			"billing": {
+				"level": "info",
+				"outputs": [{"type": "file", "path": "`+out+`", "formatter": "logfmt", "async": true}]
+			}
+		}
+	}`), 0644)
+
+	os.Setenv("LOG_LEVEL_BILLING", "debug")
+	defer os.Unsetenv("LOG_LEVEL_BILLING")
+	c, err := LoadConfig(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	closer, err := c.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resetConfig()
+
+	if GetLevel() != logrus.WarnLevel {
+		t.Errorf("expected warning got %v", GetLevel())
+	}
+	billing := Channel("billing")
+	if billing.GetLevel() != logrus.DebugLevel {
+		t.Errorf("expected the env override, got %v", billing.GetLevel())
+	}
+	billing.DebugMessage("invoice %v", 42)
+	invoices := Channel("billing.invoices")
+	if invoices.GetLevel() != logrus.DebugLevel {
+		t.Errorf("expected the level of billing, got %v", invoices.GetLevel())
+	}
+	invoices.Info("sent")
+	if err := closer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := ioutil.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(b), `level=debug msg= channel=billing debug="invoice 42"`) {
+		t.Errorf("expected the entry in the file, got %q", b)
+	}
+	if !strings.Contains(string(b), `level=info msg=sent channel=billing.invoices`) {
+		t.Errorf("expected the entry of the child channel in the file, got %q", b)
+	}
+
+	if _, err := (&Config{Outputs: []OutputConfig{{Type: "kafka"}}}).Build(); err == nil {
+		t.Errorf("expected an error for an unknown output")
+	}
+}
@@ -0,0 +1,31 @@
+package log
+
+import (
+	"context"
+
+	logrus "github.com/sirupsen/logrus"
+)
+
+type contextKey struct{}
+
+// NewContext returns a copy of ctx carrying entry. Everything logged through
+// FromContext on the returned context includes the entry's fields.
+func NewContext(ctx context.Context, entry *logrus.Entry) context.Context {
+	return context.WithValue(ctx, contextKey{}, entry)
+}
+
+// FromContext returns the entry stored in ctx by NewContext, or a bare entry
+// on the standard logger when there is none. The entry carries ctx so hooks
+// can read request-scoped values from it.
+func FromContext(ctx context.Context) *logrus.Entry {
+	if entry, ok := ctx.Value(contextKey{}).(*logrus.Entry); ok {
+		return entry.WithContext(ctx)
+	}
+	return logrus.NewEntry(logrus.StandardLogger()).WithContext(ctx)
+}
+
+// WithContext adds fields to the entry in ctx, e.g. a request or user ID, and
+// returns the context carrying the result.
+func WithContext(ctx context.Context, fields logrus.Fields) context.Context {
+	return NewContext(ctx, FromContext(ctx).WithFields(fields))
+}
@@ -0,0 +1,37 @@
+package log
+
+import (
+	"context"
+	"testing"
+
+	logrus "github.com/sirupsen/logrus"
+)
+
+type contextTestKey struct{}
+
+func TestContext(t *testing.T) {
+	ctx := context.WithValue(context.Background(), contextTestKey{}, "tenant")
+	entry := FromContext(ctx)
+	if entry.Logger != logrus.StandardLogger() || len(entry.Data) != 0 {
+		t.Errorf("expected a bare entry on the standard logger, got %v", entry.Data)
+	}
+	if entry.Context != ctx {
+		t.Errorf("expected the entry to carry ctx")
+	}
+
+	ctx = WithContext(ctx, logrus.Fields{"request_id": "req-1"})
+	ctx = WithContext(ctx, logrus.Fields{"user": 42})
+	entry = FromContext(ctx)
+	if entry.Data["request_id"] != "req-1" || entry.Data["user"] != 42 {
+		t.Errorf("expected the fields of both WithContext calls, got %v", entry.Data)
+	}
+	if entry.Context.Value(contextTestKey{}) != "tenant" {
+		t.Errorf("expected the entry to carry the values of ctx")
+	}
+
+	logger := logrus.New()
+	ctx = NewContext(context.Background(), logrus.NewEntry(logger).WithField("channel", "payments"))
+	if entry := FromContext(ctx); entry.Logger != logger || entry.Data["channel"] != "payments" {
+		t.Errorf("expected the entry of NewContext, got %v", entry.Data)
+	}
+}
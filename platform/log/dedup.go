@@ -0,0 +1,136 @@
+package log
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+
+	logrus "github.com/sirupsen/logrus"
+)
+
+const defaultDedupFlushInterval = 30 * time.Second
+
+// DedupFormatter wraps a formatter and collapses runs of identical entries,
+// same level, message and fields, the way syslogd does: the first entry is
+// formatted, the repeats are dropped and a "last message repeated N times"
+// entry is written before the next different entry. A repeat coming
+// FlushInterval after the first entry of the run is written again and starts
+// a new run.
+//
+// It is a formatter rather than a hook because hooks can't drop entries.
+type DedupFormatter struct {
+	Formatter logrus.Formatter
+
+	// FlushInterval after which a pending count is logged. Defaults to 30s.
+	FlushInterval time.Duration
+
+	mu       sync.Mutex
+	last     *logrus.Entry
+	repeated int
+	since    time.Time
+	lastSeen time.Time
+	timer    *time.Timer
+}
+
+// Format renders a single log entry, or nothing when it repeats the previous one
+func (f *DedupFormatter) Format(entry *logrus.Entry) ([]byte, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.last != nil && sameEntry(f.last, entry) && entry.Time.Sub(f.since) < f.flushInterval() {
+		f.repeated++
+		f.lastSeen = entry.Time
+		recordDropped(1)
+		f.schedule()
+		return []byte{}, nil
+	}
+
+	out := []byte{}
+	if f.repeated > 0 {
+		b, err := f.summary()
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, b...)
+	}
+	f.last = dedupCopy(entry)
+	f.repeated = 0
+	f.since = entry.Time
+	f.lastSeen = entry.Time
+
+	b, err := f.Formatter.Format(entry)
+	if err != nil {
+		return nil, err
+	}
+	return append(out, b...), nil
+}
+
+func (f *DedupFormatter) flushInterval() time.Duration {
+	if f.FlushInterval <= 0 {
+		return defaultDedupFlushInterval
+	}
+	return f.FlushInterval
+}
+
+// schedule arranges for the pending count to be flushed. f.mu must be held.
+func (f *DedupFormatter) schedule() {
+	if f.timer != nil {
+		f.timer.Stop()
+	}
+	f.timer = time.AfterFunc(f.flushInterval(), func() {
+		f.Flush()
+	})
+}
+
+// Flush writes the pending count, if any, to the output of the logger of the
+// repeated entry, without firing its hooks for the entries they already saw.
+func (f *DedupFormatter) Flush() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.repeated == 0 || f.last.Logger == nil {
+		return nil
+	}
+	b, err := f.summary()
+	f.repeated = 0
+	if err != nil {
+		return err
+	}
+	// written under f.mu, so it comes before the entries formatted next
+	_, err = f.last.Logger.Out.Write(b)
+	return err
+}
+
+// summary formats the "last message repeated" entry. f.mu must be held.
+func (f *DedupFormatter) summary() ([]byte, error) {
+	summary := &logrus.Entry{
+		Logger:  f.last.Logger,
+		Data:    logrus.Fields{},
+		Time:    f.lastSeen,
+		Level:   f.last.Level,
+		Message: fmt.Sprintf("last message repeated %d times", f.repeated),
+	}
+	if v, ok := f.last.Data[ChannelKey]; ok {
+		summary.Data[ChannelKey] = v
+	}
+	return f.Formatter.Format(summary)
+}
+
+func sameEntry(a, b *logrus.Entry) bool {
+	return a.Level == b.Level && a.Message == b.Message && reflect.DeepEqual(a.Data, b.Data)
+}
+
+// dedupCopy keeps what sameEntry compares, logrus reuses entries
+func dedupCopy(entry *logrus.Entry) *logrus.Entry {
+	e := &logrus.Entry{
+		Logger:  entry.Logger,
+		Data:    make(logrus.Fields, len(entry.Data)),
+		Time:    entry.Time,
+		Level:   entry.Level,
+		Message: entry.Message,
+	}
+	for k, v := range entry.Data {
+		e.Data[k] = v
+	}
+	return e
+}
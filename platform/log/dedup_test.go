@@ -0,0 +1,123 @@
+package log
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	logrus "github.com/sirupsen/logrus"
+)
+
+func TestDedupFormatter(t *testing.T) {
+	b := &bytes.Buffer{}
+	f := &DedupFormatter{Formatter: &ChannelTextFormatter{DisableTimestamp: true}, FlushInterval: time.Minute}
+	now := time.Now()
+	format := func(msg string, offset time.Duration) {
+		entry := logrus.NewEntry(logrus.StandardLogger())
+		entry.Time = now.Add(offset)
+		entry.Level = logrus.WarnLevel
+		entry.Message = msg
+		out, err := f.Format(entry)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(out) > 0 {
+			b.Write(out)
+		}
+	}
+
+	format("db timeout", 0)
+	format("db timeout", time.Second)
+	format("db timeout", 2*time.Second)
+	format("db ok", 3*time.Second)
+	format("db ok", 2*time.Minute)
+
+	expected := []string{
+		`level=warning msg="db timeout"`,
+		`level=warning msg="last message repeated 2 times"`,
+		`level=warning msg="db ok"`,
+		`level=warning msg="db ok"`,
+	}
+	if got := strings.Split(strings.TrimSpace(b.String()), "\n"); strings.Join(got, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("expected\n%v\ngot\n%v", strings.Join(expected, "\n"), b.String())
+	}
+
+	// the pending count is logged through the logger once FlushInterval
+	// passes without another entry
+	w := &collectWriter{}
+	logger := logrus.New()
+	logger.Out = w
+	logger.Formatter = f
+	f.FlushInterval = 10 * time.Millisecond
+	logger.WithTime(now.Add(3 * time.Minute)).Warn("cache miss")
+	logger.WithTime(now.Add(3*time.Minute + time.Millisecond)).Warn("cache miss")
+	time.Sleep(50 * time.Millisecond)
+	logger.WithTime(now.Add(3*time.Minute + time.Second)).Warn("cache miss")
+
+	expected = []string{
+		`level=warning msg="cache miss"` + "\n",
+		`level=warning msg="last message repeated 1 times"` + "\n",
+		`level=warning msg="cache miss"` + "\n",
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if strings.Join(w.lines, "") != strings.Join(expected, "") {
+		t.Errorf("expected\n%v\ngot\n%v", strings.Join(expected, ""), strings.Join(w.lines, ""))
+	}
+}
+
+// countingHook counts the entries fired
+type countingHook struct {
+	mu    sync.Mutex
+	fired int
+}
+
+func (h *countingHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (h *countingHook) Fire(entry *logrus.Entry) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.fired++
+	return nil
+}
+
+func TestDedupFormatterFlush(t *testing.T) {
+	w := &collectWriter{}
+	hook := &countingHook{}
+	logger := logrus.New()
+	logger.Out = w
+	logger.AddHook(hook)
+	f := &DedupFormatter{Formatter: &ChannelTextFormatter{DisableTimestamp: true}, FlushInterval: time.Minute}
+	logger.Formatter = f
+
+	now := time.Now()
+	for i := 0; i < 3; i++ {
+		entry := logrus.NewEntry(logger).WithTime(now.Add(time.Duration(i) * time.Second))
+		entry.Level = logrus.PanicLevel
+		entry.Message = "ledger corrupt"
+		if _, err := f.Format(entry); err != nil {
+			t.Fatal(err)
+		}
+	}
+	logger.Warn("retrying")
+	logger.Warn("retrying")
+	if err := f.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	expected := `level=panic msg="last message repeated 2 times"` + "\n" +
+		`level=warning msg=retrying` + "\n" +
+		`level=warning msg="last message repeated 1 times"` + "\n"
+	if strings.Join(w.lines, "") != expected {
+		t.Errorf("expected\n%v\ngot\n%v", expected, strings.Join(w.lines, ""))
+	}
+	if hook.fired != 2 {
+		t.Errorf("expected the hooks fired only for the logged entries, got %d", hook.fired)
+	}
+}
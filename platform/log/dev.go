@@ -0,0 +1,173 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	logrus "github.com/sirupsen/logrus"
+)
+
+// DevFormatter is a multi-line formatter for reading logs during development.
+// The message goes on the first line and every field on its own indented
+// line below it. JSON strings, maps and structs are pretty-printed and
+// multi-line values such as stack traces keep their line breaks.
+type DevFormatter struct {
+	// Set to true to bypass checking for a TTY before outputting colors.
+	// Colors are also on with CLICOLOR_FORCE set or a WriterHint, and off
+	// with NO_COLOR or CLICOLOR=0 set, unless forced.
+	ForceColors bool
+
+	// Force disabling colors.
+	DisableColors bool
+
+	// Theme sets the colors. Defaults to DefaultTheme.
+	Theme *Theme
+
+	// Glyphs puts a level marker such as EmojiGlyphs in front of colored
+	// entries. Nil leaves it out.
+	Glyphs *Glyphs
+
+	// TimestampFormat to use for display. Defaults to "15:04:05.000".
+	TimestampFormat string
+
+	// Indent of field lines. Defaults to four spaces.
+	Indent string
+
+	// ReportCaller adds a line with the file:line and function that logged
+	// the entry below the message.
+	ReportCaller bool
+
+	// CallerSkip is the number of frames to skip above the first caller
+	// outside this package, for code that wraps the log functions.
+	CallerSkip int
+
+	// CallerTrimPrefixes are stripped from caller file paths. By default
+	// paths are trimmed up to the GOPATH src directory.
+	CallerTrimPrefixes []string
+
+	isTerminal bool
+	noColor    bool
+
+	sync.Once
+}
+
+// Format renders a single log entry
+func (f *DevFormatter) Format(entry *logrus.Entry) ([]byte, error) {
+	f.Do(func() {
+		if entry.Logger != nil {
+			f.isTerminal = isTerminal(entry.Logger.Out) || forceColor()
+		}
+		f.noColor = noColor()
+	})
+	highlighted, messageColor := entryStyle(entry)
+	level, entry := prepareEntry(entry)
+	isColored := (f.ForceColors || (f.isTerminal && !f.noColor)) && !f.DisableColors
+	theme := themeOrDefault(f.Theme)
+
+	timestampFormat := f.TimestampFormat
+	if timestampFormat == "" {
+		timestampFormat = "15:04:05.000"
+	}
+	indent := f.Indent
+	if indent == "" {
+		indent = "    "
+	}
+
+	levelColor, keyColor := theme.Level(entry.Level), theme.key(entry.Level)
+	if messageColor != "" {
+		levelColor = messageColor
+		if theme.Key == "" {
+			keyColor = messageColor
+		}
+	}
+
+	b := &bytes.Buffer{}
+	levelText := strings.ToUpper(level.Name)
+	if isColored {
+		if f.Glyphs != nil {
+			f.Glyphs.write(b, entry.Level, levelColor)
+		}
+		levelColor.write(b, fmt.Sprintf("%-7s", levelText))
+		b.WriteByte(' ')
+		theme.Timestamp.write(b, entryTime(entry).Format(timestampFormat))
+		b.WriteByte(' ')
+		messageColor.write(b, entry.Message)
+		b.WriteByte('\n')
+	} else {
+		fmt.Fprintf(b, "%-7s %s %s\n", levelText, entryTime(entry).Format(timestampFormat), entry.Message)
+	}
+	if f.ReportCaller {
+		if frame, ok := callerFrame(f.CallerSkip); ok {
+			caller, function := formatCaller(frame, f.CallerTrimPrefixes)
+			b.WriteString(indent)
+			if isColored {
+				theme.Caller.write(b, caller+" "+function)
+			} else {
+				b.WriteString(caller + " " + function)
+			}
+			b.WriteByte('\n')
+		}
+	}
+
+	keys := make([]string, 0, len(entry.Data))
+	for k := range entry.Data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		b.WriteString(indent)
+		valueColor := Color("")
+		if isColored {
+			keyColor.write(b, k)
+			b.WriteString(": ")
+			valueColor = highlighted.color(k, theme)
+		} else {
+			fmt.Fprintf(b, "%s: ", k)
+		}
+		lines := strings.Split(devValue(entry.Data[k]), "\n")
+		valueColor.write(b, lines[0])
+		b.WriteByte('\n')
+		for _, line := range lines[1:] {
+			b.WriteString(indent)
+			b.WriteString(indent)
+			valueColor.write(b, line)
+			b.WriteByte('\n')
+		}
+	}
+	return b.Bytes(), nil
+}
+
+// devValue renders v for DevFormatter, pretty-printing JSON and composite
+// values and keeping the stack trace of errors that print one with %+v.
+func devValue(v interface{}) string {
+	v = resolveLazy(v)
+	switch v := v.(type) {
+	case string:
+		trimmed := strings.TrimSpace(v)
+		if (strings.HasPrefix(trimmed, "{") || strings.HasPrefix(trimmed, "[")) && json.Valid([]byte(trimmed)) {
+			out := &bytes.Buffer{}
+			if err := json.Indent(out, []byte(trimmed), "", "  "); err == nil {
+				return out.String()
+			}
+		}
+		return strings.TrimRight(v, "\n")
+	case error:
+		return strings.TrimRight(fmt.Sprintf("%+v", v), "\n")
+	case fmt.Stringer:
+		return v.String()
+	}
+
+	switch reflect.Indirect(reflect.ValueOf(v)).Kind() {
+	case reflect.Map, reflect.Struct, reflect.Slice, reflect.Array:
+		if b, err := json.MarshalIndent(v, "", "  "); err == nil {
+			return string(b)
+		}
+		return fmt.Sprintf("%+v", v)
+	}
+	return fmt.Sprint(v)
+}
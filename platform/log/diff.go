@@ -0,0 +1,202 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+
+	logrus "github.com/sirupsen/logrus"
+)
+
+const (
+	DefaultBeforeKey = "before"
+	DefaultAfterKey  = "after"
+
+	diffAdded   = Color("32")
+	diffRemoved = Color("31")
+	diffChanged = Color("33")
+)
+
+// DiffFormatter wraps a formatter and, for entries carrying a before and an
+// after field holding maps or structs, writes the entry without them
+// followed by a line per key that changed, e.g. in an audit of settings:
+//
+//	INFO[0042] settings updated                              user=7
+//	    ~ timeout: 30 → 60
+//	    + retries: 3
+//	    - legacy: true
+//
+// Nested values are compared by their dotted keys. The lines are colored
+// like ChannelTextFormatter colors entries, so use it with the text or dev
+// formatter rather than one writing a line of JSON per entry.
+type DiffFormatter struct {
+	Formatter logrus.Formatter
+
+	// BeforeKey and AfterKey name the compared fields. Default to before
+	// and after.
+	BeforeKey string
+	AfterKey  string
+
+	// Set to true to bypass checking for a TTY before outputting colors.
+	// Colors are also on with CLICOLOR_FORCE set or a WriterHint, and off
+	// with NO_COLOR or CLICOLOR=0 set, unless forced.
+	ForceColors bool
+
+	// Force disabling colors.
+	DisableColors bool
+
+	// Indent of the lines. Defaults to four spaces.
+	Indent string
+
+	// MaxDepth of the compared values, defaults to 5.
+	MaxDepth int
+
+	isTerminal bool
+	noColor    bool
+
+	sync.Once
+}
+
+// Format renders a single log entry and the differences of its before and
+// after fields
+func (f *DiffFormatter) Format(entry *logrus.Entry) ([]byte, error) {
+	f.Do(func() {
+		if entry.Logger != nil {
+			f.isTerminal = isTerminal(entry.Logger.Out) || forceColor()
+		}
+		f.noColor = noColor()
+	})
+
+	beforeKey, afterKey := f.BeforeKey, f.AfterKey
+	if beforeKey == "" {
+		beforeKey = DefaultBeforeKey
+	}
+	if afterKey == "" {
+		afterKey = DefaultAfterKey
+	}
+	before, okBefore := entry.Data[beforeKey]
+	after, okAfter := entry.Data[afterKey]
+	if !okBefore || !okAfter {
+		return f.Formatter.Format(entry)
+	}
+	beforeFields, okBefore := diffFields(before, f.MaxDepth)
+	afterFields, okAfter := diffFields(after, f.MaxDepth)
+	if !okBefore || !okAfter {
+		return f.Formatter.Format(entry)
+	}
+
+	stripped := CopyEntry(entry)
+	delete(stripped.Data, beforeKey)
+	delete(stripped.Data, afterKey)
+	line, err := f.Formatter.Format(stripped)
+	if err != nil || len(line) == 0 {
+		return line, err
+	}
+
+	indent := f.Indent
+	if indent == "" {
+		indent = "    "
+	}
+	isColored := (f.ForceColors || (f.isTerminal && !f.noColor)) && !f.DisableColors
+	b := bytes.NewBuffer(line)
+	for _, d := range diff(beforeFields, afterFields) {
+		b.WriteString(indent)
+		text := ""
+		switch d.op {
+		case '+':
+			text = fmt.Sprintf("+ %s: %s", d.key, diffValue(d.after))
+		case '-':
+			text = fmt.Sprintf("- %s: %s", d.key, diffValue(d.before))
+		default:
+			text = fmt.Sprintf("~ %s: %s → %s", d.key, diffValue(d.before), diffValue(d.after))
+		}
+		if isColored {
+			d.color().write(b, text)
+		} else {
+			b.WriteString(text)
+		}
+		b.WriteByte('\n')
+	}
+	return b.Bytes(), nil
+}
+
+type difference struct {
+	op            byte
+	key           string
+	before, after interface{}
+}
+
+func (d difference) color() Color {
+	switch d.op {
+	case '+':
+		return diffAdded
+	case '-':
+		return diffRemoved
+	}
+	return diffChanged
+}
+
+// diffFields returns v, a map or struct, flattened to dotted keys.
+func diffFields(v interface{}, maxDepth int) (logrus.Fields, bool) {
+	rv, ok := nestedValue(v)
+	if !ok {
+		return nil, false
+	}
+	if maxDepth <= 0 {
+		maxDepth = defaultMaxDepth
+	}
+	flat := logrus.Fields{}
+	seen := map[uintptr]bool{}
+	if p, ok := pointerOf(rv); ok {
+		seen[p] = true
+	}
+	nestedEach(reflect.Indirect(rv), func(k string, child interface{}) {
+		flattenValue(flat, k, child, maxDepth-1, seen)
+	})
+	return flat, true
+}
+
+// diff returns the keys added, removed or changed from before to after, in
+// key order.
+func diff(before, after logrus.Fields) []difference {
+	keys := make([]string, 0, len(before)+len(after))
+	for k := range before {
+		keys = append(keys, k)
+	}
+	for k := range after {
+		if _, ok := before[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	var diffs []difference
+	for _, k := range keys {
+		b, inBefore := before[k]
+		a, inAfter := after[k]
+		switch {
+		case !inBefore:
+			diffs = append(diffs, difference{op: '+', key: k, after: a})
+		case !inAfter:
+			diffs = append(diffs, difference{op: '-', key: k, before: b})
+		case !reflect.DeepEqual(b, a):
+			diffs = append(diffs, difference{op: '~', key: k, before: b, after: a})
+		}
+	}
+	return diffs
+}
+
+// diffValue renders v as JSON, so strings are quoted and numbers aren't.
+func diffValue(v interface{}) string {
+	v = resolveLazy(v)
+	if err, ok := v.(error); ok {
+		v = err.Error()
+	}
+	if b, err := json.Marshal(v); err == nil {
+		return string(b)
+	}
+	return fmt.Sprint(v)
+}
@@ -0,0 +1,56 @@
+package log
+
+import (
+	"testing"
+
+	logrus "github.com/sirupsen/logrus"
+)
+
+type diffSettings struct {
+	Timeout int               `json:"timeout"`
+	Legacy  bool              `json:"legacy,omitempty"`
+	Retries int               `json:"-"`
+	Limits  map[string]string `json:"limits"`
+}
+
+func TestDiffFormatter(t *testing.T) {
+	entry := benchmarkEntry(logrus.Fields{
+		"user":   7,
+		"before": diffSettings{Timeout: 30, Legacy: true, Limits: map[string]string{"rate": "10/s"}},
+		"after":  map[string]interface{}{"timeout": 60, "retries": 3, "legacy": true, "limits": map[string]string{"rate": "20/s"}},
+	})
+	f := &DiffFormatter{Formatter: &ChannelTextFormatter{DisableColors: true, DisableTimestamp: true}}
+	b, err := f.Format(entry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `level=info msg="charge succeeded" user=7` + "\n" +
+		`    ~ limits.rate: "10/s" → "20/s"` + "\n" +
+		`    + retries: 3` + "\n" +
+		`    ~ timeout: 30 → 60` + "\n"
+	if string(b) != expected {
+		t.Errorf("expected %q got %q", expected, b)
+	}
+
+	f = &DiffFormatter{Formatter: &ChannelTextFormatter{DisableColors: true, DisableTimestamp: true}, ForceColors: true, Indent: " "}
+	entry.Data["after"] = map[string]interface{}{"timeout": 30, "legacy": true}
+	b, err = f.Format(entry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected = `level=info msg="charge succeeded" user=7` + "\n" +
+		" \x1b[31m- limits.rate: \"10/s\"\x1b[0m\n"
+	if string(b) != expected {
+		t.Errorf("expected %q got %q", expected, b)
+	}
+
+	entry.Data["after"] = "disabled"
+	b, err = f.Format(entry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected = `level=info msg="charge succeeded" after=disabled before="{30 true 0 map[rate:10/s]}" user=7` + "\n"
+	if string(b) != expected {
+		t.Errorf("expected values that can't be compared to be left as they are, got %q", b)
+	}
+}
@@ -0,0 +1,507 @@
+package log
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultSegmentSize   = 4 << 20
+	defaultDiskQueueSize = 256 << 20
+
+	// every record is framed by its length and CRC-32
+	diskQueueFrameHeader = 8
+	diskQueueSegmentExt  = ".seg"
+	diskQueueCursorFile  = "cursor"
+
+	spillMinBackoff = 500 * time.Millisecond
+	spillMaxBackoff = 30 * time.Second
+	spillBatchSize  = 100
+)
+
+var errCorruptRecord = errors.New("log: corrupt queue record")
+
+// DiskQueue is a persistent FIFO of formatted entries kept in segment files
+// under a directory, so entries waiting for a network sink survive restarts
+// and collector outages. A reader Peeks records and Commits them once sent;
+// the read position is saved so a restart resumes after the last commit.
+//
+// Once the queue holds MaxBytes the oldest segment is dropped. On open a
+// record cut short by a crash is truncated, and a record failing its
+// checksum while reading skips the rest of its segment.
+type DiskQueue struct {
+	// SegmentSize after which a new segment file is started. Defaults to
+	// 4 MiB.
+	SegmentSize int64
+
+	// MaxBytes of all segments. Defaults to 256 MiB.
+	MaxBytes int64
+
+	dir string
+
+	mu       sync.Mutex
+	segments []uint64
+	sizes    map[uint64]int64
+	total    int64
+	w        *os.File
+	ready    chan struct{}
+
+	// read position and the position after each peeked record
+	rSeq    uint64
+	rOff    int64
+	pending []diskQueuePos
+}
+
+type diskQueuePos struct {
+	seq uint64
+	off int64
+}
+
+// OpenDiskQueue opens the queue in dir, creating it when needed.
+func OpenDiskQueue(dir string) (*DiskQueue, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	q := &DiskQueue{
+		SegmentSize: defaultSegmentSize,
+		MaxBytes:    defaultDiskQueueSize,
+		dir:         dir,
+		sizes:       map[uint64]int64{},
+		ready:       make(chan struct{}, 1),
+	}
+
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range files {
+		if !strings.HasSuffix(f.Name(), diskQueueSegmentExt) {
+			continue
+		}
+		seq, err := strconv.ParseUint(strings.TrimSuffix(f.Name(), diskQueueSegmentExt), 10, 64)
+		if err != nil {
+			continue
+		}
+		q.segments = append(q.segments, seq)
+		q.sizes[seq] = f.Size()
+		q.total += f.Size()
+	}
+	sort.Slice(q.segments, func(i, j int) bool { return q.segments[i] < q.segments[j] })
+
+	if len(q.segments) == 0 {
+		q.segments = []uint64{1}
+	} else if err := q.recover(q.last()); err != nil {
+		return nil, err
+	}
+	q.rSeq, q.rOff = q.segments[0], 0
+	q.readCursor()
+
+	q.w, err = os.OpenFile(q.path(q.last()), os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+	if q.total > 0 {
+		q.ready <- struct{}{}
+	}
+	return q, nil
+}
+
+// recover truncates segment seq after its last complete record.
+func (q *DiskQueue) recover(seq uint64) error {
+	f, err := os.OpenFile(q.path(seq), os.O_RDWR, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	off := int64(0)
+	for {
+		_, next, err := readDiskQueueRecord(f, off, q.sizes[seq])
+		if err != nil {
+			break
+		}
+		off = next
+	}
+	if off < q.sizes[seq] {
+		q.total -= q.sizes[seq] - off
+		q.sizes[seq] = off
+		return f.Truncate(off)
+	}
+	return nil
+}
+
+// readCursor restores the read position saved by Commit, when it is still
+// within the queue.
+func (q *DiskQueue) readCursor() {
+	b, err := ioutil.ReadFile(filepath.Join(q.dir, diskQueueCursorFile))
+	if err != nil {
+		return
+	}
+	var seq uint64
+	var off int64
+	if _, err := fmt.Sscanf(string(b), "%d %d", &seq, &off); err != nil {
+		return
+	}
+	if size, ok := q.sizes[seq]; ok && off <= size {
+		q.rSeq, q.rOff = seq, off
+	}
+}
+
+func (q *DiskQueue) path(seq uint64) string {
+	return filepath.Join(q.dir, fmt.Sprintf("%020d%s", seq, diskQueueSegmentExt))
+}
+
+func (q *DiskQueue) last() uint64 {
+	return q.segments[len(q.segments)-1]
+}
+
+// Put appends a copy of p to the queue.
+func (q *DiskQueue) Put(p []byte) error {
+	frame := make([]byte, diskQueueFrameHeader+len(p))
+	binary.BigEndian.PutUint32(frame[0:], uint32(len(p)))
+	binary.BigEndian.PutUint32(frame[4:], crc32.ChecksumIEEE(p))
+	copy(frame[diskQueueFrameHeader:], p)
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.sizes[q.last()] > 0 && q.sizes[q.last()]+int64(len(frame)) > q.SegmentSize {
+		if err := q.rotate(); err != nil {
+			return err
+		}
+	}
+	for q.total+int64(len(frame)) > q.MaxBytes && len(q.segments) > 1 {
+		q.dropOldest()
+	}
+
+	if _, err := q.w.Write(frame); err != nil {
+		return err
+	}
+	q.sizes[q.last()] += int64(len(frame))
+	q.total += int64(len(frame))
+
+	select {
+	case q.ready <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+func (q *DiskQueue) rotate() error {
+	seq := q.last() + 1
+	w, err := os.OpenFile(q.path(seq), os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return err
+	}
+	q.w.Close()
+	q.w = w
+	q.segments = append(q.segments, seq)
+	q.sizes[seq] = 0
+	return nil
+}
+
+// dropOldest removes the oldest segment to stay within MaxBytes, counting
+// its unread records as dropped.
+func (q *DiskQueue) dropOldest() {
+	seq := q.segments[0]
+	from := int64(0)
+	if seq == q.rSeq {
+		from = q.rOff
+	}
+	if seq >= q.rSeq {
+		recordDropped(uint64(q.countRecords(seq, from)))
+	}
+	os.Remove(q.path(seq))
+	q.total -= q.sizes[seq]
+	delete(q.sizes, seq)
+	q.segments = q.segments[1:]
+
+	if q.rSeq <= seq {
+		q.rSeq, q.rOff = q.segments[0], 0
+	}
+	q.pending = nil
+}
+
+func (q *DiskQueue) countRecords(seq uint64, off int64) int {
+	f, err := os.Open(q.path(seq))
+	if err != nil {
+		return 0
+	}
+	defer f.Close()
+	n := 0
+	for {
+		_, next, err := readDiskQueueRecord(f, off, q.sizes[seq])
+		if err != nil {
+			return n
+		}
+		off = next
+		n++
+	}
+}
+
+// Ready receives a value when records were put since the queue was last
+// found empty.
+func (q *DiskQueue) Ready() <-chan struct{} {
+	return q.ready
+}
+
+// Peek returns up to max records after the last committed one, or none when
+// the queue is empty. Peeking again returns the same records until they are
+// committed.
+func (q *DiskQueue) Peek(max int) ([][]byte, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.pending = q.pending[:0]
+	records := [][]byte{}
+	seq, off := q.rSeq, q.rOff
+	var f *os.File
+	defer func() {
+		if f != nil {
+			f.Close()
+		}
+	}()
+	for len(records) < max {
+		if off >= q.sizes[seq] {
+			next, ok := q.nextSegment(seq)
+			if !ok {
+				break
+			}
+			seq, off = next, 0
+			continue
+		}
+		if f == nil || f.Name() != q.path(seq) {
+			if f != nil {
+				f.Close()
+			}
+			var err error
+			if f, err = os.Open(q.path(seq)); err != nil {
+				return nil, err
+			}
+		}
+		b, next, err := readDiskQueueRecord(f, off, q.sizes[seq])
+		if err == errCorruptRecord || err == io.ErrUnexpectedEOF {
+			if len(records) > 0 {
+				break
+			}
+			// skip the rest of the segment, its framing can't be trusted
+			recordDropped(1)
+			off = q.sizes[seq]
+			q.rSeq, q.rOff = seq, off
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		off = next
+		q.pending = append(q.pending, diskQueuePos{seq, off})
+		records = append(records, b)
+	}
+	return records, nil
+}
+
+func (q *DiskQueue) nextSegment(seq uint64) (uint64, bool) {
+	for _, s := range q.segments {
+		if s > seq {
+			return s, true
+		}
+	}
+	return 0, false
+}
+
+// readDiskQueueRecord reads the record at off of a segment of size bytes and
+// returns it with the offset of the next one.
+func readDiskQueueRecord(r io.ReaderAt, off, size int64) ([]byte, int64, error) {
+	header := make([]byte, diskQueueFrameHeader)
+	if _, err := r.ReadAt(header, off); err != nil {
+		if err == io.EOF {
+			return nil, 0, io.ErrUnexpectedEOF
+		}
+		return nil, 0, err
+	}
+	n := binary.BigEndian.Uint32(header[0:])
+	if n > defaultDiskQueueSize {
+		return nil, 0, errCorruptRecord
+	}
+	if int64(n) > size-off-diskQueueFrameHeader {
+		// a torn record, or a corrupt length
+		return nil, 0, io.ErrUnexpectedEOF
+	}
+	b := make([]byte, n)
+	if _, err := r.ReadAt(b, off+diskQueueFrameHeader); err != nil {
+		if err == io.EOF {
+			return nil, 0, io.ErrUnexpectedEOF
+		}
+		return nil, 0, err
+	}
+	if crc32.ChecksumIEEE(b) != binary.BigEndian.Uint32(header[4:]) {
+		return nil, 0, errCorruptRecord
+	}
+	return b, off + diskQueueFrameHeader + int64(n), nil
+}
+
+// Commit removes the first n records returned by the last Peek, deleting the
+// segments read to the end, and saves the read position.
+func (q *DiskQueue) Commit(n int) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if n <= 0 || n > len(q.pending) {
+		return nil
+	}
+	pos := q.pending[n-1]
+	q.pending = q.pending[:0]
+	q.rSeq, q.rOff = pos.seq, pos.off
+
+	for len(q.segments) > 1 && q.segments[0] < q.rSeq {
+		seq := q.segments[0]
+		os.Remove(q.path(seq))
+		q.total -= q.sizes[seq]
+		delete(q.sizes, seq)
+		q.segments = q.segments[1:]
+	}
+	// the write segment is reused once read, there is nothing left to keep
+	if q.rSeq == q.last() && q.rOff == q.sizes[q.rSeq] {
+		if err := q.w.Truncate(0); err == nil {
+			q.total -= q.sizes[q.rSeq]
+			q.sizes[q.rSeq] = 0
+			q.rOff = 0
+		}
+	}
+
+	tmp := filepath.Join(q.dir, diskQueueCursorFile+".tmp")
+	if err := ioutil.WriteFile(tmp, []byte(fmt.Sprintf("%d %d\n", q.rSeq, q.rOff)), 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filepath.Join(q.dir, diskQueueCursorFile))
+}
+
+// Size returns the bytes held by the queue, read records included until
+// their segment is removed.
+func (q *DiskQueue) Size() int64 {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.total
+}
+
+func (q *DiskQueue) Close() error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.w.Close()
+}
+
+// SpillWriter queues every entry in a DiskQueue and writes them to a network
+// sink, such as a SyslogWriter or GELFWriter, on a background goroutine,
+// retrying with exponential backoff while the sink fails. Entries not sent
+// by Close are sent after the next start.
+type SpillWriter struct {
+	queue *DiskQueue
+	sink  io.Writer
+	stop  chan struct{}
+	done  chan struct{}
+	once  sync.Once
+
+	registration sinkRegistration
+}
+
+// NewSpillWriter opens the queue in dir and starts sending it to sink.
+func NewSpillWriter(dir string, maxBytes int64, sink io.Writer) (*SpillWriter, error) {
+	queue, err := OpenDiskQueue(dir)
+	if err != nil {
+		return nil, err
+	}
+	if maxBytes > 0 {
+		queue.MaxBytes = maxBytes
+	}
+	w := &SpillWriter{
+		queue: queue,
+		sink:  sink,
+		stop:  make(chan struct{}),
+		done:  make(chan struct{}),
+	}
+	w.registration.register(w, SinkQueue)
+	go w.run()
+	return w, nil
+}
+
+func (w *SpillWriter) Write(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	if err := w.queue.Put(p); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (w *SpillWriter) run() {
+	defer close(w.done)
+	backoff := spillMinBackoff
+	for {
+		records, err := w.queue.Peek(spillBatchSize)
+		if err != nil {
+			// retried after the backoff below, Ready may never come
+			reportf("Failed to read the spill queue because %+v", err)
+		} else if len(records) == 0 {
+			select {
+			case <-w.queue.Ready():
+				continue
+			case <-w.stop:
+				return
+			}
+		}
+
+		sent := 0
+		for _, b := range records {
+			if _, err = w.sink.Write(b); err != nil {
+				break
+			}
+			sent++
+		}
+		if cerr := w.queue.Commit(sent); cerr != nil {
+			reportf("Failed to commit the spill queue because %+v", cerr)
+		}
+		if err == nil {
+			backoff = spillMinBackoff
+			continue
+		}
+
+		select {
+		case <-time.After(backoff):
+		case <-w.stop:
+			return
+		}
+		backoff *= 2
+		if backoff > spillMaxBackoff {
+			backoff = spillMaxBackoff
+		}
+	}
+}
+
+// Close stops sending, leaving unsent entries on disk, and closes the queue
+// and the sink if it is an io.Closer.
+func (w *SpillWriter) Close() error {
+	w.registration.release()
+	w.once.Do(func() {
+		close(w.stop)
+	})
+	<-w.done
+	err := w.queue.Close()
+	if c, ok := w.sink.(io.Closer); ok {
+		if cerr := c.Close(); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
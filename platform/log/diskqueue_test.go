@@ -0,0 +1,214 @@
+package log
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func peekStrings(t *testing.T, q *DiskQueue, max int) []string {
+	records, err := q.Peek(max)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := []string{}
+	for _, b := range records {
+		s = append(s, string(b))
+	}
+	return s
+}
+
+func TestDiskQueue(t *testing.T) {
+	dir, err := ioutil.TempDir("", "queue")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	q, err := OpenDiskQueue(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q.SegmentSize = 20
+	for i := 1; i <= 5; i++ {
+		if err := q.Put([]byte(fmt.Sprintf("entry %d", i))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if s := peekStrings(t, q, 2); strings.Join(s, ",") != "entry 1,entry 2" {
+		t.Errorf("unexpected records %v", s)
+	}
+	if s := peekStrings(t, q, 2); strings.Join(s, ",") != "entry 1,entry 2" {
+		t.Errorf("expected the same records before a commit, got %v", s)
+	}
+	if err := q.Commit(1); err != nil {
+		t.Fatal(err)
+	}
+	q.Close()
+
+	// a restart resumes after the commit and drops a torn last record
+	segments, _ := filepath.Glob(filepath.Join(dir, "*.seg"))
+	if len(segments) < 2 {
+		t.Fatalf("expected several segments, got %v", segments)
+	}
+	f, _ := os.OpenFile(segments[len(segments)-1], os.O_WRONLY|os.O_APPEND, 0600)
+	f.Write([]byte{0, 0, 0, 9, 1})
+	f.Close()
+
+	q, err = OpenDiskQueue(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer q.Close()
+	if s := peekStrings(t, q, 10); strings.Join(s, ",") != "entry 2,entry 3,entry 4,entry 5" {
+		t.Errorf("unexpected records after reopening %v", s)
+	}
+	if err := q.Commit(4); err != nil {
+		t.Fatal(err)
+	}
+	if s := peekStrings(t, q, 10); len(s) != 0 {
+		t.Errorf("expected an empty queue, got %v", s)
+	}
+	if q.Size() != 0 {
+		t.Errorf("expected read segments to be removed, %d bytes left", q.Size())
+	}
+}
+
+func TestDiskQueueLimits(t *testing.T) {
+	dir, err := ioutil.TempDir("", "queue")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	q, err := OpenDiskQueue(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer q.Close()
+	q.SegmentSize = 16
+	q.MaxBytes = 48
+	for i := 1; i <= 6; i++ {
+		q.Put([]byte(fmt.Sprintf("entry %d", i)))
+	}
+	if s := peekStrings(t, q, 10); strings.Join(s, ",") != "entry 4,entry 5,entry 6" {
+		t.Errorf("expected the oldest segments to be dropped, got %v", s)
+	}
+
+	// a corrupt record skips the rest of its segment
+	segments, _ := filepath.Glob(filepath.Join(dir, "*.seg"))
+	b, _ := ioutil.ReadFile(segments[0])
+	b[len(b)-1] ^= 0xff
+	ioutil.WriteFile(segments[0], b, 0600)
+	if s := peekStrings(t, q, 10); strings.Join(s, ",") != "entry 5,entry 6" {
+		t.Errorf("expected the corrupt record to be skipped, got %v", s)
+	}
+}
+
+type recordingSink struct {
+	mu      sync.Mutex
+	lines   []string
+	failing int
+}
+
+func (s *recordingSink) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.failing > 0 {
+		s.failing--
+		return 0, fmt.Errorf("collector down")
+	}
+	s.lines = append(s.lines, string(p))
+	return len(p), nil
+}
+
+func (s *recordingSink) Lines() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.lines...)
+}
+
+func TestSpillWriter(t *testing.T) {
+	dir, err := ioutil.TempDir("", "spill")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	sink := &recordingSink{failing: 1}
+	w, err := NewSpillWriter(dir, 0, sink)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Write([]byte("a\n"))
+	w.Write([]byte("b\n"))
+
+	deadline := time.Now().Add(5 * time.Second)
+	for len(sink.Lines()) < 2 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(sink.Lines(), "") != "a\nb\n" {
+		t.Errorf("expected the entries to be sent after the failure, got %q", sink.Lines())
+	}
+}
+
+func TestReadDiskQueueRecordLength(t *testing.T) {
+	// a length header far beyond the end of the segment
+	segment := bytes.NewReader([]byte{0x0f, 0, 0, 0, 0, 0, 0, 0, 'a', 'b'})
+	allocs := testing.AllocsPerRun(10, func() {
+		if _, _, err := readDiskQueueRecord(segment, 0, int64(segment.Len())); err != io.ErrUnexpectedEOF {
+			t.Errorf("expected a torn record, got %v", err)
+		}
+	})
+	if allocs > 1 {
+		t.Errorf("expected the record not to be allocated, got %v allocations", allocs)
+	}
+}
+
+func TestSpillWriterPeekError(t *testing.T) {
+	dir, err := ioutil.TempDir("", "spill")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	sink := &recordingSink{}
+	w, err := NewSpillWriter(dir, 0, sink)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	// the segment is written through its open file but can't be read back
+	segments, _ := filepath.Glob(filepath.Join(dir, "*.seg"))
+	if len(segments) != 1 {
+		t.Fatalf("expected a segment, got %v", segments)
+	}
+	hidden := segments[0] + ".hidden"
+	if err := os.Rename(segments[0], hidden); err != nil {
+		t.Fatal(err)
+	}
+	w.Write([]byte("a\n"))
+	time.Sleep(50 * time.Millisecond)
+	if err := os.Rename(hidden, segments[0]); err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(5 * spillMinBackoff)
+	for len(sink.Lines()) < 1 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if strings.Join(sink.Lines(), "") != "a\n" {
+		t.Errorf("expected the entry sent once the queue can be read, got %q", sink.Lines())
+	}
+}
@@ -0,0 +1,100 @@
+package log
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	logrus "github.com/sirupsen/logrus"
+)
+
+const ecsVersion = "1.12.0"
+
+// ECSFormatter formats logs as Elastic Common Schema JSON so Elasticsearch
+// and Kibana pick up level, message, errors and traces without mappings.
+type ECSFormatter struct {
+	// ServiceName is written as service.name when set
+	ServiceName string
+
+	// FieldsKey is the object holding entry fields that have no ECS
+	// equivalent. Defaults to "fields".
+	FieldsKey string
+}
+
+// Format renders a single log entry
+func (f *ECSFormatter) Format(entry *logrus.Entry) ([]byte, error) {
+	level, entry := prepareEntry(entry)
+	fieldsKey := f.FieldsKey
+	if fieldsKey == "" {
+		fieldsKey = "fields"
+	}
+
+	logObj := map[string]interface{}{"level": level.Name}
+	data := map[string]interface{}{
+		"@timestamp": entry.Time.UTC().Format(time.RFC3339Nano),
+		"message":    entry.Message,
+		"ecs":        map[string]interface{}{"version": ecsVersion},
+		"log":        logObj,
+	}
+	service := map[string]interface{}{}
+	if f.ServiceName != "" {
+		service["name"] = f.ServiceName
+	}
+
+	fields := map[string]interface{}{}
+	for k, v := range entry.Data {
+		switch k {
+		case "error":
+			errObj := map[string]interface{}{"message": fmt.Sprint(v)}
+			if err, ok := v.(error); ok {
+				errObj["message"] = err.Error()
+				errObj["type"] = fmt.Sprintf("%T", err)
+			}
+			data["error"] = errObj
+		case TraceIDKey:
+			data["trace"] = map[string]interface{}{"id": v}
+		case SpanIDKey:
+			data["span"] = map[string]interface{}{"id": v}
+		case ChannelKey:
+			logObj["logger"] = v
+		case EventKey:
+			data["event"] = map[string]interface{}{"action": v}
+		case HostnameKey:
+			data["host"] = map[string]interface{}{"name": v}
+		case PIDKey:
+			data["process"] = map[string]interface{}{"pid": v}
+		case ServiceKey:
+			if f.ServiceName == "" {
+				service["name"] = v
+			}
+		case VersionKey:
+			service["version"] = v
+		case EnvKey:
+			service["environment"] = v
+		default:
+			if err, ok := v.(error); ok {
+				v = err.Error()
+			}
+			fields[k] = v
+		}
+	}
+	// ECS has a place for the code of structured errors
+	if errObj, ok := data["error"].(map[string]interface{}); ok {
+		if code, ok := fields["error.code"]; ok {
+			errObj["code"] = code
+			delete(fields, "error.code")
+		}
+	}
+	if len(fields) > 0 {
+		data[fieldsKey] = fields
+	}
+	if len(service) > 0 {
+		data["service"] = service
+	}
+
+	serialized, err := json.Marshal(data)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to marshal fields to JSON, %v", err)
+	}
+	return append(serialized, '\n'), nil
+}
@@ -0,0 +1,84 @@
+package log
+
+import (
+	"strconv"
+	"sync"
+	"time"
+
+	logrus "github.com/sirupsen/logrus"
+)
+
+// ElapsedFormat is how ChannelTextFormatter writes the time since the start
+// of the process on a terminal when FullTimestamp is off.
+type ElapsedFormat int
+
+const (
+	// ElapsedSeconds writes whole seconds, [0042]
+	ElapsedSeconds ElapsedFormat = iota
+
+	// ElapsedMillis writes seconds with milliseconds, [0042.318]
+	ElapsedMillis
+
+	// ElapsedClock writes hours, minutes and seconds, [0:00:42], which
+	// stays narrow for processes running for days
+	ElapsedClock
+
+	// ElapsedClockMillis writes ElapsedClock with milliseconds,
+	// [0:00:42.318]
+	ElapsedClockMillis
+)
+
+// elapsedBases are the times ResetElapsed restarted channels at
+var elapsedBases sync.Map
+
+// ResetElapsed restarts the elapsed time of the entries of channel at zero,
+// e.g. when a worker channel starts its next job. "" restarts the entries
+// logged without a channel.
+func ResetElapsed(channel string) {
+	elapsedBases.Store(channel, now())
+}
+
+// elapsed returns the time from the start of the process, or from the last
+// ResetElapsed of its channel, to entry.
+func elapsed(entry *logrus.Entry) time.Duration {
+	channel, _ := entry.Data[ChannelKey].(string)
+	if base, ok := elapsedBases.Load(channel); ok {
+		return entry.Time.Sub(base.(time.Time))
+	}
+	return entry.Time.Sub(baseTimestamp)
+}
+
+// formatElapsed writes d in format, zero padding the leading number to
+// width digits. A width of 0 pads seconds to 4 digits and leaves hours.
+func formatElapsed(d time.Duration, format ElapsedFormat, width int) string {
+	if d < 0 {
+		d = 0
+	}
+	var b []byte
+	lead := int64(d / time.Second)
+	if format == ElapsedClock || format == ElapsedClockMillis {
+		lead = int64(d / time.Hour)
+	} else if width <= 0 {
+		width = 4
+	}
+	b = appendPadded(b, lead, width)
+	if format == ElapsedClock || format == ElapsedClockMillis {
+		b = append(b, ':')
+		b = appendPadded(b, int64(d/time.Minute%60), 2)
+		b = append(b, ':')
+		b = appendPadded(b, int64(d/time.Second%60), 2)
+	}
+	if format == ElapsedMillis || format == ElapsedClockMillis {
+		b = append(b, '.')
+		b = appendPadded(b, int64(d/time.Millisecond%1000), 3)
+	}
+	return string(b)
+}
+
+func appendPadded(b []byte, n int64, width int) []byte {
+	s := strconv.FormatInt(n, 10)
+	for i := len(s); i < width; i++ {
+		b = append(b, '0')
+	}
+	return append(b, s...)
+}
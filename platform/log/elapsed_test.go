@@ -0,0 +1,53 @@
+package log
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	logrus "github.com/sirupsen/logrus"
+)
+
+func TestFormatElapsed(t *testing.T) {
+	d := 2*time.Hour + 46*time.Minute + 40*time.Second + 318*time.Millisecond
+	for _, tt := range []struct {
+		format   ElapsedFormat
+		width    int
+		expected string
+	}{
+		{ElapsedSeconds, 0, "10000"},
+		{ElapsedSeconds, 6, "010000"},
+		{ElapsedMillis, 0, "10000.318"},
+		{ElapsedClock, 0, "2:46:40"},
+		{ElapsedClock, 3, "002:46:40"},
+		{ElapsedClockMillis, 0, "2:46:40.318"},
+	} {
+		if got := formatElapsed(d, tt.format, tt.width); got != tt.expected {
+			t.Errorf("format %v width %v: expected %q got %q", tt.format, tt.width, tt.expected, got)
+		}
+	}
+	if got := formatElapsed(42*time.Second, ElapsedSeconds, 0); got != "0042" {
+		t.Errorf("expected the former %%04d output, got %q", got)
+	}
+}
+
+func TestResetElapsed(t *testing.T) {
+	clock := &fixedClock{t: time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)}
+	SetClock(clock)
+	defer SetClock(nil)
+	ResetElapsed("job")
+	defer elapsedBases.Delete("job")
+
+	b := &bytes.Buffer{}
+	job := Channel("job")
+	job.SetOutput(b)
+	job.SetFormatter(&ChannelTextFormatter{ForceColors: true, ElapsedFormat: ElapsedClockMillis})
+	job.SetLevel(logrus.InfoLevel)
+
+	clock.t = clock.t.Add(65*time.Second + 5*time.Millisecond)
+	job.Info("step")
+	if !strings.Contains(b.String(), "[0:01:05.005]") {
+		t.Errorf("expected the time since the reset, got %q", b.String())
+	}
+}
@@ -0,0 +1,260 @@
+package log
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	logrus "github.com/sirupsen/logrus"
+)
+
+const (
+	defaultEmailThrottle    = 10 * time.Minute
+	defaultEmailContextSize = 100
+)
+
+// EmailHook mails Fatal and Panic entries through the SMTP server at Addr,
+// host:port, with the entries logged before them attached when Context is
+// set, so the cause of a crash is in the inbox before anyone logs in. At
+// most one mail is sent per Throttle; entries in between are counted and
+// the count is added to the next mail.
+type EmailHook struct {
+	Addr string
+	Auth smtp.Auth
+	From string
+	To   []string
+
+	// Level is the least severe level mailed. Defaults to Fatal.
+	Level logrus.Level
+
+	// Context attaches the last ContextSize entries of the buffer as
+	// context.log. ContextSize defaults to 100.
+	Context     *RingBuffer
+	ContextSize int
+
+	// Formatter renders the entry and the context. Defaults to
+	// ChannelTextFormatter without colors.
+	Formatter logrus.Formatter
+
+	// Throttle is the minimum time between two mails. Defaults to 10m.
+	Throttle time.Duration
+
+	// sendMail is sendMailTimeout, replaced by tests
+	sendMail func(addr string, a smtp.Auth, from string, to []string, msg []byte) error
+
+	mu         sync.Mutex
+	last       time.Time
+	suppressed int
+}
+
+// NewEmailHook returns a hook mailing Fatal and Panic entries from from to
+// the to addresses through the server at addr.
+func NewEmailHook(addr, from string, to ...string) *EmailHook {
+	return &EmailHook{
+		Addr:        addr,
+		From:        from,
+		To:          to,
+		Level:       logrus.FatalLevel,
+		ContextSize: defaultEmailContextSize,
+		Formatter:   &ChannelTextFormatter{DisableColors: true, FullTimestamp: true},
+		Throttle:    defaultEmailThrottle,
+		sendMail:    sendMailTimeout,
+	}
+}
+
+func (h *EmailHook) Levels() []logrus.Level {
+	levels := []logrus.Level{}
+	for _, l := range logrus.AllLevels {
+		if l <= h.Level {
+			levels = append(levels, l)
+		}
+	}
+	return levels
+}
+
+func (h *EmailHook) Fire(entry *logrus.Entry) error {
+	h.mu.Lock()
+	if !h.last.IsZero() && entry.Time.Sub(h.last) < h.Throttle {
+		h.suppressed++
+		h.mu.Unlock()
+		return nil
+	}
+	h.last = entry.Time
+	suppressed := h.suppressed
+	h.suppressed = 0
+	h.mu.Unlock()
+
+	msg, err := h.message(entry, suppressed)
+	if err != nil {
+		return err
+	}
+
+	// the process goes down after Fatal and Panic, so wait for those
+	if entry.Level <= logrus.FatalLevel {
+		return h.send(msg)
+	}
+	go func() {
+		if err := h.send(msg); err != nil {
+			reportf("Failed to mail log entry because %+v", err)
+		}
+	}()
+	return nil
+}
+
+func (h *EmailHook) send(msg []byte) error {
+	send := h.sendMail
+	if send == nil {
+		send = sendMailTimeout
+	}
+	return send(h.Addr, h.Auth, h.From, h.To, msg)
+}
+
+// sendMailTimeout is sendMail bounded by notifyTimeout.
+func sendMailTimeout(addr string, a smtp.Auth, from string, to []string, msg []byte) error {
+	return sendMail(time.Now().Add(notifyTimeout), addr, a, from, to, msg)
+}
+
+// sendMail sends msg like smtp.SendMail, with the connection bounded by
+// deadline so a server that doesn't answer can't hang the Fatal path.
+func sendMail(deadline time.Time, addr string, a smtp.Auth, from string, to []string, msg []byte) error {
+	conn, err := net.DialTimeout("tcp", addr, time.Until(deadline))
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if err := conn.SetDeadline(deadline); err != nil {
+		return err
+	}
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return err
+	}
+	c, err := smtp.NewClient(conn, host)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err := c.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			return err
+		}
+	}
+	if a != nil {
+		if ok, _ := c.Extension("AUTH"); !ok {
+			return fmt.Errorf("log: %s doesn't support AUTH", addr)
+		}
+		if err := c.Auth(a); err != nil {
+			return err
+		}
+	}
+	if err := c.Mail(from); err != nil {
+		return err
+	}
+	for _, rcpt := range to {
+		if err := c.Rcpt(rcpt); err != nil {
+			return err
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
+// message renders entry as a MIME mail with the context attached.
+func (h *EmailHook) message(entry *logrus.Entry, suppressed int) ([]byte, error) {
+	formatter := h.Formatter
+	if formatter == nil {
+		formatter = &ChannelTextFormatter{DisableColors: true, FullTimestamp: true}
+	}
+	text, err := formatter.Format(entry)
+	if err != nil {
+		return nil, err
+	}
+	body := &bytes.Buffer{}
+	body.Write(text)
+	if suppressed > 0 {
+		fmt.Fprintf(body, "\n(%d more suppressed)\n", suppressed)
+	}
+
+	host, _ := os.Hostname()
+	subject := fmt.Sprintf("[%s] %s: %s", strings.ToUpper(entry.Level.String()), host, entry.Message)
+	b := &bytes.Buffer{}
+	fmt.Fprintf(b, "From: %s\r\n", h.From)
+	fmt.Fprintf(b, "To: %s\r\n", strings.Join(h.To, ", "))
+	fmt.Fprintf(b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(b, "Date: %s\r\n", entry.Time.Format(time.RFC1123Z))
+	fmt.Fprintf(b, "MIME-Version: 1.0\r\n")
+
+	mw := multipart.NewWriter(b)
+	fmt.Fprintf(b, "Content-Type: multipart/mixed; boundary=%s\r\n\r\n", mw.Boundary())
+	part, err := mw.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {"text/plain; charset=utf-8"},
+		"Content-Transfer-Encoding": {"base64"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	writeBase64(part, body.Bytes())
+
+	if h.Context != nil {
+		context := &bytes.Buffer{}
+		entries := h.Context.Entries()
+		size := h.ContextSize
+		if size <= 0 {
+			size = defaultEmailContextSize
+		}
+		if len(entries) > size {
+			entries = entries[len(entries)-size:]
+		}
+		for i := range entries {
+			line, err := formatter.Format(&entries[i])
+			if err != nil {
+				return nil, err
+			}
+			context.Write(line)
+		}
+		part, err := mw.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {"text/plain; charset=utf-8"},
+			"Content-Disposition":       {`attachment; filename="context.log"`},
+			"Content-Transfer-Encoding": {"base64"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		writeBase64(part, context.Bytes())
+	}
+	if err := mw.Close(); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// writeBase64 writes p base64 encoded in lines of 76 characters, as RFC 2045
+// asks.
+func writeBase64(w io.Writer, p []byte) {
+	encoded := base64.StdEncoding.EncodeToString(p)
+	for len(encoded) > 76 {
+		w.Write([]byte(encoded[:76] + "\r\n"))
+		encoded = encoded[76:]
+	}
+	w.Write([]byte(encoded + "\r\n"))
+}
@@ -0,0 +1,166 @@
+package log
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"strings"
+	"testing"
+	"time"
+
+	logrus "github.com/sirupsen/logrus"
+)
+
+func TestEmailHook(t *testing.T) {
+	var mails [][]byte
+	ring := NewRingBuffer(10)
+	h := NewEmailHook("smtp.internal:25", "api@example.com", "oncall@example.com")
+	h.Context = ring
+	h.ContextSize = 2
+	h.sendMail = func(addr string, a smtp.Auth, from string, to []string, msg []byte) error {
+		mails = append(mails, msg)
+		return nil
+	}
+
+	start := time.Now()
+	for _, msg := range []string{"connecting", "retrying", "connection refused"} {
+		ring.Fire(&logrus.Entry{Level: logrus.ErrorLevel, Time: start, Message: msg, Data: logrus.Fields{}})
+	}
+	fatal := &logrus.Entry{Level: logrus.FatalLevel, Time: start, Message: "database unreachable", Data: logrus.Fields{"db": "primary"}}
+	if err := h.Fire(fatal); err != nil {
+		t.Fatal(err)
+	}
+	if len(mails) != 1 {
+		t.Fatalf("expected a mail, got %d", len(mails))
+	}
+	subject, parts := readMail(t, mails[0])
+	if !strings.HasPrefix(subject, "[FATAL] ") || !strings.HasSuffix(subject, ": database unreachable") {
+		t.Errorf("unexpected subject %q", subject)
+	}
+	if len(parts) != 2 {
+		t.Fatalf("expected the entry and the context, got %q", parts)
+	}
+	if !strings.Contains(parts[0], `msg="database unreachable" db=primary`) {
+		t.Errorf("unexpected body %q", parts[0])
+	}
+	if strings.Contains(parts[1], "msg=connecting") || !strings.Contains(parts[1], "msg=retrying") || !strings.Contains(parts[1], `msg="connection refused"`) {
+		t.Errorf("expected the last 2 entries as context, got %q", parts[1])
+	}
+
+	h.Fire(fatal)
+	fatal.Time = start.Add(time.Hour)
+	h.Fire(fatal)
+	if len(mails) != 2 {
+		t.Fatalf("expected the throttle to hold back one mail, got %d", len(mails))
+	}
+	if _, parts := readMail(t, mails[1]); !strings.Contains(parts[0], "(1 more suppressed)") {
+		t.Errorf("expected the suppressed count in the next mail, got %q", parts[0])
+	}
+}
+
+// readMail returns the subject and the decoded parts of msg
+func readMail(t *testing.T, msg []byte) (string, []string) {
+	m, err := mail.ReadMessage(bytes.NewReader(msg))
+	if err != nil {
+		t.Fatal(err)
+	}
+	subject, _ := new(mime.WordDecoder).DecodeHeader(m.Header.Get("Subject"))
+	_, params, err := mime.ParseMediaType(m.Header.Get("Content-Type"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	parts := []string{}
+	r := multipart.NewReader(m.Body, params["boundary"])
+	for {
+		p, err := r.NextPart()
+		if err != nil {
+			break
+		}
+		b, _ := ioutil.ReadAll(p)
+		decoded, err := base64.StdEncoding.DecodeString(strings.Replace(strings.TrimSpace(string(b)), "\r\n", "", -1))
+		if err != nil {
+			t.Fatal(err)
+		}
+		parts = append(parts, string(decoded))
+	}
+	return subject, parts
+}
+
+// serveSMTP answers a single session on ln and sends the message received
+func serveSMTP(ln net.Listener, received chan<- string) {
+	conn, err := ln.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	tp := textproto.NewConn(conn)
+	tp.PrintfLine("220 localhost ESMTP")
+	for {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return
+		}
+		switch cmd := strings.ToUpper(strings.SplitN(line, " ", 2)[0]); cmd {
+		case "EHLO", "HELO":
+			tp.PrintfLine("250 localhost")
+		case "DATA":
+			tp.PrintfLine("354 go ahead")
+			b, _ := tp.ReadDotBytes()
+			received <- string(b)
+			tp.PrintfLine("250 queued")
+		case "QUIT":
+			tp.PrintfLine("221 bye")
+			return
+		default:
+			tp.PrintfLine("250 ok")
+		}
+	}
+}
+
+func TestSendMail(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	received := make(chan string, 1)
+	go serveSMTP(ln, received)
+
+	if err := sendMail(time.Now().Add(time.Second), ln.Addr().String(), nil, "api@example.com", []string{"oncall@example.com"}, []byte("Subject: down\r\n\r\nfatal\r\n")); err != nil {
+		t.Fatal(err)
+	}
+	if msg := <-received; !strings.Contains(msg, "fatal") {
+		t.Errorf("expected the message to be sent, got %q", msg)
+	}
+}
+
+func TestSendMailDeadline(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	// accepts and never greets
+	go func() {
+		conn, err := ln.Accept()
+		if err == nil {
+			defer conn.Close()
+			time.Sleep(time.Second)
+		}
+	}()
+
+	start := time.Now()
+	err = sendMail(time.Now().Add(50*time.Millisecond), ln.Addr().String(), nil, "api@example.com", []string{"oncall@example.com"}, []byte("fatal"))
+	if err == nil {
+		t.Fatal("expected a server that doesn't answer to fail the mail")
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("expected the deadline to bound the mail, took %v", elapsed)
+	}
+}
@@ -0,0 +1,233 @@
+package log
+
+import (
+	"bufio"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	// encryptedRecordMagic starts every record, telling encrypted logs apart
+	// from plain ones
+	encryptedRecordMagic = "OPL1"
+
+	// encryptedHeaderSize is the magic, key ID and sealed length
+	encryptedHeaderSize = len(encryptedRecordMagic) + 4 + 4
+
+	// maxEncryptedRecord bounds the length read from a damaged file
+	maxEncryptedRecord = 64 << 20
+)
+
+// EncryptionKey is an AES key of 16, 24 or 32 bytes. ID is stored with
+// every record it seals, so records are decrypted with the right key after
+// the key was rotated.
+type EncryptionKey struct {
+	ID  uint32
+	Key []byte
+}
+
+// EncryptingWriter seals every Write with AES-GCM, for log files on disks
+// other services or people can read. Each Write, an entry when used as the
+// writer of a logger or Destination, becomes one record:
+//
+//	"OPL1" | key ID uint32 | length uint32 | nonce | ciphertext and tag
+//
+// The header is authenticated with the entry. Records need no state from
+// earlier ones, so the file can be rotated or appended to after a restart.
+// Read them back with DecryptLogs or the logdecrypt command. Nonces are
+// random, rotate the key well before 2^32 records.
+type EncryptingWriter struct {
+	w io.Writer
+
+	mu    sync.Mutex
+	keyID uint32
+	aead  cipher.AEAD
+	buf   []byte
+}
+
+// NewEncryptingWriter returns a writer sealing entries with key before
+// writing them to w.
+func NewEncryptingWriter(w io.Writer, key EncryptionKey) (*EncryptingWriter, error) {
+	e := &EncryptingWriter{w: w}
+	if err := e.RotateKey(key); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// RotateKey seals the following writes with key. Keep the old key to
+// decrypt what was written before.
+func (e *EncryptingWriter) RotateKey(key EncryptionKey) error {
+	aead, err := newLogAEAD(key.Key)
+	if err != nil {
+		return err
+	}
+	e.mu.Lock()
+	e.keyID = key.ID
+	e.aead = aead
+	e.mu.Unlock()
+	return nil
+}
+
+func (e *EncryptingWriter) Write(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	nonceSize := e.aead.NonceSize()
+	sealed := nonceSize + len(p) + e.aead.Overhead()
+	if cap(e.buf) < encryptedHeaderSize+sealed {
+		e.buf = make([]byte, 0, encryptedHeaderSize+sealed)
+	}
+	b := append(e.buf[:0], encryptedRecordMagic...)
+	b = appendUint32(b, e.keyID)
+	b = appendUint32(b, uint32(sealed))
+	header := b
+	nonce := b[encryptedHeaderSize : encryptedHeaderSize+nonceSize]
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return 0, err
+	}
+	b = e.aead.Seal(b[:encryptedHeaderSize+nonceSize], nonce, p, header[:encryptedHeaderSize])
+
+	if _, err := e.w.Write(b); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Reopen reopens the underlying writer when it is a Reopener.
+func (e *EncryptingWriter) Reopen() error {
+	if r, ok := e.w.(Reopener); ok {
+		return r.Reopen()
+	}
+	return nil
+}
+
+// Close closes the underlying writer when it is an io.Closer.
+func (e *EncryptingWriter) Close() error {
+	if c, ok := e.w.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+// DecryptLogs writes the entries of the records in r to w, opening them with
+// the key of their ID in keys.
+func DecryptLogs(w io.Writer, r io.Reader, keys []EncryptionKey) error {
+	aeads := make(map[uint32]cipher.AEAD, len(keys))
+	for _, k := range keys {
+		aead, err := newLogAEAD(k.Key)
+		if err != nil {
+			return fmt.Errorf("log: key %d, %v", k.ID, err)
+		}
+		aeads[k.ID] = aead
+	}
+
+	br := bufio.NewReader(r)
+	header := make([]byte, encryptedHeaderSize)
+	var sealed, plain []byte
+	for record := 1; ; record++ {
+		if _, err := io.ReadFull(br, header); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return fmt.Errorf("log: record %d is truncated", record)
+		}
+		if string(header[:len(encryptedRecordMagic)]) != encryptedRecordMagic {
+			return fmt.Errorf("log: record %d is not an encrypted log record", record)
+		}
+		id := binary.BigEndian.Uint32(header[len(encryptedRecordMagic):])
+		n := binary.BigEndian.Uint32(header[len(encryptedRecordMagic)+4:])
+		aead, ok := aeads[id]
+		if !ok {
+			return fmt.Errorf("log: record %d needs key %d", record, id)
+		}
+		if n > maxEncryptedRecord || int(n) < aead.NonceSize()+aead.Overhead() {
+			return fmt.Errorf("log: record %d has a bad length %d", record, n)
+		}
+
+		if cap(sealed) < int(n) {
+			sealed = make([]byte, n)
+		}
+		sealed = sealed[:n]
+		if _, err := io.ReadFull(br, sealed); err != nil {
+			return fmt.Errorf("log: record %d is truncated", record)
+		}
+		nonce := sealed[:aead.NonceSize()]
+		var err error
+		plain, err = aead.Open(plain[:0], nonce, sealed[aead.NonceSize():], header)
+		if err != nil {
+			return fmt.Errorf("log: record %d can't be decrypted, %v", record, err)
+		}
+		if _, err := w.Write(plain); err != nil {
+			return err
+		}
+	}
+}
+
+// LoadEncryptionKeys reads keys from file, one per line as the ID and the
+// base64 encoded key:
+//
+//	1 q3JxYWJ5c2VjcmV0a2V5MTIzNDU2Nzg5MDEyMzQ1Ng==
+//	2 bm90aGVyc2VjcmV0a2V5MTIzNDU2Nzg5MDEyMzQ1Njc=
+//
+// Empty lines and lines starting with # are skipped. The last key is the
+// one to encrypt with, the others decrypt older records.
+func LoadEncryptionKeys(file string) ([]EncryptionKey, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var keys []EncryptionKey
+	s := bufio.NewScanner(f)
+	for line := 1; s.Scan(); line++ {
+		text := strings.TrimSpace(s.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		parts := strings.Fields(text)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("log: %s:%d: expected an ID and a key", file, line)
+		}
+		id, err := strconv.ParseUint(parts[0], 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("log: %s:%d: bad key ID %q", file, line, parts[0])
+		}
+		key, err := base64.StdEncoding.DecodeString(parts[1])
+		if err != nil {
+			return nil, fmt.Errorf("log: %s:%d: bad key, %v", file, line, err)
+		}
+		if _, err := newLogAEAD(key); err != nil {
+			return nil, fmt.Errorf("log: %s:%d: %v", file, line, err)
+		}
+		keys = append(keys, EncryptionKey{ID: uint32(id), Key: key})
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("log: no keys in %s", file)
+	}
+	return keys, nil
+}
+
+func newLogAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,75 @@
+package log
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestEncryptingWriter(t *testing.T) {
+	first := EncryptionKey{ID: 1, Key: bytes.Repeat([]byte{1}, 32)}
+	second := EncryptionKey{ID: 2, Key: bytes.Repeat([]byte{2}, 16)}
+
+	out := &bytes.Buffer{}
+	w, err := NewEncryptingWriter(out, first)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Write([]byte("level=info msg=one\n"))
+	if err := w.RotateKey(second); err != nil {
+		t.Fatal(err)
+	}
+	w.Write([]byte("level=info msg=two\n"))
+
+	if bytes.Contains(out.Bytes(), []byte("msg=")) {
+		t.Fatalf("expected no plain text in %q", out.Bytes())
+	}
+
+	plain := &bytes.Buffer{}
+	if err := DecryptLogs(plain, bytes.NewReader(out.Bytes()), []EncryptionKey{first, second}); err != nil {
+		t.Fatal(err)
+	}
+	if plain.String() != "level=info msg=one\nlevel=info msg=two\n" {
+		t.Errorf("unexpected entries %q", plain.String())
+	}
+
+	err = DecryptLogs(ioutil.Discard, bytes.NewReader(out.Bytes()), []EncryptionKey{first})
+	if err == nil || !strings.Contains(err.Error(), "record 2 needs key 2") {
+		t.Errorf("expected the missing key to be reported, got %v", err)
+	}
+
+	tampered := append([]byte{}, out.Bytes()...)
+	tampered[len(tampered)-1] ^= 1
+	if err := DecryptLogs(ioutil.Discard, bytes.NewReader(tampered), []EncryptionKey{first, second}); err == nil {
+		t.Error("expected a modified record to fail")
+	}
+	if err := DecryptLogs(ioutil.Discard, bytes.NewReader(out.Bytes()[:out.Len()-3]), []EncryptionKey{first, second}); err == nil {
+		t.Error("expected a truncated record to fail")
+	}
+}
+
+func TestLoadEncryptionKeys(t *testing.T) {
+	dir, err := ioutil.TempDir("", "keys")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "log.keys")
+	ioutil.WriteFile(file, []byte("# rotated monthly\n1 AAAAAAAAAAAAAAAAAAAAAA==\n\n7 AQEBAQEBAQEBAQEBAQEBAQEBAQEBAQEBAQEBAQEBAQE=\n"), 0600)
+	keys, err := LoadEncryptionKeys(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 2 || keys[0].ID != 1 || len(keys[0].Key) != 16 || keys[1].ID != 7 || len(keys[1].Key) != 32 {
+		t.Errorf("unexpected keys %v", keys)
+	}
+
+	ioutil.WriteFile(file, []byte("1 AAAA\n"), 0600)
+	if _, err := LoadEncryptionKeys(file); err == nil {
+		t.Error("expected a key of the wrong size to fail")
+	}
+}
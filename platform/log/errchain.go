@@ -0,0 +1,172 @@
+package log
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	logrus "github.com/sirupsen/logrus"
+)
+
+// ErrorChainMode controls how formatters render error values.
+type ErrorChainMode int
+
+const (
+	// ErrorChainOff prints err.Error()
+	ErrorChainOff ErrorChainMode = iota
+
+	// ErrorChainInline prints every error of the chain, outermost first,
+	// joined with " <- ", followed by the %+v stack trace when there is one
+	ErrorChainInline
+
+	// ErrorChainFields keeps err.Error() under the field and adds a
+	// field.cause.N sub-field per wrapped error and field.stack
+	ErrorChainFields
+)
+
+// errorChain returns err followed by the errors it wraps, through Unwrap or
+// the Cause method of github.com/pkg/errors.
+func errorChain(err error) []error {
+	chain := []error{}
+	for err != nil && len(chain) < 32 {
+		chain = append(chain, err)
+		if c, ok := err.(interface{ Cause() error }); ok && c.Cause() != err {
+			err = c.Cause()
+			continue
+		}
+		err = errors.Unwrap(err)
+	}
+	return chain
+}
+
+// chainMessages returns the message each error of chain adds, dropping the
+// ": cause" suffix fmt.Errorf("...: %w") leaves on the wrapping errors.
+func chainMessages(chain []error) []string {
+	messages := make([]string, 0, len(chain))
+	for i, err := range chain {
+		msg := err.Error()
+		if i+1 < len(chain) {
+			msg = strings.TrimSuffix(msg, ": "+chain[i+1].Error())
+		}
+		if msg != "" && (len(messages) == 0 || messages[len(messages)-1] != msg) {
+			messages = append(messages, msg)
+		}
+	}
+	return messages
+}
+
+// errorStack returns the %+v rendering of err when it is more than its
+// message, which is how github.com/pkg/errors prints stack traces.
+func errorStack(err error) string {
+	s := fmt.Sprintf("%+v", err)
+	if s == err.Error() {
+		return ""
+	}
+	return s
+}
+
+func inlineErrorChain(err error) string {
+	s := strings.Join(chainMessages(errorChain(err)), " <- ")
+	if stack := errorStack(err); stack != "" {
+		s += "\n" + stack
+	}
+	return s
+}
+
+// expandErrorFields returns a copy of data with the cause and stack
+// sub-fields of ErrorChainFields added for every error value.
+func expandErrorFields(data logrus.Fields) logrus.Fields {
+	expanded := make(logrus.Fields, len(data))
+	for k, v := range data {
+		expanded[k] = v
+		err, ok := v.(error)
+		if !ok {
+			continue
+		}
+		expanded[k] = err.Error()
+		for i, msg := range chainMessages(errorChain(err)) {
+			if i > 0 {
+				expanded[fmt.Sprintf("%s.cause.%d", k, i)] = msg
+			}
+		}
+		if stack := errorStack(err); stack != "" {
+			expanded[k+".stack"] = stack
+		}
+	}
+	return expanded
+}
+
+// errorDetails is implemented by errors carrying a code and fields, such as
+// errors.StructuredError of platform/errors.
+type errorDetails interface {
+	ErrorCode() string
+	ErrorFields() map[string]interface{}
+}
+
+// prepareEntry returns the level of entry and the entry formatters write,
+// without LevelKey, HighlightKey and ColorKey and with the details of errors
+// expanded.
+func prepareEntry(entry *logrus.Entry) (Level, *logrus.Entry) {
+	level, entry := entryLevel(entry)
+	return level, expandErrorDetails(stripStyle(entry))
+}
+
+// expandErrorDetails returns entry with a field.code field and a
+// field.<name> field per error field added for every error value with
+// details anywhere in its chain. The outermost code and fields win. entry is
+// returned as it is when there are none.
+func expandErrorDetails(entry *logrus.Entry) *logrus.Entry {
+	found := false
+	for _, v := range entry.Data {
+		if err, ok := v.(error); ok && hasErrorDetails(err) {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return entry
+	}
+
+	expanded := *entry
+	expanded.Data = make(logrus.Fields, len(entry.Data)+4)
+	for k, v := range entry.Data {
+		expanded.Data[k] = v
+	}
+	for k, v := range entry.Data {
+		err, ok := v.(error)
+		if !ok {
+			continue
+		}
+		for _, e := range errorChain(err) {
+			d, ok := e.(errorDetails)
+			if !ok {
+				continue
+			}
+			if code := d.ErrorCode(); code != "" {
+				if _, ok := expanded.Data[k+".code"]; !ok {
+					expanded.Data[k+".code"] = code
+				}
+			}
+			for name, value := range d.ErrorFields() {
+				if _, ok := expanded.Data[k+"."+name]; !ok {
+					expanded.Data[k+"."+name] = value
+				}
+			}
+		}
+	}
+	return &expanded
+}
+
+func hasErrorDetails(err error) bool {
+	for i := 0; err != nil && i < 32; i++ {
+		if _, ok := err.(errorDetails); ok {
+			return true
+		}
+		if c, ok := err.(interface{ Cause() error }); ok && c.Cause() != err {
+			err = c.Cause()
+			continue
+		}
+		err = errors.Unwrap(err)
+	}
+	return false
+}
@@ -0,0 +1,51 @@
+package log
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	perrors "github.com/o3labs/openpoint/platform/errors"
+	logrus "github.com/sirupsen/logrus"
+)
+
+func TestErrorChain(t *testing.T) {
+	root := errors.New("connection refused")
+	err := fmt.Errorf("charge ch_1: %w", fmt.Errorf("dial stripe: %w", root))
+
+	if s := inlineErrorChain(err); s != "charge ch_1 <- dial stripe <- connection refused" {
+		t.Errorf("unexpected inline chain %q", s)
+	}
+
+	fields := expandErrorFields(map[string]interface{}{"error": err, "amount": 10})
+	if fields["error"] != err.Error() || fields["error.cause.1"] != "dial stripe" || fields["error.cause.2"] != "connection refused" {
+		t.Errorf("unexpected fields %+v", fields)
+	}
+	if fields["amount"] != 10 {
+		t.Errorf("expected other fields untouched %+v", fields)
+	}
+}
+
+func TestStructuredErrorFields(t *testing.T) {
+	declined := perrors.Newf("card_declined", "card declined").With("decline_code", "insufficient_funds")
+	err := fmt.Errorf("charge ch_1: %w", perrors.Wrap(declined, "", "charge failed").With("charge", "ch_1"))
+	if perrors.CodeOf(err) != "card_declined" {
+		t.Errorf("unexpected code %q", perrors.CodeOf(err))
+	}
+
+	entry := &logrus.Entry{Level: logrus.ErrorLevel, Data: logrus.Fields{"error": err}}
+	b, _ := (&LogfmtFormatter{DisableTimestamp: true}).Format(entry)
+	expected := `level=error msg= error="charge ch_1: charge failed: card declined" error.charge=ch_1 error.code=card_declined error.decline_code=insufficient_funds` + "\n"
+	if string(b) != expected {
+		t.Errorf("expected %q got %q", expected, b)
+	}
+	if len(entry.Data) != 1 {
+		t.Errorf("expected the entry left alone, got %+v", entry.Data)
+	}
+
+	b, _ = (&ECSFormatter{}).Format(entry)
+	if !strings.Contains(string(b), `"code":"card_declined"`) || strings.Contains(string(b), `"error.code"`) {
+		t.Errorf("expected the code in the ECS error object, got %s", b)
+	}
+}
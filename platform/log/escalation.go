@@ -0,0 +1,226 @@
+package log
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	logrus "github.com/sirupsen/logrus"
+)
+
+const (
+	defaultEscalationCooldown = 15 * time.Minute
+	escalationCheckInterval   = 10 * time.Second
+)
+
+// EscalationRule triggers its notifiers once Threshold entries at Level or
+// more severe are logged on Channel within Window, e.g. 10 errors on
+// payments within 5 minutes:
+//
+//	log.EscalationRule{Name: "payments", Channel: "payments", Threshold: 10, Window: 5 * time.Minute, Notifiers: []string{"oncall"}}
+//
+// The rule resolves, notifying again, once no entry was counted for a
+// whole Window.
+type EscalationRule struct {
+	Name string
+
+	// Channel counts the entries of the channel and its children, e.g.
+	// http counts http.client. Empty counts every entry.
+	Channel string
+
+	// Level is the least severe level counted. Defaults to Error.
+	Level logrus.Level
+
+	Threshold int
+	Window    time.Duration
+
+	// Cooldown is the minimum time between two triggers of the rule, so a
+	// flapping error doesn't page every Window. Defaults to 15m.
+	Cooldown time.Duration
+
+	// Notifiers are names given to RegisterNotifier
+	Notifiers []string
+}
+
+// escalationState counts the entries of a rule
+type escalationState struct {
+	rule EscalationRule
+
+	// times of the last Threshold entries counted, oldest first
+	times []time.Time
+
+	level       logrus.Level
+	message     string
+	triggered   bool
+	triggeredAt time.Time
+}
+
+// EscalationHook sends alerts when entries cross the thresholds of its
+// rules, lightweight alerting for services without a metrics stack. Close
+// it on shutdown to stop checking for resolved rules.
+type EscalationHook struct {
+	mu     sync.Mutex
+	states []*escalationState
+
+	stop chan struct{}
+	done chan struct{}
+	once sync.Once
+}
+
+// NewEscalationHook starts a hook checking rules.
+func NewEscalationHook(rules ...EscalationRule) (*EscalationHook, error) {
+	h := &EscalationHook{stop: make(chan struct{}), done: make(chan struct{})}
+	for _, r := range rules {
+		if r.Threshold <= 0 || r.Window <= 0 {
+			return nil, fmt.Errorf("log: escalation rule %q without a threshold and window", r.Name)
+		}
+		if r.Level == logrus.PanicLevel {
+			r.Level = logrus.ErrorLevel
+		}
+		if r.Cooldown <= 0 {
+			r.Cooldown = defaultEscalationCooldown
+		}
+		h.states = append(h.states, &escalationState{rule: r, level: r.Level})
+	}
+	go h.run()
+	return h, nil
+}
+
+func (h *EscalationHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (h *EscalationHook) Fire(entry *logrus.Entry) error {
+	channel, _ := entry.Data[ChannelKey].(string)
+	var rules []*escalationState
+	var alerts []Alert
+	h.mu.Lock()
+	for _, s := range h.states {
+		if entry.Level > s.rule.Level || !escalationChannel(s.rule.Channel, channel) {
+			continue
+		}
+		if a, ok := s.count(entry); ok {
+			rules = append(rules, s)
+			alerts = append(alerts, a)
+		}
+	}
+	h.mu.Unlock()
+	for i, a := range alerts {
+		notify(a, rules[i].rule.Notifiers)
+	}
+	return nil
+}
+
+// count adds entry to the rule and returns the alert when it triggers.
+func (s *escalationState) count(entry *logrus.Entry) (Alert, bool) {
+	if len(s.times) == 0 || entry.Level < s.level {
+		s.level = entry.Level
+	}
+	s.message = entry.Message
+	s.times = append(s.times, entry.Time)
+	if len(s.times) > s.rule.Threshold {
+		s.times = s.times[1:]
+	}
+	if s.triggered || len(s.times) < s.rule.Threshold || entry.Time.Sub(s.times[0]) > s.rule.Window {
+		return Alert{}, false
+	}
+	if !s.triggeredAt.IsZero() && entry.Time.Sub(s.triggeredAt) < s.rule.Cooldown {
+		return Alert{}, false
+	}
+	s.triggered = true
+	s.triggeredAt = entry.Time
+	return s.alert(AlertTriggered, entry.Time), true
+}
+
+func (s *escalationState) alert(status AlertStatus, t time.Time) Alert {
+	return Alert{
+		Rule:    s.rule.Name,
+		Status:  status,
+		Channel: s.rule.Channel,
+		Level:   s.level,
+		Count:   len(s.times),
+		Window:  s.rule.Window,
+		Message: s.message,
+		Time:    t,
+	}
+}
+
+// Close stops checking for resolved rules.
+func (h *EscalationHook) Close() error {
+	closed := false
+	h.once.Do(func() {
+		closed = true
+		close(h.stop)
+	})
+	if !closed {
+		return ErrWriterClosed
+	}
+	<-h.done
+	return nil
+}
+
+func (h *EscalationHook) run() {
+	defer close(h.done)
+	ticker := time.NewTicker(escalationCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			h.check(now())
+		case <-h.stop:
+			return
+		}
+	}
+}
+
+// check resolves the triggered rules that counted no entry within their
+// window before t.
+func (h *EscalationHook) check(t time.Time) {
+	var resolved []*escalationState
+	var alerts []Alert
+	h.mu.Lock()
+	for _, s := range h.states {
+		if !s.triggered || t.Sub(s.times[len(s.times)-1]) < s.rule.Window {
+			continue
+		}
+		resolved = append(resolved, s)
+		alerts = append(alerts, s.alert(AlertResolved, t))
+		s.triggered = false
+		s.times = nil
+	}
+	h.mu.Unlock()
+	for i, a := range alerts {
+		notify(a, resolved[i].rule.Notifiers)
+	}
+}
+
+// notify sends a to the notifiers named names in the background, as the
+// hook is fired while entries are logged.
+func notify(a Alert, names []string) {
+	for _, name := range names {
+		n, ok := notifier(name)
+		if !ok {
+			reportf("Failed to send alert %s because notifier %s isn't registered", a.Rule, name)
+			continue
+		}
+		go func(name string, n Notifier) {
+			ctx, cancel := context.WithTimeout(context.Background(), notifyTimeout)
+			defer cancel()
+			if err := n.Notify(ctx, a); err != nil {
+				reportf("Failed to send alert %s to %s because %+v", a.Rule, name, err)
+			}
+		}(name, n)
+	}
+}
+
+// escalationChannel reports whether channel is rule or one of its children.
+// Rules for every channel leave out the internal channel, which reports the
+// notifiers failing.
+func escalationChannel(rule, channel string) bool {
+	if rule == "" {
+		return channel != InternalChannel
+	}
+	return channel == rule || strings.HasPrefix(channel, rule+".")
+}
@@ -0,0 +1,102 @@
+package log
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	logrus "github.com/sirupsen/logrus"
+)
+
+func TestEscalationHook(t *testing.T) {
+	alerts := make(chan Alert, 10)
+	defer RegisterNotifier("test", NotifierFunc(func(ctx context.Context, a Alert) error {
+		alerts <- a
+		return nil
+	}))()
+	h, err := NewEscalationHook(EscalationRule{Name: "payments", Channel: "payments", Threshold: 3, Window: time.Minute, Cooldown: time.Hour, Notifiers: []string{"test"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+
+	start := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	fire := func(channel string, level logrus.Level, after time.Duration) {
+		h.Fire(&logrus.Entry{Level: level, Time: start.Add(after), Message: "charge failed", Data: logrus.Fields{ChannelKey: channel}})
+	}
+	fire("payments", logrus.ErrorLevel, 0)
+	fire("payments", logrus.WarnLevel, time.Second)
+	fire("billing", logrus.ErrorLevel, 2*time.Second)
+	fire("payments.stripe", logrus.ErrorLevel, 3*time.Second)
+	fire("payments", logrus.ErrorLevel, 90*time.Second)
+	select {
+	case a := <-alerts:
+		t.Fatalf("expected no alert for entries spread over more than the window, got %+v", a)
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	fire("payments", logrus.FatalLevel, 100*time.Second)
+	fire("payments", logrus.ErrorLevel, 101*time.Second)
+	a := <-alerts
+	if a.Status != AlertTriggered || a.Count != 3 || a.Level != logrus.FatalLevel || a.Rule != "payments" {
+		t.Errorf("unexpected alert %+v", a)
+	}
+	fire("payments", logrus.ErrorLevel, 102*time.Second)
+
+	h.check(start.Add(140 * time.Second))
+	select {
+	case a := <-alerts:
+		t.Fatalf("expected the rule to stay triggered within the window, got %+v", a)
+	case <-time.After(20 * time.Millisecond):
+	}
+	h.check(start.Add(200 * time.Second))
+	if a := <-alerts; a.Status != AlertResolved {
+		t.Errorf("expected the rule to resolve, got %+v", a)
+	}
+
+	for i := 0; i < 3; i++ {
+		fire("payments", logrus.ErrorLevel, 300*time.Second)
+	}
+	select {
+	case a := <-alerts:
+		t.Fatalf("expected the cooldown to hold the alert back, got %+v", a)
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	if _, err := NewEscalationHook(EscalationRule{Name: "empty"}); err == nil {
+		t.Errorf("expected an error for a rule without a threshold")
+	}
+}
+
+func TestPagerDutyNotifier(t *testing.T) {
+	events := make(chan map[string]interface{}, 2)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		event := map[string]interface{}{}
+		json.NewDecoder(r.Body).Decode(&event)
+		events <- event
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	n := &PagerDutyNotifier{RoutingKey: "key", Source: "api-1", URL: server.URL}
+	a := Alert{Rule: "payments", Channel: "payments", Level: logrus.ErrorLevel, Count: 10, Window: 5 * time.Minute, Message: "charge failed", Time: time.Now()}
+	if err := n.Notify(context.Background(), a); err != nil {
+		t.Fatal(err)
+	}
+	event := <-events
+	payload, _ := event["payload"].(map[string]interface{})
+	if event["event_action"] != "trigger" || event["dedup_key"] != "api-1/payments" || payload["severity"] != "error" {
+		t.Errorf("unexpected event %v", event)
+	}
+
+	a.Status = AlertResolved
+	if err := n.Notify(context.Background(), a); err != nil {
+		t.Fatal(err)
+	}
+	if event := <-events; event["event_action"] != "resolve" || event["dedup_key"] != "api-1/payments" {
+		t.Errorf("unexpected event %v", event)
+	}
+}
@@ -0,0 +1,100 @@
+package log
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// Escaping is how the text and logfmt formatters write values that contain
+// spaces, quotes or other characters that need quoting.
+type Escaping int
+
+const (
+	// EscapeDefault is EscapeGo for ChannelTextFormatter and EscapeJSON for
+	// LogfmtFormatter.
+	EscapeDefault Escaping = iota
+
+	// EscapeGo quotes as strconv.Quote does, escaping control characters,
+	// invalid UTF-8 and runes that aren't printable, such as zero width
+	// joiners.
+	EscapeGo
+
+	// EscapeJSON quotes as JSON strings, escaping quotes, backslashes and
+	// control characters and keeping every other rune as it is.
+	EscapeJSON
+
+	// EscapeMinimal quotes escaping only quotes, backslashes and line
+	// breaks, so entries stay on one line and parse back.
+	EscapeMinimal
+
+	// EscapeRaw writes values as they are, without quotes, for consumers
+	// that don't parse the values back. Entries may span lines.
+	EscapeRaw
+)
+
+// ParseEscaping parses go, json, minimal or raw.
+func ParseEscaping(s string) (Escaping, error) {
+	switch strings.ToLower(s) {
+	case "":
+		return EscapeDefault, nil
+	case "go":
+		return EscapeGo, nil
+	case "json":
+		return EscapeJSON, nil
+	case "minimal":
+		return EscapeMinimal, nil
+	case "raw":
+		return EscapeRaw, nil
+	}
+	return 0, fmt.Errorf("log: unknown escaping %q", s)
+}
+
+// or returns def for EscapeDefault.
+func (e Escaping) or(def Escaping) Escaping {
+	if e == EscapeDefault {
+		return def
+	}
+	return e
+}
+
+// appendQuoted writes s quoted and escaped as e, as it is for EscapeRaw.
+func (e Escaping) appendQuoted(b *bytes.Buffer, s string) {
+	switch e {
+	case EscapeJSON:
+		appendLogfmtQuoted(b, s)
+	case EscapeMinimal:
+		appendMinimalQuoted(b, s)
+	case EscapeRaw:
+		b.WriteString(s)
+	default:
+		appendQuoted(b, s)
+	}
+}
+
+// appendMinimalQuoted writes s quoted, escaping quotes, backslashes and
+// line breaks.
+func appendMinimalQuoted(b *bytes.Buffer, s string) {
+	b.WriteByte('"')
+	start := 0
+	for i := 0; i < len(s); i++ {
+		var esc string
+		switch s[i] {
+		case '"':
+			esc = `\"`
+		case '\\':
+			esc = `\\`
+		case '\n':
+			esc = `\n`
+		case '\r':
+			esc = `\r`
+		default:
+			continue
+		}
+		b.WriteString(s[start:i])
+		b.WriteString(esc)
+		start = i + 1
+	}
+	b.WriteString(s[start:])
+	b.WriteByte('"')
+}
@@ -0,0 +1,55 @@
+package log
+
+import (
+	"testing"
+
+	logrus "github.com/sirupsen/logrus"
+)
+
+func TestEscaping(t *testing.T) {
+	// a zero width joiner, which Go quoting escapes, between the emoji
+	value := "family 👨\u200d👩\t\"ü\"\nnext"
+	entry := benchmarkEntry(logrus.Fields{"note": value})
+	goQuoted := `note="family 👨\u200d👩\t\"ü\"\nnext"`
+	jsonQuoted := "note=\"family 👨\u200d👩\\t\\\"ü\\\"\\nnext\""
+	minimalQuoted := "note=\"family 👨\u200d👩\t\\\"ü\\\"\\nnext\""
+	for _, tt := range []struct {
+		escaping Escaping
+		text     string
+		logfmt   string
+	}{
+		{EscapeDefault, goQuoted, jsonQuoted},
+		{EscapeGo, goQuoted, goQuoted},
+		{EscapeJSON, jsonQuoted, jsonQuoted},
+		{EscapeMinimal, minimalQuoted, minimalQuoted},
+		{EscapeRaw, "note=" + value, "note=" + value},
+	} {
+		b, err := (&ChannelTextFormatter{DisableColors: true, DisableTimestamp: true, Escaping: tt.escaping}).Format(entry)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected := `level=info msg="charge succeeded" ` + tt.text + "\n"
+		if tt.escaping == EscapeRaw {
+			expected = `level=info msg=charge succeeded ` + tt.text + "\n"
+		}
+		if string(b) != expected {
+			t.Errorf("text %d: expected %q got %q", tt.escaping, expected, b)
+		}
+
+		b, err = (&LogfmtFormatter{DisableTimestamp: true, Escaping: tt.escaping}).Format(entry)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected = `level=info msg="charge succeeded" ` + tt.logfmt + "\n"
+		if tt.escaping == EscapeRaw {
+			expected = `level=info msg=charge succeeded ` + tt.logfmt + "\n"
+		}
+		if string(b) != expected {
+			t.Errorf("logfmt %d: expected %q got %q", tt.escaping, expected, b)
+		}
+	}
+
+	if _, err := ParseEscaping("shell"); err == nil {
+		t.Errorf("expected an error for an unknown escaping")
+	}
+}
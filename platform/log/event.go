@@ -0,0 +1,108 @@
+package log
+
+import (
+	"fmt"
+	"time"
+
+	logrus "github.com/sirupsen/logrus"
+)
+
+// EventKey is the field holding the name of entries logged with LogEvent,
+// written by every formatter under the same key, event.action for ECS, so
+// analytics can count and aggregate events without matching messages.
+const EventKey = "event"
+
+// Event is something that happened, as opposed to a message for people to
+// read, e.g. a completed checkout:
+//
+//	log.LogEvent(log.FromContext(ctx), log.Event{
+//		Name:  "checkout.completed",
+//		Attrs: []log.Attr{log.IntAttr("items", 3), log.StringAttr("plan", "pro")},
+//	})
+//
+// Keep names stable, they are what events are counted by.
+type Event struct {
+	Name string
+
+	// Severity is a level name, see LookupLevel. Defaults to info.
+	Severity string
+
+	// Attrs are written as fields of the entry. An attribute named like
+	// EventKey is written as fields.event.
+	Attrs []Attr
+
+	// Message is the message of the entry. Defaults to Name.
+	Message string
+}
+
+// Attr is an attribute of an Event.
+type Attr struct {
+	Key   string
+	Value interface{}
+}
+
+// StringAttr, IntAttr, FloatAttr, BoolAttr, DurationAttr and TimeAttr
+// return attributes of the types analytics can aggregate. Durations render
+// like Duration.
+func StringAttr(key, value string) Attr {
+	return Attr{Key: key, Value: value}
+}
+
+func IntAttr(key string, value int64) Attr {
+	return Attr{Key: key, Value: value}
+}
+
+func FloatAttr(key string, value float64) Attr {
+	return Attr{Key: key, Value: value}
+}
+
+func BoolAttr(key string, value bool) Attr {
+	return Attr{Key: key, Value: value}
+}
+
+func DurationAttr(key string, value time.Duration) Attr {
+	return Attr{Key: key, Value: Duration(value)}
+}
+
+func TimeAttr(key string, value time.Time) Attr {
+	return Attr{Key: key, Value: value}
+}
+
+// LogEvent logs e on entry. It returns an error for an event without a name
+// or with an unknown severity.
+func LogEvent(entry *logrus.Entry, e Event) error {
+	if e.Name == "" {
+		return fmt.Errorf("log: event without a name")
+	}
+	severity := e.Severity
+	if severity == "" {
+		severity = "info"
+	}
+	level, err := LookupLevel(severity)
+	if err != nil {
+		return err
+	}
+	if !entry.Logger.IsLevelEnabled(level.Level) {
+		return nil
+	}
+
+	fields := make(logrus.Fields, len(e.Attrs)+1)
+	for _, a := range e.Attrs {
+		if a.Key == EventKey {
+			fields["fields."+EventKey] = a.Value
+			continue
+		}
+		fields[a.Key] = a.Value
+	}
+	fields[EventKey] = e.Name
+	msg := e.Message
+	if msg == "" {
+		msg = e.Name
+	}
+	return LogAt(entry.WithFields(fields), level.Name, msg)
+}
+
+// Event logs e on the channel, see LogEvent.
+func (l *Logger) Event(e Event) error {
+	return LogEvent(l.WithFields(logrus.Fields{}), e)
+}
@@ -0,0 +1,54 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	logrus "github.com/sirupsen/logrus"
+)
+
+func TestLogEvent(t *testing.T) {
+	b := &bytes.Buffer{}
+	channel := Channel("checkout")
+	channel.SetOutput(b)
+	channel.SetFormatter(&ChannelTextFormatter{DisableColors: true, DisableTimestamp: true})
+	channel.SetLevel(logrus.InfoLevel)
+
+	err := channel.Event(Event{
+		Name:  "checkout.completed",
+		Attrs: []Attr{IntAttr("items", 3), StringAttr("plan", "pro"), DurationAttr("took", 1234*time.Millisecond), BoolAttr("event", true)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	channel.Event(Event{Name: "cart.viewed", Severity: "debug"})
+	expected := `level=info msg=checkout.completed channel=checkout event=checkout.completed fields.event=true items=3 plan=pro took=1.23s` + "\n"
+	if b.String() != expected {
+		t.Errorf("expected %q got %q", expected, b.String())
+	}
+
+	if err := channel.Event(Event{}); err == nil {
+		t.Errorf("expected an error for an event without a name")
+	}
+	if err := channel.Event(Event{Name: "x", Severity: "loud"}); err == nil {
+		t.Errorf("expected an error for an unknown severity")
+	}
+
+	b.Reset()
+	channel.SetFormatter(&ECSFormatter{})
+	channel.Event(Event{Name: "refund.issued", Severity: "warning", Message: "refund over limit"})
+	record := struct {
+		Message string `json:"message"`
+		Event   struct {
+			Action string `json:"action"`
+		} `json:"event"`
+	}{}
+	if err := json.Unmarshal(b.Bytes(), &record); err != nil {
+		t.Fatal(err)
+	}
+	if record.Event.Action != "refund.issued" || record.Message != "refund over limit" {
+		t.Errorf("unexpected ECS record %q", b.String())
+	}
+}
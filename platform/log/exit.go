@@ -0,0 +1,132 @@
+package log
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ExitHook runs before the process exits on a Fatal entry, e.g. to log a
+// final heartbeat or write a crash marker. ctx is done once the exit
+// timeout passes.
+type ExitHook func(ctx context.Context) error
+
+type exitHookEntry struct {
+	name string
+	hook ExitHook
+	seq  uint64
+}
+
+var (
+	exitHooksMu  sync.Mutex
+	exitHooks    = map[*exitHookEntry]bool{}
+	exitHooksSeq uint64
+
+	exitTimeout int64 = int64(fatalShutdownTimeout)
+	exiting     int32
+)
+
+// RegisterExitHook adds hook to the hooks run before a Fatal entry exits
+// the process and returns the function removing it. The hooks run the most
+// recently registered first, like deferred calls, and before the sinks are
+// flushed, so what they log is written. name identifies the hook when it
+// fails.
+func RegisterExitHook(name string, hook ExitHook) func() {
+	exitHooksMu.Lock()
+	exitHooksSeq++
+	e := &exitHookEntry{name: name, hook: hook, seq: exitHooksSeq}
+	exitHooks[e] = true
+	exitHooksMu.Unlock()
+
+	return func() {
+		exitHooksMu.Lock()
+		delete(exitHooks, e)
+		exitHooksMu.Unlock()
+	}
+}
+
+// SetExitTimeout bounds the exit hooks and the flush of the sinks together.
+// Defaults to 5s.
+func SetExitTimeout(d time.Duration) {
+	atomic.StoreInt64(&exitTimeout, int64(d))
+}
+
+// RunExitHooks runs the exit hooks and then Shutdown within the exit
+// timeout, for processes exiting on their own after a fatal error, e.g. a
+// recovered panic in main. Fatal entries run it. It returns the first
+// error. Calls made while it runs, e.g. by a hook logging at Fatal, return
+// right away.
+func RunExitHooks() error {
+	if !atomic.CompareAndSwapInt32(&exiting, 0, 1) {
+		return nil
+	}
+	defer atomic.StoreInt32(&exiting, 0)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(atomic.LoadInt64(&exitTimeout)))
+	defer cancel()
+
+	var firstErr error
+	for _, e := range registeredExitHooks() {
+		if err := runExitHook(ctx, e); err != nil {
+			reportf("Failed to run exit hook %s because %+v", e.name, err)
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	if err := Shutdown(ctx); err != nil && firstErr == nil {
+		firstErr = err
+	}
+	return firstErr
+}
+
+// runExitHook runs e, giving up on it once ctx is done.
+func runExitHook(ctx context.Context, e *exitHookEntry) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if v := recover(); v != nil {
+				done <- fmt.Errorf("panic: %v", v)
+			}
+		}()
+		done <- e.hook(ctx)
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// registeredExitHooks returns the exit hooks, the most recent first.
+func registeredExitHooks() []*exitHookEntry {
+	exitHooksMu.Lock()
+	entries := make([]*exitHookEntry, 0, len(exitHooks))
+	for e := range exitHooks {
+		entries = append(entries, e)
+	}
+	exitHooksMu.Unlock()
+	sort.Slice(entries, func(i, j int) bool { return entries[i].seq > entries[j].seq })
+	return entries
+}
+
+// CrashMarker returns an exit hook writing the time and process ID to path,
+// so a supervisor or the next start can tell the process died on a fatal
+// error rather than being stopped:
+//
+//	log.RegisterExitHook("crash marker", log.CrashMarker("/var/run/api.crashed"))
+func CrashMarker(path string) ExitHook {
+	return func(ctx context.Context) error {
+		marker := fmt.Sprintf("time=%s pid=%d\n", now().Format(time.RFC3339Nano), os.Getpid())
+		return ioutil.WriteFile(path, []byte(marker), 0644)
+	}
+}
@@ -0,0 +1,71 @@
+package log
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRunExitHooks(t *testing.T) {
+	dir, err := ioutil.TempDir("", "exit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "crashed")
+
+	var order []string
+	defer RegisterExitHook("marker", CrashMarker(path))()
+	defer RegisterExitHook("first", func(ctx context.Context) error {
+		order = append(order, "first")
+		return nil
+	})()
+	defer RegisterExitHook("heartbeat", func(ctx context.Context) error {
+		order = append(order, "heartbeat")
+		return nil
+	})()
+
+	if err := RunExitHooks(); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(order, ",") != "heartbeat,first" {
+		t.Errorf("expected the most recent hook first, got %v", order)
+	}
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(b), "pid=") {
+		t.Errorf("unexpected crash marker %q", b)
+	}
+}
+
+func TestExitTimeout(t *testing.T) {
+	SetExitTimeout(20 * time.Millisecond)
+	defer SetExitTimeout(fatalShutdownTimeout)
+
+	ran := false
+	defer RegisterExitHook("late", func(ctx context.Context) error {
+		ran = true
+		return nil
+	})()
+	defer RegisterExitHook("stuck", func(ctx context.Context) error {
+		time.Sleep(time.Second)
+		return nil
+	})()
+
+	start := time.Now()
+	if err := RunExitHooks(); err == nil {
+		t.Errorf("expected the stuck hook to time out")
+	}
+	if time.Since(start) > 500*time.Millisecond {
+		t.Errorf("expected the timeout to bound the hooks, took %v", time.Since(start))
+	}
+	if ran {
+		t.Errorf("expected the hooks after the timeout to be skipped")
+	}
+}
@@ -0,0 +1,105 @@
+package log
+
+import (
+	"io"
+	"sync"
+	"time"
+)
+
+const defaultFailoverProbeInterval = 10 * time.Second
+
+// FailoverWriter writes to Primary, e.g. a remote collector, and switches to
+// Secondary, e.g. a local file or stderr, as soon as a write to Primary
+// fails, writing the failed entry there too. While failed over, the first
+// write after every ProbeInterval is tried on Primary again and switches back
+// when it succeeds. Entries are only dropped when both writers fail.
+type FailoverWriter struct {
+	Primary   io.Writer
+	Secondary io.Writer
+
+	// ProbeInterval between attempts to go back to Primary. Defaults to 10s.
+	ProbeInterval time.Duration
+
+	// OnSwitch, when set, is called after switching writers with the error
+	// of Primary, or nil when switching back. It runs while the logger holds
+	// its lock, so it must not log to the same logger.
+	OnSwitch func(err error)
+
+	mu       sync.Mutex
+	failed   bool
+	failedAt time.Time
+}
+
+// NewFailoverWriter returns a writer falling back from primary to secondary.
+func NewFailoverWriter(primary, secondary io.Writer) *FailoverWriter {
+	return &FailoverWriter{
+		Primary:       primary,
+		Secondary:     secondary,
+		ProbeInterval: defaultFailoverProbeInterval,
+	}
+}
+
+func (w *FailoverWriter) Write(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if !w.failed || now().Sub(w.failedAt) >= w.probeInterval() {
+		_, err := w.Primary.Write(p)
+		if err == nil {
+			if w.failed {
+				w.failed = false
+				w.notify(nil)
+			}
+			return len(p), nil
+		}
+		// a failed probe waits another interval
+		w.failedAt = now()
+		if !w.failed {
+			w.failed = true
+			w.notify(err)
+		}
+	}
+
+	if _, err := w.Secondary.Write(p); err != nil {
+		recordDropped(1)
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Failed reports whether entries currently go to Secondary.
+func (w *FailoverWriter) Failed() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.failed
+}
+
+func (w *FailoverWriter) probeInterval() time.Duration {
+	if w.ProbeInterval <= 0 {
+		return defaultFailoverProbeInterval
+	}
+	return w.ProbeInterval
+}
+
+func (w *FailoverWriter) notify(err error) {
+	if w.OnSwitch != nil {
+		w.OnSwitch(err)
+	}
+}
+
+// Close closes both writers if they are io.Closers and returns the first
+// error.
+func (w *FailoverWriter) Close() error {
+	var err error
+	for _, x := range []io.Writer{w.Primary, w.Secondary} {
+		if c, ok := x.(io.Closer); ok {
+			if cerr := c.Close(); err == nil {
+				err = cerr
+			}
+		}
+	}
+	return err
+}
@@ -0,0 +1,51 @@
+package log
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+)
+
+type flakyWriter struct {
+	bytes.Buffer
+	down bool
+}
+
+func (w *flakyWriter) Write(p []byte) (int, error) {
+	if w.down {
+		return 0, errors.New("connection refused")
+	}
+	return w.Buffer.Write(p)
+}
+
+func TestFailoverWriter(t *testing.T) {
+	primary := &flakyWriter{}
+	secondary := &bytes.Buffer{}
+	switches := []error{}
+	w := NewFailoverWriter(primary, secondary)
+	w.ProbeInterval = 20 * time.Millisecond
+	w.OnSwitch = func(err error) { switches = append(switches, err) }
+
+	w.Write([]byte("a\n"))
+	primary.down = true
+	w.Write([]byte("b\n"))
+	primary.down = false
+	w.Write([]byte("c\n"))
+	if !w.Failed() {
+		t.Error("expected to stay on the secondary until the next probe")
+	}
+
+	time.Sleep(30 * time.Millisecond)
+	w.Write([]byte("d\n"))
+	if w.Failed() {
+		t.Error("expected to switch back to the primary")
+	}
+
+	if primary.String() != "a\nd\n" || secondary.String() != "b\nc\n" {
+		t.Errorf("unexpected writes, primary %q secondary %q", primary.String(), secondary.String())
+	}
+	if len(switches) != 2 || switches[0] == nil || switches[1] != nil {
+		t.Errorf("expected a switch to the secondary and back, got %v", switches)
+	}
+}
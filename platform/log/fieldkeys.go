@@ -0,0 +1,122 @@
+package log
+
+import (
+	"fmt"
+	"strings"
+
+	logrus "github.com/sirupsen/logrus"
+)
+
+// FieldKey names a key the text, json and logfmt formatters write for every
+// entry.
+type FieldKey string
+
+const (
+	FieldKeyTime  FieldKey = "time"
+	FieldKeyLevel FieldKey = "level"
+	FieldKeyMsg   FieldKey = "msg"
+)
+
+// FieldMap renames the keys of the time, level and message, e.g. to match
+// what an aggregator expects:
+//
+//	FieldMap{FieldKeyTime: "@timestamp", FieldKeyMsg: "message"}
+type FieldMap map[FieldKey]string
+
+// resolve returns the name of key, def when it isn't renamed. The json
+// formatter writes the time as date and the message as message by default.
+func (m FieldMap) resolve(key FieldKey, def string) string {
+	if k, ok := m[key]; ok {
+		return k
+	}
+	return def
+}
+
+// ParseFieldMap checks the keys of m, as read from a Config.
+func ParseFieldMap(m map[string]string) (FieldMap, error) {
+	if len(m) == 0 {
+		return nil, nil
+	}
+	fm := make(FieldMap, len(m))
+	for k, v := range m {
+		switch key := FieldKey(k); key {
+		case FieldKeyTime, FieldKeyLevel, FieldKeyMsg:
+			fm[key] = v
+		default:
+			return nil, fmt.Errorf("log: cannot rename %q, only time, level and msg", k)
+		}
+	}
+	return fm, nil
+}
+
+// prefixFieldClashes renames the fields of data named like the keys the
+// formatter writes itself to fields.<key>, so a field named time doesn't
+// make a second time key.
+func prefixFieldClashes(data logrus.Fields, timeKey, levelKey, msgKey string) {
+	for _, k := range [...]string{timeKey, levelKey, msgKey} {
+		if v, ok := data[k]; ok {
+			delete(data, k)
+			data["fields."+k] = v
+		}
+	}
+}
+
+// takeNestedClashes removes the fields prefixFieldClashes renamed, and the
+// dotted fields under the keys such as message.id, from data and returns them
+// by their original names, for groupNestedClashes to add once data is nested.
+func takeNestedClashes(data logrus.Fields, timeKey, levelKey, msgKey string) map[string]interface{} {
+	clashes := map[string]interface{}{}
+	for k, v := range data {
+		for _, key := range [...]string{timeKey, levelKey, msgKey} {
+			if k == "fields."+key {
+				clashes[key] = v
+			} else if strings.HasPrefix(k, key+".") {
+				clashes[k] = v
+			} else {
+				continue
+			}
+			delete(data, k)
+			break
+		}
+	}
+	return clashes
+}
+
+// groupNestedClashes adds clashes to the fields object of nested, keyed by
+// their original names, so message and message.id both end up in
+// "fields":{"message":...,"message.id":...}. A clash stays a fields.<name>
+// key when the fields object is taken.
+func groupNestedClashes(nested logrus.Fields, clashes map[string]interface{}, maxDepth int) {
+	if len(clashes) == 0 {
+		return
+	}
+	if maxDepth <= 0 {
+		maxDepth = defaultMaxDepth
+	}
+	group, ok := nested["fields"].(map[string]interface{})
+	if _, taken := nested["fields"]; !taken {
+		group, ok = map[string]interface{}{}, true
+		nested["fields"] = group
+	}
+	for k, v := range clashes {
+		v = nestValue(v, maxDepth, map[uintptr]bool{})
+		if _, taken := group[k]; ok && !taken {
+			group[k] = v
+		} else {
+			nested["fields."+k] = v
+		}
+	}
+}
+
+// prefixEntryClashes returns entry, or a copy of it when some of its fields
+// clash with the keys, see prefixFieldClashes.
+func prefixEntryClashes(entry *logrus.Entry, timeKey, levelKey, msgKey string) *logrus.Entry {
+	for _, k := range [...]string{timeKey, levelKey, msgKey} {
+		if _, ok := entry.Data[k]; ok {
+			c := CopyEntry(entry)
+			prefixFieldClashes(c.Data, timeKey, levelKey, msgKey)
+			return c
+		}
+	}
+	return entry
+}
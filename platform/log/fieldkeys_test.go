@@ -0,0 +1,74 @@
+package log
+
+import (
+	"testing"
+	"time"
+
+	logrus "github.com/sirupsen/logrus"
+)
+
+func TestFieldClashes(t *testing.T) {
+	entry := &logrus.Entry{
+		Time:    time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC),
+		Level:   logrus.InfoLevel,
+		Message: "imported",
+		Data:    logrus.Fields{"time": "yesterday", "level": 3, "msg": "row", "rows": 10},
+	}
+	for _, tt := range []struct {
+		formatter logrus.Formatter
+		expected  string
+	}{
+		{&ChannelTextFormatter{DisableColors: true, TimestampFormat: time.RFC3339},
+			`time="2024-03-01T12:00:00Z" level=info msg=imported fields.level=3 fields.msg=row fields.time=yesterday rows=10` + "\n"},
+		{&LogfmtFormatter{},
+			`time=2024-03-01T12:00:00Z level=info msg=imported fields.level=3 fields.msg=row fields.time=yesterday rows=10` + "\n"},
+		{&ChannelJSONFormatter{},
+			`{"date":"2024-03-01T12:00:00Z","level":"info","message":"imported","fields.level":3,"msg":"row","rows":10,"time":"yesterday"}` + "\n"},
+		{&ChannelJSONFormatter{FieldMap: FieldMap{FieldKeyTime: "time", FieldKeyMsg: "msg"}},
+			`{"time":"2024-03-01T12:00:00Z","level":"info","msg":"imported","fields.level":3,"fields.msg":"row","fields.time":"yesterday","rows":10}` + "\n"},
+		{&LogfmtFormatter{FieldMap: FieldMap{FieldKeyTime: "@timestamp", FieldKeyLevel: "severity"}},
+			`@timestamp=2024-03-01T12:00:00Z severity=info msg=imported fields.msg=row level=3 rows=10 time=yesterday` + "\n"},
+	} {
+		b, err := tt.formatter.Format(entry)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != tt.expected {
+			t.Errorf("%T: expected %s got %s", tt.formatter, tt.expected, b)
+		}
+	}
+	if _, ok := entry.Data["time"]; !ok {
+		t.Errorf("expected the entry to be left as it is")
+	}
+
+	if _, err := ParseFieldMap(map[string]string{"caller": "src"}); err == nil {
+		t.Errorf("expected an unknown key to fail")
+	}
+}
+
+func TestNestedFieldClashes(t *testing.T) {
+	entry := &logrus.Entry{
+		Level:   logrus.InfoLevel,
+		Message: "imported",
+		Data:    logrus.Fields{"message": "row", "message.id": 7, "level.name": "debug"},
+	}
+	b, err := (&ChannelJSONFormatter{DisableTimestamp: true, NestFields: true}).Format(entry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `{"level":"info","message":"imported","fields":{"level.name":"debug","message":"row","message.id":7}}` + "\n"
+	if string(b) != expected {
+		t.Errorf("expected %s got %s", expected, b)
+	}
+
+	// the renamed keys clash instead of the defaults
+	entry.Data = logrus.Fields{"msg": "row", "msg.id": 7, "message.id": 8}
+	b, err = (&ChannelJSONFormatter{DisableTimestamp: true, NestFields: true, FieldMap: FieldMap{FieldKeyMsg: "msg"}}).Format(entry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected = `{"level":"info","msg":"imported","fields":{"msg":"row","msg.id":7},"message":{"id":8}}` + "\n"
+	if string(b) != expected {
+		t.Errorf("expected %s got %s", expected, b)
+	}
+}
@@ -0,0 +1,242 @@
+package log
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	logrus "github.com/sirupsen/logrus"
+)
+
+const (
+	defaultFluentBufferSize = 8192
+	defaultFluentTimeout    = 3 * time.Second
+)
+
+// FluentHook streams entries to Fluentd or Fluent Bit over the forward
+// protocol from a background goroutine. Entries are buffered while the server
+// is unreachable and sent in order once it is back; past BufferSize the
+// oldest entries are dropped.
+type FluentHook struct {
+	// Addr of the forward input, e.g. "127.0.0.1:24224"
+	Addr string
+
+	// Tag of every entry. The channel name is appended when there is one,
+	// e.g. "openpoint.payments".
+	Tag string
+
+	// RequireAck waits for the server to acknowledge every message
+	RequireAck bool
+
+	// BufferSize is the number of entries kept while the server is down.
+	// Defaults to 8192.
+	BufferSize int
+
+	// Timeout for connecting, writing and waiting for an ack, and the wait
+	// before connecting again. Defaults to 3s.
+	Timeout time.Duration
+
+	mu     sync.Mutex
+	buffer []fluentMessage
+	closed bool
+
+	// conn is only used by the run goroutine
+	conn     net.Conn
+	closeErr error
+
+	wake chan struct{}
+	stop chan struct{}
+	done chan struct{}
+
+	registration sinkRegistration
+}
+
+type fluentMessage struct {
+	chunk string
+	b     []byte
+}
+
+// NewFluentHook starts a hook sending to the forward input at addr. Call
+// Close on shutdown to send what is still buffered.
+func NewFluentHook(addr, tag string) *FluentHook {
+	h := &FluentHook{
+		Addr: addr,
+		Tag:  tag,
+		wake: make(chan struct{}, 1),
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+	h.registration.register(h, SinkQueue)
+	go h.run()
+	return h
+}
+
+func (h *FluentHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (h *FluentHook) Fire(entry *logrus.Entry) error {
+	tag := h.Tag
+	if channel, ok := entry.Data[ChannelKey].(string); ok && channel != "" {
+		tag += "." + channel
+	}
+
+	record := make(map[string]interface{}, len(entry.Data)+2)
+	for k, v := range entry.Data {
+		if k != HighlightKey && k != ColorKey {
+			record[k] = v
+		}
+	}
+	record["message"] = entry.Message
+	record["level"] = entry.Level.String()
+
+	msg := fluentMessage{}
+	option := map[string]interface{}{}
+	if h.RequireAck {
+		id := make([]byte, 16)
+		if _, err := rand.Read(id); err != nil {
+			return err
+		}
+		msg.chunk = base64.StdEncoding.EncodeToString(id)
+		option["chunk"] = msg.chunk
+	}
+	msg.b = appendMsgpack(nil, []interface{}{tag, entry.Time.Unix(), record, option})
+
+	h.mu.Lock()
+	if h.closed {
+		// still attached to the logger after Close
+		h.mu.Unlock()
+		recordDropped(1)
+		return nil
+	}
+	h.buffer = append(h.buffer, msg)
+	h.trim()
+	h.mu.Unlock()
+
+	select {
+	case h.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// trim drops the oldest messages past BufferSize. h.mu must be held.
+func (h *FluentHook) trim() {
+	bufferSize := h.BufferSize
+	if bufferSize <= 0 {
+		bufferSize = defaultFluentBufferSize
+	}
+	if over := len(h.buffer) - bufferSize; over > 0 {
+		h.buffer = h.buffer[over:]
+		recordDropped(uint64(over))
+	}
+}
+
+func (h *FluentHook) run() {
+	defer close(h.done)
+	var retry <-chan time.Time
+	for {
+		// don't dial a server that is down for every entry
+		wake := h.wake
+		if retry != nil {
+			wake = nil
+		}
+		select {
+		case <-wake:
+		case <-retry:
+		case <-h.stop:
+			h.closeErr = h.flush()
+			if h.conn != nil {
+				h.conn.Close()
+				h.conn = nil
+			}
+			h.mu.Lock()
+			recordDropped(uint64(len(h.buffer)))
+			h.buffer = nil
+			h.mu.Unlock()
+			return
+		}
+		retry = nil
+		if err := h.flush(); err != nil {
+			retry = time.After(h.timeout())
+		}
+	}
+}
+
+// flush sends the buffered messages in order and puts back those it
+// couldn't send. Only called by run.
+func (h *FluentHook) flush() error {
+	h.mu.Lock()
+	pending := h.buffer
+	h.buffer = nil
+	h.mu.Unlock()
+
+	for i, msg := range pending {
+		if err := h.send(msg); err != nil {
+			if h.conn != nil {
+				h.conn.Close()
+				h.conn = nil
+			}
+			h.mu.Lock()
+			h.buffer = append(pending[i:len(pending):len(pending)], h.buffer...)
+			h.trim()
+			h.mu.Unlock()
+			return err
+		}
+	}
+	return nil
+}
+
+func (h *FluentHook) timeout() time.Duration {
+	if h.Timeout <= 0 {
+		return defaultFluentTimeout
+	}
+	return h.Timeout
+}
+
+func (h *FluentHook) send(msg fluentMessage) error {
+	timeout := h.timeout()
+	if h.conn == nil {
+		conn, err := net.DialTimeout("tcp", h.Addr, timeout)
+		if err != nil {
+			return err
+		}
+		h.conn = conn
+	}
+
+	h.conn.SetDeadline(time.Now().Add(timeout))
+	if _, err := h.conn.Write(msg.b); err != nil {
+		return err
+	}
+	if msg.chunk == "" {
+		return nil
+	}
+	resp, err := readMsgpackStringMap(h.conn)
+	if err != nil {
+		return err
+	}
+	if resp["ack"] != msg.chunk {
+		return fmt.Errorf("log: fluentd acked %q, expected %q", resp["ack"], msg.chunk)
+	}
+	return nil
+}
+
+// Close sends what is still buffered, dropping it when the server is down,
+// and closes the connection. Entries fired after Close are dropped.
+func (h *FluentHook) Close() error {
+	h.registration.release()
+	h.mu.Lock()
+	if h.closed {
+		h.mu.Unlock()
+		<-h.done
+		return nil
+	}
+	h.closed = true
+	h.mu.Unlock()
+	close(h.stop)
+	<-h.done
+	return h.closeErr
+}
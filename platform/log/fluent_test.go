@@ -0,0 +1,94 @@
+package log
+
+import (
+	"bytes"
+	"net"
+	"testing"
+	"time"
+
+	logrus "github.com/sirupsen/logrus"
+)
+
+func TestFluentHook(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	received := make(chan []byte, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		b := make([]byte, 512)
+		n, _ := conn.Read(b)
+		received <- b[:n]
+	}()
+
+	h := NewFluentHook(ln.Addr().String(), "openpoint")
+	entry := logrus.NewEntry(logrus.StandardLogger()).WithField(ChannelKey, "payments")
+	entry.Level = logrus.InfoLevel
+	entry.Message = "paid"
+	if err := h.Fire(entry); err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+
+	select {
+	case b := <-received:
+		// [tag, time, record, option]
+		expected := append([]byte{0x94, 0xa0 | 18}, "openpoint.payments"...)
+		if !bytes.HasPrefix(b, expected) {
+			t.Errorf("expected forward message for openpoint.payments, got %x", b)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("nothing received")
+	}
+}
+
+func TestFluentHookDoesNotBlock(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	// accepts and never acks
+	go func() {
+		conn, err := ln.Accept()
+		if err == nil {
+			defer conn.Close()
+			time.Sleep(time.Second)
+		}
+	}()
+
+	h := NewFluentHook(ln.Addr().String(), "openpoint")
+	h.RequireAck = true
+	h.Timeout = 100 * time.Millisecond
+	entry := logrus.NewEntry(logrus.StandardLogger())
+	entry.Message = "paid"
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if err := h.Fire(entry); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed > 50*time.Millisecond {
+		t.Errorf("expected Fire not to wait for the ack, took %v", elapsed)
+	}
+
+	dropped := Dropped()
+	if err := h.Close(); err == nil {
+		t.Error("expected Close to return the failed send")
+	}
+	if Dropped() != dropped+3 {
+		t.Errorf("expected the unsent entries to be dropped, %d were", Dropped()-dropped)
+	}
+	h.Fire(entry)
+	if Dropped() != dropped+4 {
+		t.Errorf("expected the entry fired after Close to be dropped")
+	}
+}
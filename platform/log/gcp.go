@@ -0,0 +1,192 @@
+package log
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/logging"
+	logrus "github.com/sirupsen/logrus"
+)
+
+// Fields of the Cloud Logging structured payload
+const (
+	cloudLoggingTraceKey          = "logging.googleapis.com/trace"
+	cloudLoggingSpanIDKey         = "logging.googleapis.com/spanId"
+	cloudLoggingLabelsKey         = "logging.googleapis.com/labels"
+	cloudLoggingSourceLocationKey = "logging.googleapis.com/sourceLocation"
+)
+
+// CloudLoggingFormatter formats logs as the JSON payload the Cloud Logging
+// agent of GKE and Cloud Run parses, so entries get their severity, trace
+// and channel label instead of all showing as INFO.
+type CloudLoggingFormatter struct {
+	// ProjectID completes the trace field as projects/<id>/traces/<trace_id>
+	// so Cloud Trace links to the entry. Without it the bare trace id is
+	// written.
+	ProjectID string
+
+	// ReportCaller adds the file, line and function that logged the entry
+	// as its source location.
+	ReportCaller bool
+
+	// CallerSkip is the number of frames to skip above the first caller
+	// outside logrus and this package.
+	CallerSkip int
+}
+
+// Format renders a single log entry
+func (f *CloudLoggingFormatter) Format(entry *logrus.Entry) ([]byte, error) {
+	level, entry := prepareEntry(entry)
+	data := cloudLoggingPayload(entry)
+	data["severity"] = strings.ToUpper(cloudLoggingSeverity(level).String())
+	data["timestamp"] = entry.Time.UTC().Format(time.RFC3339Nano)
+
+	if v, ok := entry.Data[TraceIDKey]; ok {
+		data[cloudLoggingTraceKey] = cloudLoggingTrace(f.ProjectID, v)
+	}
+	if v, ok := entry.Data[SpanIDKey]; ok {
+		data[cloudLoggingSpanIDKey] = v
+	}
+	if labels := cloudLoggingLabels(entry); labels != nil {
+		data[cloudLoggingLabelsKey] = labels
+	}
+	if f.ReportCaller {
+		if frame, ok := callerFrame(f.CallerSkip); ok {
+			data[cloudLoggingSourceLocationKey] = map[string]interface{}{
+				"file":     frame.File,
+				"line":     fmt.Sprint(frame.Line),
+				"function": frame.Function,
+			}
+		}
+	}
+
+	serialized, err := json.Marshal(data)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to marshal fields to JSON, %v", err)
+	}
+	return append(serialized, '\n'), nil
+}
+
+// CloudLoggingHook writes entries straight to the Cloud Logging API, for
+// deployments outside GKE and Cloud Run where no agent reads stdout. The
+// client batches entries in the background.
+type CloudLoggingHook struct {
+	ProjectID string
+
+	client *logging.Client
+	logger *logging.Logger
+
+	registration sinkRegistration
+}
+
+// NewCloudLoggingHook returns a hook writing to the log logID of projectID
+// with the application default credentials. Call Close on shutdown to send
+// what is still batched.
+func NewCloudLoggingHook(projectID, logID string) (*CloudLoggingHook, error) {
+	client, err := logging.NewClient(context.Background(), projectID)
+	if err != nil {
+		return nil, err
+	}
+	client.OnError = func(err error) {
+		recordDropped(1)
+		reportf("Failed to send log entries to Cloud Logging because %+v", err)
+	}
+	h := &CloudLoggingHook{
+		ProjectID: projectID,
+		client:    client,
+		logger:    client.Logger(logID),
+	}
+	h.registration.register(h, SinkQueue)
+	return h, nil
+}
+
+func (h *CloudLoggingHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (h *CloudLoggingHook) Fire(entry *logrus.Entry) error {
+	level, entry := prepareEntry(entry)
+	e := logging.Entry{
+		Timestamp: entry.Time,
+		Severity:  cloudLoggingSeverity(level),
+		Payload:   cloudLoggingPayload(entry),
+		Labels:    cloudLoggingLabels(entry),
+	}
+	if v, ok := entry.Data[TraceIDKey]; ok {
+		e.Trace = cloudLoggingTrace(h.ProjectID, v)
+	}
+	if v, ok := entry.Data[SpanIDKey]; ok {
+		e.SpanID = fmt.Sprint(v)
+	}
+	h.logger.Log(e)
+
+	if entry.Level <= logrus.FatalLevel {
+		// the process is going down, don't lose the entry
+		return h.logger.Flush()
+	}
+	return nil
+}
+
+// Close sends the pending entries and closes the client.
+func (h *CloudLoggingHook) Close() error {
+	h.registration.release()
+	return h.client.Close()
+}
+
+// cloudLoggingPayload returns the message and the fields the payload
+// carries as they are, errors as their message.
+func cloudLoggingPayload(entry *logrus.Entry) map[string]interface{} {
+	data := make(map[string]interface{}, len(entry.Data)+4)
+	for k, v := range entry.Data {
+		switch k {
+		case TraceIDKey, SpanIDKey, ChannelKey:
+			continue
+		}
+		if err, ok := v.(error); ok {
+			v = err.Error()
+		}
+		data[k] = v
+	}
+	data["message"] = entry.Message
+	return data
+}
+
+// cloudLoggingLabels returns the channel as a label, or nil without channel.
+func cloudLoggingLabels(entry *logrus.Entry) map[string]string {
+	v, ok := entry.Data[ChannelKey]
+	if !ok {
+		return nil
+	}
+	return map[string]string{ChannelKey: fmt.Sprint(v)}
+}
+
+func cloudLoggingTrace(projectID string, traceID interface{}) string {
+	if projectID == "" {
+		return fmt.Sprint(traceID)
+	}
+	return fmt.Sprintf("projects/%s/traces/%v", projectID, traceID)
+}
+
+func cloudLoggingSeverity(level Level) logging.Severity {
+	if level.Severity == 5 {
+		return logging.Notice
+	}
+	switch level.Level {
+	case logrus.TraceLevel, logrus.DebugLevel:
+		return logging.Debug
+	case logrus.InfoLevel:
+		return logging.Info
+	case logrus.WarnLevel:
+		return logging.Warning
+	case logrus.ErrorLevel:
+		return logging.Error
+	case logrus.FatalLevel:
+		return logging.Critical
+	case logrus.PanicLevel:
+		return logging.Alert
+	}
+	return logging.Default
+}
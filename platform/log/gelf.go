@@ -0,0 +1,196 @@
+package log
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"sync"
+
+	logrus "github.com/sirupsen/logrus"
+)
+
+const (
+	gelfVersion          = "1.1"
+	defaultGELFChunkSize = 1420
+	gelfMaxChunks        = 128
+
+	// every chunk carries a 12 byte header: magic, message id, sequence
+	gelfChunkHeaderSize = 12
+	gelfMinChunkSize    = 512
+)
+
+// GELFFormatter formats logs as GELF 1.1 messages for Graylog. Entry fields
+// become additional fields prefixed with an underscore.
+type GELFFormatter struct {
+	// Host defaults to os.Hostname()
+	Host string
+
+	sync.Once
+}
+
+// Format renders a single log entry
+func (f *GELFFormatter) Format(entry *logrus.Entry) ([]byte, error) {
+	level, entry := prepareEntry(entry)
+	f.Do(func() {
+		if f.Host == "" {
+			f.Host, _ = os.Hostname()
+		}
+	})
+
+	message := entry.Message
+	if message == "" {
+		// Errorf and friends keep the text in the error field
+		if v, ok := entry.Data["error"]; ok {
+			message = fmt.Sprint(v)
+		}
+	}
+	short := message
+	if i := strings.IndexByte(short, '\n'); i >= 0 {
+		short = short[:i]
+	}
+	if short == "" {
+		short = "-"
+	}
+
+	data := make(map[string]interface{}, len(entry.Data)+6)
+	data["version"] = gelfVersion
+	data["host"] = f.Host
+	data["short_message"] = short
+	if short != message {
+		data["full_message"] = message
+	}
+	data["timestamp"] = float64(entry.Time.UnixNano()) / 1e9
+	data["level"] = level.Severity
+
+	for k, v := range entry.Data {
+		key := "_" + gelfFieldName(k)
+		if key == "_id" {
+			// reserved by Graylog
+			key = "_id_"
+		}
+		switch v := v.(type) {
+		case error:
+			data[key] = v.Error()
+		case string, bool, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+			data[key] = v
+		default:
+			data[key] = fmt.Sprint(v)
+		}
+	}
+
+	serialized, err := json.Marshal(data)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to marshal fields to JSON, %v", err)
+	}
+	return append(serialized, '\n'), nil
+}
+
+func gelfFieldName(s string) string {
+	return strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') ||
+			r == '_' || r == '.' || r == '-' {
+			return r
+		}
+		return '_'
+	}, s)
+}
+
+// GELFWriter sends GELF messages to Graylog over UDP, splitting messages
+// larger than ChunkSize into GELF chunks.
+type GELFWriter struct {
+	// ChunkSize is the maximum datagram size, raised to 512 bytes when
+	// smaller. Defaults to 1420 bytes.
+	ChunkSize int
+
+	// Compress messages with gzip before sending
+	Compress bool
+
+	mu   sync.Mutex
+	conn net.Conn
+
+	registration sinkRegistration
+}
+
+// NewGELFWriter connects to the Graylog GELF UDP input at addr.
+func NewGELFWriter(addr string) (*GELFWriter, error) {
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	w := &GELFWriter{conn: conn}
+	w.registration.register(w, SinkOutput)
+	return w, nil
+}
+
+func (w *GELFWriter) Write(p []byte) (int, error) {
+	msg := bytes.TrimRight(p, "\n")
+	if w.Compress {
+		b := &bytes.Buffer{}
+		gz := gzip.NewWriter(b)
+		if _, err := gz.Write(msg); err != nil {
+			return 0, err
+		}
+		if err := gz.Close(); err != nil {
+			return 0, err
+		}
+		msg = b.Bytes()
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	chunkSize := w.chunkSize()
+	if len(msg) <= chunkSize {
+		if _, err := w.conn.Write(msg); err != nil {
+			return 0, err
+		}
+		return len(p), nil
+	}
+
+	dataSize := chunkSize - gelfChunkHeaderSize
+	count := (len(msg) + dataSize - 1) / dataSize
+	if count > gelfMaxChunks {
+		return 0, fmt.Errorf("log: GELF message needs %d chunks, max is %d", count, gelfMaxChunks)
+	}
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return 0, err
+	}
+	chunk := make([]byte, 0, chunkSize)
+	for i := 0; i < count; i++ {
+		end := (i + 1) * dataSize
+		if end > len(msg) {
+			end = len(msg)
+		}
+		chunk = append(chunk[:0], 0x1e, 0x0f)
+		chunk = append(chunk, id...)
+		chunk = append(chunk, byte(i), byte(count))
+		chunk = append(chunk, msg[i*dataSize:end]...)
+		if _, err := w.conn.Write(chunk); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// chunkSize returns ChunkSize, defaulted and large enough for chunks to
+// carry data after their header.
+func (w *GELFWriter) chunkSize() int {
+	switch {
+	case w.ChunkSize <= 0:
+		return defaultGELFChunkSize
+	case w.ChunkSize < gelfMinChunkSize:
+		return gelfMinChunkSize
+	}
+	return w.ChunkSize
+}
+
+func (w *GELFWriter) Close() error {
+	w.registration.release()
+	return w.conn.Close()
+}
@@ -0,0 +1,46 @@
+package log
+
+import (
+	"bytes"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestGELFWriterChunks(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	w, err := NewGELFWriter(conn.LocalAddr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	// too small for a chunk header, raised to the minimum
+	w.ChunkSize = 12
+
+	msg := bytes.Repeat([]byte("x"), 1000)
+	if _, err := w.Write(append(msg, '\n')); err != nil {
+		t.Fatal(err)
+	}
+
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	var data []byte
+	for i := 0; i < 2; i++ {
+		b := make([]byte, 2048)
+		n, _, err := conn.ReadFrom(b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n > gelfMinChunkSize || b[0] != 0x1e || b[1] != 0x0f || b[10] != byte(i) || b[11] != 2 {
+			t.Fatalf("expected chunk %d of 2 within %d bytes, got %d bytes % x", i, gelfMinChunkSize, n, b[:12])
+		}
+		data = append(data, b[gelfChunkHeaderSize:n]...)
+	}
+	if !bytes.Equal(data, msg) {
+		t.Errorf("expected the chunks to carry the message")
+	}
+}
@@ -0,0 +1,183 @@
+package log
+
+import (
+	"bytes"
+	"unicode"
+
+	logrus "github.com/sirupsen/logrus"
+)
+
+// Glyphs are the level markers ChannelTextFormatter and DevFormatter put in
+// front of colored entries, making levels easy to spot when scrolling.
+type Glyphs struct {
+	Trace string
+	Debug string
+	Info  string
+	Warn  string
+	Error string
+	Fatal string
+	Panic string
+}
+
+// Built-in glyphs. EmojiGlyphs show in any terminal with an emoji font,
+// NerdFontGlyphs need a patched font from https://www.nerdfonts.com.
+var (
+	EmojiGlyphs = &Glyphs{
+		Trace: "🔍",
+		Debug: "🐛",
+		Info:  "🔵",
+		Warn:  "🟡",
+		Error: "🔴",
+		Fatal: "💀",
+		Panic: "🔥",
+	}
+
+	NerdFontGlyphs = &Glyphs{
+		Trace: "\uf002", // nf-fa-search
+		Debug: "\uf188", // nf-fa-bug
+		Info:  "\uf05a", // nf-fa-info_circle
+		Warn:  "\uf071", // nf-fa-warning
+		Error: "\uf057", // nf-fa-times_circle
+		Fatal: "\uf1e2", // nf-fa-bomb
+		Panic: "\uf06d", // nf-fa-fire
+	}
+)
+
+// glyphWidth is the column glyphs are padded to, emoji take two cells
+const glyphWidth = 2
+
+// Level returns the glyph of level.
+func (g *Glyphs) Level(level logrus.Level) string {
+	switch level {
+	case logrus.TraceLevel:
+		return g.Trace
+	case logrus.DebugLevel:
+		return g.Debug
+	case logrus.InfoLevel:
+		return g.Info
+	case logrus.WarnLevel:
+		return g.Warn
+	case logrus.ErrorLevel:
+		return g.Error
+	case logrus.FatalLevel:
+		return g.Fatal
+	default:
+		return g.Panic
+	}
+}
+
+// write writes the glyph of level in color c, padded to glyphWidth
+// cells and followed by a space.
+func (g *Glyphs) write(b *bytes.Buffer, level logrus.Level, c Color) {
+	glyph := g.Level(level)
+	c.write(b, glyph)
+	for w := displayWidth(glyph); w < glyphWidth; w++ {
+		b.WriteByte(' ')
+	}
+	b.WriteByte(' ')
+}
+
+// padDisplay writes s in color c followed by spaces up to width terminal
+// cells, the way fmt's %-*s pads by runes.
+func padDisplay(b *bytes.Buffer, s string, width int, c Color) {
+	c.write(b, s)
+	for w := displayWidth(s); w < width; w++ {
+		b.WriteByte(' ')
+	}
+}
+
+// displayWidth returns the number of terminal cells s takes: two for East
+// Asian wide characters and emoji, none for combining marks and joiners.
+func displayWidth(s string) int {
+	width := 0
+	for _, r := range s {
+		width += runeWidth(r)
+	}
+	return width
+}
+
+func runeWidth(r rune) int {
+	switch {
+	case r < 0x20 || r == 0x7f:
+		return 0
+	case r < 0x300:
+		return 1
+	case r == 0x200d || (r >= 0xfe00 && r <= 0xfe0f):
+		// zero width joiner and variation selectors
+		return 0
+	case unicode.In(r, unicode.Mn, unicode.Me, unicode.Cf):
+		return 0
+	}
+	for _, w := range wideRanges {
+		if r < w[0] {
+			return 1
+		}
+		if r <= w[1] {
+			return 2
+		}
+	}
+	return 1
+}
+
+// wideRanges are the East Asian Wide and Fullwidth blocks and the emoji
+// blocks shown in emoji presentation, in order.
+var wideRanges = [][2]rune{
+	{0x1100, 0x115f},   // Hangul Jamo
+	{0x231a, 0x231b},   // watch, hourglass
+	{0x23e9, 0x23ec},   // media buttons
+	{0x23f0, 0x23f0},   // alarm clock
+	{0x23f3, 0x23f3},   // hourglass
+	{0x25fd, 0x25fe},   // small squares
+	{0x2614, 0x2615},   // umbrella, hot beverage
+	{0x2648, 0x2653},   // zodiac
+	{0x267f, 0x267f},   // wheelchair
+	{0x2693, 0x2693},   // anchor
+	{0x26a1, 0x26a1},   // high voltage
+	{0x26aa, 0x26ab},   // circles
+	{0x26bd, 0x26be},   // balls
+	{0x26c4, 0x26c5},   // snowman, sun
+	{0x26ce, 0x26ce},   // ophiuchus
+	{0x26d4, 0x26d4},   // no entry
+	{0x26ea, 0x26ea},   // church
+	{0x26f2, 0x26f5},   // fountain to sailboat
+	{0x26fa, 0x26fa},   // tent
+	{0x26fd, 0x26fd},   // fuel pump
+	{0x2705, 0x2705},   // check mark
+	{0x270a, 0x270b},   // fists
+	{0x2728, 0x2728},   // sparkles
+	{0x274c, 0x274c},   // cross mark
+	{0x274e, 0x274e},   // cross mark button
+	{0x2753, 0x2755},   // question marks
+	{0x2757, 0x2757},   // exclamation mark
+	{0x2795, 0x2797},   // math signs
+	{0x27b0, 0x27b0},   // curly loop
+	{0x27bf, 0x27bf},   // double curly loop
+	{0x2b1b, 0x2b1c},   // large squares
+	{0x2b50, 0x2b50},   // star
+	{0x2b55, 0x2b55},   // circle
+	{0x2e80, 0x303e},   // CJK radicals to CJK symbols
+	{0x3041, 0x33ff},   // Hiragana to CJK compatibility
+	{0x3400, 0x4dbf},   // CJK extension A
+	{0x4e00, 0x9fff},   // CJK unified ideographs
+	{0xa000, 0xa4cf},   // Yi
+	{0xa960, 0xa97f},   // Hangul Jamo extended A
+	{0xac00, 0xd7a3},   // Hangul syllables
+	{0xf900, 0xfaff},   // CJK compatibility ideographs
+	{0xfe10, 0xfe19},   // vertical forms
+	{0xfe30, 0xfe6f},   // CJK compatibility forms, small forms
+	{0xff00, 0xff60},   // fullwidth forms
+	{0xffe0, 0xffe6},   // fullwidth signs
+	{0x16fe0, 0x18cff}, // Tangut
+	{0x1b000, 0x1b2ff}, // Kana supplement and extensions
+	{0x1f004, 0x1f004}, // mahjong tile
+	{0x1f0cf, 0x1f0cf}, // joker
+	{0x1f18e, 0x1f18e}, // AB button
+	{0x1f191, 0x1f19a}, // squared words
+	{0x1f200, 0x1f2ff}, // enclosed ideographic supplement
+	{0x1f300, 0x1f64f}, // pictographs, emoticons
+	{0x1f680, 0x1f6ff}, // transport and map
+	{0x1f7e0, 0x1f7eb}, // colored circles and squares
+	{0x1f90c, 0x1f9ff}, // supplemental pictographs
+	{0x1fa70, 0x1faff}, // symbols and pictographs extended A
+	{0x20000, 0x3fffd}, // CJK extensions B and later
+}
@@ -0,0 +1,55 @@
+package log
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	logrus "github.com/sirupsen/logrus"
+)
+
+func TestDisplayWidth(t *testing.T) {
+	for s, want := range map[string]int{
+		"payment":    7,
+		"支払い":        6,
+		"결제 완료":      9,
+		"café":       4,
+		"cafe\u0301": 4,
+		"🔴":          2,
+		"\uf071":     1,
+		"👩\u200d💻":   4,
+	} {
+		if got := displayWidth(s); got != want {
+			t.Errorf("%q: expected %d cells, got %d", s, want, got)
+		}
+	}
+}
+
+func TestTextFormatterGlyphs(t *testing.T) {
+	f := &ChannelTextFormatter{ForceColors: true, DisableTimestamp: true, Theme: &Theme{}, Glyphs: EmojiGlyphs}
+	var lines []string
+	for _, msg := range []string{"payment received", "支払いを受け取りました"} {
+		b, err := f.Format(&logrus.Entry{Level: logrus.InfoLevel, Time: time.Now(), Message: msg, Data: logrus.Fields{"id": 1}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		lines = append(lines, string(b))
+	}
+	if !strings.HasPrefix(lines[0], "🔵 INFO ") {
+		t.Errorf("expected the info glyph first, got %q", lines[0])
+	}
+	// the fields start at the same column on screen
+	for _, line := range lines {
+		i := strings.Index(line, "id=")
+		if w := displayWidth(line[:i]); w != displayWidth(lines[0][:strings.Index(lines[0], "id=")]) {
+			t.Errorf("expected the fields aligned, got %q", lines)
+		}
+	}
+
+	b := &bytes.Buffer{}
+	NerdFontGlyphs.write(b, logrus.WarnLevel, "")
+	if b.String() != "  " {
+		t.Errorf("expected narrow glyphs padded to two cells, got %q", b.String())
+	}
+}
@@ -0,0 +1,144 @@
+package log
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	logrus "github.com/sirupsen/logrus"
+)
+
+// Run "go test -run TestGolden -update" after changing a formatter and review
+// the diff of testdata/*.golden.
+var update = flag.Bool("update", false, "rewrite the golden files")
+
+type goldenCard struct {
+	Brand string
+	Last4 string
+}
+
+// goldenEntries returns an entry per level, each carrying the values
+// formatters tend to get wrong.
+func goldenEntries() []*logrus.Entry {
+	entries := []*logrus.Entry{}
+	for _, level := range []logrus.Level{logrus.TraceLevel, logrus.DebugLevel, logrus.InfoLevel, logrus.WarnLevel, logrus.ErrorLevel, logrus.FatalLevel, logrus.PanicLevel} {
+		entry := logrus.NewEntry(logrus.StandardLogger())
+		entry.Time = time.Date(2018, 2, 26, 10, 4, 5, 123000000, time.UTC)
+		entry.Level = level
+		entry.Message = "charge " + level.String()
+		entry.Data = logrus.Fields{
+			ChannelKey: "payments",
+			"amount":   1000,
+			"rate":     0.25,
+			"captured": false,
+			"empty":    "",
+			"nothing":  nil,
+			"special":  "quote \" backslash \\ newline\n tab\t equals= unicode ü",
+			"card":     goldenCard{Brand: "visa", Last4: "4242"},
+			"meta":     map[string]interface{}{"order": "o_1", "items": []int{1, 2}},
+			"tags":     []string{"web", "retry"},
+		}
+		if level <= logrus.ErrorLevel {
+			entry.Data["error"] = errors.New("card declined: insufficient funds")
+		}
+		entries = append(entries, entry)
+	}
+	// an entry without message or fields
+	entries = append(entries, &logrus.Entry{Logger: logrus.StandardLogger(), Time: entries[0].Time, Level: logrus.InfoLevel, Data: logrus.Fields{}})
+	// an entry at a custom level
+	entries = append(entries, &logrus.Entry{Logger: logrus.StandardLogger(), Time: entries[0].Time, Level: logrus.InfoLevel, Message: "refund approved", Data: logrus.Fields{ChannelKey: "payments", LevelKey: "audit"}})
+	return entries
+}
+
+type namedFormatter struct {
+	name      string
+	formatter logrus.Formatter
+}
+
+// goldenFormatters returns every formatter with the options the golden files
+// were written with.
+func goldenFormatters() ([]namedFormatter, error) {
+	tmpl, err := NewTemplateFormatter(`{{.Timestamp}} {{.Level | upper | pad 7}} {{.Field "channel"}}: {{.Message}} {{.Logfmt "channel"}}`)
+	if err != nil {
+		return nil, err
+	}
+	return []namedFormatter{
+		{"text", &ChannelTextFormatter{DisableColors: true}},
+		{"text_color", &ChannelTextFormatter{ForceColors: true, FullTimestamp: true}},
+		{"text_glyphs", &ChannelTextFormatter{ForceColors: true, FullTimestamp: true, Glyphs: EmojiGlyphs}},
+		{"json", &ChannelJSONFormatter{}},
+		{"logfmt", &LogfmtFormatter{}},
+		{"dev", &DevFormatter{DisableColors: true}},
+		{"dev_color", &DevFormatter{ForceColors: true}},
+		{"ecs", &ECSFormatter{ServiceName: "openpoint"}},
+		{"gelf", &GELFFormatter{Host: "host"}},
+		{"syslog", &SyslogFormatter{Facility: FacilityUser, Hostname: "host", AppName: "openpoint", ProcID: "1", MsgIDKey: ChannelKey}},
+		{"cloudlogging", &CloudLoggingFormatter{ProjectID: "openpoint"}},
+		{"journald", &JournaldFormatter{SyslogIdentifier: "openpoint"}},
+		{"template", tmpl},
+	}, nil
+}
+
+func TestGoldenFormatters(t *testing.T) {
+	formatters, err := goldenFormatters()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range formatters {
+		b := &bytes.Buffer{}
+		for _, entry := range goldenEntries() {
+			out, err := tt.formatter.Format(entry)
+			if err != nil {
+				t.Fatalf("%v: %v", tt.name, err)
+			}
+			b.Write(out)
+		}
+
+		file := filepath.Join("testdata", tt.name+".golden")
+		if *update {
+			if err := ioutil.WriteFile(file, b.Bytes(), 0644); err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+		expected, err := ioutil.ReadFile(file)
+		if err != nil {
+			t.Fatalf("%v, run with -update to create it", err)
+		}
+		if !bytes.Equal(b.Bytes(), expected) {
+			t.Errorf("%v output differs from %v, run with -update and review the diff\ngot:\n%s", tt.name, file, b.Bytes())
+		}
+	}
+}
+
+// BenchmarkFormatters formats the golden entries with every formatter, the
+// way logrus calls them, with a buffer to write into.
+func BenchmarkFormatters(b *testing.B) {
+	formatters, err := goldenFormatters()
+	if err != nil {
+		b.Fatal(err)
+	}
+	entries := goldenEntries()
+	buf := &bytes.Buffer{}
+	for _, tt := range formatters {
+		b.Run(tt.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				entry := entries[i%len(entries)]
+				buf.Reset()
+				entry.Buffer = buf
+				if _, err := tt.formatter.Format(entry); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+	for _, entry := range entries {
+		entry.Buffer = nil
+	}
+}
@@ -0,0 +1,201 @@
+package grpclogging
+
+import (
+	"context"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/o3labs/openpoint/platform/log"
+	logrus "github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	DefaultChannel      = "grpc"
+	DefaultRequestIDKey = "x-request-id"
+)
+
+type Options struct {
+	// Channel the calls are logged to. Defaults to "grpc".
+	Channel string
+
+	// Metadata are the metadata keys logged, others are left out as they may
+	// carry credentials or personal data. Defaults to user-agent and the
+	// RequestIDKey, set it to an empty slice to log none.
+	Metadata []string
+
+	// RedactMetadata are metadata keys whose values are masked in the
+	// logged metadata. Defaults to authorization and cookie.
+	RedactMetadata []string
+
+	// RequestIDKey is the metadata key holding the request ID, logged as
+	// request_id. Defaults to x-request-id.
+	RequestIDKey string
+}
+
+func (o Options) withDefaults() Options {
+	if o.Channel == "" {
+		o.Channel = DefaultChannel
+	}
+	if o.RedactMetadata == nil {
+		o.RedactMetadata = []string{"authorization", "cookie"}
+	}
+	if o.RequestIDKey == "" {
+		o.RequestIDKey = DefaultRequestIDKey
+	}
+	if o.Metadata == nil {
+		o.Metadata = []string{"user-agent", o.RequestIDKey}
+	}
+	return o
+}
+
+// UnaryServerInterceptor logs method, code, latency and peer of every call
+// and the call's metadata listed in o.Metadata. The handler gets a logger carrying the method and
+// request ID through log.FromContext(ctx).
+func UnaryServerInterceptor(o Options) grpc.UnaryServerInterceptor {
+	o = o.withDefaults()
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		entry := o.entry(ctx, info.FullMethod, true)
+		start := time.Now()
+		resp, err := handler(log.NewContext(ctx, entry), req)
+		logCall(entry, start, err)
+		return resp, err
+	}
+}
+
+func StreamServerInterceptor(o Options) grpc.StreamServerInterceptor {
+	o = o.withDefaults()
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		entry := o.entry(ss.Context(), info.FullMethod, true)
+		start := time.Now()
+		err := handler(srv, &serverStream{ServerStream: ss, ctx: log.NewContext(ss.Context(), entry)})
+		logCall(entry, start, err)
+		return err
+	}
+}
+
+// UnaryClientInterceptor logs method, code, latency and target of every
+// outgoing call.
+func UnaryClientInterceptor(o Options) grpc.UnaryClientInterceptor {
+	o = o.withDefaults()
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		entry := o.entry(ctx, method, false).WithField("peer", cc.Target())
+		start := time.Now()
+		err := invoker(ctx, method, req, reply, cc, opts...)
+		logCall(entry, start, err)
+		return err
+	}
+}
+
+// StreamClientInterceptor logs outgoing streams once they end, that is when
+// receiving returns io.EOF or an error.
+func StreamClientInterceptor(o Options) grpc.StreamClientInterceptor {
+	o = o.withDefaults()
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		entry := o.entry(ctx, method, false).WithField("peer", cc.Target())
+		start := time.Now()
+		cs, err := streamer(ctx, desc, cc, method, opts...)
+		if err != nil {
+			logCall(entry, start, err)
+			return nil, err
+		}
+		return &clientStream{ClientStream: cs, entry: entry, start: start}, nil
+	}
+}
+
+// entry returns the entry calls to method are logged with
+func (o Options) entry(ctx context.Context, method string, incoming bool) *logrus.Entry {
+	fields := logrus.Fields{"method": method}
+
+	var md metadata.MD
+	if incoming {
+		md, _ = metadata.FromIncomingContext(ctx)
+		if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+			fields["peer"] = p.Addr.String()
+		}
+	} else {
+		md, _ = metadata.FromOutgoingContext(ctx)
+	}
+	if len(md) > 0 {
+		logged := map[string]string{}
+		for _, k := range o.Metadata {
+			v := md.Get(k)
+			switch {
+			case len(v) == 0:
+			case o.isRedacted(k):
+				logged[strings.ToLower(k)] = log.RedactedPlaceholder
+			default:
+				logged[strings.ToLower(k)] = strings.Join(v, ",")
+			}
+		}
+		if len(logged) > 0 {
+			fields["metadata"] = logged
+		}
+		if id := md.Get(o.RequestIDKey); len(id) > 0 {
+			fields["request_id"] = id[0]
+		}
+	}
+	return log.Channel(o.Channel).WithFields(fields)
+}
+
+func (o Options) isRedacted(key string) bool {
+	for _, k := range o.RedactMetadata {
+		if strings.EqualFold(k, key) {
+			return true
+		}
+	}
+	return false
+}
+
+// logCall logs a finished call, at Error for server side failures, at Warn
+// for the codes blaming the caller and at Info otherwise.
+func logCall(entry *logrus.Entry, start time.Time, err error) {
+	code := status.Code(err)
+	entry = entry.WithFields(logrus.Fields{"code": code.String(), "latency": time.Since(start).String()})
+	if err != nil {
+		entry = entry.WithField(logrus.ErrorKey, err)
+	}
+	switch code {
+	case codes.OK:
+		entry.Info("rpc")
+	case codes.Canceled, codes.InvalidArgument, codes.NotFound, codes.AlreadyExists,
+		codes.PermissionDenied, codes.Unauthenticated, codes.FailedPrecondition, codes.OutOfRange:
+		entry.Warn("rpc")
+	default:
+		entry.Error("rpc")
+	}
+}
+
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
+
+type clientStream struct {
+	grpc.ClientStream
+	entry *logrus.Entry
+	start time.Time
+	done  bool
+}
+
+func (s *clientStream) RecvMsg(m interface{}) error {
+	err := s.ClientStream.RecvMsg(m)
+	if err != nil && !s.done {
+		s.done = true
+		if err == io.EOF {
+			logCall(s.entry, s.start, nil)
+		} else {
+			logCall(s.entry, s.start, err)
+		}
+	}
+	return err
+}
@@ -0,0 +1,150 @@
+package grpclogging
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/o3labs/openpoint/platform/log"
+	"github.com/o3labs/openpoint/platform/log/logtest"
+	logrus "github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// serve starts a health server over bufconn with the interceptors on both
+// ends and returns a client for it
+func serve(t *testing.T, o Options) (healthpb.HealthClient, func()) {
+	lis := bufconn.Listen(1 << 20)
+	s := grpc.NewServer(
+		grpc.UnaryInterceptor(UnaryServerInterceptor(o)),
+		grpc.StreamInterceptor(StreamServerInterceptor(o)),
+	)
+	healthpb.RegisterHealthServer(s, health.NewServer())
+	go s.Serve(lis)
+
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) { return lis.Dial() }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithUnaryInterceptor(UnaryClientInterceptor(o)),
+		grpc.WithStreamInterceptor(StreamClientInterceptor(o)),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return healthpb.NewHealthClient(conn), func() {
+		conn.Close()
+		s.Stop()
+	}
+}
+
+// calls returns the server and client entries of the calls, waiting for
+// them to be logged
+func calls(t *testing.T, h *logtest.Hook, n int) (server, client []logrus.Entry) {
+	deadline := time.Now().Add(time.Second)
+	for {
+		server, client = nil, nil
+		for _, e := range h.Entries() {
+			if e.Data["peer"] == "bufnet" {
+				client = append(client, e)
+			} else {
+				server = append(server, e)
+			}
+		}
+		if len(server) >= n && len(client) >= n {
+			return server, client
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected %d calls logged on both ends, got %d and %d", n, len(server), len(client))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestUnaryInterceptors(t *testing.T) {
+	h := logtest.Capture(log.Channel(DefaultChannel))
+	defer h.Remove()
+	client, stop := serve(t, Options{})
+	defer stop()
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(),
+		"x-request-id", "req-1", "authorization", "Bearer secret", "x-api-key", "key")
+	if _, err := client.Check(ctx, &healthpb.HealthCheckRequest{}); err != nil {
+		t.Fatal(err)
+	}
+	_, err := client.Check(ctx, &healthpb.HealthCheckRequest{Service: "payments"})
+	if status.Code(err) != codes.NotFound {
+		t.Fatalf("expected NotFound, got %v", err)
+	}
+
+	server, sent := calls(t, h, 2)
+	for _, e := range append(server, sent...) {
+		if e.Data["method"] != "/grpc.health.v1.Health/Check" || e.Data["request_id"] != "req-1" {
+			t.Errorf("expected the method and request ID, got %v", e.Data)
+		}
+		md, _ := e.Data["metadata"].(map[string]string)
+		if md["x-request-id"] != "req-1" || md["authorization"] != "" || md["x-api-key"] != "" {
+			t.Errorf("expected only the allowed metadata, got %v", md)
+		}
+	}
+	if md, _ := server[0].Data["metadata"].(map[string]string); md["user-agent"] == "" {
+		t.Errorf("expected the user agent in the server metadata, got %v", md)
+	}
+	if server[0].Level != logrus.InfoLevel || server[0].Data["code"] != "OK" {
+		t.Errorf("expected an OK call at info, got %v %v", server[0].Level, server[0].Data)
+	}
+	if server[1].Level != logrus.WarnLevel || server[1].Data["code"] != "NotFound" {
+		t.Errorf("expected a NotFound call at warning, got %v %v", server[1].Level, server[1].Data)
+	}
+}
+
+func TestRedactMetadata(t *testing.T) {
+	h := logtest.Capture(log.Channel(DefaultChannel))
+	defer h.Remove()
+	client, stop := serve(t, Options{Metadata: []string{"authorization", "x-tenant"}})
+	defer stop()
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer secret", "x-tenant", "acme")
+	if _, err := client.Check(ctx, &healthpb.HealthCheckRequest{}); err != nil {
+		t.Fatal(err)
+	}
+	server, _ := calls(t, h, 1)
+	md, _ := server[0].Data["metadata"].(map[string]string)
+	if md["authorization"] != log.RedactedPlaceholder || md["x-tenant"] != "acme" || md["user-agent"] != "" {
+		t.Errorf("expected the listed metadata with authorization masked, got %v", md)
+	}
+}
+
+func TestStreamInterceptors(t *testing.T) {
+	h := logtest.Capture(log.Channel(DefaultChannel))
+	defer h.Remove()
+	client, stop := serve(t, Options{})
+	defer stop()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stream, err := client.Watch(ctx, &healthpb.HealthCheckRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := stream.Recv(); err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+	if _, err := stream.Recv(); status.Code(err) != codes.Canceled {
+		t.Fatalf("expected Canceled, got %v", err)
+	}
+
+	server, sent := calls(t, h, 1)
+	for _, e := range []logrus.Entry{server[0], sent[0]} {
+		if e.Data["method"] != "/grpc.health.v1.Health/Watch" || e.Data["code"] != "Canceled" || e.Level != logrus.WarnLevel {
+			t.Errorf("expected a canceled Watch at warning, got %v %v", e.Level, e.Data)
+		}
+	}
+}
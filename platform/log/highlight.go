@@ -0,0 +1,99 @@
+package log
+
+import (
+	"bytes"
+
+	logrus "github.com/sirupsen/logrus"
+)
+
+const (
+	// HighlightKey is the field holding the fields Highlight asks to color.
+	HighlightKey = "log_highlight"
+
+	// ColorKey is the field holding the color EntryColor asks for.
+	ColorKey = "log_color"
+)
+
+// highlights maps the keys of highlighted fields to their color, empty for
+// the Highlight color of the theme.
+type highlights map[string]Color
+
+// Highlight returns a field making ChannelTextFormatter and DevFormatter
+// color the values of keys with the Highlight color of the theme, so they
+// stand out:
+//
+//	entry.WithFields(log.Highlight("latency")).WithField("latency", d).Warn("slow request")
+//
+// Pass all the keys to one call, a later Highlight on the same entry
+// replaces the earlier one. Formatters without colors leave the field out.
+func Highlight(keys ...string) logrus.Fields {
+	return HighlightColor("", keys...)
+}
+
+// HighlightColor is Highlight with color c.
+func HighlightColor(c Color, keys ...string) logrus.Fields {
+	h := make(highlights, len(keys))
+	for _, k := range keys {
+		h[k] = c
+	}
+	return logrus.Fields{HighlightKey: h}
+}
+
+// EntryColor returns a field making ChannelTextFormatter and DevFormatter
+// write the level and message of an entry in color c instead of the color
+// of its level. Formatters without colors leave the field out.
+func EntryColor(c Color) logrus.Fields {
+	return logrus.Fields{ColorKey: c}
+}
+
+// entryStyle returns the highlighted fields of entry and the color it asks
+// for with EntryColor, empty when none.
+func entryStyle(entry *logrus.Entry) (highlights, Color) {
+	h, _ := entry.Data[HighlightKey].(highlights)
+	c, _ := entry.Data[ColorKey].(Color)
+	return h, c
+}
+
+// stripStyle returns entry without HighlightKey and ColorKey, a copy when
+// it has any.
+func stripStyle(entry *logrus.Entry) *logrus.Entry {
+	_, highlighted := entry.Data[HighlightKey]
+	_, colored := entry.Data[ColorKey]
+	if !highlighted && !colored {
+		return entry
+	}
+	stripped := *entry
+	stripped.Data = make(logrus.Fields, len(entry.Data))
+	for k, v := range entry.Data {
+		if k != HighlightKey && k != ColorKey {
+			stripped.Data[k] = v
+		}
+	}
+	return &stripped
+}
+
+// color returns the color of the value of key, empty when it isn't
+// highlighted.
+func (h highlights) color(key string, theme *Theme) Color {
+	c, ok := h[key]
+	if !ok {
+		return ""
+	}
+	if c == "" {
+		return theme.highlight()
+	}
+	return c
+}
+
+// writeValue writes what write appends to b in color c.
+func (c Color) writeValue(b *bytes.Buffer, write func()) {
+	if c == "" {
+		write()
+		return
+	}
+	b.WriteString("\x1b[")
+	b.WriteString(string(c))
+	b.WriteByte('m')
+	write()
+	b.WriteString("\x1b[0m")
+}
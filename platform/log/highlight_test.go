@@ -0,0 +1,49 @@
+package log
+
+import (
+	"strings"
+	"testing"
+
+	logrus "github.com/sirupsen/logrus"
+)
+
+func TestHighlight(t *testing.T) {
+	entry := benchmarkEntry(logrus.Fields{"amount": 1000, "latency": "2.5s"})
+	entry.Data[HighlightKey] = Highlight("latency")[HighlightKey]
+
+	f := &ChannelTextFormatter{ForceColors: true, DisableTimestamp: true, MessageWidth: MessageWidthNone}
+	b, err := f.Format(entry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "\x1b[36mINFO\x1b[0m charge succeeded \x1b[36mamount\x1b[0m=1000 \x1b[36mlatency\x1b[0m=\x1b[1;35m2.5s\x1b[0m\n"
+	if string(b) != expected {
+		t.Errorf("expected %q got %q", expected, b)
+	}
+
+	entry.Data[ColorKey] = Color256(202)
+	b, err = (&DevFormatter{ForceColors: true, TimestampFormat: "-"}).Format(entry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected = "\x1b[38;5;202mINFO   \x1b[0m - \x1b[38;5;202mcharge succeeded\x1b[0m\n" +
+		"    \x1b[38;5;202mamount\x1b[0m: 1000\n" +
+		"    \x1b[38;5;202mlatency\x1b[0m: \x1b[1;35m2.5s\x1b[0m\n"
+	if string(b) != expected {
+		t.Errorf("expected %q got %q", expected, b)
+	}
+
+	for _, f := range []logrus.Formatter{
+		&ChannelTextFormatter{DisableColors: true},
+		&ChannelJSONFormatter{},
+		&LogfmtFormatter{},
+	} {
+		b, err := f.Format(entry)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.Contains(string(b), "log_") || strings.Contains(string(b), "\x1b[") {
+			t.Errorf("%T: expected plain output, got %q", f, b)
+		}
+	}
+}
@@ -0,0 +1,298 @@
+package httplog
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/o3labs/openpoint/platform/errors"
+	"github.com/o3labs/openpoint/platform/log"
+	"github.com/o3labs/openpoint/platform/log/requestid"
+	logrus "github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	DefaultChannel         = "http"
+	DefaultRequestIDHeader = requestid.Header
+)
+
+// FieldNames are the keys of the fields logged for every request
+type FieldNames struct {
+	Method    string
+	Path      string
+	Status    string
+	Latency   string
+	Bytes     string
+	RemoteIP  string
+	RequestID string
+	Query     string
+	Proto     string
+	Referer   string
+	UserAgent string
+}
+
+var DefaultFieldNames = FieldNames{
+	Method:    "method",
+	Path:      "path",
+	Status:    "status",
+	Latency:   "latency",
+	Bytes:     "bytes",
+	RemoteIP:  "remote_ip",
+	RequestID: "request_id",
+	Query:     "query",
+	Proto:     "proto",
+	Referer:   "referer",
+	UserAgent: "user_agent",
+}
+
+type Options struct {
+	// Channel the requests are logged to. Defaults to "http".
+	Channel string
+
+	// Fields overrides the default field names. Empty names keep the default.
+	Fields FieldNames
+
+	// SkipPaths are not logged, e.g. health checks
+	SkipPaths []string
+
+	// RequestIDHeader is read for the request ID and set on the response.
+	// An ID is generated with requestid.New when the request has none and
+	// no earlier requestid.Middleware set one.
+	RequestIDHeader string
+
+	// TrustProxy takes the remote IP from X-Forwarded-For
+	TrustProxy bool
+
+	// IgnoreTraceParent leaves the traceparent and tracestate headers
+	// alone. By default the trace context they carry is put in the request
+	// context, unless an OpenTelemetry middleware already put a span there,
+	// and its trace and span ID are logged with the request and by the
+	// handler through log.FromContext. The span ID is the caller's.
+	IgnoreTraceParent bool
+
+	// BufferDebug keeps the debug entries the handler logs through
+	// log.FromContext while the channel doesn't log at Debug, see
+	// log.RequestBuffer. They are written before the request entry when the
+	// request fails with a server error, the handler logged an error or it
+	// took FlushLatency or more, and dropped otherwise.
+	BufferDebug bool
+
+	// BufferSize is the number of debug entries kept per request. Defaults
+	// to 1000.
+	BufferSize int
+
+	// FlushLatency flushes the debug entries of slow requests. Zero only
+	// flushes on errors.
+	FlushLatency time.Duration
+}
+
+// New returns a middleware logging method, path, status, latency, bytes
+// written, remote IP, request ID and protocol of every request once it is
+// served, along with the query, referer and user agent when there are any,
+// which is what log.AccessLogFormatter needs.
+// Server errors are logged at Error, client errors at Warn and the rest at
+// Info. The handler gets a logger carrying the request ID through
+// log.FromContext(r.Context()).
+func New(o Options) func(http.HandlerFunc) http.HandlerFunc {
+	if o.Channel == "" {
+		o.Channel = DefaultChannel
+	}
+	if o.RequestIDHeader == "" {
+		o.RequestIDHeader = DefaultRequestIDHeader
+	}
+	o.Fields = o.Fields.withDefaults()
+	skip := make(map[string]bool, len(o.SkipPaths))
+	for _, p := range o.SkipPaths {
+		skip[p] = true
+	}
+
+	return func(h http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if skip[r.URL.Path] {
+				h(w, r)
+				return
+			}
+			channel := log.Channel(o.Channel)
+
+			requestID := requestid.FromContext(r.Context())
+			if requestID == "" {
+				requestID = r.Header.Get(o.RequestIDHeader)
+			}
+			if !requestid.Valid(requestID) {
+				requestID = requestid.New()
+			}
+			w.Header().Set(o.RequestIDHeader, requestID)
+			ctx := requestid.NewContext(r.Context(), requestID)
+			requestFields := logrus.Fields{o.Fields.RequestID: requestID}
+			if !o.IgnoreTraceParent {
+				ctx = traceContext(ctx, r, requestFields)
+			}
+			ctx = log.NewContext(ctx, channel.WithFields(requestFields))
+			var buf *log.RequestBuffer
+			if o.BufferDebug {
+				ctx, buf = log.NewRequestBuffer(ctx, o.BufferSize)
+			}
+
+			record := &responseRecord{ResponseWriter: w}
+			start := time.Now()
+			h(record, r.WithContext(ctx))
+			latency := time.Since(start)
+
+			if record.status == 0 {
+				record.status = http.StatusOK
+			}
+			if buf != nil {
+				if record.status >= 500 || buf.Failed() || (o.FlushLatency > 0 && latency >= o.FlushLatency) {
+					buf.Flush()
+				} else {
+					buf.Discard()
+				}
+			}
+			fields := logrus.Fields{
+				o.Fields.Method:    r.Method,
+				o.Fields.Path:      r.URL.Path,
+				o.Fields.Status:    record.status,
+				o.Fields.Latency:   latency.String(),
+				o.Fields.Bytes:     record.bytes,
+				o.Fields.RemoteIP:  remoteIP(r, o.TrustProxy),
+				o.Fields.RequestID: requestID,
+				o.Fields.Proto:     r.Proto,
+			}
+			for k, v := range requestFields {
+				fields[k] = v
+			}
+			if r.URL.RawQuery != "" {
+				fields[o.Fields.Query] = r.URL.RawQuery
+			}
+			if referer := r.Referer(); referer != "" {
+				fields[o.Fields.Referer] = referer
+			}
+			if userAgent := r.UserAgent(); userAgent != "" {
+				fields[o.Fields.UserAgent] = userAgent
+			}
+			entry := channel.WithFields(fields)
+			switch {
+			case record.status >= 500:
+				entry.Error("request")
+			case record.status >= 400:
+				entry.Warn("request")
+			default:
+				entry.Info("request")
+			}
+		}
+	}
+}
+
+// Handler wraps an http.Handler with the middleware returned by New.
+func Handler(h http.Handler, o Options) http.Handler {
+	return New(o)(h.ServeHTTP)
+}
+
+func (f FieldNames) withDefaults() FieldNames {
+	set := func(name *string, def string) {
+		if *name == "" {
+			*name = def
+		}
+	}
+	set(&f.Method, DefaultFieldNames.Method)
+	set(&f.Path, DefaultFieldNames.Path)
+	set(&f.Status, DefaultFieldNames.Status)
+	set(&f.Latency, DefaultFieldNames.Latency)
+	set(&f.Bytes, DefaultFieldNames.Bytes)
+	set(&f.RemoteIP, DefaultFieldNames.RemoteIP)
+	set(&f.RequestID, DefaultFieldNames.RequestID)
+	set(&f.Query, DefaultFieldNames.Query)
+	set(&f.Proto, DefaultFieldNames.Proto)
+	set(&f.Referer, DefaultFieldNames.Referer)
+	set(&f.UserAgent, DefaultFieldNames.UserAgent)
+	return f
+}
+
+// traceContext returns ctx with the trace context of r's traceparent header
+// unless ctx has a span already, and adds the trace and span ID to fields.
+func traceContext(ctx context.Context, r *http.Request, fields logrus.Fields) context.Context {
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		header := r.Header.Get(log.TraceParentHeader)
+		if header == "" {
+			return ctx
+		}
+		parsed, err := log.ParseTraceParent(header, strings.Join(r.Header.Values(log.TraceStateHeader), ","))
+		if err != nil {
+			return ctx
+		}
+		sc = parsed
+		ctx = trace.ContextWithRemoteSpanContext(ctx, sc)
+	}
+	fields[log.TraceIDKey] = sc.TraceID().String()
+	fields[log.SpanIDKey] = sc.SpanID().String()
+	return ctx
+}
+
+func remoteIP(r *http.Request, trustProxy bool) string {
+	if trustProxy {
+		if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+			return strings.TrimSpace(strings.Split(forwarded, ",")[0])
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// responseRecord keeps the status and size of the response
+type responseRecord struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+}
+
+func (r *responseRecord) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *responseRecord) Write(p []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	n, err := r.ResponseWriter.Write(p)
+	r.bytes += n
+	return n, err
+}
+
+// Flush lets event streams through the recorder
+func (r *responseRecord) Flush() {
+	if f, ok := r.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Recover is a middleware recovering panics of the handler. The panic is
+// logged at Error with its stack trace and the request's fields and the
+// client gets a 500. Put it inside the middleware returned by New so the
+// entry carries the request ID.
+func Recover(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			v := recover()
+			if v == nil {
+				return
+			}
+			if v == http.ErrAbortHandler {
+				panic(v)
+			}
+			ctx := log.WithContext(r.Context(), logrus.Fields{"method": r.Method, "path": r.URL.Path})
+			log.LogPanic(ctx, v, logrus.ErrorLevel)
+			errors.ServerError("Internal server error").Write(w)
+		}()
+		h(w, r)
+	}
+}
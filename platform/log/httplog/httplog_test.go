@@ -0,0 +1,135 @@
+package httplog
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/o3labs/openpoint/platform/log"
+	logrus "github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestMiddleware(t *testing.T) {
+	b := &bytes.Buffer{}
+	channel := log.Channel("httplog")
+	channel.SetOutput(b)
+	channel.SetFormatter(&log.ChannelTextFormatter{DisableTimestamp: true})
+
+	handler := New(Options{Channel: "httplog", SkipPaths: []string{"/health"}})(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := log.FromContext(r.Context()).Data["request_id"]; !ok && r.URL.Path != "/health" {
+			t.Errorf("expected the request ID in the context logger")
+		}
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("not found"))
+	})
+
+	r := httptest.NewRequest("GET", "/cards/1?expand=owner", nil)
+	r.Header.Set("X-Request-ID", "r1")
+	r.Header.Set("User-Agent", "curl/7.58.0")
+	w := httptest.NewRecorder()
+	handler(w, r)
+	if w.Header().Get("X-Request-ID") != "r1" {
+		t.Errorf("expected the request ID on the response")
+	}
+	for _, s := range []string{"level=warning", "method=GET", "path=/cards/1", "status=404", "bytes=9", "remote_ip=192.0.2.1", "request_id=r1", `query="expand=owner"`, "proto=HTTP/1.1", "user_agent=curl/7.58.0"} {
+		if !strings.Contains(b.String(), s) {
+			t.Errorf("expected %v in %q", s, b.String())
+		}
+	}
+
+	b.Reset()
+	channel.SetFormatter(&log.AccessLogFormatter{})
+	handler(httptest.NewRecorder(), r)
+	if !strings.HasPrefix(b.String(), "192.0.2.1 - - [") || !strings.HasSuffix(b.String(), `] "GET /cards/1?expand=owner HTTP/1.1" 404 9 "-" "curl/7.58.0"`+"\n") {
+		t.Errorf("unexpected access log %q", b.String())
+	}
+	channel.SetFormatter(&log.ChannelTextFormatter{DisableTimestamp: true})
+
+	b.Reset()
+	handler(httptest.NewRecorder(), httptest.NewRequest("GET", "/health", nil))
+	if b.Len() != 0 {
+		t.Errorf("expected skipped path not to be logged, got %q", b.String())
+	}
+}
+
+func TestRecover(t *testing.T) {
+	b := &bytes.Buffer{}
+	channel := log.Channel("httplog-recover")
+	channel.SetOutput(b)
+	channel.SetFormatter(&log.ChannelTextFormatter{DisableTimestamp: true})
+
+	handler := New(Options{Channel: "httplog-recover"})(Recover(func(w http.ResponseWriter, r *http.Request) {
+		panic("nil card")
+	}))
+	w := httptest.NewRecorder()
+	handler(w, httptest.NewRequest("POST", "/charges", nil))
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("expected 500 got %v", w.Code)
+	}
+	for _, s := range []string{"panic=\"nil card\"", "path=/charges", "request_id=", "TestRecover"} {
+		if !strings.Contains(b.String(), s) {
+			t.Errorf("expected %v in %q", s, b.String())
+		}
+	}
+}
+
+func TestBufferDebug(t *testing.T) {
+	b := &bytes.Buffer{}
+	channel := log.Channel("httplog-buffer")
+	channel.SetOutput(b)
+	channel.SetFormatter(&log.ChannelTextFormatter{DisableTimestamp: true})
+	channel.SetLevel(logrus.InfoLevel)
+
+	handler := New(Options{Channel: "httplog-buffer", BufferDebug: true})(func(w http.ResponseWriter, r *http.Request) {
+		log.FromContext(r.Context()).Debug("loading card")
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	})
+
+	handler(httptest.NewRecorder(), httptest.NewRequest("GET", "/ok", nil))
+	if strings.Contains(b.String(), "loading card") {
+		t.Errorf("expected the debug entries of a successful request to be dropped, got %q", b.String())
+	}
+
+	b.Reset()
+	handler(httptest.NewRecorder(), httptest.NewRequest("GET", "/fail", nil))
+	debug := strings.Index(b.String(), `level=debug msg="loading card"`)
+	request := strings.Index(b.String(), "msg=request")
+	if debug < 0 || request < debug || !strings.Contains(b.String()[:request], "request_id=") {
+		t.Errorf("expected the debug entries before the request entry, got %q", b.String())
+	}
+}
+
+func TestTraceParent(t *testing.T) {
+	b := &bytes.Buffer{}
+	channel := log.Channel("httplog.trace")
+	channel.SetOutput(b)
+	channel.SetFormatter(&log.ChannelTextFormatter{DisableTimestamp: true})
+
+	handler := New(Options{Channel: "httplog.trace"})(func(w http.ResponseWriter, r *http.Request) {
+		if log.FromContext(r.Context()).Data[log.TraceIDKey] != "4bf92f3577b34da6a3ce929d0e0e4736" {
+			t.Errorf("expected the trace ID in the context logger")
+		}
+		if !trace.SpanContextFromContext(r.Context()).IsRemote() {
+			t.Errorf("expected the remote span context in the request context")
+		}
+	})
+	r := httptest.NewRequest("GET", "/cards", nil)
+	r.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	handler(httptest.NewRecorder(), r)
+	if !strings.Contains(b.String(), "trace_id=4bf92f3577b34da6a3ce929d0e0e4736") || !strings.Contains(b.String(), "span_id=00f067aa0ba902b7") {
+		t.Errorf("expected the trace context in %q", b.String())
+	}
+
+	b.Reset()
+	r = httptest.NewRequest("GET", "/cards", nil)
+	r.Header.Set("traceparent", "00-invalid-01")
+	New(Options{Channel: "httplog.trace"})(func(w http.ResponseWriter, r *http.Request) {})(httptest.NewRecorder(), r)
+	if strings.Contains(b.String(), "trace_id") {
+		t.Errorf("expected an invalid traceparent to be ignored, got %q", b.String())
+	}
+}
@@ -0,0 +1,207 @@
+package httplog
+
+import (
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/o3labs/openpoint/platform/log"
+	"github.com/o3labs/openpoint/platform/log/requestid"
+	logrus "github.com/sirupsen/logrus"
+)
+
+const (
+	DefaultClientChannel = "http.client"
+	DefaultRetryBackoff  = 100 * time.Millisecond
+)
+
+type TransportOptions struct {
+	// Channel the requests are logged to. Defaults to "http.client".
+	Channel string
+
+	// HostLevels are the levels successful requests to a host are logged
+	// at, e.g. Debug for a chatty metrics endpoint. Defaults to Info.
+	// Requests failing with an error or a status of 400 or more are logged
+	// at Warn or Error whatever the host.
+	HostLevels map[string]logrus.Level
+
+	// KeepQuery are the query parameters logged as they are, the values of
+	// the others are replaced with log.RedactedPlaceholder
+	KeepQuery []string
+
+	// MaxRetries retries requests failing with an error or a 502, 503 or
+	// 504 status that can be sent again: idempotent methods, or requests
+	// with an Idempotency-Key header, without a body or with GetBody set.
+	MaxRetries int
+
+	// RetryBackoff is the wait before the first retry, doubled for every
+	// other. Defaults to 100ms.
+	RetryBackoff time.Duration
+}
+
+// Transport is an http.RoundTripper logging method, URL, status, latency
+// and retries of every outgoing request, along with the request ID of its
+// context.
+type Transport struct {
+	// Base sends the requests. Defaults to http.DefaultTransport.
+	Base http.RoundTripper
+
+	o    TransportOptions
+	keep map[string]bool
+}
+
+// NewTransport returns a transport logging the requests sent with base:
+//
+//	client := &http.Client{Transport: httplog.NewTransport(nil, httplog.TransportOptions{})}
+func NewTransport(base http.RoundTripper, o TransportOptions) *Transport {
+	if o.Channel == "" {
+		o.Channel = DefaultClientChannel
+	}
+	if o.RetryBackoff <= 0 {
+		o.RetryBackoff = DefaultRetryBackoff
+	}
+	keep := make(map[string]bool, len(o.KeepQuery))
+	for _, k := range o.KeepQuery {
+		keep[k] = true
+	}
+	return &Transport{Base: base, o: o, keep: keep}
+}
+
+func (t *Transport) RoundTrip(r *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	start := time.Now()
+	retries := 0
+	resp, err := base.RoundTrip(r)
+	for retries < t.o.MaxRetries && idempotent(r) && retryable(resp, err) {
+		retry, ok := rewind(r)
+		if !ok {
+			break
+		}
+		// canceled, the caller gets the last response with its body
+		if !sleep(r, t.o.RetryBackoff<<uint(retries)) {
+			break
+		}
+		if resp != nil {
+			resp.Body.Close()
+		}
+		retries++
+		resp, err = base.RoundTrip(retry)
+	}
+	t.log(r, resp, err, retries, time.Since(start))
+	return resp, err
+}
+
+func (t *Transport) log(r *http.Request, resp *http.Response, err error, retries int, latency time.Duration) {
+	fields := logrus.Fields{
+		"method":  r.Method,
+		"url":     t.redactURL(r.URL),
+		"latency": latency.String(),
+	}
+	if retries > 0 {
+		fields["retries"] = retries
+	}
+	if requestID := requestid.FromContext(r.Context()); requestID != "" {
+		fields["request_id"] = requestID
+	}
+	entry := log.Channel(t.o.Channel).WithFields(fields)
+	switch {
+	case err != nil:
+		entry.WithField("error", err).Error("request")
+	case resp.StatusCode >= 500:
+		entry.WithField("status", resp.StatusCode).Error("request")
+	case resp.StatusCode >= 400:
+		entry.WithField("status", resp.StatusCode).Warn("request")
+	default:
+		level, ok := t.o.HostLevels[r.URL.Hostname()]
+		if !ok {
+			level = logrus.InfoLevel
+		}
+		entry.WithField("status", resp.StatusCode).Log(level, "request")
+	}
+}
+
+// redactURL returns u without its password and with the values of the
+// query parameters not kept replaced.
+func (t *Transport) redactURL(u *url.URL) string {
+	redacted := *u
+	redacted.RawQuery = ""
+	redacted.ForceQuery = false
+	s := redacted.Redacted()
+	if u.RawQuery == "" {
+		return s
+	}
+
+	query := u.Query()
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	params := make([]string, 0, len(keys))
+	for _, k := range keys {
+		for _, v := range query[k] {
+			if !t.keep[k] {
+				v = log.RedactedPlaceholder
+			}
+			params = append(params, url.QueryEscape(k)+"="+v)
+		}
+	}
+	return s + "?" + strings.Join(params, "&")
+}
+
+// idempotent reports whether sending r twice has the effect of sending it
+// once, as net/http decides before replaying a request.
+func idempotent(r *http.Request) bool {
+	switch r.Method {
+	case "", http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return r.Header.Get("Idempotency-Key") != "" || r.Header.Get("X-Idempotency-Key") != ""
+}
+
+func retryable(resp *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+	switch resp.StatusCode {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// rewind returns a copy of r with a fresh body to send it again, false when
+// the body can't be read twice.
+func rewind(r *http.Request) (*http.Request, bool) {
+	if r.Body == nil || r.Body == http.NoBody {
+		return r, true
+	}
+	if r.GetBody == nil {
+		return nil, false
+	}
+	body, err := r.GetBody()
+	if err != nil {
+		return nil, false
+	}
+	retry := r.Clone(r.Context())
+	retry.Body = body
+	return retry, true
+}
+
+// sleep waits for d, false when the request is canceled first.
+func sleep(r *http.Request, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-r.Context().Done():
+		return false
+	}
+}
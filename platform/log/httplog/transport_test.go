@@ -0,0 +1,141 @@
+package httplog
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/o3labs/openpoint/platform/log"
+	"github.com/o3labs/openpoint/platform/log/requestid"
+	logrus "github.com/sirupsen/logrus"
+)
+
+func TestTransport(t *testing.T) {
+	b := &bytes.Buffer{}
+	channel := log.Channel("httplog.client")
+	channel.SetOutput(b)
+	channel.SetFormatter(&log.ChannelTextFormatter{DisableTimestamp: true})
+	channel.SetLevel(logrus.InfoLevel)
+
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if r.URL.Path == "/flaky" && calls == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	transport := NewTransport(nil, TransportOptions{
+		Channel:      "httplog.client",
+		KeepQuery:    []string{"page"},
+		MaxRetries:   2,
+		RetryBackoff: time.Millisecond,
+		HostLevels:   map[string]logrus.Level{"localhost": logrus.DebugLevel},
+	})
+	client := &http.Client{Transport: transport}
+
+	r, _ := http.NewRequest("GET", server.URL+"/flaky?page=2&token=secret", nil)
+	r = r.WithContext(requestid.NewContext(r.Context(), "r1"))
+	resp, err := client.Do(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || calls != 2 {
+		t.Errorf("expected the request to be retried, got %v after %v calls", resp.StatusCode, calls)
+	}
+	for _, s := range []string{"level=info", "method=GET", `url="` + server.URL + `/flaky?page=2&token=[REDACTED]"`, "status=200", "retries=1", "request_id=r1", "latency="} {
+		if !strings.Contains(b.String(), s) {
+			t.Errorf("expected %v in %q", s, b.String())
+		}
+	}
+
+	b.Reset()
+	local := strings.Replace(server.URL, "127.0.0.1", "localhost", 1)
+	resp, err = client.Get(local + "/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if b.Len() != 0 {
+		t.Errorf("expected the host level to hide the request, got %q", b.String())
+	}
+
+	if _, err := client.Get("http://127.0.0.1:1/"); err == nil {
+		t.Errorf("expected the request to fail")
+	}
+	if !strings.Contains(b.String(), "level=error") || !strings.Contains(b.String(), "retries=2") {
+		t.Errorf("expected the failed request with its retries, got %q", b.String())
+	}
+}
+
+func TestTransportRetriesIdempotentOnly(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	client := &http.Client{Transport: NewTransport(nil, TransportOptions{
+		Channel:      "httplog.client",
+		MaxRetries:   2,
+		RetryBackoff: time.Millisecond,
+	})}
+
+	resp, err := client.Post(server.URL+"/charges", "application/json", strings.NewReader("{}"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if calls != 1 {
+		t.Errorf("expected a POST to be sent once, got %d calls", calls)
+	}
+
+	calls = 0
+	r, _ := http.NewRequest("POST", server.URL+"/charges", strings.NewReader("{}"))
+	r.Header.Set("Idempotency-Key", "c1")
+	resp, err = client.Do(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if calls != 3 {
+		t.Errorf("expected a POST with an Idempotency-Key to be retried, got %d calls", calls)
+	}
+}
+
+func TestTransportCanceledRetry(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write([]byte("unavailable"))
+	}))
+	defer server.Close()
+
+	client := &http.Client{Transport: NewTransport(nil, TransportOptions{
+		Channel:      "httplog.client",
+		MaxRetries:   2,
+		RetryBackoff: time.Hour,
+	})}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	r, _ := http.NewRequest("GET", server.URL, nil)
+	resp, err := client.Do(r.WithContext(ctx))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil || string(body) != "unavailable" {
+		t.Errorf("expected the last response with its body, got %q, %v", body, err)
+	}
+}
@@ -0,0 +1,99 @@
+package log
+
+import (
+	"bytes"
+	"io/ioutil"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	logrus "github.com/sirupsen/logrus"
+)
+
+// collectWriter keeps the lines written to it
+type collectWriter struct {
+	mu    sync.Mutex
+	lines []string
+}
+
+func (w *collectWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.lines = append(w.lines, string(p))
+	return len(p), nil
+}
+
+func TestMiddlewareCopyOnWrite(t *testing.T) {
+	entry := &logrus.Entry{Level: logrus.InfoLevel, Time: time.Now(), Message: "login", Data: logrus.Fields{"service": "api"}}
+	matcher, err := ParseMatcher("level>=info")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e, _ := FilterMiddleware(matcher)(entry); e != entry {
+		t.Errorf("expected a middleware inspecting entries not to copy them")
+	}
+	if e, _ := FieldsMiddleware(logrus.Fields{"service": "web"})(entry); e != entry {
+		t.Errorf("expected no copy when no field is added")
+	}
+	e, _ := FieldsMiddleware(logrus.Fields{"region": "eu"})(entry)
+	if e == entry || e.Data["region"] != "eu" || entry.Data["region"] != nil {
+		t.Errorf("expected the field added to a copy, got %v and %v", e.Data, entry.Data)
+	}
+	if c := CopyEntry(&logrus.Entry{Buffer: &bytes.Buffer{}, Data: logrus.Fields{}}); c.Buffer != nil {
+		t.Errorf("expected the copy without the buffer of the entry")
+	}
+}
+
+// TestConcurrentPipeline logs from many goroutines through WithFields chains
+// sharing a base entry, logger hooks and outputs with middleware changing
+// entries, which the race detector checks.
+func TestConcurrentPipeline(t *testing.T) {
+	redacted, plain := &collectWriter{}, &collectWriter{}
+	hook := NewMultiHook(
+		&Destination{
+			Writer:    redacted,
+			Formatter: &MiddlewareFormatter{Formatter: &LogfmtFormatter{DisableTimestamp: true}, Middleware: []Middleware{HookMiddleware(NewRedactHook()), FieldsMiddleware(logrus.Fields{"output": "redacted"})}},
+			Level:     logrus.InfoLevel,
+		},
+		&Destination{
+			Writer:    plain,
+			Formatter: &LogfmtFormatter{DisableTimestamp: true},
+			Level:     logrus.InfoLevel,
+		},
+	)
+	hook.Middleware = HookMiddleware(NewTruncateHook(64, 64))
+	l := logrus.New()
+	l.Out = ioutil.Discard
+	l.AddHook(NewRedactHook())
+	l.AddHook(hook)
+
+	base := l.WithFields(logrus.Fields{"token": "t_secret", "user": "u1"})
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				base.WithField("attempt", j).WithField("worker", i).Info("charge")
+				base.Info("tick")
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if base.Data["token"] != "t_secret" || len(base.Data) != 2 {
+		t.Errorf("expected the shared entry unchanged, got %v", base.Data)
+	}
+	if len(redacted.lines) != 800 || len(plain.lines) != 800 {
+		t.Fatalf("expected 800 entries per output, got %d and %d", len(redacted.lines), len(plain.lines))
+	}
+	for i := range plain.lines {
+		if !strings.Contains(redacted.lines[i], "output=redacted") || strings.Contains(plain.lines[i], "output=") {
+			t.Fatalf("expected the changes of the middleware on their output only, got %q and %q", redacted.lines[i], plain.lines[i])
+		}
+		if strings.Contains(plain.lines[i], "t_secret") {
+			t.Fatalf("expected the logger hooks to apply to every output, got %q", plain.lines[i])
+		}
+	}
+}
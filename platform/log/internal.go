@@ -0,0 +1,108 @@
+package log
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	logrus "github.com/sirupsen/logrus"
+)
+
+// InternalChannel is the channel the logging subsystem reports its own
+// problems on, such as sinks failing to write, entries being dropped and
+// log files failing to rotate. It starts with the outputs of the log channel
+// or the standard logger, see Channel, and can be given outputs of its own
+// so the reports don't go to the sink that is failing.
+const InternalChannel = "log.internal"
+
+const (
+	droppedReportInterval = time.Minute
+	reportQueueSize       = 16
+)
+
+type queuedReport struct {
+	level  logrus.Level
+	msg    string
+	fields logrus.Fields
+}
+
+var (
+	// reporting is set while a report is logged. Reports made meanwhile,
+	// by other goroutines or by the sink the report is written to, are
+	// queued and logged after it. Reports made while the queue is logged,
+	// or once it is full, go to internalFallback, so a sink failing on
+	// every report can't loop.
+	reportMu         sync.Mutex
+	reporting        bool
+	draining         bool
+	reportQueue      []queuedReport
+	internalFallback io.Writer = os.Stderr
+
+	droppedReports sync.Once
+)
+
+// Reportf reports a problem of a sink outside this package on the internal
+// channel at Error, like RecordDropped counts the entries it drops.
+func Reportf(format string, args ...interface{}) {
+	reportf(format, args...)
+}
+
+// reportf reports a problem of the logging subsystem on the internal
+// channel at Error and counts it in the PipelineStats.
+func reportf(format string, args ...interface{}) {
+	recordSinkError()
+	report(logrus.ErrorLevel, fmt.Sprintf(format, args...), nil)
+}
+
+// report logs msg on the internal channel, or queues it when a report is
+// being logged already, see reporting.
+func report(level logrus.Level, msg string, fields logrus.Fields) {
+	reportMu.Lock()
+	if reporting {
+		full := draining || len(reportQueue) >= reportQueueSize
+		if !full {
+			reportQueue = append(reportQueue, queuedReport{level, msg, fields})
+		}
+		reportMu.Unlock()
+		if full {
+			fmt.Fprintln(internalFallback, msg)
+		}
+		return
+	}
+	reporting = true
+	reportMu.Unlock()
+
+	Channel(InternalChannel).WithFields(fields).Log(level, msg)
+
+	reportMu.Lock()
+	queued := reportQueue
+	reportQueue = nil
+	draining = true
+	reportMu.Unlock()
+	for _, r := range queued {
+		Channel(InternalChannel).WithFields(r.fields).Log(r.level, r.msg)
+	}
+	reportMu.Lock()
+	reporting, draining = false, false
+	reportMu.Unlock()
+}
+
+// reportDropped starts reporting the number of dropped entries on the
+// internal channel once a minute, when there are any.
+func reportDropped() {
+	droppedReports.Do(func() {
+		go func() {
+			last := Dropped()
+			for range time.Tick(droppedReportInterval) {
+				dropped := Dropped()
+				if dropped == last {
+					continue
+				}
+				report(logrus.WarnLevel, "Dropped log entries", logrus.Fields{"dropped": dropped - last, "total": dropped})
+				last = dropped
+			}
+		}()
+	})
+}
@@ -0,0 +1,94 @@
+package log
+
+import (
+	"bytes"
+	"os"
+	"sync"
+	"testing"
+
+	logrus "github.com/sirupsen/logrus"
+)
+
+type reportingHook struct{}
+
+func (reportingHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (reportingHook) Fire(entry *logrus.Entry) error {
+	reportf("Failed to write to the sink the report went to")
+	return nil
+}
+
+func TestReportf(t *testing.T) {
+	b, fallback := &bytes.Buffer{}, &bytes.Buffer{}
+	internalFallback = fallback
+	defer func() { internalFallback = os.Stderr }()
+
+	internal := Channel(InternalChannel)
+	internal.SetOutput(b)
+	internal.SetFormatter(&ChannelTextFormatter{DisableColors: true, DisableTimestamp: true})
+	hook := reportingHook{}
+	internal.AddHook(hook)
+	defer internal.RemoveHook(hook)
+
+	Reportf("Failed to push %d entries because %v", 3, "timeout")
+	expected := `level=error msg="Failed to push 3 entries because timeout" channel=log.internal` + "\n" +
+		`level=error msg="Failed to write to the sink the report went to" channel=log.internal` + "\n"
+	if b.String() != expected {
+		t.Errorf("expected %q got %q", expected, b.String())
+	}
+	if fallback.String() != "Failed to write to the sink the report went to\n" {
+		t.Errorf("expected the report made while logging the queued reports on stderr, got %q", fallback.String())
+	}
+}
+
+// pausingHook holds the first entry fired until proceed is closed
+type pausingHook struct {
+	once    sync.Once
+	started chan struct{}
+	proceed chan struct{}
+}
+
+func (h *pausingHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (h *pausingHook) Fire(entry *logrus.Entry) error {
+	h.once.Do(func() {
+		close(h.started)
+		<-h.proceed
+	})
+	return nil
+}
+
+func TestReportfConcurrent(t *testing.T) {
+	b, fallback := &bytes.Buffer{}, &bytes.Buffer{}
+	internalFallback = fallback
+	defer func() { internalFallback = os.Stderr }()
+
+	internal := Channel(InternalChannel)
+	internal.SetOutput(b)
+	internal.SetFormatter(&ChannelTextFormatter{DisableColors: true, DisableTimestamp: true})
+	hook := &pausingHook{started: make(chan struct{}), proceed: make(chan struct{})}
+	internal.AddHook(hook)
+	defer internal.RemoveHook(hook)
+
+	done := make(chan struct{})
+	go func() {
+		Reportf("first")
+		close(done)
+	}()
+	<-hook.started
+	Reportf("second")
+	close(hook.proceed)
+	<-done
+
+	expected := "level=error msg=first channel=log.internal\nlevel=error msg=second channel=log.internal\n"
+	if b.String() != expected {
+		t.Errorf("expected %q got %q", expected, b.String())
+	}
+	if fallback.Len() != 0 {
+		t.Errorf("expected no report on stderr, got %q", fallback.String())
+	}
+}
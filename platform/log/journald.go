@@ -0,0 +1,154 @@
+package log
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+
+	logrus "github.com/sirupsen/logrus"
+)
+
+const journaldSocket = "/run/systemd/journal/socket"
+
+// JournaldFormatter formats logs in the systemd-journald native protocol.
+// Entry fields become journal fields named in uppercase, e.g. "order_id" as
+// ORDER_ID, so "journalctl CHANNEL=payments" filters on them, and the level
+// becomes PRIORITY. Write it with a JournaldWriter.
+type JournaldFormatter struct {
+	// SyslogIdentifier is the SYSLOG_IDENTIFIER journalctl -t filters on.
+	// Defaults to the executable name.
+	SyslogIdentifier string
+
+	sync.Once
+}
+
+func (f *JournaldFormatter) init() {
+	if f.SyslogIdentifier == "" {
+		f.SyslogIdentifier = filepath.Base(os.Args[0])
+	}
+}
+
+// Format renders a single log entry
+func (f *JournaldFormatter) Format(entry *logrus.Entry) ([]byte, error) {
+	f.Do(f.init)
+	level, entry := prepareEntry(entry)
+
+	b := &bytes.Buffer{}
+	appendJournaldField(b, "MESSAGE", entry.Message)
+	appendJournaldField(b, "PRIORITY", strconv.Itoa(level.Severity))
+	appendJournaldField(b, "SYSLOG_IDENTIFIER", f.SyslogIdentifier)
+
+	keys := make([]string, 0, len(entry.Data))
+	for k := range entry.Data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		name := journaldFieldName(k)
+		switch name {
+		case "", "MESSAGE", "PRIORITY", "SYSLOG_IDENTIFIER":
+			continue
+		}
+		v := entry.Data[k]
+		if err, ok := v.(error); ok {
+			v = err.Error()
+		}
+		s, ok := v.(string)
+		if !ok {
+			s = fmt.Sprint(v)
+		}
+		appendJournaldField(b, name, s)
+	}
+	return b.Bytes(), nil
+}
+
+// appendJournaldField writes NAME=value, or the length-prefixed form for
+// values spanning several lines.
+func appendJournaldField(b *bytes.Buffer, name, value string) {
+	b.WriteString(name)
+	if !strings.Contains(value, "\n") {
+		b.WriteByte('=')
+		b.WriteString(value)
+		b.WriteByte('\n')
+		return
+	}
+	b.WriteByte('\n')
+	binary.Write(b, binary.LittleEndian, uint64(len(value)))
+	b.WriteString(value)
+	b.WriteByte('\n')
+}
+
+// journaldFieldName returns s in uppercase with characters journald doesn't
+// accept replaced by '_'. Leading underscores are dropped, they mark the
+// fields journald sets itself, and a leading digit gets a "F" prefix.
+func journaldFieldName(s string) string {
+	clean := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9'):
+			return r
+		}
+		return '_'
+	}, s)
+	clean = strings.TrimLeft(clean, "_")
+	if clean != "" && clean[0] >= '0' && clean[0] <= '9' {
+		clean = "F" + clean
+	}
+	if len(clean) > 64 {
+		clean = clean[:64]
+	}
+	return clean
+}
+
+// JournaldWriter sends JournaldFormatter entries to the local journal over
+// its native socket. Entries too large for a datagram are passed as a file
+// descriptor, as sd_journal_send does.
+type JournaldWriter struct {
+	mu   sync.Mutex
+	conn *net.UnixConn
+
+	registration sinkRegistration
+}
+
+// NewJournaldWriter connects to the journal socket.
+func NewJournaldWriter() (*JournaldWriter, error) {
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: journaldSocket, Net: "unixgram"})
+	if err != nil {
+		return nil, err
+	}
+	w := &JournaldWriter{conn: conn}
+	w.registration.register(w, SinkOutput)
+	return w, nil
+}
+
+func (w *JournaldWriter) Write(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	_, err := w.conn.Write(p)
+	if err != nil && (errors.Is(err, syscall.EMSGSIZE) || errors.Is(err, syscall.ENOBUFS)) {
+		err = journaldWriteFile(w.conn, p)
+	}
+	if err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (w *JournaldWriter) Close() error {
+	w.registration.release()
+	return w.conn.Close()
+}
@@ -0,0 +1,13 @@
+//go:build !linux
+// +build !linux
+
+package log
+
+import (
+	"fmt"
+	"net"
+)
+
+func journaldWriteFile(conn *net.UnixConn, p []byte) error {
+	return fmt.Errorf("log: journal entry of %d bytes is too large", len(p))
+}
@@ -1,6 +1,7 @@
 package log
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	log "github.com/sirupsen/logrus"
@@ -11,11 +12,50 @@ const defaultTimestampFormat = time.RFC3339
 
 // var markers = [2]string{"sourcecode", "golang"}
 
+// ChannelJSONFormatter formats logs into newline-delimited JSON. It takes the
+// same options as ChannelTextFormatter so switching between the two only
+// means swapping the formatter.
 type ChannelJSONFormatter struct {
-	Test string
+	// Disable timestamp logging. useful when output is redirected to logging
+	// system that already adds timestamps.
+	DisableTimestamp bool
+
+	// TimestampFormat to use for the date field
+	TimestampFormat string
+
+	// The fields are sorted by default for a consistent output. date, level
+	// and message always come first.
+	DisableSorting bool
+
+	// KeyOrder lists fields written right after message, in this order
+	KeyOrder []string
+
+	// QuoteValues renders every field value as a JSON string, the same way
+	// the text formatter would print it. Useful for aggregators that reject
+	// a field changing type between entries.
+	QuoteValues bool
+
+	// ErrorChain renders the causes of error values, see ErrorChainMode.
+	// With ErrorChainFields the error becomes an object holding message,
+	// causes and stack.
+	ErrorChain ErrorChainMode
+
+	// NestFields groups dotted keys into objects, so http.method and
+	// http.status become "http":{"method":...,"status":...}, and converts
+	// map and struct values down to MaxDepth levels, which defaults to 5,
+	// writing "(cycle)" for values containing themselves. Fields clashing
+	// with the keys below, and dotted fields under them such as message.id,
+	// go in a fields object under their own names.
+	NestFields bool
+	MaxDepth   int
+
+	// FieldMap renames the date, level and message keys. Fields with the
+	// same names as these keys are renamed fields.<key>.
+	FieldMap FieldMap
 }
 
 func (f *ChannelJSONFormatter) Format(entry *log.Entry) ([]byte, error) {
+	level, entry := prepareEntry(entry)
 
 	data := make(log.Fields, len(entry.Data)+4)
 	for k, v := range entry.Data {
@@ -23,21 +63,86 @@ func (f *ChannelJSONFormatter) Format(entry *log.Entry) ([]byte, error) {
 		case error:
 			// Otherwise errors are ignored by `encoding/json`
 			// https://github.com/Sirupsen/logrus/issues/137
-			data[k] = v.Error()
+			data[k] = f.errorValue(v)
 		default:
-			data[k] = v
+			if f.QuoteValues {
+				data[k] = fmt.Sprint(v)
+			} else {
+				data[k] = v
+			}
 		}
 	}
-	//prefixFieldClashes(data)
+	timeKey, levelKey, msgKey := f.FieldMap.resolve(FieldKeyTime, "date"), f.FieldMap.resolve(FieldKeyLevel, "level"), f.FieldMap.resolve(FieldKeyMsg, "message")
+	prefixFieldClashes(data, timeKey, levelKey, msgKey)
+	if f.NestFields {
+		clashes := takeNestedClashes(data, timeKey, levelKey, msgKey)
+		data = nestFields(data, f.MaxDepth)
+		groupNestedClashes(data, clashes, f.MaxDepth)
+	}
+
+	keys := make([]string, 0, len(data))
+	for k := range data {
+		keys = append(keys, k)
+	}
+	orderKeys(keys, f.KeyOrder, !f.DisableSorting)
 
-	data["date"] = entry.Time.Format(defaultTimestampFormat)
-	data["message"] = entry.Message
-	data["level"] = entry.Level.String()
+	timestampFormat := f.TimestampFormat
+	if timestampFormat == "" {
+		timestampFormat = defaultTimestampFormat
+	}
+
+	b := &bytes.Buffer{}
+	b.WriteByte('{')
+	if !f.DisableTimestamp {
+		if err := f.appendKeyValue(b, timeKey, entryTime(entry).Format(timestampFormat)); err != nil {
+			return nil, err
+		}
+	}
+	if err := f.appendKeyValue(b, levelKey, level.Name); err != nil {
+		return nil, err
+	}
+	if err := f.appendKeyValue(b, msgKey, entry.Message); err != nil {
+		return nil, err
+	}
 	// data["@marker"] = markers
+	for _, k := range keys {
+		if err := f.appendKeyValue(b, k, data[k]); err != nil {
+			return nil, err
+		}
+	}
+	b.WriteByte('}')
+	b.WriteByte('\n')
+	return b.Bytes(), nil
+}
 
-	serialized, err := json.Marshal(data)
+func (f *ChannelJSONFormatter) errorValue(err error) interface{} {
+	switch f.ErrorChain {
+	case ErrorChainInline:
+		return inlineErrorChain(err)
+	case ErrorChainFields:
+		obj := map[string]interface{}{"message": err.Error()}
+		if causes := chainMessages(errorChain(err)); len(causes) > 1 {
+			obj["causes"] = causes[1:]
+		}
+		if stack := errorStack(err); stack != "" {
+			obj["stack"] = stack
+		}
+		return obj
+	}
+	return err.Error()
+}
+
+func (f *ChannelJSONFormatter) appendKeyValue(b *bytes.Buffer, key string, value interface{}) error {
+	serialized, err := json.Marshal(value)
 	if err != nil {
-		return nil, fmt.Errorf("Failed to marshal fields to JSON, %v", err)
+		return fmt.Errorf("Failed to marshal fields to JSON, %v", err)
+	}
+	if b.Len() > 1 {
+		b.WriteByte(',')
 	}
-	return append(serialized, '\n'), nil
+	k, _ := json.Marshal(key)
+	b.Write(k)
+	b.WriteByte(':')
+	b.Write(serialized)
+	return nil
 }
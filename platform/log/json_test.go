@@ -0,0 +1,48 @@
+package log
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	logrus "github.com/sirupsen/logrus"
+)
+
+func TestJSONFormatter(t *testing.T) {
+	entry := logrus.NewEntry(logrus.StandardLogger())
+	entry.Time = time.Date(2018, 2, 26, 10, 0, 0, 0, time.UTC)
+	entry.Level = logrus.WarnLevel
+	entry.Message = "charge failed"
+	entry.Data = logrus.Fields{"amount": 1000, "error": errors.New("card declined")}
+
+	f := &ChannelJSONFormatter{}
+	b, err := f.Format(entry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(string(b), "}\n") {
+		t.Errorf("expected newline-delimited JSON, got %q", b)
+	}
+	expected := `{"date":"2018-02-26T10:00:00Z","level":"warning","message":"charge failed","amount":1000,"error":"card declined"}` + "\n"
+	if string(b) != expected {
+		t.Errorf("expected %s got %s", expected, b)
+	}
+
+	f = &ChannelJSONFormatter{DisableTimestamp: true, QuoteValues: true}
+	b, err = f.Format(entry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := map[string]interface{}{}
+	if err := json.Unmarshal(b, &out); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := out["date"]; ok {
+		t.Errorf("expected no date field %+v", out)
+	}
+	if out["amount"] != "1000" {
+		t.Errorf("expected quoted amount %+v", out)
+	}
+}
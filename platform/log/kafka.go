@@ -0,0 +1,175 @@
+package log
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	logrus "github.com/sirupsen/logrus"
+)
+
+const (
+	defaultKafkaBatchSize  = 100
+	defaultKafkaBatchWait  = time.Second
+	defaultKafkaBufferSize = 8192
+	defaultKafkaRetryWait  = 5 * time.Second
+)
+
+// KafkaHook publishes formatted entries to a Kafka topic. Messages are
+// batched and sent in the background; those that fail to be delivered are
+// kept in a retry buffer and sent again every RetryWait. Past BufferSize the
+// oldest are dropped.
+type KafkaHook struct {
+	// PartitionKey is the field whose value keys each message, so entries
+	// with the same value land in the same partition in order. Defaults to
+	// the channel name; entries without the field get no key.
+	PartitionKey string
+
+	// Formatter renders the message value. Defaults to ChannelJSONFormatter.
+	Formatter logrus.Formatter
+
+	// OnError is called with the number of messages that failed to be
+	// delivered. They are retried afterwards.
+	OnError func(err error, messages int)
+
+	// BufferSize is the number of failed messages kept for retrying.
+	// Defaults to 8192.
+	BufferSize int
+
+	// RetryWait is the time between retries. Defaults to 5s.
+	RetryWait time.Duration
+
+	writer kafkaWriter
+
+	mu    sync.Mutex
+	retry []kafka.Message
+
+	done chan struct{}
+	wg   sync.WaitGroup
+	once sync.Once
+
+	registration sinkRegistration
+}
+
+// kafkaWriter is the part of kafka.Writer the hook uses
+type kafkaWriter interface {
+	WriteMessages(ctx context.Context, msgs ...kafka.Message) error
+	Close() error
+}
+
+// NewKafkaHook starts a hook publishing to topic on brokers. Call Close on
+// shutdown to send what is still batched.
+func NewKafkaHook(brokers []string, topic string) *KafkaHook {
+	w := &kafka.Writer{
+		Addr:         kafka.TCP(brokers...),
+		Topic:        topic,
+		Balancer:     &kafka.Hash{},
+		BatchSize:    defaultKafkaBatchSize,
+		BatchTimeout: defaultKafkaBatchWait,
+		Async:        true,
+	}
+	h := newKafkaHook(w)
+	w.Completion = h.completion
+	return h
+}
+
+func newKafkaHook(writer kafkaWriter) *KafkaHook {
+	h := &KafkaHook{
+		PartitionKey: ChannelKey,
+		Formatter:    &ChannelJSONFormatter{},
+		BufferSize:   defaultKafkaBufferSize,
+		RetryWait:    defaultKafkaRetryWait,
+		writer:       writer,
+		done:         make(chan struct{}),
+	}
+	h.registration.register(h, SinkQueue)
+	h.wg.Add(1)
+	go h.run()
+	return h
+}
+
+func (h *KafkaHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (h *KafkaHook) Fire(entry *logrus.Entry) error {
+	b, err := h.Formatter.Format(entry)
+	if err != nil {
+		return err
+	}
+	if len(b) == 0 {
+		return nil
+	}
+	msg := kafka.Message{
+		// the formatter may return a buffer it reuses
+		Value: append([]byte(nil), b...),
+		Time:  entry.Time,
+	}
+	if v, ok := entry.Data[h.PartitionKey]; ok && h.PartitionKey != "" {
+		msg.Key = []byte(fmt.Sprint(v))
+	}
+	return h.writer.WriteMessages(context.Background(), msg)
+}
+
+// completion is called by the writer after every batch
+func (h *KafkaHook) completion(messages []kafka.Message, err error) {
+	if err == nil {
+		return
+	}
+	if h.OnError != nil {
+		h.OnError(err, len(messages))
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.retry = append(h.retry, messages...)
+	if over := len(h.retry) - h.BufferSize; over > 0 && h.BufferSize > 0 {
+		h.retry = h.retry[over:]
+		recordDropped(uint64(over))
+	}
+}
+
+func (h *KafkaHook) run() {
+	defer h.wg.Done()
+
+	ticker := time.NewTicker(h.RetryWait)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			h.resend()
+		case <-h.done:
+			return
+		}
+	}
+}
+
+// resend hands the messages in the retry buffer back to the writer
+func (h *KafkaHook) resend() {
+	h.mu.Lock()
+	messages := h.retry
+	h.retry = nil
+	h.mu.Unlock()
+
+	if len(messages) == 0 {
+		return
+	}
+	if err := h.writer.WriteMessages(context.Background(), messages...); err != nil {
+		h.completion(messages, err)
+	}
+}
+
+// Close retries the buffered messages once, sends what is still batched and
+// closes the writer.
+func (h *KafkaHook) Close() error {
+	h.registration.release()
+	h.once.Do(func() {
+		close(h.done)
+	})
+	h.wg.Wait()
+	h.resend()
+	return h.writer.Close()
+}
@@ -0,0 +1,93 @@
+package log
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/segmentio/kafka-go"
+	logrus "github.com/sirupsen/logrus"
+)
+
+// fakeKafka records the messages written to it, failing them while down
+type fakeKafka struct {
+	mu       sync.Mutex
+	down     bool
+	messages []kafka.Message
+	closed   bool
+}
+
+func (w *fakeKafka) WriteMessages(ctx context.Context, msgs ...kafka.Message) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.down {
+		return errors.New("leader not available")
+	}
+	w.messages = append(w.messages, msgs...)
+	return nil
+}
+
+func (w *fakeKafka) Close() error {
+	w.closed = true
+	return nil
+}
+
+func TestKafkaHook(t *testing.T) {
+	w := &fakeKafka{}
+	h := newKafkaHook(w)
+	h.Formatter = &LogfmtFormatter{DisableTimestamp: true}
+
+	entry := logrus.NewEntry(logrus.StandardLogger()).WithField(ChannelKey, "payments")
+	entry.Level = logrus.InfoLevel
+	entry.Message = "paid"
+	if err := h.Fire(entry); err != nil {
+		t.Fatal(err)
+	}
+	entry = logrus.NewEntry(logrus.StandardLogger())
+	entry.Message = "served"
+	h.Fire(entry)
+	if err := h.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(w.messages) != 2 || !w.closed {
+		t.Fatalf("expected 2 messages and the writer closed, got %d", len(w.messages))
+	}
+	if string(w.messages[0].Key) != "payments" || string(w.messages[0].Value) != "level=info msg=paid channel=payments\n" {
+		t.Errorf("expected the entry keyed by channel, got %q %q", w.messages[0].Key, w.messages[0].Value)
+	}
+	if w.messages[1].Key != nil {
+		t.Errorf("expected no key without a channel, got %q", w.messages[1].Key)
+	}
+}
+
+func TestKafkaHookRetry(t *testing.T) {
+	w := &fakeKafka{down: true}
+	h := newKafkaHook(w)
+	h.BufferSize = 2
+	failed := 0
+	h.OnError = func(err error, messages int) {
+		failed += messages
+	}
+
+	dropped := Dropped()
+	h.completion([]kafka.Message{{Value: []byte("1")}, {Value: []byte("2")}, {Value: []byte("3")}}, errors.New("leader not available"))
+	if failed != 3 || Dropped() != dropped+1 {
+		t.Errorf("expected 3 failed messages and the oldest dropped, got %d failed and %d dropped", failed, Dropped()-dropped)
+	}
+
+	// still down, the messages go back to the buffer
+	h.resend()
+	if len(h.retry) != 2 || failed != 5 {
+		t.Errorf("expected the messages kept for retrying, got %d", len(h.retry))
+	}
+
+	w.mu.Lock()
+	w.down = false
+	w.mu.Unlock()
+	h.Close()
+	if len(w.messages) != 2 || string(w.messages[0].Value) != "2" || string(w.messages[1].Value) != "3" {
+		t.Errorf("expected Close to resend the buffered messages, got %v", w.messages)
+	}
+}
@@ -0,0 +1,56 @@
+package log
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+)
+
+// LazyValue is a field value computed when a formatter first renders it. See
+// Lazy.
+type LazyValue struct {
+	f     func() interface{}
+	once  sync.Once
+	value interface{}
+}
+
+// Lazy defers an expensive field value, e.g. a serialized struct or database
+// state, until the entry is formatted, so it isn't computed for entries below
+// the level or dropped by a SamplingFormatter:
+//
+//	logrus.WithField("state", log.Lazy(func() interface{} { return db.Stats() })).Debug("pool")
+//
+// f runs at most once however many outputs write the entry. Hooks see the
+// LazyValue rather than its value: RedactHook masks the value once it is
+// computed, TruncateHook leaves it as is.
+func Lazy(f func() interface{}) *LazyValue {
+	return &LazyValue{f: f}
+}
+
+// Value computes the value on the first call and returns it.
+func (l *LazyValue) Value() interface{} {
+	l.once.Do(func() {
+		l.value = l.f()
+	})
+	return l.value
+}
+
+func (l *LazyValue) String() string {
+	return fmt.Sprint(l.Value())
+}
+
+func (l *LazyValue) MarshalJSON() ([]byte, error) {
+	v := l.Value()
+	if err, ok := v.(error); ok {
+		v = err.Error()
+	}
+	return json.Marshal(v)
+}
+
+// resolveLazy returns the value of v when it is a LazyValue, and v otherwise.
+func resolveLazy(v interface{}) interface{} {
+	if l, ok := v.(*LazyValue); ok {
+		return l.Value()
+	}
+	return v
+}
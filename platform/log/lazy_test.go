@@ -0,0 +1,49 @@
+package log
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	logrus "github.com/sirupsen/logrus"
+)
+
+func TestLazy(t *testing.T) {
+	b := &bytes.Buffer{}
+	logger := logrus.New()
+	logger.Out = b
+	logger.Formatter = &ChannelJSONFormatter{}
+	logger.Level = logrus.InfoLevel
+
+	calls := 0
+	value := func() interface{} {
+		calls++
+		return map[string]int{"open": 3}
+	}
+
+	logger.WithField("pool", Lazy(value)).Debug("stats")
+	if calls != 0 {
+		t.Errorf("expected the value not to be computed below the level")
+	}
+
+	logger.WithField("pool", Lazy(value)).Info("stats")
+	if calls != 1 {
+		t.Errorf("expected the value to be computed once, got %d", calls)
+	}
+	if !strings.Contains(b.String(), `"pool":{"open":3}`) {
+		t.Errorf("expected the computed value in %q", b.String())
+	}
+
+	lazy := Lazy(value)
+	text := &ChannelTextFormatter{DisableColors: true}
+	out, err := text.Format(&logrus.Entry{Logger: logger, Data: logrus.Fields{"pool": lazy}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := text.Format(&logrus.Entry{Logger: logger, Data: logrus.Fields{"pool": lazy}}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(out), `pool="map[open:3]"`) || calls != 2 {
+		t.Errorf("expected one more computation rendering %q, got %d", out, calls)
+	}
+}
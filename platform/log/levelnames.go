@@ -0,0 +1,181 @@
+package log
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	logrus "github.com/sirupsen/logrus"
+)
+
+// LevelKey is the field holding the name of a custom level on entries logged
+// with LogAt. Formatters write the name in place of the logrus level and
+// don't write the field itself.
+const LevelKey = "log_level"
+
+// Level is a level name and the logrus level entries at that level are
+// logged, filtered and routed at. For the logrus levels Name is the logrus
+// name, custom levels registered with RegisterLevel have their own.
+type Level struct {
+	Name  string
+	Level logrus.Level
+
+	// Severity is the syslog severity written by the syslog, GELF, journald
+	// and Cloud Logging formatters.
+	Severity int
+}
+
+var (
+	customLevelsMu sync.RWMutex
+	customLevels   = map[string]Level{
+		"notice": {Name: "notice", Level: logrus.InfoLevel, Severity: 5},
+		"audit":  {Name: "audit", Level: logrus.InfoLevel, Severity: 5},
+	}
+
+	// levelAliases are other common names of the logrus levels.
+	levelAliases = map[string]logrus.Level{
+		"err":      logrus.ErrorLevel,
+		"crit":     logrus.FatalLevel,
+		"critical": logrus.FatalLevel,
+		"emerg":    logrus.PanicLevel,
+	}
+)
+
+// RegisterLevel adds a custom level, e.g. "security" logged at WarnLevel.
+// Entries at a custom level are logged at the logrus level, so level
+// settings, hooks and output destinations treat them as such, while the
+// formatters write name and severity. notice and audit are registered by
+// default, both at InfoLevel with the syslog notice severity.
+func RegisterLevel(name string, level logrus.Level, severity int) error {
+	name = strings.ToLower(name)
+	if name == "" || strings.ContainsAny(name, " \t\n=\"") {
+		return fmt.Errorf("log: invalid level name %q", name)
+	}
+	if level > logrus.TraceLevel {
+		return fmt.Errorf("log: invalid level %d for %q", level, name)
+	}
+	if severity < 0 || severity > 7 {
+		return fmt.Errorf("log: invalid syslog severity %d for %q", severity, name)
+	}
+	if _, err := logrus.ParseLevel(name); err == nil {
+		return fmt.Errorf("log: level %q already exists", name)
+	}
+	if _, ok := levelAliases[name]; ok {
+		return fmt.Errorf("log: level %q already exists", name)
+	}
+
+	customLevelsMu.Lock()
+	defer customLevelsMu.Unlock()
+	if _, ok := customLevels[name]; ok {
+		return fmt.Errorf("log: level %q already exists", name)
+	}
+	customLevels[name] = Level{Name: name, Level: level, Severity: severity}
+	return nil
+}
+
+// LookupLevel returns the level named name, a logrus level, an alias of one,
+// e.g. "err" or "crit", or a custom level. Names are case insensitive.
+func LookupLevel(name string) (Level, error) {
+	name = strings.ToLower(name)
+	customLevelsMu.RLock()
+	l, ok := customLevels[name]
+	customLevelsMu.RUnlock()
+	if ok {
+		return l, nil
+	}
+	if level, ok := levelAliases[name]; ok {
+		return logrusLevel(level), nil
+	}
+	level, err := logrus.ParseLevel(name)
+	if err != nil {
+		return Level{}, fmt.Errorf("log: not a valid level %q", name)
+	}
+	return logrusLevel(level), nil
+}
+
+// ParseLevel returns the logrus level of name, accepting what LookupLevel
+// does, so "notice" parses as InfoLevel.
+func ParseLevel(name string) (logrus.Level, error) {
+	l, err := LookupLevel(name)
+	return l.Level, err
+}
+
+// logrusLevelNames are the names logrus.Level.String returns, which
+// allocates them on every call.
+var logrusLevelNames = [...]string{"panic", "fatal", "error", "warning", "info", "debug", "trace"}
+
+func logrusLevel(level logrus.Level) Level {
+	name := "unknown"
+	if int(level) < len(logrusLevelNames) {
+		name = logrusLevelNames[level]
+	}
+	return Level{Name: name, Level: level, Severity: SyslogSeverity(level)}
+}
+
+func (l Level) String() string {
+	return l.Name
+}
+
+func (l Level) MarshalText() ([]byte, error) {
+	if l.Name == "" {
+		return nil, fmt.Errorf("log: not a valid level %d", l.Level)
+	}
+	return []byte(l.Name), nil
+}
+
+func (l *Level) UnmarshalText(text []byte) error {
+	v, err := LookupLevel(string(text))
+	if err != nil {
+		return err
+	}
+	*l = v
+	return nil
+}
+
+// LogAt logs msg on entry at the level named name, which may be a custom
+// level:
+//
+//	log.LogAt(logrus.WithField("user", id), "audit", "password changed")
+func LogAt(entry *logrus.Entry, name string, msg string) error {
+	l, err := LookupLevel(name)
+	if err != nil {
+		return err
+	}
+	if _, ok := customLevelByName(l.Name); ok {
+		entry = entry.WithField(LevelKey, l.Name)
+	}
+	entry.Log(l.Level, msg)
+	return nil
+}
+
+func customLevelByName(name string) (Level, bool) {
+	customLevelsMu.RLock()
+	defer customLevelsMu.RUnlock()
+	l, ok := customLevels[name]
+	return l, ok
+}
+
+// entryLevel returns the level of entry, which is its custom level when it
+// was logged with LogAt, and the entry without LevelKey for formatters to
+// write the fields of.
+func entryLevel(entry *logrus.Entry) (Level, *logrus.Entry) {
+	name, ok := entry.Data[LevelKey].(string)
+	if !ok {
+		return logrusLevel(entry.Level), entry
+	}
+	l, ok := customLevelByName(name)
+	if !ok {
+		return logrusLevel(entry.Level), entry
+	}
+	// the entry may have been logged at another level by hand
+	l.Level = entry.Level
+
+	stripped := *entry
+	stripped.Data = make(logrus.Fields, len(entry.Data)-1)
+	for k, v := range entry.Data {
+		if k != LevelKey {
+			stripped.Data[k] = v
+		}
+	}
+	return l, &stripped
+}
@@ -0,0 +1,84 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	logrus "github.com/sirupsen/logrus"
+)
+
+func TestParseLevel(t *testing.T) {
+	for name, want := range map[string]logrus.Level{
+		"trace":   logrus.TraceLevel,
+		"WARNING": logrus.WarnLevel,
+		"err":     logrus.ErrorLevel,
+		"crit":    logrus.FatalLevel,
+		"notice":  logrus.InfoLevel,
+		"Audit":   logrus.InfoLevel,
+	} {
+		l, err := ParseLevel(name)
+		if err != nil || l != want {
+			t.Errorf("%s: expected %v, got %v, %v", name, want, l, err)
+		}
+	}
+	if _, err := ParseLevel("loud"); err == nil {
+		t.Errorf("expected an error for an unknown level")
+	}
+
+	var v struct{ Level Level }
+	if err := json.Unmarshal([]byte(`{"Level":"notice"}`), &v); err != nil {
+		t.Fatal(err)
+	}
+	if v.Level.Level != logrus.InfoLevel || v.Level.Severity != 5 {
+		t.Errorf("unexpected level %+v", v.Level)
+	}
+	b, _ := json.Marshal(v)
+	if string(b) != `{"Level":"notice"}` {
+		t.Errorf("unexpected json %s", b)
+	}
+}
+
+func TestRegisterLevel(t *testing.T) {
+	if err := RegisterLevel("security", logrus.WarnLevel, 4); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		customLevelsMu.Lock()
+		delete(customLevels, "security")
+		customLevelsMu.Unlock()
+	}()
+	for _, name := range []string{"security", "info", "err", "bad name"} {
+		if err := RegisterLevel(name, logrus.WarnLevel, 4); err == nil {
+			t.Errorf("expected an error registering %q", name)
+		}
+	}
+
+	out := &bytes.Buffer{}
+	logger := logrus.New()
+	logger.Out = out
+	logger.Formatter = &LogfmtFormatter{DisableTimestamp: true}
+	logger.SetLevel(logrus.ErrorLevel)
+
+	if err := LogAt(logrus.NewEntry(logger), "security", "skipped"); err != nil {
+		t.Fatal(err)
+	}
+	logger.SetLevel(logrus.WarnLevel)
+	LogAt(logger.WithField("user", "u_1"), "security", "login blocked")
+	LogAt(logger.WithField("user", "u_1"), "warn", "plain")
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected the entry below the level filtered, got %q", out.String())
+	}
+	if lines[0] != `level=security msg="login blocked" user=u_1` {
+		t.Errorf("unexpected line %q", lines[0])
+	}
+	if lines[1] != `level=warning msg=plain user=u_1` {
+		t.Errorf("unexpected line %q", lines[1])
+	}
+	if err := LogAt(logrus.NewEntry(logger), "loud", "x"); err == nil {
+		t.Errorf("expected an error for an unknown level")
+	}
+}
@@ -0,0 +1,155 @@
+package log
+
+import (
+	"path"
+	"strings"
+	"sync"
+
+	logrus "github.com/sirupsen/logrus"
+)
+
+var (
+	levelsMu    sync.RWMutex
+	globalLevel = logrus.InfoLevel
+	levelRules  = map[string]logrus.Level{}
+
+	// packageLevels caches matchLevel by package for packageEnabled. It is
+	// replaced whenever levelRules changes.
+	packageLevels = &sync.Map{}
+)
+
+type packageLevel struct {
+	level   logrus.Level
+	matched bool
+}
+
+// SetLevel sets the level of the package level functions. Packages and
+// channels matched by SetLevelFor keep their own level.
+func SetLevel(level logrus.Level) {
+	levelsMu.Lock()
+	globalLevel = level
+	levelsMu.Unlock()
+	applyLevels()
+}
+
+func GetLevel() logrus.Level {
+	levelsMu.RLock()
+	defer levelsMu.RUnlock()
+	return globalLevel
+}
+
+// SetLevelFor overrides the level of every channel name or Go package
+// matching pattern, e.g. SetLevelFor("platform/db", logrus.DebugLevel). The
+// pattern uses path.Match syntax and matches a package by any trailing part
+// of its import path. When several patterns match, the longest one wins.
+func SetLevelFor(pattern string, level logrus.Level) error {
+	if _, err := path.Match(pattern, ""); err != nil {
+		return err
+	}
+	levelsMu.Lock()
+	levelRules[pattern] = level
+	packageLevels = &sync.Map{}
+	levelsMu.Unlock()
+	applyLevels()
+	return nil
+}
+
+// ResetLevelFor removes the override set by SetLevelFor.
+func ResetLevelFor(pattern string) {
+	levelsMu.Lock()
+	delete(levelRules, pattern)
+	packageLevels = &sync.Map{}
+	levelsMu.Unlock()
+	applyLevels()
+}
+
+// LevelRules returns a copy of the overrides set by SetLevelFor.
+func LevelRules() map[string]logrus.Level {
+	levelsMu.RLock()
+	defer levelsMu.RUnlock()
+	rules := make(map[string]logrus.Level, len(levelRules))
+	for k, v := range levelRules {
+		rules[k] = v
+	}
+	return rules
+}
+
+// matchLevel returns the level of the longest pattern matching name.
+// levelsMu must be held.
+func matchLevel(name string) (logrus.Level, bool) {
+	var level logrus.Level
+	best := -1
+	for pattern, l := range levelRules {
+		if len(pattern) > best && matchName(pattern, name) {
+			level, best = l, len(pattern)
+		}
+	}
+	return level, best >= 0
+}
+
+// matchName matches pattern against name and each of its trailing
+// slash-separated parts, so "platform/db" matches ".../openpoint/platform/db".
+func matchName(pattern, name string) bool {
+	for {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+		i := strings.Index(name, "/")
+		if i < 0 {
+			return false
+		}
+		name = name[i+1:]
+	}
+}
+
+// applyLevels lets the standard logger through at the most verbose level any
+// package needs, packageEnabled filters per caller from there, and updates
+// the level of every channel.
+func applyLevels() {
+	levelsMu.RLock()
+	max := globalLevel
+	for _, l := range levelRules {
+		if l > max {
+			max = l
+		}
+	}
+	levelsMu.RUnlock()
+	logrus.SetLevel(max)
+
+	channelsMu.Lock()
+	defer channelsMu.Unlock()
+	for _, l := range channels {
+		l.applyLevel()
+	}
+}
+
+// packageEnabled reports whether the package calling into the log functions
+// logs at level. The package of each call site and the level of each package
+// are cached, so only the first call from a site walks the stack.
+func packageEnabled(level logrus.Level) bool {
+	levelsMu.RLock()
+	rules := len(levelRules)
+	g := globalLevel
+	levels := packageLevels
+	levelsMu.RUnlock()
+
+	if rules == 0 {
+		return level <= g
+	}
+	pkg, ok := callerPackage()
+	if !ok {
+		return level <= g
+	}
+	cached, ok := levels.Load(pkg)
+	if !ok {
+		levelsMu.RLock()
+		l, matched := matchLevel(pkg)
+		levelsMu.RUnlock()
+		cached = packageLevel{level: l, matched: matched}
+		levels.Store(pkg, cached)
+	}
+	if p := cached.(packageLevel); p.matched {
+		return level <= p.level
+	}
+	return level <= g
+}
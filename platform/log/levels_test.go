@@ -0,0 +1,60 @@
+package log
+
+import (
+	"testing"
+
+	logrus "github.com/sirupsen/logrus"
+)
+
+func TestSetLevelFor(t *testing.T) {
+	db := Channel("platform/db")
+	db.SetLevel(logrus.WarnLevel)
+
+	if err := SetLevelFor("platform/*", logrus.DebugLevel); err != nil {
+		t.Fatal(err)
+	}
+	defer ResetLevelFor("platform/*")
+	if db.GetLevel() != logrus.DebugLevel {
+		t.Errorf("expected debug from the override, got %v", db.GetLevel())
+	}
+
+	SetLevelFor("platform/db", logrus.ErrorLevel)
+	if db.GetLevel() != logrus.ErrorLevel {
+		t.Errorf("expected the longest pattern to win, got %v", db.GetLevel())
+	}
+
+	ResetLevelFor("platform/db")
+	ResetLevelFor("platform/*")
+	if db.GetLevel() != logrus.WarnLevel {
+		t.Errorf("expected the channel level back, got %v", db.GetLevel())
+	}
+
+	if !matchName("platform/db", "github.com/o3labs/openpoint/platform/db") {
+		t.Errorf("expected a package to match by its trailing path")
+	}
+	if err := SetLevelFor("[", logrus.DebugLevel); err == nil {
+		t.Errorf("expected an error for a bad pattern")
+	}
+}
+
+func TestPackageEnabledCache(t *testing.T) {
+	if pkg, ok := callerPackage(); !ok || pkg != logPackage {
+		t.Errorf("expected the package of the test, got %q", pkg)
+	}
+
+	SetLevelFor("platform/db", logrus.TraceLevel)
+	defer ResetLevelFor("platform/db")
+	if TraceEnabled() {
+		t.Errorf("expected the global level for a package without a rule")
+	}
+
+	// the rules changed after the package level was cached
+	SetLevelFor("platform/log", logrus.TraceLevel)
+	if !TraceEnabled() {
+		t.Errorf("expected a new rule to apply")
+	}
+	ResetLevelFor("platform/log")
+	if TraceEnabled() {
+		t.Errorf("expected a removed rule not to apply")
+	}
+}
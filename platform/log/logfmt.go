@@ -0,0 +1,164 @@
+package log
+
+import (
+	"bytes"
+	"fmt"
+	"unicode/utf8"
+
+	logrus "github.com/sirupsen/logrus"
+)
+
+const hexDigits = "0123456789abcdef"
+
+// LogfmtFormatter formats logs as strict logfmt, which any logfmt parser
+// reads back. Unlike ChannelTextFormatter it escapes values the way logfmt
+// expects rather than Go-quoting them, and it replaces characters that are
+// not allowed in keys.
+type LogfmtFormatter struct {
+	// Disable timestamp logging. useful when output is redirected to logging
+	// system that already adds timestamps.
+	DisableTimestamp bool
+
+	// TimestampFormat to use for the time field
+	TimestampFormat string
+
+	// The fields are sorted by default for a consistent output.
+	DisableSorting bool
+
+	// KeyOrder lists fields that always come first and in this order, see
+	// ChannelTextFormatter.KeyOrder.
+	KeyOrder []string
+
+	// FlattenFields and MaxDepth, see ChannelTextFormatter.FlattenFields
+	FlattenFields bool
+	MaxDepth      int
+
+	// FieldMap renames the time, level and msg keys. Fields with the same
+	// names as these keys are renamed fields.<key>.
+	FieldMap FieldMap
+
+	// Escaping of values that need quoting. Defaults to EscapeJSON, which
+	// logfmt parsers read back.
+	Escaping Escaping
+}
+
+// Format renders a single log entry
+func (f *LogfmtFormatter) Format(entry *logrus.Entry) ([]byte, error) {
+	level, entry := prepareEntry(entry)
+	if f.FlattenFields {
+		flat := *entry
+		flat.Data = flattenFields(entry.Data, f.MaxDepth)
+		entry = &flat
+	}
+	timeKey, levelKey, msgKey := f.FieldMap.resolve(FieldKeyTime, "time"), f.FieldMap.resolve(FieldKeyLevel, "level"), f.FieldMap.resolve(FieldKeyMsg, "msg")
+	entry = prefixEntryClashes(entry, timeKey, levelKey, msgKey)
+	keys := make([]string, 0, len(entry.Data))
+	for k := range entry.Data {
+		keys = append(keys, k)
+	}
+	orderKeys(keys, f.KeyOrder, !f.DisableSorting)
+
+	timestampFormat := f.TimestampFormat
+	if timestampFormat == "" {
+		timestampFormat = defaultTimestampFormat
+	}
+
+	b := bufferPool.Get().(*bytes.Buffer)
+	b.Reset()
+	defer bufferPool.Put(b)
+
+	if !f.DisableTimestamp {
+		appendLogfmtEscaped(b, timeKey, entryTime(entry).Format(timestampFormat), f.Escaping)
+	}
+	appendLogfmtEscaped(b, levelKey, level.Name, f.Escaping)
+	appendLogfmtEscaped(b, msgKey, entry.Message, f.Escaping)
+	for _, k := range keys {
+		appendLogfmtEscaped(b, k, entry.Data[k], f.Escaping)
+	}
+	b.WriteByte('\n')
+	return append([]byte(nil), b.Bytes()...), nil
+}
+
+func appendLogfmt(b *bytes.Buffer, key string, value interface{}) {
+	appendLogfmtEscaped(b, key, value, EscapeDefault)
+}
+
+func appendLogfmtEscaped(b *bytes.Buffer, key string, value interface{}, escaping Escaping) {
+	if b.Len() > 0 {
+		b.WriteByte(' ')
+	}
+	appendLogfmtKey(b, key)
+	b.WriteByte('=')
+
+	var s string
+	switch v := resolveLazy(value).(type) {
+	case string:
+		s = v
+	case error:
+		s = v.Error()
+	case fmt.Stringer:
+		s = v.String()
+	case nil:
+		return
+	default:
+		s = fmt.Sprint(v)
+	}
+	if logfmtNeedsQuoting(s) {
+		escaping.or(EscapeJSON).appendQuoted(b, s)
+	} else {
+		b.WriteString(s)
+	}
+}
+
+// appendLogfmtKey writes key with spaces, '=', '"' and control characters
+// replaced by '_', as logfmt keys can't be quoted.
+func appendLogfmtKey(b *bytes.Buffer, key string) {
+	if key == "" {
+		b.WriteByte('_')
+		return
+	}
+	for _, r := range key {
+		if r <= ' ' || r == '=' || r == '"' || r == utf8.RuneError || r == 0x7f {
+			b.WriteByte('_')
+		} else {
+			b.WriteRune(r)
+		}
+	}
+}
+
+func logfmtNeedsQuoting(s string) bool {
+	for _, r := range s {
+		if r <= ' ' || r == '=' || r == '"' || r == '\\' || r == utf8.RuneError || r == 0x7f {
+			return true
+		}
+	}
+	return false
+}
+
+// appendLogfmtQuoted writes s quoted, escaping quotes, backslashes and
+// control characters the way JSON strings do.
+func appendLogfmtQuoted(b *bytes.Buffer, s string) {
+	b.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"', '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\t':
+			b.WriteString(`\t`)
+		default:
+			if r < ' ' || r == 0x7f {
+				b.WriteString(`\u00`)
+				b.WriteByte(hexDigits[r>>4])
+				b.WriteByte(hexDigits[r&0xf])
+			} else {
+				b.WriteRune(r)
+			}
+		}
+	}
+	b.WriteByte('"')
+}
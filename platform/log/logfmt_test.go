@@ -0,0 +1,36 @@
+package log
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	logrus "github.com/sirupsen/logrus"
+)
+
+func TestLogfmtFormatter(t *testing.T) {
+	entry := logrus.NewEntry(logrus.StandardLogger())
+	entry.Time = time.Date(2018, 2, 26, 10, 0, 0, 0, time.UTC)
+	entry.Level = logrus.ErrorLevel
+	entry.Message = "charge failed"
+	entry.Data = logrus.Fields{
+		"amount":     1000,
+		"empty":      "",
+		"error":      errors.New("card \"4242\" declined\nretry"),
+		"query":      "a=b",
+		"path":       `C:\tmp`,
+		"bad key=":   "x",
+		"control":    "\x01",
+		"user_agent": "Mozilla/5.0",
+	}
+
+	b, err := (&LogfmtFormatter{}).Format(entry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `time=2018-02-26T10:00:00Z level=error msg="charge failed" amount=1000 bad_key_=x control="\u0001" empty= ` +
+		`error="card \"4242\" declined\nretry" path="C:\\tmp" query="a=b" user_agent=Mozilla/5.0` + "\n"
+	if string(b) != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, b)
+	}
+}
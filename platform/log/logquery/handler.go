@@ -0,0 +1,130 @@
+package logquery
+
+import (
+	"bytes"
+	"net/http"
+	"strconv"
+	"sync"
+
+	"github.com/o3labs/openpoint/platform/errors"
+	"github.com/o3labs/openpoint/platform/log"
+	logrus "github.com/sirupsen/logrus"
+)
+
+// Handler is an http.Handler searching log files, e.g. mounted at
+// /debug/logs/search, to see what an instance logged beyond what a
+// log.RingBuffer keeps.
+//
+// GET takes the query parameters
+//
+//	q=level>=warn AND since=1h  the query, see Parse
+//	limit=100                   the most recent entries, 1000 by default and 10000 at most
+//	format=text                 ChannelTextFormatter lines instead of a JSON array
+type Handler struct {
+	// Files to search, indexed on the first request
+	Files []string
+
+	// Token required as "Authorization: Bearer <token>", see log.LevelHandler
+	Token string
+
+	mu      sync.Mutex
+	indexes map[string]*Index
+}
+
+const (
+	defaultLimit = 1000
+	maxLimit     = 10000
+)
+
+// ServeHTTP answers a search.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if !log.BearerAuthorized(req, h.Token) {
+		errors.Unauthorized().Write(w)
+		return
+	}
+	if req.Method != http.MethodGet {
+		errors.NewError(http.StatusMethodNotAllowed, "Use GET", http.StatusText(http.StatusMethodNotAllowed)).Write(w)
+		return
+	}
+
+	query := req.URL.Query()
+	q, err := Parse(query.Get("q"))
+	if err != nil {
+		errors.BadRequest("%v", err).Write(w)
+		return
+	}
+	q.Limit = defaultLimit
+	if v := query.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 || n > maxLimit {
+			errors.BadRequest("Invalid limit %v, between 1 and %v", v, maxLimit).Write(w)
+			return
+		}
+		q.Limit = n
+	}
+
+	indexes, err := h.open()
+	if err != nil {
+		errors.ServerError("%v", err).Write(w)
+		return
+	}
+	entries, err := Search(q, indexes...)
+	if err != nil {
+		errors.ServerError("%v", err).Write(w)
+		return
+	}
+
+	text := query.Get("format") == "text"
+	var formatter logrus.Formatter = &log.ChannelJSONFormatter{}
+	if text {
+		formatter = &log.ChannelTextFormatter{DisableColors: true}
+	}
+	b := &bytes.Buffer{}
+	if !text {
+		b.WriteByte('[')
+	}
+	for i, entry := range entries {
+		out, err := formatter.Format(entry)
+		if err != nil {
+			errors.ServerError("%v", err).Write(w)
+			return
+		}
+		if !text {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			out = bytes.TrimRight(out, "\n")
+		}
+		b.Write(out)
+	}
+
+	if text {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	} else {
+		b.WriteString("]\n")
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	}
+	w.Write(b.Bytes())
+}
+
+// open returns the indexes of Files, indexing the files it hasn't yet.
+func (h *Handler) open() ([]*Index, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.indexes == nil {
+		h.indexes = map[string]*Index{}
+	}
+	indexes := make([]*Index, 0, len(h.Files))
+	for _, file := range h.Files {
+		ix, ok := h.indexes[file]
+		if !ok {
+			var err error
+			if ix, err = OpenIndex(file); err != nil {
+				return nil, err
+			}
+			h.indexes[file] = ix
+		}
+		indexes = append(indexes, ix)
+	}
+	return indexes, nil
+}
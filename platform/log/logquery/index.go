@@ -0,0 +1,268 @@
+package logquery
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/o3labs/openpoint/platform/log"
+	logrus "github.com/sirupsen/logrus"
+)
+
+const (
+	// a block ends after this many lines or bytes, whichever comes first
+	blockLines = 1000
+	blockBytes = 256 << 10
+
+	// blocks with more channels than this don't keep them
+	maxBlockChannels = 64
+)
+
+// block is a run of whole lines of the file with what its entries hold.
+type block struct {
+	offset, end int64
+	lines       int
+
+	// min and max are the time range of the entries with a time, untimed
+	// is set when some have none
+	min, max time.Time
+	untimed  bool
+
+	levels uint8
+
+	// channels of the entries, "" for those without one. nil once there
+	// are too many to keep.
+	channels map[string]bool
+}
+
+func (b *block) add(entry *logrus.Entry) {
+	b.levels |= 1 << entry.Level
+	switch {
+	case entry.Time.IsZero():
+		b.untimed = true
+	case b.min.IsZero():
+		b.min, b.max = entry.Time, entry.Time
+	case entry.Time.Before(b.min):
+		b.min = entry.Time
+	case entry.Time.After(b.max):
+		b.max = entry.Time
+	}
+	if b.channels != nil {
+		channel := ""
+		if v, ok := entry.Data[log.ChannelKey]; ok {
+			channel = fmt.Sprint(v)
+		}
+		b.channels[channel] = true
+		if len(b.channels) > maxBlockChannels {
+			b.channels = nil
+		}
+	}
+}
+
+func (b *block) copy() *block {
+	c := *b
+	if b.channels != nil {
+		c.channels = make(map[string]bool, len(b.channels))
+		for k := range b.channels {
+			c.channels[k] = true
+		}
+	}
+	return &c
+}
+
+func (b *block) full() bool {
+	return b.lines >= blockLines || b.end-b.offset >= blockBytes
+}
+
+// skip reports whether no entry of the block can match q.
+func (b *block) skip(q *Query) bool {
+	if b.levels&q.levels == 0 {
+		return true
+	}
+	if q.channel != "" && b.channels != nil && !b.channels[q.channel] {
+		return true
+	}
+	if b.untimed || b.min.IsZero() {
+		return false
+	}
+	if !q.Since.IsZero() && b.max.Before(q.Since) {
+		return true
+	}
+	return !q.Until.IsZero() && !b.min.Before(q.Until)
+}
+
+// Index is an index of an NDJSON log file. It is updated as the file grows
+// and rebuilt when the file is truncated or replaced by a rotation.
+type Index struct {
+	file string
+
+	mu     sync.Mutex
+	blocks []*block
+	size   int64
+	info   os.FileInfo
+}
+
+// OpenIndex indexes file.
+func OpenIndex(file string) (*Index, error) {
+	ix := &Index{file: file}
+	if err := ix.Refresh(); err != nil {
+		return nil, err
+	}
+	return ix, nil
+}
+
+// File returns the name of the indexed file.
+func (ix *Index) File() string {
+	return ix.file
+}
+
+// Refresh indexes the lines appended to the file since the last refresh.
+// Search and Each refresh the index themselves.
+func (ix *Index) Refresh() error {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+
+	f, err := os.Open(ix.file)
+	if err != nil {
+		return fmt.Errorf("logquery: failed to open %s, %v", ix.file, err)
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return fmt.Errorf("logquery: failed to stat %s, %v", ix.file, err)
+	}
+	if ix.info != nil && (!os.SameFile(ix.info, info) || info.Size() < ix.size) {
+		ix.blocks, ix.size = nil, 0
+	}
+	ix.info = info
+	if info.Size() == ix.size {
+		return nil
+	}
+	if _, err := f.Seek(ix.size, io.SeekStart); err != nil {
+		return fmt.Errorf("logquery: failed to read %s, %v", ix.file, err)
+	}
+
+	// carry on with a copy of the last block while it has room, Each may
+	// be reading the blocks
+	var b *block
+	if n := len(ix.blocks); n > 0 && !ix.blocks[n-1].full() {
+		b = ix.blocks[n-1].copy()
+		ix.blocks = ix.blocks[: n-1 : n-1]
+	}
+	br := bufio.NewReader(f)
+	offset := ix.size
+	for {
+		line, err := br.ReadBytes('\n')
+		if err == io.EOF {
+			// a partial line is indexed once it is complete
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("logquery: failed to read %s, %v", ix.file, err)
+		}
+		if b == nil {
+			b = &block{offset: offset, channels: map[string]bool{}}
+		}
+		offset += int64(len(line))
+		b.end = offset
+		b.lines++
+		if entry, ok := ParseEntry(line); ok {
+			b.add(entry)
+		}
+		if b.full() {
+			ix.blocks = append(ix.blocks, b)
+			b = nil
+		}
+	}
+	if b != nil {
+		ix.blocks = append(ix.blocks, b)
+	}
+	ix.size = offset
+	return nil
+}
+
+// Each calls fn with the entries of the file matching q in file order,
+// until fn returns false. Lines that aren't JSON objects are skipped, as
+// are the blocks the index tells hold no matching entry.
+func (ix *Index) Each(q *Query, fn func(*logrus.Entry) bool) error {
+	if err := ix.Refresh(); err != nil {
+		return err
+	}
+	ix.mu.Lock()
+	blocks := ix.blocks
+	ix.mu.Unlock()
+
+	f, err := os.Open(ix.file)
+	if err != nil {
+		return fmt.Errorf("logquery: failed to open %s, %v", ix.file, err)
+	}
+	defer f.Close()
+
+	br := bufio.NewReader(nil)
+	for _, b := range blocks {
+		if b.skip(q) {
+			continue
+		}
+		br.Reset(io.NewSectionReader(f, b.offset, b.end-b.offset))
+		for {
+			line, err := br.ReadBytes('\n')
+			if len(line) > 0 {
+				if entry, ok := ParseEntry(line); ok && q.Matches(entry) && !fn(entry) {
+					return nil
+				}
+			}
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return fmt.Errorf("logquery: failed to read %s, %v", ix.file, err)
+			}
+		}
+	}
+	return nil
+}
+
+// Search returns the entries of the file matching q, the most recent
+// q.Limit when it is set.
+func (ix *Index) Search(q *Query) ([]*logrus.Entry, error) {
+	var entries []*logrus.Entry
+	err := ix.Each(q, func(entry *logrus.Entry) bool {
+		entries = append(entries, entry)
+		if q.Limit > 0 && len(entries) >= 2*q.Limit {
+			entries = append(entries[:0], entries[len(entries)-q.Limit:]...)
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return last(entries, q.Limit), nil
+}
+
+// Search returns the entries of the indexed files matching q, ordered by
+// time, the most recent q.Limit when it is set.
+func Search(q *Query, indexes ...*Index) ([]*logrus.Entry, error) {
+	var entries []*logrus.Entry
+	for _, ix := range indexes {
+		found, err := ix.Search(q)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, found...)
+	}
+	if len(indexes) > 1 {
+		sort.SliceStable(entries, func(i, j int) bool { return entries[i].Time.Before(entries[j].Time) })
+	}
+	return last(entries, q.Limit), nil
+}
+
+func last(entries []*logrus.Entry, limit int) []*logrus.Entry {
+	if limit > 0 && len(entries) > limit {
+		return entries[len(entries)-limit:]
+	}
+	return entries
+}
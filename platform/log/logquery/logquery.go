@@ -0,0 +1,273 @@
+// Package logquery searches NDJSON log files, such as the output of the json
+// formatter, with the routing expressions of log.ParseMatcher plus a time
+// range:
+//
+//	q, err := logquery.Parse("level>=warn AND channel=payments AND status~^5 AND since=1h")
+//	ix, err := logquery.OpenIndex("/var/log/openpoint.log")
+//	entries, err := ix.Search(q)
+//
+// An Index keeps the time range, levels and channels of every block of a
+// file, so a query only reads the blocks that can hold matching entries.
+// Handler serves queries over HTTP, the logview command takes them with
+// -match.
+package logquery
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/o3labs/openpoint/platform/log"
+	logrus "github.com/sirupsen/logrus"
+)
+
+var (
+	timeKeys    = []string{"time", "date", "@timestamp", "timestamp"}
+	levelKeys   = []string{"level", "severity"}
+	messageKeys = []string{"msg", "message", "short_message"}
+
+	timeFormats = []string{time.RFC3339Nano, "2006-01-02 15:04:05", "2006-01-02T15:04:05.000000Z07:00"}
+)
+
+// allLevels is the level mask of every level
+const allLevels = 1<<(logrus.TraceLevel+1) - 1
+
+// Query selects entries.
+type Query struct {
+	// Since and Until bound the time of the entries, zero times leave the
+	// range open. Until is exclusive.
+	Since time.Time
+	Until time.Time
+
+	// Match selects the entries in the range, nil matches all of them
+	Match log.Matcher
+
+	// Limit keeps the most recent entries only, 0 keeps all of them
+	Limit int
+
+	// levels and channel narrow down the blocks to read, taken from the
+	// expression
+	levels  uint8
+	channel string
+}
+
+// Parse parses a log.ParseMatcher expression whose top level AND terms may
+// include since= and until=, followed by an RFC 3339 time or by a duration
+// before now: "level>=error AND since=30m".
+func Parse(expr string) (*Query, error) {
+	return parse(expr, time.Now())
+}
+
+func parse(expr string, now time.Time) (*Query, error) {
+	q := &Query{levels: allLevels}
+	if strings.TrimSpace(expr) == "" {
+		return q, nil
+	}
+	terms, conjunction := splitAnd(expr)
+	if !conjunction {
+		m, err := log.ParseMatcher(expr)
+		if err != nil {
+			return nil, err
+		}
+		q.Match = m
+		return q, nil
+	}
+
+	var rest []string
+	for _, term := range terms {
+		switch {
+		case strings.HasPrefix(term, "since="):
+			t, err := parseBound(strings.TrimPrefix(term, "since="), now)
+			if err != nil {
+				return nil, err
+			}
+			q.Since = t
+		case strings.HasPrefix(term, "until="):
+			t, err := parseBound(strings.TrimPrefix(term, "until="), now)
+			if err != nil {
+				return nil, err
+			}
+			q.Until = t
+		default:
+			rest = append(rest, term)
+			if err := q.hint(term); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if len(rest) > 0 {
+		m, err := log.ParseMatcher(strings.Join(rest, " AND "))
+		if err != nil {
+			return nil, err
+		}
+		q.Match = m
+	}
+	return q, nil
+}
+
+// hint narrows the blocks to read with a level or channel term.
+func (q *Query) hint(term string) error {
+	i := strings.IndexAny(term, "!=<>~")
+	if i <= 0 {
+		return nil
+	}
+	switch key, rest := term[:i], term[i:]; key {
+	case "level":
+		m, err := log.ParseMatcher(term)
+		if err != nil {
+			return err
+		}
+		var mask uint8
+		for l := logrus.PanicLevel; l <= logrus.TraceLevel; l++ {
+			if m.Match(&logrus.Entry{Level: l, Data: logrus.Fields{}}) {
+				mask |= 1 << l
+			}
+		}
+		// custom levels are told apart by a field the mask doesn't see
+		if strings.HasPrefix(rest, "=") {
+			if l, err := log.LookupLevel(strings.Trim(rest[1:], `"`)); err == nil {
+				mask |= 1 << l.Level
+			}
+		}
+		q.levels &= mask
+	case "channel", "field:channel":
+		if strings.HasPrefix(rest, "=") && !strings.HasPrefix(rest, `="`) {
+			q.channel = rest[1:]
+		}
+	}
+	return nil
+}
+
+// splitAnd splits expr at the AND outside of quotes. It reports false when
+// expr isn't a conjunction of single conditions, with OR, NOT or
+// parentheses.
+func splitAnd(expr string) ([]string, bool) {
+	var words []string
+	quoted, start := false, -1
+	for i := 0; i <= len(expr); i++ {
+		if i == len(expr) || (!quoted && strings.IndexByte(" \t\n", expr[i]) >= 0) {
+			if start >= 0 {
+				words = append(words, expr[start:i])
+				start = -1
+			}
+			continue
+		}
+		if start < 0 {
+			start = i
+		}
+		switch c := expr[i]; {
+		case quoted && c == '\\':
+			i++
+		case c == '"':
+			quoted = !quoted
+		case !quoted && (c == '(' || c == ')'):
+			return nil, false
+		}
+	}
+	if quoted || len(words)%2 == 0 {
+		return nil, false
+	}
+
+	terms := make([]string, 0, len(words)/2+1)
+	for i, w := range words {
+		keyword := strings.EqualFold(w, "AND") || strings.EqualFold(w, "OR") || strings.EqualFold(w, "NOT")
+		if i%2 == 1 {
+			if !strings.EqualFold(w, "AND") {
+				return nil, false
+			}
+			continue
+		}
+		if keyword {
+			return nil, false
+		}
+		terms = append(terms, w)
+	}
+	return terms, true
+}
+
+func parseBound(s string, now time.Time) (time.Time, error) {
+	if d, err := time.ParseDuration(s); err == nil {
+		return now.Add(-d), nil
+	}
+	for _, layout := range timeFormats {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("logquery: %q is neither a time nor a duration", s)
+}
+
+// Matches reports whether entry is in the time range and matches.
+func (q *Query) Matches(entry *logrus.Entry) bool {
+	if !q.Since.IsZero() && entry.Time.Before(q.Since) {
+		return false
+	}
+	if !q.Until.IsZero() && !entry.Time.Before(q.Until) {
+		return false
+	}
+	return q.Match == nil || q.Match.Match(entry)
+}
+
+// ParseEntry turns a JSON log line back into an entry, taking the time,
+// level and message from the keys the formatters of the log package and
+// logrus write them under. Custom levels are kept in the log.LevelKey
+// field.
+func ParseEntry(line []byte) (*logrus.Entry, bool) {
+	line = bytes.TrimSpace(line)
+	if len(line) == 0 || line[0] != '{' {
+		return nil, false
+	}
+	d := json.NewDecoder(bytes.NewReader(line))
+	d.UseNumber()
+	data := logrus.Fields{}
+	if err := d.Decode(&data); err != nil {
+		return nil, false
+	}
+
+	entry := &logrus.Entry{Level: logrus.InfoLevel, Data: data}
+	if v, ok := take(data, timeKeys); ok {
+		entry.Time = parseTime(v)
+	}
+	if v, ok := take(data, levelKeys); ok {
+		if l, err := log.LookupLevel(fmt.Sprint(v)); err == nil {
+			entry.Level = l.Level
+			if l.Name != l.Level.String() {
+				data[log.LevelKey] = l.Name
+			}
+		}
+	}
+	if v, ok := take(data, messageKeys); ok {
+		entry.Message = fmt.Sprint(v)
+	}
+	return entry, true
+}
+
+// take removes and returns the first of keys data holds.
+func take(data logrus.Fields, keys []string) (interface{}, bool) {
+	for _, k := range keys {
+		if v, ok := data[k]; ok {
+			delete(data, k)
+			return v, true
+		}
+	}
+	return nil, false
+}
+
+func parseTime(v interface{}) time.Time {
+	switch v := v.(type) {
+	case string:
+		for _, layout := range timeFormats {
+			if t, err := time.Parse(layout, v); err == nil {
+				return t
+			}
+		}
+	case json.Number:
+		// GELF writes seconds since the epoch
+		if f, err := v.Float64(); err == nil {
+			return time.Unix(0, int64(f*1e9))
+		}
+	}
+	return time.Time{}
+}
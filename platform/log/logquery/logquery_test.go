@@ -0,0 +1,200 @@
+package logquery
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/o3labs/openpoint/platform/log"
+	logrus "github.com/sirupsen/logrus"
+)
+
+var base = time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+// writeLog writes n entries a second apart, warnings every tenth and in the
+// payments channel from the 2000th on.
+func writeLog(t *testing.T, file string, from, n int) {
+	f, err := os.OpenFile(file, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	for i := from; i < from+n; i++ {
+		level, channel := "info", "api"
+		if i%10 == 0 {
+			level = "warning"
+		}
+		if i >= 2000 {
+			channel = "payments"
+		}
+		fmt.Fprintf(f, `{"time":%q,"level":%q,"msg":"request","channel":%q,"n":%d}`+"\n",
+			base.Add(time.Duration(i)*time.Second).Format(time.RFC3339Nano), level, channel, i)
+	}
+}
+
+func TestParse(t *testing.T) {
+	q, err := parse("level>=warn AND channel=payments AND since=1h AND until=2024-03-01T12:30:00Z", base.Add(2*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !q.Since.Equal(base.Add(time.Hour)) || !q.Until.Equal(base.Add(30*time.Minute)) {
+		t.Errorf("unexpected range %v %v", q.Since, q.Until)
+	}
+	if q.channel != "payments" || q.levels != 1<<logrus.PanicLevel|1<<logrus.FatalLevel|1<<logrus.ErrorLevel|1<<logrus.WarnLevel {
+		t.Errorf("unexpected hints %q %b", q.channel, q.levels)
+	}
+
+	entry := &logrus.Entry{Level: logrus.ErrorLevel, Time: base.Add(75 * time.Minute), Data: logrus.Fields{log.ChannelKey: "payments"}}
+	if q.Matches(entry) {
+		t.Errorf("expected entries after until not to match")
+	}
+	q.Until = time.Time{}
+	if !q.Matches(entry) {
+		t.Errorf("expected %+v to match", entry)
+	}
+
+	// expressions with OR keep since= a field
+	q, err = parse(`since=1h OR msg="a AND b"`, base)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !q.Since.IsZero() || q.levels != allLevels || !q.Matches(&logrus.Entry{Data: logrus.Fields{"since": "1h"}}) {
+		t.Errorf("expected a plain matcher, got %+v", q)
+	}
+
+	for _, expr := range []string{"since=yesterday AND level=info", "level=loud AND since=1h"} {
+		if _, err := Parse(expr); err == nil {
+			t.Errorf("expected %q to fail", expr)
+		}
+	}
+}
+
+func TestIndexSkipsBlocks(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logquery")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "openpoint.log")
+	writeLog(t, file, 0, 3000)
+	ix, err := OpenIndex(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ix.blocks) != 3 {
+		t.Fatalf("expected 3 blocks, got %v", len(ix.blocks))
+	}
+
+	read := 0
+	q, _ := Parse("channel=payments AND level=warning")
+	for _, b := range ix.blocks {
+		if !b.skip(q) {
+			read++
+		}
+	}
+	if read != 1 {
+		t.Errorf("expected to read 1 block, read %v", read)
+	}
+	entries, err := ix.Search(q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 100 || entries[0].Data["n"] != json.Number("2000") {
+		t.Errorf("expected the 100 payment warnings, got %v", len(entries))
+	}
+
+	q, _ = parse("until=30m", base.Add(time.Hour))
+	q.Limit = 5
+	entries, err = ix.Search(q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 5 || entries[4].Data["n"] != json.Number("1799") {
+		t.Errorf("expected the last 5 entries before until, got %+v", entries)
+	}
+
+	// appended lines continue the last block
+	writeLog(t, file, 3000, 500)
+	q, _ = Parse("level=warning")
+	entries, err = ix.Search(q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ix.blocks) != 4 || len(entries) != 350 {
+		t.Errorf("expected 4 blocks and 350 warnings, got %v and %v", len(ix.blocks), len(entries))
+	}
+
+	// and a truncated file is indexed again
+	os.Remove(file)
+	writeLog(t, file, 0, 10)
+	entries, err = ix.Search(q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ix.blocks) != 1 || len(entries) != 1 {
+		t.Errorf("expected the index to be rebuilt, got %v blocks and %v warnings", len(ix.blocks), len(entries))
+	}
+}
+
+func TestHandler(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logquery")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	old, current := filepath.Join(dir, "old.log"), filepath.Join(dir, "current.log")
+	writeLog(t, old, 0, 1000)
+	writeLog(t, current, 1000, 1500)
+	h := &Handler{Files: []string{current, old}, Token: "secret"}
+
+	r := httptest.NewRequest("GET", "/debug/logs/search?limit=3&q="+url.QueryEscape("level=warning AND n<1500"), nil)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if w.Code != 401 {
+		t.Errorf("expected 401 without the token, got %v", w.Code)
+	}
+
+	r.Header.Set("Authorization", "Bearer secret")
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	out := []map[string]interface{}{}
+	if err := json.Unmarshal(w.Body.Bytes(), &out); err != nil {
+		t.Fatalf("%v %s", err, w.Body.Bytes())
+	}
+	if len(out) != 3 || out[0]["n"] != float64(1470) || out[2]["n"] != float64(1490) || out[2]["level"] != "warning" {
+		t.Errorf("unexpected entries %v", out)
+	}
+
+	r = httptest.NewRequest("GET", "/debug/logs/search?q=n~(", nil)
+	r.Header.Set("Authorization", "Bearer secret")
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if w.Code != 400 {
+		t.Errorf("expected 400 for a bad query, got %v", w.Code)
+	}
+	r = httptest.NewRequest("GET", "/debug/logs/search", nil)
+	r.Header.Set("Authorization", "Bearer secret")
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	out = nil
+	if err := json.Unmarshal(w.Body.Bytes(), &out); err != nil {
+		t.Fatalf("%v %s", err, w.Body.Bytes())
+	}
+	if len(out) != defaultLimit || out[defaultLimit-1]["n"] != float64(2499) {
+		t.Errorf("expected the last %v entries by default, got %v", defaultLimit, len(out))
+	}
+
+	r = httptest.NewRequest("GET", "/debug/logs/search?limit=20000", nil)
+	r.Header.Set("Authorization", "Bearer secret")
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if w.Code != 400 {
+		t.Errorf("expected 400 for a limit over %v, got %v", maxLimit, w.Code)
+	}
+}
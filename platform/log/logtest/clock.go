@@ -0,0 +1,47 @@
+package logtest
+
+import (
+	"sync"
+	"time"
+
+	"github.com/o3labs/openpoint/platform/log"
+)
+
+// Clock is a log.Clock that only moves when told to, so timestamps and
+// rotations are the same on every run:
+//
+//	clock := logtest.NewClock(time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC))
+//	log.SetClock(clock)
+//	defer log.SetClock(nil)
+//	clock.Advance(24 * time.Hour)
+type Clock struct {
+	mu sync.Mutex
+	t  time.Time
+}
+
+var _ log.Clock = (*Clock)(nil)
+
+// NewClock returns a clock stopped at t.
+func NewClock(t time.Time) *Clock {
+	return &Clock{t: t}
+}
+
+func (c *Clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.t
+}
+
+// Set moves the clock to t.
+func (c *Clock) Set(t time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.t = t
+}
+
+// Advance moves the clock forward by d.
+func (c *Clock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.t = c.t.Add(d)
+}
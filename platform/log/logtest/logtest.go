@@ -0,0 +1,190 @@
+// Package logtest records log entries in memory so tests can assert on what
+// the code under test logged.
+//
+//	hook := logtest.Capture(log.Channel("payments"))
+//	defer hook.Remove()
+//	charge(card)
+//	hook.AssertLogged(t, logrus.WarnLevel, "declined", logrus.Fields{"amount": 1000})
+package logtest
+
+import (
+	"fmt"
+	"io/ioutil"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/o3labs/openpoint/platform/log"
+	logrus "github.com/sirupsen/logrus"
+)
+
+// Hook records a copy of every entry fired on the loggers it is added to.
+type Hook struct {
+	mu      sync.Mutex
+	entries []logrus.Entry
+	remove  func()
+}
+
+// New returns a logger logging every level to nothing but the returned hook.
+func New() (*logrus.Logger, *Hook) {
+	l := logrus.New()
+	l.Out = ioutil.Discard
+	l.Level = logrus.TraceLevel
+	h := &Hook{}
+	l.AddHook(h)
+	return l, h
+}
+
+// Capture records the entries of a channel. They are still written to the
+// channel's output.
+func Capture(l *log.Logger) *Hook {
+	h := &Hook{}
+	l.AddHook(h)
+	h.remove = func() { l.RemoveHook(h) }
+	return h
+}
+
+// CaptureStandard records the entries of the package level log functions.
+func CaptureStandard() *Hook {
+	h := &Hook{}
+	logrus.AddHook(h)
+	h.remove = func() { log.RemoveHook(h) }
+	return h
+}
+
+// Remove stops recording from the logger passed to Capture or
+// CaptureStandard.
+func (h *Hook) Remove() {
+	if h.remove != nil {
+		h.remove()
+	}
+}
+
+func (h *Hook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (h *Hook) Fire(entry *logrus.Entry) error {
+	e := logrus.Entry{
+		Logger:  entry.Logger,
+		Data:    make(logrus.Fields, len(entry.Data)),
+		Time:    entry.Time,
+		Level:   entry.Level,
+		Message: entry.Message,
+	}
+	for k, v := range entry.Data {
+		e.Data[k] = v
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.entries = append(h.entries, e)
+	return nil
+}
+
+// Entries returns the recorded entries, oldest first.
+func (h *Hook) Entries() []logrus.Entry {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	entries := make([]logrus.Entry, len(h.entries))
+	copy(entries, h.entries)
+	return entries
+}
+
+// LastEntry returns the most recent entry or nil when there is none.
+func (h *Hook) LastEntry() *logrus.Entry {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if len(h.entries) == 0 {
+		return nil
+	}
+	e := h.entries[len(h.entries)-1]
+	return &e
+}
+
+// Reset drops the recorded entries.
+func (h *Hook) Reset() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.entries = nil
+}
+
+// Find returns the first entry at level containing msg and carrying fields,
+// see AssertLogged.
+func (h *Hook) Find(level logrus.Level, msg string, fields logrus.Fields) (*logrus.Entry, bool) {
+	for _, e := range h.Entries() {
+		if e.Level == level && containsMessage(e, msg) && hasFields(e, fields) {
+			return &e, true
+		}
+	}
+	return nil, false
+}
+
+// AssertLogged fails the test unless an entry was logged at level whose text
+// contains msg and whose fields include fields. The text is the message, or
+// the error and debug fields the log package's Errorf and DebugMessage write
+// it to. Field values are compared with reflect.DeepEqual, or by their
+// string form when the types differ.
+func (h *Hook) AssertLogged(t testing.TB, level logrus.Level, msg string, fields logrus.Fields) {
+	t.Helper()
+	if _, ok := h.Find(level, msg, fields); !ok {
+		t.Errorf("expected a %v entry containing %q with %v, logged:\n%v", level, msg, fields, h.dump())
+	}
+}
+
+// AssertNotLogged fails the test if an entry at level containing msg was
+// logged.
+func (h *Hook) AssertNotLogged(t testing.TB, level logrus.Level, msg string) {
+	t.Helper()
+	if e, ok := h.Find(level, msg, nil); ok {
+		t.Errorf("expected no %v entry containing %q, got %q %v", level, msg, e.Message, e.Data)
+	}
+}
+
+func containsMessage(e logrus.Entry, msg string) bool {
+	if strings.Contains(e.Message, msg) {
+		return true
+	}
+	for _, k := range []string{logrus.ErrorKey, "debug"} {
+		if v, ok := e.Data[k]; ok && strings.Contains(fmt.Sprint(v), msg) {
+			return true
+		}
+	}
+	return false
+}
+
+func hasFields(e logrus.Entry, fields logrus.Fields) bool {
+	for k, expected := range fields {
+		v, ok := e.Data[k]
+		if !ok {
+			return false
+		}
+		if !reflect.DeepEqual(v, expected) && fmt.Sprint(v) != fmt.Sprint(expected) {
+			return false
+		}
+	}
+	return true
+}
+
+func (h *Hook) dump() string {
+	entries := h.Entries()
+	if len(entries) == 0 {
+		return "  nothing"
+	}
+	b := &strings.Builder{}
+	for _, e := range entries {
+		keys := make([]string, 0, len(e.Data))
+		for k := range e.Data {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		fmt.Fprintf(b, "  %v %q", e.Level, e.Message)
+		for _, k := range keys {
+			fmt.Fprintf(b, " %v=%v", k, e.Data[k])
+		}
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
@@ -0,0 +1,29 @@
+package logtest
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/o3labs/openpoint/platform/log"
+	logrus "github.com/sirupsen/logrus"
+)
+
+func TestCapture(t *testing.T) {
+	payments := log.Channel("logtest")
+	hook := Capture(payments)
+
+	payments.WithFields(logrus.Fields{"amount": 1000}).Warn("charge declined")
+	payments.Error(errors.New("card expired"))
+	hook.AssertLogged(t, logrus.WarnLevel, "declined", logrus.Fields{"amount": 1000, "channel": "logtest"})
+	hook.AssertLogged(t, logrus.ErrorLevel, "expired", nil)
+	hook.AssertNotLogged(t, logrus.InfoLevel, "charge")
+	if e := hook.LastEntry(); e == nil || e.Level != logrus.ErrorLevel {
+		t.Errorf("expected the error entry last, got %+v", e)
+	}
+
+	hook.Remove()
+	payments.Warn("after remove")
+	if len(hook.Entries()) != 2 {
+		t.Errorf("expected no entries after remove, got %v", len(hook.Entries()))
+	}
+}
@@ -0,0 +1,263 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/o3labs/openpoint/platform/config"
+	logrus "github.com/sirupsen/logrus"
+)
+
+const (
+	defaultLokiBatchSize  = 100
+	defaultLokiBatchWait  = time.Second
+	defaultLokiMaxRetries = 5
+	lokiMinBackoff        = 500 * time.Millisecond
+)
+
+// LokiHook batches entries and pushes them to the Grafana Loki push API.
+// Entries are grouped into streams by Labels plus the fields in LabelKeys.
+type LokiHook struct {
+	// URL of the push API, e.g. "http://loki:3100/loki/api/v1/push"
+	URL string
+
+	// Labels of every stream, e.g. app. env defaults to the config name.
+	Labels map[string]string
+
+	// LabelKeys are entry fields promoted to stream labels. Defaults to the
+	// channel name. Keep them low-cardinality.
+	LabelKeys []string
+
+	// Formatter renders the log line. Defaults to ChannelJSONFormatter.
+	Formatter logrus.Formatter
+
+	BatchSize  int
+	BatchWait  time.Duration
+	MaxRetries int
+
+	Client *http.Client
+
+	entries chan lokiEntry
+	done    chan struct{}
+	once    sync.Once
+
+	mu     sync.RWMutex
+	closed bool
+
+	registration sinkRegistration
+}
+
+type lokiEntry struct {
+	labels map[string]string
+	time   time.Time
+	line   string
+}
+
+type lokiStream struct {
+	Stream map[string]string `json:"stream"`
+	Values [][2]string       `json:"values"`
+}
+
+// NewLokiHook returns a hook pushing to url. BatchSize, BatchWait, MaxRetries
+// and Client may be changed until the first entry is fired. Call Close on
+// shutdown to push what is still batched.
+func NewLokiHook(url string, labels map[string]string) *LokiHook {
+	h := &LokiHook{
+		URL:        url,
+		Labels:     labels,
+		LabelKeys:  []string{ChannelKey},
+		Formatter:  &ChannelJSONFormatter{},
+		BatchSize:  defaultLokiBatchSize,
+		BatchWait:  defaultLokiBatchWait,
+		MaxRetries: defaultLokiMaxRetries,
+		Client:     &http.Client{Timeout: 10 * time.Second},
+		entries:    make(chan lokiEntry, defaultLokiBatchSize*10),
+		done:       make(chan struct{}),
+	}
+	if h.Labels == nil {
+		h.Labels = map[string]string{}
+	}
+	if _, ok := h.Labels["env"]; !ok && config.Env.Name != "" {
+		h.Labels["env"] = config.Env.Name
+	}
+	h.registration.register(h, SinkQueue)
+	return h
+}
+
+func (h *LokiHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (h *LokiHook) Fire(entry *logrus.Entry) error {
+	line, err := h.Formatter.Format(entry)
+	if err != nil {
+		return err
+	}
+	labels := make(map[string]string, len(h.Labels)+len(h.LabelKeys))
+	for k, v := range h.Labels {
+		labels[k] = v
+	}
+	for _, k := range h.LabelKeys {
+		if v, ok := entry.Data[k]; ok {
+			labels[k] = fmt.Sprint(v)
+		}
+	}
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	if h.closed {
+		// still attached to the logger after Close
+		recordDropped(1)
+		return nil
+	}
+	h.start()
+	select {
+	case h.entries <- lokiEntry{labels: labels, time: entry.Time, line: strings.TrimRight(string(line), "\n")}:
+	default:
+		recordDropped(1)
+	}
+	return nil
+}
+
+// Close pushes the pending batch and stops the hook. Entries fired after
+// Close are dropped.
+func (h *LokiHook) Close() error {
+	h.registration.release()
+	h.mu.Lock()
+	if !h.closed {
+		h.closed = true
+		close(h.entries)
+	}
+	h.mu.Unlock()
+	h.start()
+	<-h.done
+	return nil
+}
+
+// start runs the batching goroutine once, after the settings are final.
+func (h *LokiHook) start() {
+	h.once.Do(func() {
+		go h.run()
+	})
+}
+
+func (h *LokiHook) run() {
+	defer close(h.done)
+
+	ticker := time.NewTicker(h.BatchWait)
+	defer ticker.Stop()
+
+	batch := map[string]*lokiStream{}
+	size := 0
+	push := func() {
+		if size == 0 {
+			return
+		}
+		if err := h.push(batch); err != nil {
+			recordDropped(uint64(size))
+			reportf("Failed to push %d entries to loki because %+v", size, err)
+		}
+		batch = map[string]*lokiStream{}
+		size = 0
+	}
+
+	for {
+		select {
+		case e, ok := <-h.entries:
+			if !ok {
+				push()
+				return
+			}
+			key := lokiLabelsKey(e.labels)
+			s, ok := batch[key]
+			if !ok {
+				s = &lokiStream{Stream: e.labels}
+				batch[key] = s
+			}
+			s.Values = append(s.Values, [2]string{strconv.FormatInt(e.time.UnixNano(), 10), e.line})
+			size++
+			if size >= h.BatchSize {
+				push()
+			}
+		case <-ticker.C:
+			push()
+		}
+	}
+}
+
+func lokiLabelsKey(labels map[string]string) string {
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	b := &bytes.Buffer{}
+	for _, k := range keys {
+		fmt.Fprintf(b, "%s=%q,", k, labels[k])
+	}
+	return b.String()
+}
+
+// push sends batch, retrying with exponential backoff on network errors,
+// 429 and 5xx responses.
+func (h *LokiHook) push(batch map[string]*lokiStream) error {
+	streams := make([]*lokiStream, 0, len(batch))
+	for _, s := range batch {
+		streams = append(streams, s)
+	}
+	body, err := json.Marshal(map[string]interface{}{"streams": streams})
+	if err != nil {
+		return err
+	}
+
+	backoff := lokiMinBackoff
+	for attempt := 0; ; attempt++ {
+		err = h.send(body)
+		if err == nil {
+			return nil
+		}
+		if _, retry := err.(lokiRetryableError); !retry || attempt >= h.MaxRetries {
+			return err
+		}
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+type lokiRetryableError struct {
+	error
+}
+
+func (h *LokiHook) send(body []byte) error {
+	req, err := http.NewRequest(http.MethodPost, h.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := h.Client.Do(req)
+	if err != nil {
+		return lokiRetryableError{err}
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode/100 == 2 {
+		return nil
+	}
+	err = fmt.Errorf("loki responded %v", resp.Status)
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500 {
+		return lokiRetryableError{err}
+	}
+	return err
+}
+
+// QueueLen returns the number of entries waiting to be written.
+func (h *LokiHook) QueueLen() int {
+	return len(h.entries)
+}
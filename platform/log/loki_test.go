@@ -0,0 +1,151 @@
+package log
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	logrus "github.com/sirupsen/logrus"
+)
+
+func lokiEntryFor(channel, msg string) *logrus.Entry {
+	entry := logrus.NewEntry(logrus.StandardLogger()).WithField(ChannelKey, channel)
+	entry.Level = logrus.InfoLevel
+	entry.Time = time.Unix(1500000000, 0)
+	entry.Message = msg
+	return entry
+}
+
+func TestLokiHook(t *testing.T) {
+	pushed := make(chan []*lokiStream, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Streams []*lokiStream `json:"streams"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Error(err)
+		}
+		pushed <- body.Streams
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	h := NewLokiHook(server.URL, map[string]string{"app": "openpoint"})
+	h.Fire(lokiEntryFor("payments", "paid"))
+	h.Fire(lokiEntryFor("payments", "refunded"))
+	h.Fire(lokiEntryFor("http", "served"))
+	if err := h.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case streams := <-pushed:
+		if len(streams) != 2 {
+			t.Fatalf("expected 2 streams, got %d", len(streams))
+		}
+		for _, s := range streams {
+			if s.Stream["app"] != "openpoint" {
+				t.Errorf("expected app label, got %v", s.Stream)
+			}
+			expected := 1
+			if s.Stream[ChannelKey] == "payments" {
+				expected = 2
+			}
+			if len(s.Values) != expected {
+				t.Errorf("expected %d values on %v, got %d", expected, s.Stream, len(s.Values))
+			}
+			if s.Values[0][0] != "1500000000000000000" {
+				t.Errorf("expected nanosecond timestamp, got %s", s.Values[0][0])
+			}
+		}
+	case <-time.After(time.Second):
+		t.Fatal("nothing pushed")
+	}
+}
+
+func TestLokiHookFireAfterClose(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	h := NewLokiHook(server.URL, nil)
+	if err := h.Close(); err != nil {
+		t.Fatal(err)
+	}
+	dropped := Dropped()
+	if err := h.Fire(lokiEntryFor("payments", "paid")); err != nil {
+		t.Fatal(err)
+	}
+	if Dropped() != dropped+1 {
+		t.Errorf("expected the entry to be dropped")
+	}
+	if err := h.Close(); err != nil {
+		t.Errorf("expected a second Close to succeed, got %v", err)
+	}
+}
+
+func TestLokiHookRetries(t *testing.T) {
+	statuses := make(chan int, 3)
+	statuses <- http.StatusServiceUnavailable
+	statuses <- http.StatusNoContent
+	statuses <- http.StatusBadRequest
+	requests := make(chan time.Time, 3)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests <- time.Now()
+		w.WriteHeader(<-statuses)
+	}))
+	defer server.Close()
+
+	h := NewLokiHook(server.URL, nil)
+	// set after the constructor, before the first entry
+	h.BatchSize = 1
+	h.BatchWait = time.Hour
+	h.MaxRetries = 1
+	defer h.Close()
+
+	dropped := Dropped()
+	h.Fire(lokiEntryFor("payments", "paid"))
+	first, retried := <-requests, <-requests
+	if wait := retried.Sub(first); wait < lokiMinBackoff {
+		t.Errorf("expected the retry after %v, got %v", lokiMinBackoff, wait)
+	}
+
+	// a client error isn't retried
+	h.Fire(lokiEntryFor("payments", "refunded"))
+	<-requests
+	deadline := time.Now().Add(time.Second)
+	for Dropped() == dropped && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if Dropped() == dropped {
+		t.Error("expected the rejected entry dropped")
+	}
+	select {
+	case <-requests:
+		t.Error("expected a 400 not retried")
+	case <-time.After(2 * lokiMinBackoff):
+	}
+}
+
+func TestLokiHookBatchWait(t *testing.T) {
+	pushed := make(chan struct{}, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		pushed <- struct{}{}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	h := NewLokiHook(server.URL, nil)
+	h.BatchWait = 10 * time.Millisecond
+	defer h.Close()
+
+	h.Fire(lokiEntryFor("payments", "paid"))
+	select {
+	case <-pushed:
+	case <-time.After(defaultLokiBatchWait / 2):
+		t.Fatal("expected the batch pushed after BatchWait")
+	}
+}
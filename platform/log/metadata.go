@@ -0,0 +1,60 @@
+package log
+
+import (
+	"os"
+
+	"github.com/o3labs/openpoint/platform/config"
+	logrus "github.com/sirupsen/logrus"
+)
+
+const (
+	HostnameKey = "hostname"
+	PIDKey      = "pid"
+	ServiceKey  = "service"
+	VersionKey  = "version"
+	EnvKey      = "env"
+)
+
+// MetadataHook stamps the host, process and service an entry came from into
+// every entry, so lines shipped from several instances can be told apart.
+// The values are looked up once by NewMetadataHook; fields already set on
+// an entry are left alone.
+//
+//	logrus.AddHook(log.NewMetadataHook("openpoint", version))
+type MetadataHook struct {
+	// Fields added to every entry. Delete or rename keys to change what is
+	// written, e.g. remove pid for shorter text lines.
+	Fields logrus.Fields
+}
+
+// NewMetadataHook returns a hook adding the hostname, pid and config name as
+// env, plus service and version when they are not empty.
+func NewMetadataHook(service, version string) *MetadataHook {
+	fields := logrus.Fields{PIDKey: os.Getpid()}
+	if hostname, err := os.Hostname(); err == nil {
+		fields[HostnameKey] = hostname
+	}
+	if service != "" {
+		fields[ServiceKey] = service
+	}
+	if version != "" {
+		fields[VersionKey] = version
+	}
+	if config.Env.Name != "" {
+		fields[EnvKey] = config.Env.Name
+	}
+	return &MetadataHook{Fields: fields}
+}
+
+func (h *MetadataHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (h *MetadataHook) Fire(entry *logrus.Entry) error {
+	for k, v := range h.Fields {
+		if _, ok := entry.Data[k]; !ok {
+			entry.Data[k] = v
+		}
+	}
+	return nil
+}
@@ -0,0 +1,33 @@
+package log
+
+import (
+	"os"
+	"testing"
+
+	"github.com/o3labs/openpoint/platform/config"
+	logrus "github.com/sirupsen/logrus"
+)
+
+func TestMetadataHook(t *testing.T) {
+	defer func(name string) { config.Env.Name = name }(config.Env.Name)
+	config.Env.Name = config.StagingEnv
+
+	h := NewMetadataHook("openpoint", "")
+	if h.Fields[PIDKey] != os.Getpid() || h.Fields[ServiceKey] != "openpoint" || h.Fields[EnvKey] != "staging" {
+		t.Errorf("expected the pid, service and env, got %v", h.Fields)
+	}
+	if _, ok := h.Fields[VersionKey]; ok {
+		t.Errorf("expected no empty version, got %v", h.Fields)
+	}
+	if hostname, _ := os.Hostname(); h.Fields[HostnameKey] != hostname {
+		t.Errorf("expected hostname %q, got %v", hostname, h.Fields[HostnameKey])
+	}
+
+	entry := logrus.NewEntry(logrus.StandardLogger()).WithField(ServiceKey, "worker")
+	if err := h.Fire(entry); err != nil {
+		t.Fatal(err)
+	}
+	if entry.Data[ServiceKey] != "worker" || entry.Data[PIDKey] != os.Getpid() {
+		t.Errorf("expected the metadata without overriding fields set on the entry, got %v", entry.Data)
+	}
+}
@@ -0,0 +1,77 @@
+package log
+
+import (
+	"sync/atomic"
+
+	"github.com/prometheus/client_golang/prometheus"
+	logrus "github.com/sirupsen/logrus"
+)
+
+var droppedEntries uint64
+
+// recordDropped counts entries a writer or hook threw away. The count is
+// reported on the internal channel once a minute.
+func recordDropped(n uint64) {
+	atomic.AddUint64(&droppedEntries, n)
+	reportDropped()
+}
+
+// RecordDropped counts entries a sink outside this package threw away, so
+// they show in Dropped and the metrics.
+func RecordDropped(n uint64) {
+	recordDropped(n)
+}
+
+// Dropped returns the number of entries dropped since startup.
+func Dropped() uint64 {
+	return atomic.LoadUint64(&droppedEntries)
+}
+
+// MetricsHook counts entries by level and channel for Prometheus. It is also
+// a prometheus.Collector:
+//
+//	hook := log.NewMetricsHook("openpoint")
+//	logrus.AddHook(hook)
+//	log.Channel("payments").AddHook(hook)
+//	prometheus.MustRegister(hook)
+type MetricsHook struct {
+	entries *prometheus.CounterVec
+	dropped prometheus.GaugeFunc
+}
+
+func NewMetricsHook(namespace string) *MetricsHook {
+	return &MetricsHook{
+		entries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "log_entries_total",
+			Help:      "Number of log entries by level and channel.",
+		}, []string{"level", "channel"}),
+		dropped: prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "log_entries_dropped",
+			Help:      "Number of log entries dropped since startup.",
+		}, func() float64 {
+			return float64(Dropped())
+		}),
+	}
+}
+
+func (h *MetricsHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (h *MetricsHook) Fire(entry *logrus.Entry) error {
+	channel, _ := entry.Data[ChannelKey].(string)
+	h.entries.WithLabelValues(entry.Level.String(), channel).Inc()
+	return nil
+}
+
+func (h *MetricsHook) Describe(ch chan<- *prometheus.Desc) {
+	h.entries.Describe(ch)
+	h.dropped.Describe(ch)
+}
+
+func (h *MetricsHook) Collect(ch chan<- prometheus.Metric) {
+	h.entries.Collect(ch)
+	h.dropped.Collect(ch)
+}
@@ -0,0 +1,50 @@
+package log
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	logrus "github.com/sirupsen/logrus"
+)
+
+func TestMetricsHook(t *testing.T) {
+	h := NewMetricsHook("openpoint")
+	for _, e := range []struct {
+		level   logrus.Level
+		channel interface{}
+	}{
+		{logrus.InfoLevel, "payments"},
+		{logrus.InfoLevel, "payments"},
+		{logrus.ErrorLevel, "payments"},
+		{logrus.InfoLevel, nil},
+	} {
+		entry := logrus.NewEntry(logrus.StandardLogger())
+		entry.Level = e.level
+		if e.channel != nil {
+			entry.Data[ChannelKey] = e.channel
+		}
+		h.Fire(entry)
+	}
+
+	for _, tt := range []struct {
+		level, channel string
+		count          float64
+	}{
+		{"info", "payments", 2},
+		{"error", "payments", 1},
+		{"info", "", 1},
+	} {
+		if n := testutil.ToFloat64(h.entries.WithLabelValues(tt.level, tt.channel)); n != tt.count {
+			t.Errorf("expected %v %v entries on %q, got %v", tt.count, tt.level, tt.channel, n)
+		}
+	}
+
+	dropped := Dropped()
+	RecordDropped(3)
+	if n := testutil.ToFloat64(h.dropped); n < float64(dropped+3) {
+		t.Errorf("expected the dropped gauge to follow Dropped, got %v", n)
+	}
+	if n := testutil.CollectAndCount(h); n != 4 {
+		t.Errorf("expected 3 counters and the gauge collected, got %v", n)
+	}
+}
@@ -0,0 +1,162 @@
+package log
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	logrus "github.com/sirupsen/logrus"
+)
+
+// Middleware inspects or changes an entry before it is formatted and
+// returns the entry to pass on, false to drop it. The entry it gets is
+// shared with the other outputs and the hooks of the logger, so it must not
+// change it but return a changed copy instead, see CopyEntry. Middleware
+// that only inspect entries cost no copy.
+type Middleware func(entry *logrus.Entry) (*logrus.Entry, bool)
+
+// Chain returns a middleware running middleware in order, stopping at the
+// first one dropping the entry.
+func Chain(middleware ...Middleware) Middleware {
+	return func(entry *logrus.Entry) (*logrus.Entry, bool) {
+		for _, m := range middleware {
+			var ok bool
+			if entry, ok = m(entry); !ok {
+				return nil, false
+			}
+		}
+		return entry, true
+	}
+}
+
+// MiddlewareFormatter passes every entry through Middleware before
+// Formatter, so enrichment, redaction, sampling and filtering compose per
+// output instead of being hooks every output sees:
+//
+//	f := &log.MiddlewareFormatter{
+//		Formatter: &log.ChannelJSONFormatter{},
+//		Middleware: []log.Middleware{
+//			log.HookMiddleware(log.NewRedactHook()),
+//			log.FilterMiddleware(matcher),
+//			log.SampleMiddleware(10, time.Second),
+//		},
+//	}
+//
+// Dropped entries are formatted to nothing.
+type MiddlewareFormatter struct {
+	Formatter  logrus.Formatter
+	Middleware []Middleware
+}
+
+// Format renders a single log entry, or nothing when it is dropped
+func (f *MiddlewareFormatter) Format(entry *logrus.Entry) ([]byte, error) {
+	if len(f.Middleware) == 0 {
+		return f.Formatter.Format(entry)
+	}
+	entry, ok := Chain(f.Middleware...)(entry)
+	if !ok {
+		return nil, nil
+	}
+	return f.Formatter.Format(entry)
+}
+
+// CopyEntry returns a copy of entry with its own Data and no Buffer, which
+// middleware can change without other outputs seeing the changes and which
+// can be kept after the entry is written. The values of the fields are
+// shared, so maps and slices among them must not be changed in place.
+func CopyEntry(entry *logrus.Entry) *logrus.Entry {
+	c := *entry
+	c.Data = make(logrus.Fields, len(entry.Data))
+	for k, v := range entry.Data {
+		c.Data[k] = v
+	}
+	c.Buffer = nil
+	return &c
+}
+
+// HookMiddleware runs a hook that changes entries, such as RedactHook,
+// TruncateHook, MetadataHook or StackHook, as a middleware, on a copy of the
+// entry. Entries at levels the hook doesn't fire for pass unchanged, a
+// failing hook drops the entry.
+func HookMiddleware(hook logrus.Hook) Middleware {
+	levels := map[logrus.Level]bool{}
+	for _, l := range hook.Levels() {
+		levels[l] = true
+	}
+	return func(entry *logrus.Entry) (*logrus.Entry, bool) {
+		if !levels[entry.Level] {
+			return entry, true
+		}
+		entry = CopyEntry(entry)
+		if err := hook.Fire(entry); err != nil {
+			reportf("Failed to run log hook %T because %+v", hook, err)
+			recordDropped(1)
+			return nil, false
+		}
+		return entry, true
+	}
+}
+
+// FieldsMiddleware adds fields to every entry that doesn't have them yet.
+func FieldsMiddleware(fields logrus.Fields) Middleware {
+	return func(entry *logrus.Entry) (*logrus.Entry, bool) {
+		copied := false
+		for k, v := range fields {
+			if _, ok := entry.Data[k]; ok {
+				continue
+			}
+			if !copied {
+				entry, copied = CopyEntry(entry), true
+			}
+			entry.Data[k] = v
+		}
+		return entry, true
+	}
+}
+
+// FilterMiddleware keeps the entries m matches, see ParseMatcher.
+func FilterMiddleware(m Matcher) Middleware {
+	return func(entry *logrus.Entry) (*logrus.Entry, bool) {
+		return entry, m.Match(entry)
+	}
+}
+
+// SampleMiddleware keeps the first entries of every interval for each
+// message and level and drops the rest. Unlike SamplingFormatter it writes
+// no summary of what it dropped.
+func SampleMiddleware(first int, interval time.Duration) Middleware {
+	var (
+		mu     sync.Mutex
+		counts = map[sampleKey]*sampleCount{}
+		sweep  time.Time
+	)
+	return func(entry *logrus.Entry) (*logrus.Entry, bool) {
+		key := sampleKey{level: entry.Level, message: entry.Message}
+		if key.message == "" {
+			key.message = fmt.Sprint(entry.Data["error"])
+		}
+		now := entry.Time
+
+		mu.Lock()
+		defer mu.Unlock()
+		if now.Sub(sweep) >= interval {
+			for k, c := range counts {
+				if now.Sub(c.start) >= interval {
+					delete(counts, k)
+				}
+			}
+			sweep = now
+		}
+		c, ok := counts[key]
+		if !ok || now.Sub(c.start) >= interval {
+			c = &sampleCount{start: now}
+			counts[key] = c
+		}
+		c.n++
+		if c.n > first {
+			recordDropped(1)
+			return nil, false
+		}
+		return entry, true
+	}
+}
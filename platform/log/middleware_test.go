@@ -0,0 +1,56 @@
+package log
+
+import (
+	"testing"
+	"time"
+
+	logrus "github.com/sirupsen/logrus"
+)
+
+func TestMiddlewareFormatter(t *testing.T) {
+	matcher, err := ParseMatcher("level>=info")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := &MiddlewareFormatter{
+		Formatter: &LogfmtFormatter{DisableTimestamp: true},
+		Middleware: []Middleware{
+			FieldsMiddleware(logrus.Fields{"service": "api"}),
+			HookMiddleware(NewRedactHook()),
+			FilterMiddleware(matcher),
+			SampleMiddleware(2, time.Minute),
+		},
+	}
+
+	now := time.Now()
+	entry := &logrus.Entry{Level: logrus.InfoLevel, Time: now, Message: "login", Data: logrus.Fields{"password": "hunter2"}}
+	b, err := f.Format(entry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "level=info msg=login password=[REDACTED] service=api\n"; string(b) != want {
+		t.Errorf("expected %q, got %q", want, b)
+	}
+	if entry.Data["password"] != "hunter2" || entry.Data["service"] != nil {
+		t.Errorf("expected the entry other outputs see unchanged, got %v", entry.Data)
+	}
+
+	if b, _ := f.Format(&logrus.Entry{Level: logrus.DebugLevel, Time: now, Message: "login", Data: logrus.Fields{}}); len(b) != 0 {
+		t.Errorf("expected the filter to drop debug entries, got %q", b)
+	}
+
+	formatted := 0
+	for i := 0; i < 5; i++ {
+		b, _ := f.Format(&logrus.Entry{Level: logrus.InfoLevel, Time: now, Message: "retry", Data: logrus.Fields{}})
+		if len(b) > 0 {
+			formatted++
+		}
+	}
+	if formatted != 2 {
+		t.Errorf("expected 2 entries sampled in, got %d", formatted)
+	}
+	b, _ = f.Format(&logrus.Entry{Level: logrus.InfoLevel, Time: now.Add(time.Minute), Message: "retry", Data: logrus.Fields{}})
+	if len(b) == 0 {
+		t.Error("expected the next interval to let entries through again")
+	}
+}
@@ -0,0 +1,124 @@
+package log
+
+import (
+	"io"
+	"sync"
+
+	logrus "github.com/sirupsen/logrus"
+)
+
+// Destination is one output of a MultiHook.
+type Destination struct {
+	Writer    io.Writer
+	Formatter logrus.Formatter
+
+	// Level is the most verbose level written to this destination
+	Level logrus.Level
+
+	// Match, when set, selects the entries written to this destination, see
+	// ParseMatcher
+	Match Matcher
+
+	// Hook, when set, is fired with the entries of this destination in
+	// place of Writer and Formatter, e.g. a WebhookHook
+	Hook logrus.Hook
+
+	mu sync.Mutex
+}
+
+// MultiHook writes every entry to several destinations, each with its own
+// formatter and level, e.g. colored text on stderr at Info and JSON to a file
+// at Debug. The logger it is added to should write to ioutil.Discard and log
+// at MaxLevel:
+//
+//	hook := log.NewMultiHook(
+//		&log.Destination{Writer: os.Stderr, Formatter: &log.ChannelTextFormatter{}, Level: logrus.InfoLevel},
+//		&log.Destination{Writer: file, Formatter: &log.ChannelJSONFormatter{}, Level: logrus.DebugLevel},
+//	)
+//	l := log.Channel("payments")
+//	l.AddHook(hook)
+//	l.SetOutput(ioutil.Discard)
+//	l.SetLevel(hook.MaxLevel())
+type MultiHook struct {
+	Destinations []*Destination
+
+	// Middleware, when set, runs once on a copy of every entry before it
+	// reaches the destinations and may change or drop it, see
+	// SuppressionPolicy
+	Middleware Middleware
+}
+
+func NewMultiHook(destinations ...*Destination) *MultiHook {
+	return &MultiHook{Destinations: destinations}
+}
+
+// MaxLevel returns the most verbose level of any destination.
+func (h *MultiHook) MaxLevel() logrus.Level {
+	max := logrus.PanicLevel
+	for _, d := range h.Destinations {
+		if d.Level > max {
+			max = d.Level
+		}
+	}
+	return max
+}
+
+func (h *MultiHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+// Fire writes entry to every destination logging at its level and returns
+// the first error, after trying all of them.
+func (h *MultiHook) Fire(entry *logrus.Entry) error {
+	if h.Middleware != nil {
+		var ok bool
+		if entry, ok = h.Middleware(entry); !ok {
+			return nil
+		}
+	}
+	var firstErr error
+	for _, d := range h.Destinations {
+		if entry.Level > d.Level || (d.Match != nil && !d.Match.Match(entry)) {
+			continue
+		}
+		if err := d.write(entry); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+func (d *Destination) write(entry *logrus.Entry) error {
+	if d.Hook != nil {
+		return d.fire(entry)
+	}
+	b, err := d.Formatter.Format(entry)
+	if err != nil {
+		return err
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	var n int
+	if lw, ok := d.Writer.(LevelWriter); ok {
+		n, err = lw.WriteLevel(entry.Level, b)
+	} else {
+		n, err = d.Writer.Write(b)
+	}
+	if err != nil {
+		recordSinkError()
+		return err
+	}
+	if len(b) > 0 {
+		recordWritten(n)
+	}
+	return nil
+}
+
+func (d *Destination) fire(entry *logrus.Entry) error {
+	for _, l := range d.Hook.Levels() {
+		if l == entry.Level {
+			return d.Hook.Fire(entry)
+		}
+	}
+	return nil
+}
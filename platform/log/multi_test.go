@@ -0,0 +1,81 @@
+package log
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	logrus "github.com/sirupsen/logrus"
+)
+
+func TestMultiHookLevels(t *testing.T) {
+	info, debug := &bytes.Buffer{}, &bytes.Buffer{}
+	hook := NewMultiHook(
+		&Destination{Writer: info, Formatter: &LogfmtFormatter{DisableTimestamp: true}, Level: logrus.InfoLevel},
+		&Destination{Writer: debug, Formatter: &LogfmtFormatter{DisableTimestamp: true}, Level: logrus.DebugLevel},
+	)
+	if hook.MaxLevel() != logrus.DebugLevel {
+		t.Errorf("expected the most verbose level, got %v", hook.MaxLevel())
+	}
+	logger := logrus.New()
+	logger.Out = &bytes.Buffer{}
+	logger.SetLevel(hook.MaxLevel())
+	logger.AddHook(hook)
+
+	logger.Debug("a")
+	logger.Warn("b")
+	logger.Trace("c")
+	if info.String() != "level=warning msg=b\n" {
+		t.Errorf("unexpected %q", info.String())
+	}
+	if debug.String() != "level=debug msg=a\nlevel=warning msg=b\n" {
+		t.Errorf("unexpected %q", debug.String())
+	}
+}
+
+func TestMultiHookMatch(t *testing.T) {
+	all, acme := &bytes.Buffer{}, &bytes.Buffer{}
+	m, _ := ParseMatcher("tenant=acme")
+	hook := NewMultiHook(
+		&Destination{Writer: all, Formatter: &LogfmtFormatter{DisableTimestamp: true}, Level: logrus.InfoLevel},
+		&Destination{Writer: acme, Formatter: &LogfmtFormatter{DisableTimestamp: true}, Level: logrus.InfoLevel, Match: m},
+	)
+	logger := logrus.New()
+	logger.Out = &bytes.Buffer{}
+	logger.AddHook(hook)
+
+	logger.WithField("tenant", "acme").Info("a")
+	logger.WithField("tenant", "globex").Info("b")
+	if all.String() != "level=info msg=a tenant=acme\nlevel=info msg=b tenant=globex\n" {
+		t.Errorf("unexpected %q", all.String())
+	}
+	if acme.String() != "level=info msg=a tenant=acme\n" {
+		t.Errorf("unexpected %q", acme.String())
+	}
+}
+
+func TestMultiHookFirstError(t *testing.T) {
+	b := &bytes.Buffer{}
+	hook := NewMultiHook(
+		&Destination{Writer: failingWriter{}, Formatter: &LogfmtFormatter{DisableTimestamp: true}, Level: logrus.InfoLevel},
+		&Destination{Writer: errorWriter{errors.New("quota exceeded")}, Formatter: &LogfmtFormatter{DisableTimestamp: true}, Level: logrus.InfoLevel},
+		&Destination{Writer: b, Formatter: &LogfmtFormatter{DisableTimestamp: true}, Level: logrus.InfoLevel},
+	)
+	entry := &logrus.Entry{Logger: logrus.New(), Level: logrus.InfoLevel, Message: "a", Data: logrus.Fields{}}
+	err := hook.Fire(entry)
+	if err == nil || err.Error() != "disk full" {
+		t.Errorf("expected the first error, got %v", err)
+	}
+	if b.String() != "level=info msg=a\n" {
+		t.Errorf("expected the destinations after the failing ones written, got %q", b.String())
+	}
+}
+
+// errorWriter fails every write with err
+type errorWriter struct {
+	err error
+}
+
+func (w errorWriter) Write(p []byte) (int, error) {
+	return 0, w.err
+}
@@ -0,0 +1,213 @@
+package log
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	logrus "github.com/sirupsen/logrus"
+)
+
+const (
+	defaultMaxDepth = 5
+
+	// cycleValue replaces a value that contains itself
+	cycleValue = "(cycle)"
+)
+
+// flattenFields returns data with map and struct values replaced by a field
+// per entry, named with dotted keys: "http": {"method": "GET"} becomes
+// "http.method": "GET". Values nested deeper than maxDepth are kept as they
+// are. data is returned as it is when nothing needs flattening.
+func flattenFields(data logrus.Fields, maxDepth int) logrus.Fields {
+	found := false
+	for _, v := range data {
+		if _, ok := nestedValue(v); ok {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return data
+	}
+	if maxDepth <= 0 {
+		maxDepth = defaultMaxDepth
+	}
+
+	flat := make(logrus.Fields, len(data)+8)
+	for k, v := range data {
+		flattenValue(flat, k, v, maxDepth, map[uintptr]bool{})
+	}
+	return flat
+}
+
+func flattenValue(flat logrus.Fields, key string, v interface{}, depth int, seen map[uintptr]bool) {
+	rv, ok := nestedValue(v)
+	if !ok || depth == 0 {
+		flat[key] = v
+		return
+	}
+	if p, ok := pointerOf(rv); ok {
+		if seen[p] {
+			flat[key] = cycleValue
+			return
+		}
+		seen[p] = true
+		defer delete(seen, p)
+	}
+	nestedEach(reflect.Indirect(rv), func(k string, child interface{}) {
+		flattenValue(flat, key+"."+k, child, depth-1, seen)
+	})
+}
+
+// nestFields returns data as JSON objects: dotted keys are grouped, so
+// "http.method" and "http.status" become "http": {"method", "status"}, and
+// map and struct values are converted to maps, down to maxDepth and without
+// following cycles. A dotted key stays as it is when its group is taken by
+// a value that isn't an object.
+func nestFields(data logrus.Fields, maxDepth int) logrus.Fields {
+	if maxDepth <= 0 {
+		maxDepth = defaultMaxDepth
+	}
+	nested := make(logrus.Fields, len(data))
+	for k, v := range data {
+		if !strings.Contains(k, ".") {
+			nested[k] = nestValue(v, maxDepth, map[uintptr]bool{})
+		}
+	}
+
+	dotted := make([]string, 0, len(data))
+	for k := range data {
+		if strings.Contains(k, ".") {
+			dotted = append(dotted, k)
+		}
+	}
+	sortKeys(dotted)
+next:
+	for _, k := range dotted {
+		parts := strings.Split(k, ".")
+		group := map[string]interface{}(nested)
+		for _, part := range parts[:len(parts)-1] {
+			switch child := group[part].(type) {
+			case nil:
+				m := map[string]interface{}{}
+				group[part] = m
+				group = m
+			case map[string]interface{}:
+				group = child
+			default:
+				nested[k] = nestValue(data[k], maxDepth, map[uintptr]bool{})
+				continue next
+			}
+		}
+		last := parts[len(parts)-1]
+		if _, taken := group[last]; taken {
+			nested[k] = nestValue(data[k], maxDepth, map[uintptr]bool{})
+			continue
+		}
+		group[last] = nestValue(data[k], maxDepth, map[uintptr]bool{})
+	}
+	return nested
+}
+
+func nestValue(v interface{}, depth int, seen map[uintptr]bool) interface{} {
+	rv, ok := nestedValue(v)
+	if !ok {
+		return v
+	}
+	if depth == 0 {
+		return fmt.Sprint(v)
+	}
+	if p, ok := pointerOf(rv); ok {
+		if seen[p] {
+			return cycleValue
+		}
+		seen[p] = true
+		defer delete(seen, p)
+	}
+	obj := map[string]interface{}{}
+	nestedEach(reflect.Indirect(rv), func(k string, child interface{}) {
+		obj[k] = nestValue(child, depth-1, seen)
+	})
+	return obj
+}
+
+var (
+	selfRenderingTypes = []reflect.Type{
+		reflect.TypeOf((*error)(nil)).Elem(),
+		reflect.TypeOf((*fmt.Stringer)(nil)).Elem(),
+		reflect.TypeOf((*json.Marshaler)(nil)).Elem(),
+		reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem(),
+	}
+	timeType = reflect.TypeOf(time.Time{})
+)
+
+// nestedValue reports whether v is a map with string keys or a struct, or a
+// pointer to one, which doesn't render itself through Error, String,
+// MarshalJSON or MarshalText.
+func nestedValue(v interface{}) (reflect.Value, bool) {
+	if v == nil {
+		return reflect.Value{}, false
+	}
+	rv := reflect.ValueOf(v)
+	t := rv.Type()
+	for _, self := range selfRenderingTypes {
+		if t.Implements(self) {
+			return rv, false
+		}
+	}
+	if t.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return rv, false
+		}
+		t = t.Elem()
+	}
+	switch {
+	case t == timeType:
+		return rv, false
+	case t.Kind() == reflect.Struct:
+		return rv, true
+	case t.Kind() == reflect.Map && t.Key().Kind() == reflect.String:
+		return rv, true
+	}
+	return rv, false
+}
+
+// pointerOf returns the address of pointers and maps, the values that can
+// contain themselves.
+func pointerOf(rv reflect.Value) (uintptr, bool) {
+	switch rv.Kind() {
+	case reflect.Ptr, reflect.Map:
+		return rv.Pointer(), true
+	}
+	return 0, false
+}
+
+// nestedEach calls f with every entry of a map or exported field of a
+// struct, named by its json tag when it has one.
+func nestedEach(rv reflect.Value, f func(key string, v interface{})) {
+	switch rv.Kind() {
+	case reflect.Map:
+		for _, k := range rv.MapKeys() {
+			f(k.String(), rv.MapIndex(k).Interface())
+		}
+	case reflect.Struct:
+		t := rv.Type()
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if field.PkgPath != "" {
+				continue
+			}
+			name := field.Name
+			if tag := strings.Split(field.Tag.Get("json"), ",")[0]; tag == "-" {
+				continue
+			} else if tag != "" {
+				name = tag
+			}
+			f(name, rv.Field(i).Interface())
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package log
+
+import (
+	"testing"
+
+	logrus "github.com/sirupsen/logrus"
+)
+
+type nestedNode struct {
+	Name   string      `json:"name"`
+	Next   *nestedNode `json:"next,omitempty"`
+	Secret string      `json:"-"`
+	hidden int
+}
+
+func TestFlattenFields(t *testing.T) {
+	loop := &nestedNode{Name: "a"}
+	loop.Next = &nestedNode{Name: "b", Next: loop}
+	entry := benchmarkEntry(logrus.Fields{
+		"http":  map[string]interface{}{"method": "GET", "status": 200, "headers": map[string]string{"accept": "json"}},
+		"node":  loop,
+		"card":  goldenCard{Brand: "visa", Last4: "4242"},
+		"err":   errNotNested,
+		"plain": "x",
+	})
+
+	f := &ChannelTextFormatter{DisableColors: true, DisableTimestamp: true, FlattenFields: true}
+	b, err := f.Format(entry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `level=info msg="charge succeeded" card.Brand=visa card.Last4=4242 err="not nested" http.headers.accept=json http.method=GET http.status=200 node.name=a node.next.name=b node.next.next="(cycle)" plain=x` + "\n"
+	if string(b) != expected {
+		t.Errorf("expected %q got %q", expected, b)
+	}
+
+	f.MaxDepth = 1
+	b, _ = f.Format(entry)
+	expected = `level=info msg="charge succeeded" card.Brand=visa card.Last4=4242 err="not nested" http.headers="map[accept:json]" http.method=GET http.status=200 node.name=a node.next="&{b 0x`
+	if string(b[:len(expected)]) != expected {
+		t.Errorf("expected %q got %q", expected, b)
+	}
+}
+
+func TestNestFields(t *testing.T) {
+	loop := &nestedNode{Name: "a"}
+	loop.Next = loop
+	entry := benchmarkEntry(logrus.Fields{
+		"http.method":  "GET",
+		"http.status":  200,
+		"error":        "declined",
+		"error.code":   "card_declined",
+		"node":         loop,
+		"meta":         map[string]interface{}{"order": "o_1"},
+		"meta.items":   2,
+		"deep.a.b.c.d": 1,
+	})
+
+	f := &ChannelJSONFormatter{DisableTimestamp: true, NestFields: true, MaxDepth: 3}
+	b, err := f.Format(entry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `{"level":"info","message":"charge succeeded","deep":{"a":{"b":{"c":{"d":1}}}},"error":"declined","error.code":"card_declined","http":{"method":"GET","status":200},"meta":{"items":2,"order":"o_1"},"node":{"name":"a","next":"(cycle)"}}` + "\n"
+	if string(b) != expected {
+		t.Errorf("expected %s got %s", expected, b)
+	}
+}
+
+var errNotNested = nestedError{"not nested"}
+
+type nestedError struct {
+	msg string
+}
+
+func (e nestedError) Error() string {
+	return e.msg
+}
@@ -0,0 +1,226 @@
+package log
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultNetTimeout = 5 * time.Second
+	netMinBackoff     = 100 * time.Millisecond
+	netMaxBackoff     = 30 * time.Second
+)
+
+// Framing separates the entries a NetWriter sends over a stream. Datagrams
+// carry one entry each and aren't framed.
+type Framing int
+
+const (
+	// FrameNewline ends every entry with a newline, for collectors reading
+	// lines such as Logstash or Vector.
+	FrameNewline Framing = iota
+
+	// FrameLength prefixes every entry with its length as a 4 byte big
+	// endian integer.
+	FrameLength
+
+	// FrameOctetCount prefixes every entry with its length in decimal and a
+	// space, the syslog over TCP framing of RFC 6587.
+	FrameOctetCount
+)
+
+// ParseFraming parses newline, length or octet-counted.
+func ParseFraming(s string) (Framing, error) {
+	switch strings.ToLower(s) {
+	case "", "newline":
+		return FrameNewline, nil
+	case "length":
+		return FrameLength, nil
+	case "octet-counted", "octet":
+		return FrameOctetCount, nil
+	}
+	return 0, fmt.Errorf("log: unknown framing %q", s)
+}
+
+// NetWriter ships entries to a generic collector over the network.
+//
+// Over TCP it connects on the first write and reconnects when the connection
+// is lost. Writes while the collector is unreachable fail without dialing
+// until a backoff growing from MinBackoff to MaxBackoff has passed, so a
+// down collector doesn't slow logging down; wrap it in a FailoverWriter or
+// SpillWriter to keep those entries. Over UDP it sends one datagram per
+// entry and drops the entries it cannot send.
+type NetWriter struct {
+	// Network is tcp, tcp4, tcp6, udp, udp4 or udp6
+	Network string
+	Addr    string
+
+	// Framing of the entries over TCP
+	Framing Framing
+
+	// TLS, when set, wraps TCP connections in TLS
+	TLS *tls.Config
+
+	// Timeout of dialing and of every write. Defaults to 5s.
+	Timeout time.Duration
+
+	// MinBackoff and MaxBackoff bound the wait between attempts to
+	// reconnect. Default to 100ms and 30s.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+
+	mu       sync.Mutex
+	conn     net.Conn
+	failures int
+	retryAt  time.Time
+	closed   bool
+
+	registration sinkRegistration
+}
+
+// NewNetWriter returns a writer sending to addr, connecting on the first
+// write.
+func NewNetWriter(network, addr string, framing Framing) *NetWriter {
+	w := &NetWriter{Network: network, Addr: addr, Framing: framing}
+	w.registration.register(w, SinkOutput)
+	return w
+}
+
+func (w *NetWriter) datagram() bool {
+	return strings.HasPrefix(w.Network, "udp")
+}
+
+func (w *NetWriter) timeout() time.Duration {
+	if w.Timeout <= 0 {
+		return defaultNetTimeout
+	}
+	return w.Timeout
+}
+
+// frame returns the entry p framed for the wire.
+func (w *NetWriter) frame(p []byte) []byte {
+	msg := bytes.TrimRight(p, "\n")
+	if w.datagram() {
+		return msg
+	}
+	switch w.Framing {
+	case FrameLength:
+		b := make([]byte, 4, 4+len(msg))
+		binary.BigEndian.PutUint32(b, uint32(len(msg)))
+		return append(b, msg...)
+	case FrameOctetCount:
+		return append([]byte(strconv.Itoa(len(msg))+" "), msg...)
+	}
+	return append(msg[:len(msg):len(msg)], '\n')
+}
+
+// Write sends one entry.
+func (w *NetWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return 0, ErrWriterClosed
+	}
+	if len(bytes.TrimRight(p, "\n")) == 0 {
+		return len(p), nil
+	}
+	msg := w.frame(p)
+
+	if w.datagram() {
+		if err := w.send(msg); err != nil {
+			// fire and forget
+			w.disconnect()
+			recordDropped(1)
+		}
+		return len(p), nil
+	}
+
+	if w.conn == nil && time.Now().Before(w.retryAt) {
+		return 0, fmt.Errorf("log: %s is unreachable, retrying in %v", w.Addr, time.Until(w.retryAt).Round(time.Millisecond))
+	}
+	connected := w.conn != nil
+	err := w.send(msg)
+	if err != nil && connected {
+		// the collector may have closed the idle connection, retry once
+		w.disconnect()
+		err = w.send(msg)
+	}
+	if err != nil {
+		w.disconnect()
+		w.backoff()
+		return 0, err
+	}
+	w.failures = 0
+	return len(p), nil
+}
+
+func (w *NetWriter) send(msg []byte) error {
+	timeout := w.timeout()
+	if w.conn == nil {
+		dialer := &net.Dialer{Timeout: timeout}
+		var (
+			conn net.Conn
+			err  error
+		)
+		if w.TLS != nil && !w.datagram() {
+			conn, err = tls.DialWithDialer(dialer, w.Network, w.Addr, w.TLS)
+		} else {
+			conn, err = dialer.Dial(w.Network, w.Addr)
+		}
+		if err != nil {
+			return err
+		}
+		w.conn = conn
+	}
+	w.conn.SetWriteDeadline(time.Now().Add(timeout))
+	_, err := w.conn.Write(msg)
+	return err
+}
+
+func (w *NetWriter) disconnect() {
+	if w.conn != nil {
+		w.conn.Close()
+		w.conn = nil
+	}
+}
+
+// backoff doubles the wait before the next attempt to connect.
+func (w *NetWriter) backoff() {
+	min, max := w.MinBackoff, w.MaxBackoff
+	if min <= 0 {
+		min = netMinBackoff
+	}
+	if max <= 0 {
+		max = netMaxBackoff
+	}
+	wait := min
+	for i := 0; i < w.failures && wait < max; i++ {
+		wait *= 2
+	}
+	if wait > max {
+		wait = max
+	}
+	w.failures++
+	w.retryAt = time.Now().Add(wait)
+}
+
+// Close closes the connection, later writes fail with ErrWriterClosed.
+func (w *NetWriter) Close() error {
+	w.registration.release()
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.closed = true
+	if w.conn == nil {
+		return nil
+	}
+	err := w.conn.Close()
+	w.conn = nil
+	return err
+}
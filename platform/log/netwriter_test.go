@@ -0,0 +1,145 @@
+package log
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNetWriterFraming(t *testing.T) {
+	for _, tt := range []struct {
+		framing Framing
+		read    func(r *bufio.Reader) (string, error)
+	}{
+		{FrameNewline, func(r *bufio.Reader) (string, error) {
+			s, err := r.ReadString('\n')
+			return strings.TrimSuffix(s, "\n"), err
+		}},
+		{FrameLength, func(r *bufio.Reader) (string, error) {
+			var n uint32
+			if err := binary.Read(r, binary.BigEndian, &n); err != nil {
+				return "", err
+			}
+			b := make([]byte, n)
+			_, err := io.ReadFull(r, b)
+			return string(b), err
+		}},
+		{FrameOctetCount, func(r *bufio.Reader) (string, error) {
+			var n int
+			if _, err := fmt.Fscan(r, &n); err != nil {
+				return "", err
+			}
+			r.ReadByte()
+			b := make([]byte, n)
+			_, err := io.ReadFull(r, b)
+			return string(b), err
+		}},
+	} {
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		w := NewNetWriter("tcp", l.Addr().String(), tt.framing)
+		for _, msg := range []string{"first entry\n", "second entry\n"} {
+			if _, err := w.Write([]byte(msg)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		conn, err := l.Accept()
+		if err != nil {
+			t.Fatal(err)
+		}
+		r := bufio.NewReader(conn)
+		for _, want := range []string{"first entry", "second entry"} {
+			got, err := tt.read(r)
+			if err != nil || got != want {
+				t.Errorf("framing %v: expected %q, got %q %v", tt.framing, want, got, err)
+			}
+		}
+		w.Close()
+		conn.Close()
+		l.Close()
+	}
+}
+
+func TestNetWriterReconnects(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	w := NewNetWriter("tcp", addr, FrameNewline)
+	w.MinBackoff = 50 * time.Millisecond
+	defer w.Close()
+
+	if _, err := w.Write([]byte("one\n")); err != nil {
+		t.Fatal(err)
+	}
+	conn, err := l.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.Close()
+	l.Close()
+
+	// the collector is gone, writes fail until the backoff has passed
+	failed := false
+	for i := 0; i < 10 && !failed; i++ {
+		_, err = w.Write([]byte("lost\n"))
+		failed = err != nil
+	}
+	if !failed {
+		t.Fatal("expected writes to fail once the collector is down")
+	}
+	if _, err := w.Write([]byte("lost\n")); err == nil || !strings.Contains(err.Error(), "retrying") {
+		t.Errorf("expected to back off, got %v", err)
+	}
+
+	if l, err = net.Listen("tcp", addr); err != nil {
+		t.Skipf("cannot listen on %s again, %v", addr, err)
+	}
+	defer l.Close()
+	time.Sleep(60 * time.Millisecond)
+	if _, err := w.Write([]byte("back\n")); err != nil {
+		t.Fatalf("expected to reconnect, got %v", err)
+	}
+	conn, err = l.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	line, _ := bufio.NewReader(conn).ReadString('\n')
+	if line != "back\n" {
+		t.Errorf("expected the entry after reconnecting, got %q", line)
+	}
+
+	w.Close()
+	if _, err := w.Write([]byte("closed\n")); err != ErrWriterClosed {
+		t.Errorf("expected ErrWriterClosed, got %v", err)
+	}
+}
+
+func TestNetWriterUDP(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	w := NewNetWriter("udp", conn.LocalAddr().String(), FrameOctetCount)
+	defer w.Close()
+
+	if _, err := w.Write([]byte("datagram\n")); err != nil {
+		t.Fatal(err)
+	}
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	b := make([]byte, 64)
+	n, _, err := conn.ReadFrom(b)
+	if err != nil || string(b[:n]) != "datagram" {
+		t.Errorf("expected an unframed datagram, got %q %v", b[:n], err)
+	}
+}
@@ -0,0 +1,250 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/smtp"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	logrus "github.com/sirupsen/logrus"
+)
+
+const (
+	defaultPagerDutyURL = "https://events.pagerduty.com/v2/enqueue"
+	notifyTimeout       = 10 * time.Second
+)
+
+// AlertStatus tells whether an Alert starts or ends
+type AlertStatus int
+
+const (
+	AlertTriggered AlertStatus = iota
+	AlertResolved
+)
+
+func (s AlertStatus) String() string {
+	if s == AlertResolved {
+		return "resolved"
+	}
+	return "triggered"
+}
+
+// Alert is what an EscalationHook sends its notifiers when a rule triggers
+// and when it resolves.
+type Alert struct {
+	Rule    string
+	Status  AlertStatus
+	Channel string
+
+	// Level is the most severe level of the entries counted
+	Level logrus.Level
+
+	// Count of the entries within Window when the rule triggered
+	Count  int
+	Window time.Duration
+
+	// Message of the last entry counted
+	Message string
+	Time    time.Time
+}
+
+// Summary is a line describing the alert
+func (a Alert) Summary() string {
+	if a.Status == AlertResolved {
+		return fmt.Sprintf("[%s] resolved: no more %s entries on %s", a.Rule, a.Level, alertChannel(a.Channel))
+	}
+	return fmt.Sprintf("[%s] %d %s entries on %s within %v, last: %s", a.Rule, a.Count, a.Level, alertChannel(a.Channel), a.Window, a.Message)
+}
+
+func alertChannel(channel string) string {
+	if channel == "" {
+		return "any channel"
+	}
+	return channel
+}
+
+// Notifier sends alerts to people, see RegisterNotifier
+type Notifier interface {
+	Notify(ctx context.Context, a Alert) error
+}
+
+// NotifierFunc is a function used as a Notifier
+type NotifierFunc func(ctx context.Context, a Alert) error
+
+// Notify calls f(ctx, a)
+func (f NotifierFunc) Notify(ctx context.Context, a Alert) error {
+	return f(ctx, a)
+}
+
+var (
+	notifiersMu sync.RWMutex
+	notifiers   = map[string]*Notifier{}
+)
+
+// RegisterNotifier makes n the notifier escalation rules name, e.g.
+//
+//	log.RegisterNotifier("oncall", &log.PagerDutyNotifier{RoutingKey: key})
+//
+// It returns the function removing the notifier and panics if name is
+// taken.
+func RegisterNotifier(name string, n Notifier) func() {
+	notifiersMu.Lock()
+	defer notifiersMu.Unlock()
+	if _, ok := notifiers[name]; ok {
+		panic(fmt.Sprintf("log: notifier %q registered twice", name))
+	}
+	e := &n
+	notifiers[name] = e
+	return func() {
+		notifiersMu.Lock()
+		if notifiers[name] == e {
+			delete(notifiers, name)
+		}
+		notifiersMu.Unlock()
+	}
+}
+
+func notifier(name string) (Notifier, bool) {
+	notifiersMu.RLock()
+	defer notifiersMu.RUnlock()
+	if n, ok := notifiers[name]; ok {
+		return *n, true
+	}
+	return nil, false
+}
+
+// WebhookNotifier posts alerts as JSON to URL
+type WebhookNotifier struct {
+	URL    string
+	Client *http.Client
+}
+
+// Notify posts a
+func (n *WebhookNotifier) Notify(ctx context.Context, a Alert) error {
+	return postJSON(ctx, n.Client, n.URL, map[string]interface{}{
+		"rule":    a.Rule,
+		"status":  a.Status.String(),
+		"channel": a.Channel,
+		"level":   a.Level.String(),
+		"count":   a.Count,
+		"window":  a.Window.String(),
+		"message": a.Message,
+		"time":    a.Time,
+		"summary": a.Summary(),
+	})
+}
+
+// EmailNotifier mails alerts through the SMTP server at Addr, host:port
+type EmailNotifier struct {
+	Addr string
+	Auth smtp.Auth
+	From string
+	To   []string
+}
+
+// Notify mails a within the deadline of ctx, or notifyTimeout.
+func (n *EmailNotifier) Notify(ctx context.Context, a Alert) error {
+	msg := &bytes.Buffer{}
+	fmt.Fprintf(msg, "From: %s\r\n", n.From)
+	fmt.Fprintf(msg, "To: %s\r\n", strings.Join(n.To, ", "))
+	fmt.Fprintf(msg, "Subject: %s\r\n", a.Summary())
+	fmt.Fprintf(msg, "Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	fmt.Fprintf(msg, "Rule: %s\r\nStatus: %s\r\nChannel: %s\r\nLevel: %s\r\nCount: %d within %v\r\nTime: %s\r\n\r\n%s\r\n",
+		a.Rule, a.Status, alertChannel(a.Channel), a.Level, a.Count, a.Window, a.Time.Format(time.RFC3339), a.Message)
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(notifyTimeout)
+	}
+	return sendMail(deadline, n.Addr, n.Auth, n.From, n.To, msg.Bytes())
+}
+
+// PagerDutyNotifier sends alerts to the PagerDuty Events API v2. Resolution
+// events resolve the incident their rule triggered.
+type PagerDutyNotifier struct {
+	// RoutingKey of the integration of the service
+	RoutingKey string
+
+	// Source names the host or service in the incident. Defaults to the
+	// hostname.
+	Source string
+
+	// URL defaults to the Events API v2 endpoint
+	URL    string
+	Client *http.Client
+}
+
+// Notify sends a
+func (n *PagerDutyNotifier) Notify(ctx context.Context, a Alert) error {
+	url := n.URL
+	if url == "" {
+		url = defaultPagerDutyURL
+	}
+	source := n.Source
+	if source == "" {
+		source, _ = os.Hostname()
+	}
+	event := map[string]interface{}{
+		"routing_key":  n.RoutingKey,
+		"event_action": "trigger",
+		"dedup_key":    source + "/" + a.Rule,
+	}
+	if a.Status == AlertResolved {
+		event["event_action"] = "resolve"
+	} else {
+		event["payload"] = map[string]interface{}{
+			"summary":   a.Summary(),
+			"source":    source,
+			"severity":  pagerDutySeverity(a.Level),
+			"timestamp": a.Time.Format(time.RFC3339),
+			"component": a.Channel,
+			"custom_details": map[string]interface{}{
+				"count":   a.Count,
+				"window":  a.Window.String(),
+				"message": a.Message,
+			},
+		}
+	}
+	return postJSON(ctx, n.Client, url, event)
+}
+
+func pagerDutySeverity(level logrus.Level) string {
+	switch {
+	case level <= logrus.FatalLevel:
+		return "critical"
+	case level == logrus.ErrorLevel:
+		return "error"
+	case level == logrus.WarnLevel:
+		return "warning"
+	}
+	return "info"
+}
+
+func postJSON(ctx context.Context, client *http.Client, url string, v interface{}) error {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest("POST", url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("%v responded %v", url, resp.Status)
+	}
+	return nil
+}
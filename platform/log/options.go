@@ -0,0 +1,134 @@
+package log
+
+// Option configures the ChannelTextFormatter returned by New
+type Option func(f *ChannelTextFormatter)
+
+// New returns a ChannelTextFormatter with opts applied, so settings added
+// later come as options rather than fields to look up:
+//
+//	logrus.SetFormatter(log.New(
+//		log.WithTimestampFormat(time.RFC3339),
+//		log.WithKeyOrder("request_id"),
+//	))
+//
+// Without options it formats like &ChannelTextFormatter{}, which keeps
+// working and takes the same settings as fields.
+func New(opts ...Option) *ChannelTextFormatter {
+	f := &ChannelTextFormatter{}
+	for _, opt := range opts {
+		opt(f)
+	}
+	return f
+}
+
+// WithColors forces colors on, or off with false. Without it terminals are
+// colored, see ChannelTextFormatter.ForceColors.
+func WithColors(on bool) Option {
+	return func(f *ChannelTextFormatter) {
+		f.ForceColors = on
+		f.DisableColors = !on
+	}
+}
+
+// WithTheme sets the colors
+func WithTheme(theme *Theme) Option {
+	return func(f *ChannelTextFormatter) {
+		f.Theme = theme
+	}
+}
+
+// WithGlyphs puts a level marker in front of colored entries
+func WithGlyphs(glyphs *Glyphs) Option {
+	return func(f *ChannelTextFormatter) {
+		f.Glyphs = glyphs
+	}
+}
+
+// WithTimestampFormat writes the full timestamp of entries in layout rather
+// than the time passed since the start.
+func WithTimestampFormat(layout string) Option {
+	return func(f *ChannelTextFormatter) {
+		f.FullTimestamp = true
+		f.TimestampFormat = layout
+	}
+}
+
+// WithoutTimestamp leaves the timestamp out, for log systems adding their
+// own.
+func WithoutTimestamp() Option {
+	return func(f *ChannelTextFormatter) {
+		f.DisableTimestamp = true
+	}
+}
+
+// WithElapsed writes the time passed since the start in format, with the
+// seconds, or hours of the clock formats, padded to width digits.
+func WithElapsed(format ElapsedFormat, width int) Option {
+	return func(f *ChannelTextFormatter) {
+		f.FullTimestamp = false
+		f.ElapsedFormat = format
+		f.ElapsedWidth = width
+	}
+}
+
+// WithMessageWidth pads colored messages to width cells, see
+// MessageWidthAuto and MessageWidthNone.
+func WithMessageWidth(width int) Option {
+	return func(f *ChannelTextFormatter) {
+		f.MessageWidth = width
+	}
+}
+
+// WithSorting sorts the fields by key, the default, or keeps them in map
+// order with false.
+func WithSorting(on bool) Option {
+	return func(f *ChannelTextFormatter) {
+		f.DisableSorting = !on
+	}
+}
+
+// WithKeyOrder writes keys first and in this order
+func WithKeyOrder(keys ...string) Option {
+	return func(f *ChannelTextFormatter) {
+		f.KeyOrder = keys
+	}
+}
+
+// WithEscaping sets the escaping of values that need quoting
+func WithEscaping(escaping Escaping) Option {
+	return func(f *ChannelTextFormatter) {
+		f.Escaping = escaping
+	}
+}
+
+// WithCaller adds the file:line and function that logged the entry,
+// skipping skip frames above the first caller outside this package.
+func WithCaller(skip int) Option {
+	return func(f *ChannelTextFormatter) {
+		f.ReportCaller = true
+		f.CallerSkip = skip
+	}
+}
+
+// WithErrorChain renders the causes of error values
+func WithErrorChain(mode ErrorChainMode) Option {
+	return func(f *ChannelTextFormatter) {
+		f.ErrorChain = mode
+	}
+}
+
+// WithFlattenFields writes map and struct values as dotted keys down to
+// maxDepth levels, 5 when 0.
+func WithFlattenFields(maxDepth int) Option {
+	return func(f *ChannelTextFormatter) {
+		f.FlattenFields = true
+		f.MaxDepth = maxDepth
+	}
+}
+
+// WithFieldMap renames the time, level and msg keys
+func WithFieldMap(fieldMap FieldMap) Option {
+	return func(f *ChannelTextFormatter) {
+		f.FieldMap = fieldMap
+	}
+}
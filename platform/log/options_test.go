@@ -0,0 +1,31 @@
+package log
+
+import (
+	"testing"
+
+	logrus "github.com/sirupsen/logrus"
+)
+
+func TestNewOptions(t *testing.T) {
+	entry := benchmarkEntry(logrus.Fields{"user": 7, "amount": 1000, "request_id": "r1"})
+	f := New(WithColors(false), WithTimestampFormat("15:04"), WithSorting(true), WithKeyOrder("request_id"))
+	literal := &ChannelTextFormatter{DisableColors: true, FullTimestamp: true, TimestampFormat: "15:04", KeyOrder: []string{"request_id"}}
+	b, err := f.Format(entry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected, err := literal.Format(entry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != string(expected) {
+		t.Errorf("expected %q got %q", expected, b)
+	}
+
+	if f := New(WithColors(true), WithSorting(false)); !f.ForceColors || f.DisableColors || !f.DisableSorting {
+		t.Errorf("unexpected formatter %+v", f)
+	}
+	if f := New(); f.ForceColors || f.DisableColors || f.DisableSorting || f.FullTimestamp {
+		t.Errorf("expected New without options to match the zero formatter, got %+v", f)
+	}
+}
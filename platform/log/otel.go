@@ -0,0 +1,33 @@
+package log
+
+import (
+	logrus "github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	TraceIDKey = "trace_id"
+	SpanIDKey  = "span_id"
+)
+
+// TraceHook adds the trace and span ID of the active OpenTelemetry span to
+// entries logged with a context, e.g. through FromContext, so log lines can be
+// looked up from a trace in Jaeger or Tempo.
+type TraceHook struct{}
+
+func (h *TraceHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (h *TraceHook) Fire(entry *logrus.Entry) error {
+	if entry.Context == nil {
+		return nil
+	}
+	sc := trace.SpanContextFromContext(entry.Context)
+	if !sc.IsValid() {
+		return nil
+	}
+	entry.Data[TraceIDKey] = sc.TraceID().String()
+	entry.Data[SpanIDKey] = sc.SpanID().String()
+	return nil
+}
@@ -0,0 +1,34 @@
+package log
+
+import (
+	"context"
+	"testing"
+
+	logrus "github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestTraceHook(t *testing.T) {
+	sc := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: trace.TraceID{0x4b, 0xf9, 0x2f, 0x35, 0x77, 0xb3, 0x4d, 0xa6, 0xa3, 0xce, 0x92, 0x9d, 0x0e, 0x0e, 0x47, 0x36},
+		SpanID:  trace.SpanID{0x00, 0xf0, 0x67, 0xaa, 0x0b, 0xa9, 0x02, 0xb7},
+	})
+	h := &TraceHook{}
+	for _, tt := range []struct {
+		ctx             context.Context
+		traceID, spanID interface{}
+	}{
+		{nil, nil, nil},
+		{context.Background(), nil, nil},
+		{trace.ContextWithSpanContext(context.Background(), sc), "4bf92f3577b34da6a3ce929d0e0e4736", "00f067aa0ba902b7"},
+	} {
+		entry := logrus.NewEntry(logrus.StandardLogger())
+		entry.Context = tt.ctx
+		if err := h.Fire(entry); err != nil {
+			t.Fatal(err)
+		}
+		if entry.Data[TraceIDKey] != tt.traceID || entry.Data[SpanIDKey] != tt.spanID {
+			t.Errorf("expected trace %v and span %v, got %v", tt.traceID, tt.spanID, entry.Data)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package otlp
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const exportMethod = "/opentelemetry.proto.collector.logs.v1.LogsService/Export"
+
+func dialGRPC(endpoint string, tlsConfig *tls.Config) (*grpc.ClientConn, error) {
+	creds := insecure.NewCredentials()
+	if tlsConfig != nil {
+		creds = credentials.NewTLS(tlsConfig)
+	}
+	return grpc.Dial(endpoint, grpc.WithTransportCredentials(creds))
+}
+
+func (h *Hook) exportGRPC(ctx context.Context, conn *grpc.ClientConn, req *exportRequest) error {
+	for k, v := range h.Headers {
+		ctx = metadata.AppendToOutgoingContext(ctx, k, v)
+	}
+	var reply []byte
+	err := conn.Invoke(ctx, exportMethod, req.marshalProto(), &reply, grpc.ForceCodec(rawCodec{}))
+	if err == nil {
+		return nil
+	}
+	switch status.Code(err) {
+	case codes.Canceled, codes.DeadlineExceeded, codes.ResourceExhausted, codes.Aborted,
+		codes.OutOfRange, codes.Unavailable, codes.DataLoss:
+		return retryableError{err}
+	}
+	return err
+}
+
+// rawCodec sends the request encoded by marshalProto as it is, the response
+// of the export is ignored.
+type rawCodec struct{}
+
+func (rawCodec) Marshal(v interface{}) ([]byte, error) {
+	b, ok := v.([]byte)
+	if !ok {
+		return nil, fmt.Errorf("otlp: cannot marshal %T", v)
+	}
+	return b, nil
+}
+
+func (rawCodec) Unmarshal(data []byte, v interface{}) error {
+	if b, ok := v.(*[]byte); ok {
+		*b = append((*b)[:0], data...)
+	}
+	return nil
+}
+
+func (rawCodec) Name() string {
+	return "proto"
+}
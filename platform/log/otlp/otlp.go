@@ -0,0 +1,535 @@
+// Package otlp exports log entries in the OpenTelemetry log data model to a
+// collector over OTLP/HTTP or OTLP/gRPC, next to the traces and metrics of
+// the service:
+//
+//	hook, err := otlp.NewHook("http://otel-collector:4318/v1/logs", otlp.HTTP, map[string]interface{}{
+//		"service.name": "openpoint-api",
+//	})
+//	logrus.AddHook(hook)
+//	defer hook.Close()
+//
+// Fields become attributes, the trace_id and span_id fields of log.TraceHook
+// or the span of the entry context link the records to their trace.
+package otlp
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/o3labs/openpoint/platform/config"
+	"github.com/o3labs/openpoint/platform/log"
+	logrus "github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	defaultBatchSize  = 512
+	defaultBatchWait  = time.Second
+	defaultMaxRetries = 5
+	defaultTimeout    = 10 * time.Second
+	minBackoff        = 500 * time.Millisecond
+
+	scopeName = "github.com/o3labs/openpoint/platform/log"
+)
+
+// Protocol is the OTLP transport.
+type Protocol int
+
+const (
+	// HTTP posts JSON encoded requests to the endpoint URL, e.g.
+	// http://otel-collector:4318/v1/logs
+	HTTP Protocol = iota
+
+	// GRPC calls the logs service at the endpoint address, e.g.
+	// otel-collector:4317
+	GRPC
+)
+
+// Hook batches entries and exports them to an OpenTelemetry collector,
+// retrying with exponential backoff while the collector is unavailable.
+type Hook struct {
+	// Headers sent with every export, e.g. an API key of a vendor
+	Headers map[string]string
+
+	batchSize  int
+	batchWait  time.Duration
+	maxRetries int
+	timeout    time.Duration
+
+	endpoint string
+	resource []keyValue
+	export   func(ctx context.Context, req *exportRequest) error
+	close    func() error
+
+	records chan *logRecord
+	stop    chan struct{}
+	done    chan struct{}
+
+	mu     sync.RWMutex
+	closed bool
+
+	unregister func()
+}
+
+// Option configures the transport and batching of a Hook.
+type Option func(*options)
+
+type options struct {
+	client     *http.Client
+	tls        *tls.Config
+	batchSize  int
+	batchWait  time.Duration
+	maxRetries int
+	timeout    time.Duration
+}
+
+// WithHTTPClient sets the client of the HTTP protocol.
+func WithHTTPClient(c *http.Client) Option {
+	return func(o *options) { o.client = c }
+}
+
+// WithTLS secures gRPC connections, which are plaintext by default.
+func WithTLS(c *tls.Config) Option {
+	return func(o *options) { o.tls = c }
+}
+
+// WithBatchSize sets the number of records exported at once. Defaults to 512.
+func WithBatchSize(n int) Option {
+	return func(o *options) { o.batchSize = n }
+}
+
+// WithBatchWait sets how long records wait for a full batch. Defaults to 1s.
+func WithBatchWait(d time.Duration) Option {
+	return func(o *options) { o.batchWait = d }
+}
+
+// WithMaxRetries sets how often a failed export is retried. Defaults to 5.
+func WithMaxRetries(n int) Option {
+	return func(o *options) { o.maxRetries = n }
+}
+
+// WithTimeout sets the timeout of every export. Defaults to 10s.
+func WithTimeout(d time.Duration) Option {
+	return func(o *options) { o.timeout = d }
+}
+
+// NewHook starts a hook exporting to endpoint. resource describes the
+// service, service.name defaults to the name of the executable and
+// deployment.environment to the config environment. Call Close on shutdown
+// to export what is still batched.
+func NewHook(endpoint string, protocol Protocol, resource map[string]interface{}, opts ...Option) (*Hook, error) {
+	o := &options{
+		batchSize:  defaultBatchSize,
+		batchWait:  defaultBatchWait,
+		maxRetries: defaultMaxRetries,
+		timeout:    defaultTimeout,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	h := &Hook{
+		batchSize:  o.batchSize,
+		batchWait:  o.batchWait,
+		maxRetries: o.maxRetries,
+		timeout:    o.timeout,
+		endpoint:   endpoint,
+		resource:   resourceAttributes(resource),
+		records:    make(chan *logRecord, o.batchSize*10),
+		stop:       make(chan struct{}),
+		done:       make(chan struct{}),
+	}
+	switch protocol {
+	case HTTP:
+		client := o.client
+		if client == nil {
+			client = &http.Client{}
+		}
+		h.export = func(ctx context.Context, req *exportRequest) error {
+			return h.exportHTTP(ctx, client, req)
+		}
+		h.close = func() error { return nil }
+	case GRPC:
+		conn, err := dialGRPC(endpoint, o.tls)
+		if err != nil {
+			return nil, fmt.Errorf("otlp: failed to dial %s, %v", endpoint, err)
+		}
+		h.export = func(ctx context.Context, req *exportRequest) error {
+			return h.exportGRPC(ctx, conn, req)
+		}
+		h.close = conn.Close
+	default:
+		return nil, fmt.Errorf("otlp: unknown protocol %d", protocol)
+	}
+
+	h.unregister = log.RegisterSink(h, log.SinkQueue)
+	go h.run()
+	return h, nil
+}
+
+func resourceAttributes(resource map[string]interface{}) []keyValue {
+	attrs := map[string]interface{}{
+		"service.name": filepath.Base(os.Args[0]),
+	}
+	if config.Env.Name != "" {
+		attrs["deployment.environment"] = config.Env.Name
+	}
+	if host, err := os.Hostname(); err == nil {
+		attrs["host.name"] = host
+	}
+	for k, v := range resource {
+		attrs[k] = v
+	}
+	return attributes(attrs)
+}
+
+func (h *Hook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (h *Hook) Fire(entry *logrus.Entry) error {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	if h.closed {
+		// still attached to the logger after Close
+		log.RecordDropped(1)
+		return nil
+	}
+	select {
+	case h.records <- newLogRecord(entry):
+	default:
+		log.RecordDropped(1)
+	}
+	return nil
+}
+
+// QueueLen returns the number of entries waiting to be exported, see
+// log.PipelineStats.
+func (h *Hook) QueueLen() int {
+	return len(h.records)
+}
+
+// Close exports the pending batch and stops the hook. Entries fired after
+// Close are dropped.
+func (h *Hook) Close() error {
+	h.unregister()
+	h.mu.Lock()
+	if h.closed {
+		h.mu.Unlock()
+		<-h.done
+		return nil
+	}
+	h.closed = true
+	close(h.stop)
+	h.mu.Unlock()
+	<-h.done
+	return h.close()
+}
+
+func (h *Hook) run() {
+	defer close(h.done)
+
+	ticker := time.NewTicker(h.batchWait)
+	defer ticker.Stop()
+
+	var batch []*logRecord
+	push := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if err := h.push(batch); err != nil {
+			log.RecordDropped(uint64(len(batch)))
+			log.Reportf("Failed to export %d entries to %s because %+v", len(batch), h.endpoint, err)
+		}
+		batch = nil
+	}
+
+	for {
+		select {
+		case r := <-h.records:
+			batch = append(batch, r)
+			if len(batch) >= h.batchSize {
+				push()
+			}
+		case <-ticker.C:
+			push()
+		case <-h.stop:
+			// Fire doesn't queue once stop is closed, export what it
+			// queued before
+			for {
+				select {
+				case r := <-h.records:
+					batch = append(batch, r)
+					if len(batch) >= h.batchSize {
+						push()
+					}
+				default:
+					push()
+					return
+				}
+			}
+		}
+	}
+}
+
+// push exports batch, retrying with exponential backoff on the errors the
+// OTLP specification deems retryable.
+func (h *Hook) push(batch []*logRecord) error {
+	req := &exportRequest{ResourceLogs: []resourceLogs{{
+		Resource:  resource{Attributes: h.resource},
+		ScopeLogs: []scopeLogs{{Scope: scope{Name: scopeName}, LogRecords: batch}},
+	}}}
+
+	backoff := minBackoff
+	for attempt := 0; ; attempt++ {
+		ctx, cancel := context.WithTimeout(context.Background(), h.timeout)
+		err := h.export(ctx, req)
+		cancel()
+		if err == nil {
+			return nil
+		}
+		if _, retry := err.(retryableError); !retry || attempt >= h.maxRetries {
+			return err
+		}
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+type retryableError struct {
+	error
+}
+
+func (h *Hook) exportHTTP(ctx context.Context, client *http.Client, req *exportRequest) error {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+	r, err := http.NewRequest(http.MethodPost, h.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	r = r.WithContext(ctx)
+	r.Header.Set("Content-Type", "application/json")
+	for k, v := range h.Headers {
+		r.Header.Set(k, v)
+	}
+	resp, err := client.Do(r)
+	if err != nil {
+		return retryableError{err}
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode/100 == 2 {
+		return nil
+	}
+	err = fmt.Errorf("collector responded %v", resp.Status)
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return retryableError{err}
+	}
+	return err
+}
+
+// The OTLP log data model, with the field names of its JSON encoding.
+type exportRequest struct {
+	ResourceLogs []resourceLogs `json:"resourceLogs"`
+}
+
+type resourceLogs struct {
+	Resource  resource    `json:"resource"`
+	ScopeLogs []scopeLogs `json:"scopeLogs"`
+}
+
+type resource struct {
+	Attributes []keyValue `json:"attributes"`
+}
+
+type scopeLogs struct {
+	Scope      scope        `json:"scope"`
+	LogRecords []*logRecord `json:"logRecords"`
+}
+
+type scope struct {
+	Name string `json:"name"`
+}
+
+type logRecord struct {
+	TimeUnixNano         uint64     `json:"timeUnixNano,string"`
+	ObservedTimeUnixNano uint64     `json:"observedTimeUnixNano,string"`
+	SeverityNumber       int        `json:"severityNumber"`
+	SeverityText         string     `json:"severityText"`
+	Body                 anyValue   `json:"body"`
+	Attributes           []keyValue `json:"attributes,omitempty"`
+	TraceID              hexBytes   `json:"traceId,omitempty"`
+	SpanID               hexBytes   `json:"spanId,omitempty"`
+}
+
+type keyValue struct {
+	Key   string   `json:"key"`
+	Value anyValue `json:"value"`
+}
+
+type anyValue struct {
+	StringValue *string  `json:"stringValue,omitempty"`
+	BoolValue   *bool    `json:"boolValue,omitempty"`
+	IntValue    *int64   `json:"intValue,omitempty,string"`
+	DoubleValue *float64 `json:"doubleValue,omitempty"`
+}
+
+// hexBytes are trace and span IDs, hex encoded in JSON
+type hexBytes []byte
+
+func (b hexBytes) MarshalJSON() ([]byte, error) {
+	return json.Marshal(hex.EncodeToString(b))
+}
+
+func newLogRecord(entry *logrus.Entry) *logRecord {
+	number, name := severity(entry)
+	r := &logRecord{
+		TimeUnixNano:         uint64(entry.Time.UnixNano()),
+		ObservedTimeUnixNano: uint64(time.Now().UnixNano()),
+		SeverityNumber:       number,
+		SeverityText:         name,
+		Body:                 stringValue(entry.Message),
+	}
+
+	fields := make(map[string]interface{}, len(entry.Data))
+	for k, v := range entry.Data {
+		switch k {
+		case log.LevelKey, log.HighlightKey, log.ColorKey:
+		case log.TraceIDKey:
+			r.TraceID, _ = hex.DecodeString(fmt.Sprint(v))
+		case log.SpanIDKey:
+			r.SpanID, _ = hex.DecodeString(fmt.Sprint(v))
+		default:
+			fields[k] = v
+		}
+	}
+	r.Attributes = attributes(fields)
+	if r.TraceID == nil && entry.Context != nil {
+		if sc := trace.SpanContextFromContext(entry.Context); sc.IsValid() {
+			traceID, spanID := sc.TraceID(), sc.SpanID()
+			r.TraceID, r.SpanID = traceID[:], spanID[:]
+		}
+	}
+	return r
+}
+
+// severity returns the OpenTelemetry severity and name of the level of
+// entry. Custom levels are mapped by their syslog severity, so notice is
+// INFO2 and critical ERROR2.
+func severity(entry *logrus.Entry) (int, string) {
+	if name, ok := entry.Data[log.LevelKey].(string); ok {
+		if l, err := log.LookupLevel(name); err == nil {
+			switch l.Severity {
+			case 7:
+				return 5, l.Name
+			case 6:
+				return 9, l.Name
+			case 5:
+				return 10, l.Name
+			case 4:
+				return 13, l.Name
+			case 3:
+				return 17, l.Name
+			case 2:
+				return 18, l.Name
+			case 1:
+				return 19, l.Name
+			}
+			return 21, l.Name
+		}
+	}
+	switch entry.Level {
+	case logrus.TraceLevel:
+		return 1, "trace"
+	case logrus.DebugLevel:
+		return 5, "debug"
+	case logrus.InfoLevel:
+		return 9, "info"
+	case logrus.WarnLevel:
+		return 13, "warning"
+	case logrus.ErrorLevel:
+		return 17, "error"
+	case logrus.FatalLevel:
+		return 21, "fatal"
+	}
+	return 22, "panic"
+}
+
+// attributes converts fields in key order, so exports are reproducible.
+func attributes(fields map[string]interface{}) []keyValue {
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	attrs := make([]keyValue, len(keys))
+	for i, k := range keys {
+		attrs[i] = keyValue{Key: k, Value: toAnyValue(fields[k])}
+	}
+	return attrs
+}
+
+func toAnyValue(v interface{}) anyValue {
+	if l, ok := v.(*log.LazyValue); ok {
+		v = l.Value()
+	}
+	switch v := v.(type) {
+	case string:
+		return stringValue(v)
+	case bool:
+		return anyValue{BoolValue: &v}
+	case int:
+		return intValue(int64(v))
+	case int8:
+		return intValue(int64(v))
+	case int16:
+		return intValue(int64(v))
+	case int32:
+		return intValue(int64(v))
+	case int64:
+		return intValue(v)
+	case uint8:
+		return intValue(int64(v))
+	case uint16:
+		return intValue(int64(v))
+	case uint32:
+		return intValue(int64(v))
+	case float32:
+		f := float64(v)
+		return anyValue{DoubleValue: &f}
+	case float64:
+		return anyValue{DoubleValue: &v}
+	case time.Duration:
+		return stringValue(v.String())
+	case error:
+		return stringValue(v.Error())
+	case fmt.Stringer:
+		return stringValue(v.String())
+	case map[string]interface{}, []interface{}:
+		if b, err := json.Marshal(v); err == nil {
+			return stringValue(string(b))
+		}
+	}
+	return stringValue(fmt.Sprint(v))
+}
+
+func stringValue(s string) anyValue {
+	return anyValue{StringValue: &s}
+}
+
+func intValue(n int64) anyValue {
+	return anyValue{IntValue: &n}
+}
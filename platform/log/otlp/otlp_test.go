@@ -0,0 +1,177 @@
+package otlp
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/o3labs/openpoint/platform/log"
+	logrus "github.com/sirupsen/logrus"
+)
+
+func TestHookHTTP(t *testing.T) {
+	var mu sync.Mutex
+	var requests []map[string]interface{}
+	failures := 1
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if failures > 0 {
+			failures--
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		if r.Header.Get("X-Api-Key") != "secret" {
+			t.Errorf("expected the headers to be sent, got %v", r.Header)
+		}
+		var req map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Error(err)
+		}
+		requests = append(requests, req)
+	}))
+	defer srv.Close()
+
+	h, err := NewHook(srv.URL, HTTP, map[string]interface{}{"service.name": "api"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.Headers = map[string]string{"X-Api-Key": "secret"}
+
+	logger := logrus.New()
+	logger.Out = &bytes.Buffer{}
+	logger.AddHook(h)
+	logger.WithFields(logrus.Fields{
+		"user":         42,
+		"ok":           true,
+		"err":          errors.New("timeout"),
+		log.TraceIDKey: "4bf92f3577b34da6a3ce929d0e0e4736",
+		log.SpanIDKey:  "00f067aa0ba902b7",
+	}).Warn("slow request")
+
+	if err := h.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(requests) != 1 {
+		t.Fatalf("expected 1 export after a retry, got %d", len(requests))
+	}
+	b, _ := json.Marshal(requests[0])
+	s := string(b)
+	for _, expected := range []string{
+		`{"key":"service.name","value":{"stringValue":"api"}}`,
+		`"severityNumber":13`,
+		`"severityText":"warning"`,
+		`"body":{"stringValue":"slow request"}`,
+		`{"key":"user","value":{"intValue":"42"}}`,
+		`{"key":"ok","value":{"boolValue":true}}`,
+		`{"key":"err","value":{"stringValue":"timeout"}}`,
+		`"traceId":"4bf92f3577b34da6a3ce929d0e0e4736"`,
+		`"spanId":"00f067aa0ba902b7"`,
+	} {
+		if !bytes.Contains(b, []byte(expected)) {
+			t.Errorf("expected %s in %s", expected, s)
+		}
+	}
+}
+
+func TestHookFireAfterClose(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	h, err := NewHook(srv.URL, HTTP, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger := logrus.New()
+	logger.Out = &bytes.Buffer{}
+	logger.AddHook(h)
+	if err := h.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	dropped := log.Dropped()
+	logger.Info("after close")
+	if log.Dropped() != dropped+1 {
+		t.Errorf("expected the entry to be dropped")
+	}
+	if err := h.Close(); err != nil {
+		t.Errorf("expected a second Close to succeed, got %v", err)
+	}
+}
+
+func TestHookOptions(t *testing.T) {
+	exports := make(chan int, 4)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ResourceLogs []struct {
+				ScopeLogs []struct {
+					LogRecords []json.RawMessage `json:"logRecords"`
+				} `json:"scopeLogs"`
+			} `json:"resourceLogs"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Error(err)
+		}
+		exports <- len(req.ResourceLogs[0].ScopeLogs[0].LogRecords)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	h, err := NewHook(srv.URL, HTTP, nil, WithBatchSize(2), WithBatchWait(10*time.Millisecond), WithMaxRetries(0), WithTimeout(time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+	logger := logrus.New()
+	logger.Out = &bytes.Buffer{}
+	logger.AddHook(h)
+
+	logger.Info("first")
+	logger.Info("second")
+	logger.Info("third")
+	for _, expected := range []int{2, 1} {
+		select {
+		case n := <-exports:
+			if n != expected {
+				t.Errorf("expected a batch of %d, got %d", expected, n)
+			}
+		case <-time.After(defaultBatchWait / 2):
+			t.Fatalf("expected a batch of %d before the default BatchWait", expected)
+		}
+	}
+	select {
+	case <-exports:
+		t.Error("expected no retry")
+	case <-time.After(2 * minBackoff):
+	}
+}
+
+func TestMarshalProto(t *testing.T) {
+	n := int64(300)
+	r := &logRecord{
+		TimeUnixNano:   uint64(time.Unix(1, 0).UnixNano()),
+		SeverityNumber: 9,
+		SeverityText:   "info",
+		Body:           stringValue("hi"),
+		Attributes:     []keyValue{{Key: "n", Value: anyValue{IntValue: &n}}},
+	}
+	expected := []byte{
+		0x09, 0x00, 0xca, 0x9a, 0x3b, 0x00, 0x00, 0x00, 0x00, // time_unix_nano
+		0x10, 0x09, // severity_number
+		0x1a, 0x04, 'i', 'n', 'f', 'o', // severity_text
+		0x2a, 0x04, 0x0a, 0x02, 'h', 'i', // body
+		0x32, 0x08, 0x0a, 0x01, 'n', 0x12, 0x03, 0x18, 0xac, 0x02, // attributes
+		0x59, 0, 0, 0, 0, 0, 0, 0, 0, // observed_time_unix_nano
+	}
+	if got := r.marshalProto(); !bytes.Equal(got, expected) {
+		t.Errorf("expected % x got % x", expected, got)
+	}
+}
@@ -0,0 +1,118 @@
+package otlp
+
+import (
+	"encoding/binary"
+	"math"
+)
+
+// Protobuf encoding of the OTLP logs request, written by hand to keep the
+// generated OpenTelemetry packages out of the dependencies. The field
+// numbers are those of opentelemetry/proto/logs/v1/logs.proto.
+
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+)
+
+func (r *exportRequest) marshalProto() []byte {
+	var b []byte
+	for i := range r.ResourceLogs {
+		b = appendMessage(b, 1, r.ResourceLogs[i].marshalProto())
+	}
+	return b
+}
+
+func (r *resourceLogs) marshalProto() []byte {
+	var res []byte
+	for _, kv := range r.Resource.Attributes {
+		res = appendMessage(res, 1, kv.marshalProto())
+	}
+	b := appendMessage(nil, 1, res)
+	for i := range r.ScopeLogs {
+		b = appendMessage(b, 2, r.ScopeLogs[i].marshalProto())
+	}
+	return b
+}
+
+func (s *scopeLogs) marshalProto() []byte {
+	b := appendMessage(nil, 1, appendString(nil, 1, s.Scope.Name))
+	for _, r := range s.LogRecords {
+		b = appendMessage(b, 2, r.marshalProto())
+	}
+	return b
+}
+
+func (r *logRecord) marshalProto() []byte {
+	b := appendFixed64(nil, 1, r.TimeUnixNano)
+	b = appendVarintField(b, 2, uint64(r.SeverityNumber))
+	b = appendString(b, 3, r.SeverityText)
+	b = appendMessage(b, 5, r.Body.marshalProto())
+	for _, kv := range r.Attributes {
+		b = appendMessage(b, 6, kv.marshalProto())
+	}
+	if len(r.TraceID) > 0 {
+		b = appendMessage(b, 9, r.TraceID)
+	}
+	if len(r.SpanID) > 0 {
+		b = appendMessage(b, 10, r.SpanID)
+	}
+	return appendFixed64(b, 11, r.ObservedTimeUnixNano)
+}
+
+func (kv *keyValue) marshalProto() []byte {
+	b := appendString(nil, 1, kv.Key)
+	return appendMessage(b, 2, kv.Value.marshalProto())
+}
+
+func (v *anyValue) marshalProto() []byte {
+	switch {
+	case v.StringValue != nil:
+		return appendString(nil, 1, *v.StringValue)
+	case v.BoolValue != nil:
+		n := uint64(0)
+		if *v.BoolValue {
+			n = 1
+		}
+		return appendVarintField(nil, 2, n)
+	case v.IntValue != nil:
+		return appendVarintField(nil, 3, uint64(*v.IntValue))
+	case v.DoubleValue != nil:
+		return appendFixed64(nil, 4, math.Float64bits(*v.DoubleValue))
+	}
+	return nil
+}
+
+func appendTag(b []byte, field, wire int) []byte {
+	return appendVarint(b, uint64(field)<<3|uint64(wire))
+}
+
+func appendVarint(b []byte, n uint64) []byte {
+	for n >= 0x80 {
+		b = append(b, byte(n)|0x80)
+		n >>= 7
+	}
+	return append(b, byte(n))
+}
+
+func appendVarintField(b []byte, field int, n uint64) []byte {
+	return appendVarint(appendTag(b, field, wireVarint), n)
+}
+
+func appendFixed64(b []byte, field int, n uint64) []byte {
+	b = appendTag(b, field, wireFixed64)
+	var tmp [8]byte
+	binary.LittleEndian.PutUint64(tmp[:], n)
+	return append(b, tmp[:]...)
+}
+
+// appendMessage writes a length delimited field, a message or bytes.
+func appendMessage(b []byte, field int, msg []byte) []byte {
+	b = appendTag(b, field, wireBytes)
+	b = appendVarint(b, uint64(len(msg)))
+	return append(b, msg...)
+}
+
+func appendString(b []byte, field int, s string) []byte {
+	return appendMessage(b, field, []byte(s))
+}
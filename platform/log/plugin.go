@@ -0,0 +1,122 @@
+package log
+
+import (
+	"fmt"
+	"io"
+	"sync"
+
+	logrus "github.com/sirupsen/logrus"
+)
+
+// SinkPlugin builds the writers of outputs of a type registered with
+// RegisterSinkPlugin, so other modules can add outputs, e.g. to a SIEM,
+// that configs name like the built-in ones. Writers implementing io.Closer
+// are closed when the config is replaced or on Shutdown.
+type SinkPlugin interface {
+	NewSink(o OutputConfig) (io.Writer, error)
+}
+
+// SinkPluginFunc is a function used as a SinkPlugin
+type SinkPluginFunc func(o OutputConfig) (io.Writer, error)
+
+// NewSink calls f(o)
+func (f SinkPluginFunc) NewSink(o OutputConfig) (io.Writer, error) {
+	return f(o)
+}
+
+// FormatterPlugin builds the formatters registered with
+// RegisterFormatterPlugin.
+type FormatterPlugin interface {
+	NewFormatter(o OutputConfig) (logrus.Formatter, error)
+}
+
+// FormatterPluginFunc is a function used as a FormatterPlugin
+type FormatterPluginFunc func(o OutputConfig) (logrus.Formatter, error)
+
+// NewFormatter calls f(o)
+func (f FormatterPluginFunc) NewFormatter(o OutputConfig) (logrus.Formatter, error) {
+	return f(o)
+}
+
+var (
+	pluginsMu        sync.RWMutex
+	sinkPlugins      = map[string]*SinkPlugin{}
+	formatterPlugins = map[string]*FormatterPlugin{}
+
+	builtinSinks      = []string{"", "stdout", "stderr", "split", "file", "archive", "syslog", "gelf", "net", "journald"}
+	builtinFormatters = []string{"", "text", "json", "logfmt", "dev", "ecs", "gelf", "syslog", "cloudlogging", "journald", "combined", "w3c", "template"}
+)
+
+// RegisterSinkPlugin makes outputs of type name build their writer with p,
+// usually from the init function of the module providing it:
+//
+//	func init() {
+//		log.RegisterSinkPlugin("siem", log.SinkPluginFunc(newSIEMWriter))
+//	}
+//
+// The OutputConfig holds the settings of the output, with the settings
+// specific to the plugin in Options. It returns the function removing the
+// plugin and panics if name is taken.
+func RegisterSinkPlugin(name string, p SinkPlugin) func() {
+	pluginsMu.Lock()
+	defer pluginsMu.Unlock()
+	if _, ok := sinkPlugins[name]; ok || contains(builtinSinks, name) {
+		panic(fmt.Sprintf("log: output %q registered twice", name))
+	}
+	e := &p
+	sinkPlugins[name] = e
+	return func() {
+		pluginsMu.Lock()
+		if sinkPlugins[name] == e {
+			delete(sinkPlugins, name)
+		}
+		pluginsMu.Unlock()
+	}
+}
+
+// RegisterFormatterPlugin makes formatter name of outputs build their
+// formatter with p. It returns the function removing the plugin and panics
+// if name is taken.
+func RegisterFormatterPlugin(name string, p FormatterPlugin) func() {
+	pluginsMu.Lock()
+	defer pluginsMu.Unlock()
+	if _, ok := formatterPlugins[name]; ok || contains(builtinFormatters, name) {
+		panic(fmt.Sprintf("log: formatter %q registered twice", name))
+	}
+	e := &p
+	formatterPlugins[name] = e
+	return func() {
+		pluginsMu.Lock()
+		if formatterPlugins[name] == e {
+			delete(formatterPlugins, name)
+		}
+		pluginsMu.Unlock()
+	}
+}
+
+func sinkPlugin(name string) (SinkPlugin, bool) {
+	pluginsMu.RLock()
+	defer pluginsMu.RUnlock()
+	if e, ok := sinkPlugins[name]; ok {
+		return *e, true
+	}
+	return nil, false
+}
+
+func formatterPlugin(name string) (FormatterPlugin, bool) {
+	pluginsMu.RLock()
+	defer pluginsMu.RUnlock()
+	if e, ok := formatterPlugins[name]; ok {
+		return *e, true
+	}
+	return nil, false
+}
+
+func contains(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
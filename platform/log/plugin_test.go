@@ -0,0 +1,55 @@
+package log
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	logrus "github.com/sirupsen/logrus"
+)
+
+type pluginSink struct {
+	bytes.Buffer
+	tenant string
+	closed bool
+}
+
+func (s *pluginSink) Close() error {
+	s.closed = true
+	return nil
+}
+
+func TestPlugins(t *testing.T) {
+	sink := &pluginSink{}
+	defer RegisterSinkPlugin("siem", SinkPluginFunc(func(o OutputConfig) (io.Writer, error) {
+		sink.tenant = o.Options["tenant"]
+		return sink, nil
+	}))()
+	defer RegisterFormatterPlugin("cef", FormatterPluginFunc(func(o OutputConfig) (logrus.Formatter, error) {
+		return &TemplateFormatter{}, nil
+	}))()
+
+	p := &pipeline{}
+	d, err := (&Config{}).buildOutput(p, OutputConfig{Type: "siem", Formatter: "cef", Options: map[string]string{"tenant": "acme"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Writer != sink || sink.tenant != "acme" {
+		t.Errorf("expected the plugin sink with its options, got %T %q", d.Writer, sink.tenant)
+	}
+	if _, ok := d.Formatter.(*TemplateFormatter); !ok {
+		t.Errorf("expected the plugin formatter, got %T", d.Formatter)
+	}
+	if err := p.Close(); err != nil || !sink.closed {
+		t.Errorf("expected the plugin sink to be closed, got %v", err)
+	}
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Errorf("expected registering a built-in output to panic")
+			}
+		}()
+		RegisterSinkPlugin("file", SinkPluginFunc(nil))
+	}()
+}
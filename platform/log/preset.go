@@ -0,0 +1,98 @@
+package log
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/o3labs/openpoint/platform/config"
+)
+
+// Development is the config of a service run on a laptop: debug entries on
+// stderr with the dev formatter, colored on terminals, with their callers.
+func Development() *Config {
+	return &Config{
+		Level:        "debug",
+		Formatter:    "dev",
+		Color:        "auto",
+		ReportCaller: true,
+		Outputs:      []OutputConfig{{Type: "stderr"}},
+	}
+}
+
+// Production is the config of a deployed service: info entries on stdout as
+// a line of JSON each, for the log agent of the platform to collect, without
+// colors or callers.
+func Production() *Config {
+	return &Config{
+		Level:     "info",
+		Formatter: "json",
+		Color:     "never",
+		Outputs:   []OutputConfig{{Type: "stdout"}},
+	}
+}
+
+// CI is the config of tests and jobs run by a CI server: debug entries on
+// stdout as text with full timestamps and their callers, colored only with
+// CLICOLOR_FORCE set, as CI servers don't run a terminal but many of them
+// render colors.
+func CI() *Config {
+	return &Config{
+		Level:        "debug",
+		Formatter:    "text",
+		Color:        "auto",
+		ReportCaller: true,
+		Outputs:      []OutputConfig{{Type: "stdout"}},
+	}
+}
+
+// Preset returns the config of an environment: development, dev or local,
+// production, prod or staging, and ci or test.
+func Preset(env string) (*Config, error) {
+	switch strings.ToLower(env) {
+	case "development", "dev", config.LocalEnv:
+		return Development(), nil
+	case config.ProductionEnv, "prod", config.StagingEnv:
+		return Production(), nil
+	case "ci", "test":
+		return CI(), nil
+	}
+	return nil, fmt.Errorf("log: unknown environment %q", env)
+}
+
+// PresetFromEnv returns the preset of APP_ENV, or of the platform
+// environment name when it isn't set, with the overrides of ApplyEnv
+// applied. Without either it returns Production, so a service missing its
+// environment doesn't log debug entries.
+func PresetFromEnv() (*Config, error) {
+	env := os.Getenv("APP_ENV")
+	if env == "" {
+		env = config.Env.Name
+	}
+	if env == "" {
+		env = config.ProductionEnv
+	}
+	c, err := Preset(env)
+	if err != nil {
+		return nil, err
+	}
+	c.ApplyEnv()
+	return c, nil
+}
+
+// Setup builds the preset of the environment, see PresetFromEnv, in place of
+// the setup every service repeats in main:
+//
+//	closer, err := log.Setup()
+//	if err != nil {
+//		panic(err)
+//	}
+//	defer closer.Close()
+func Setup() (io.Closer, error) {
+	c, err := PresetFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	return c.Build()
+}
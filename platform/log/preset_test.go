@@ -0,0 +1,58 @@
+package log
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestPreset(t *testing.T) {
+	os.Setenv("APP_ENV", "ci")
+	defer os.Unsetenv("APP_ENV")
+	os.Setenv("LOG_LEVEL", "warning")
+	defer os.Unsetenv("LOG_LEVEL")
+	c, err := PresetFromEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Formatter != "text" || c.Level != "warning" {
+		t.Errorf("expected the ci preset with the LOG_LEVEL override, got %+v", c)
+	}
+
+	d, err := c.buildOutput(&pipeline{}, c.Outputs[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f, ok := d.Formatter.(*ChannelTextFormatter); !ok || !f.ReportCaller || f.ForceColors || f.DisableColors {
+		t.Errorf("unexpected formatter %+v", d.Formatter)
+	}
+	c = Production()
+	d, err = c.buildOutput(&pipeline{}, c.Outputs[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := d.Formatter.(*ChannelJSONFormatter); !ok {
+		t.Errorf("expected the production preset to write JSON, got %T", d.Formatter)
+	}
+
+	c = Development()
+	d, err = c.buildOutput(&pipeline{}, c.Outputs[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := d.Formatter.Format(benchmarkEntry(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(b), "preset_test.go:") {
+		t.Errorf("expected the development preset to report the caller, got %q", b)
+	}
+
+	c.Color = "sometimes"
+	if _, err := c.buildOutput(&pipeline{}, c.Outputs[0]); err == nil {
+		t.Errorf("expected an error for an unknown color")
+	}
+	if _, err := Preset("qa"); err == nil {
+		t.Errorf("expected an error for an unknown environment")
+	}
+}
@@ -0,0 +1,81 @@
+package log
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	logrus "github.com/sirupsen/logrus"
+)
+
+type rateLimitKey struct {
+	channel string
+	level   logrus.Level
+}
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// RateLimitFormatter wraps a formatter with a token bucket per channel and
+// level, so a burst of entries can't saturate a disk or network sink.
+// Entries over the limit are dropped, Format runs under the logger lock and
+// can't wait for a token.
+type RateLimitFormatter struct {
+	Formatter logrus.Formatter
+
+	// Rate of entries per second let through per channel and level
+	Rate float64
+
+	// Burst is the bucket size, the entries let through at once
+	Burst int
+
+	mu      sync.Mutex
+	buckets map[rateLimitKey]*tokenBucket
+	dropped uint64
+}
+
+// Format renders a single log entry, or nothing when it is over the limit
+func (f *RateLimitFormatter) Format(entry *logrus.Entry) ([]byte, error) {
+	channel, _ := entry.Data[ChannelKey].(string)
+	key := rateLimitKey{channel: channel, level: entry.Level}
+
+	if !f.allow(key) {
+		atomic.AddUint64(&f.dropped, 1)
+		recordDropped(1)
+		return nil, nil
+	}
+	return f.Formatter.Format(entry)
+}
+
+// Dropped returns the number of entries this formatter dropped.
+func (f *RateLimitFormatter) Dropped() uint64 {
+	return atomic.LoadUint64(&f.dropped)
+}
+
+// allow takes a token from the bucket of key, false when it is empty.
+func (f *RateLimitFormatter) allow(key rateLimitKey) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	t := now()
+	if f.buckets == nil {
+		f.buckets = map[rateLimitKey]*tokenBucket{}
+	}
+	b, ok := f.buckets[key]
+	if !ok {
+		b = &tokenBucket{tokens: float64(f.Burst), last: t}
+		f.buckets[key] = b
+	}
+	b.tokens += t.Sub(b.last).Seconds() * f.Rate
+	if b.tokens > float64(f.Burst) {
+		b.tokens = float64(f.Burst)
+	}
+	b.last = t
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
@@ -0,0 +1,31 @@
+package log
+
+import (
+	"testing"
+
+	logrus "github.com/sirupsen/logrus"
+)
+
+func TestRateLimitFormatter(t *testing.T) {
+	f := &RateLimitFormatter{
+		Formatter: &ChannelTextFormatter{DisableTimestamp: true},
+		Rate:      0.001,
+		Burst:     3,
+	}
+	written := 0
+	for i := 0; i < 10; i++ {
+		entry := logrus.NewEntry(logrus.StandardLogger())
+		entry.Level = logrus.InfoLevel
+		entry.Message = "tick"
+		b, err := f.Format(entry)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(b) > 0 {
+			written++
+		}
+	}
+	if written != 3 || f.Dropped() != 7 {
+		t.Errorf("expected the burst of 3 through and 7 dropped, got %d and %d", written, f.Dropped())
+	}
+}
@@ -0,0 +1,181 @@
+// Package receiver accepts log entries from sibling processes, such as
+// sidecars not written in Go, over a Unix socket and logs them through the
+// channels of the log package, so they are formatted, routed and shipped
+// by the same Config as the entries of the service:
+//
+//	r, err := receiver.Listen("/run/openpoint/log.sock")
+//	defer r.Close()
+//
+// Clients write NDJSON entries, one per line, with the keys the json
+// formatter writes: {"time":"...","level":"warning","msg":"...","channel":"proxy"}.
+// Entries without a channel go to the standard logger, lines that aren't
+// JSON objects are logged as info messages.
+package receiver
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+
+	"github.com/o3labs/openpoint/platform/log"
+	"github.com/o3labs/openpoint/platform/log/logquery"
+	logrus "github.com/sirupsen/logrus"
+)
+
+const defaultMaxLineSize = 1 << 20
+
+// Receiver accepts connections on a Unix socket and logs the entries they
+// send.
+type Receiver struct {
+	// Fields added to every received entry that doesn't have them, e.g.
+	// the name of the sidecar. Set them before clients connect.
+	Fields logrus.Fields
+
+	// MaxLineSize is the longest entry accepted, a connection sending a
+	// longer one is closed. Defaults to 1MB.
+	MaxLineSize int
+
+	listener net.Listener
+
+	mu     sync.Mutex
+	conns  map[net.Conn]bool
+	closed bool
+	wg     sync.WaitGroup
+
+	unregister func()
+}
+
+// Listen listens on the Unix socket at path, replacing a stale socket left
+// by a process that exited, and accepts connections until Close. The
+// receiver is closed by log.Shutdown before the outputs.
+func Listen(path string) (*Receiver, error) {
+	if fi, err := os.Lstat(path); err == nil && fi.Mode()&os.ModeSocket != 0 {
+		if conn, err := net.Dial("unix", path); err == nil {
+			conn.Close()
+			return nil, fmt.Errorf("receiver: %s is in use", path)
+		}
+		os.Remove(path)
+	}
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, fmt.Errorf("receiver: failed to listen on %s, %v", path, err)
+	}
+	return Serve(l), nil
+}
+
+// Serve accepts connections on l until Close.
+func Serve(l net.Listener) *Receiver {
+	r := &Receiver{listener: l, conns: map[net.Conn]bool{}}
+	r.unregister = log.RegisterSink(r, log.SinkQueue)
+	r.wg.Add(1)
+	go r.accept()
+	return r
+}
+
+// Addr returns the address the receiver listens on.
+func (r *Receiver) Addr() net.Addr {
+	return r.listener.Addr()
+}
+
+func (r *Receiver) accept() {
+	defer r.wg.Done()
+	for {
+		conn, err := r.listener.Accept()
+		if err != nil {
+			r.mu.Lock()
+			closed := r.closed
+			r.mu.Unlock()
+			if !closed {
+				log.Reportf("Failed to accept log connection because %+v", err)
+			}
+			return
+		}
+
+		r.mu.Lock()
+		if r.closed {
+			r.mu.Unlock()
+			conn.Close()
+			return
+		}
+		r.conns[conn] = true
+		r.wg.Add(1)
+		r.mu.Unlock()
+		go r.serve(conn)
+	}
+}
+
+func (r *Receiver) serve(conn net.Conn) {
+	defer r.wg.Done()
+	defer func() {
+		r.mu.Lock()
+		delete(r.conns, conn)
+		r.mu.Unlock()
+		conn.Close()
+	}()
+
+	max := r.MaxLineSize
+	if max <= 0 {
+		max = defaultMaxLineSize
+	}
+	s := bufio.NewScanner(conn)
+	s.Buffer(make([]byte, 0, 4096), max)
+	for s.Scan() {
+		r.log(s.Bytes())
+	}
+	if err := s.Err(); err == bufio.ErrTooLong {
+		log.Reportf("Failed to receive log entry because it is longer than %d bytes", max)
+	}
+}
+
+// log logs one received line.
+func (r *Receiver) log(line []byte) {
+	entry, ok := logquery.ParseEntry(line)
+	if !ok {
+		if len(line) == 0 {
+			return
+		}
+		entry = &logrus.Entry{Level: logrus.InfoLevel, Message: string(line), Data: logrus.Fields{}}
+	}
+	for k, v := range r.Fields {
+		if _, ok := entry.Data[k]; !ok {
+			entry.Data[k] = v
+		}
+	}
+
+	var e *logrus.Entry
+	if channel, ok := entry.Data[log.ChannelKey].(string); ok && channel != "" {
+		delete(entry.Data, log.ChannelKey)
+		e = log.Channel(channel).WithFields(entry.Data)
+	} else {
+		e = logrus.WithFields(entry.Data)
+	}
+	if !entry.Time.IsZero() {
+		e = e.WithTime(entry.Time)
+	}
+	if entry.Level == logrus.PanicLevel {
+		// a panic of the sidecar must not panic the service
+		defer func() { recover() }()
+	}
+	e.Log(entry.Level, entry.Message)
+}
+
+// Close stops accepting connections, closes those that are open and waits
+// for the entry being logged of each.
+func (r *Receiver) Close() error {
+	r.unregister()
+	r.mu.Lock()
+	if r.closed {
+		r.mu.Unlock()
+		return nil
+	}
+	r.closed = true
+	err := r.listener.Close()
+	for conn := range r.conns {
+		conn.Close()
+	}
+	r.mu.Unlock()
+	r.wg.Wait()
+	return err
+}
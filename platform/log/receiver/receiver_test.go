@@ -0,0 +1,82 @@
+package receiver
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/o3labs/openpoint/platform/log"
+	logrus "github.com/sirupsen/logrus"
+)
+
+type syncBuffer struct {
+	mu sync.Mutex
+	b  bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.b.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.b.String()
+}
+
+func TestReceiver(t *testing.T) {
+	dir, err := ioutil.TempDir("", "receiver")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "log.sock")
+
+	b := &syncBuffer{}
+	proxy := log.Channel("proxy")
+	proxy.SetOutput(b)
+	proxy.SetFormatter(&log.ChannelTextFormatter{DisableTimestamp: true})
+	proxy.SetLevel(logrus.InfoLevel)
+
+	r, err := Listen(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	r.Fields = logrus.Fields{"sidecar": "envoy"}
+	if _, err := Listen(path); err == nil {
+		t.Errorf("expected a socket in use to fail")
+	}
+
+	conn, err := net.Dial("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.Write([]byte(`{"time":"2024-03-01T12:00:00Z","level":"debug","msg":"filtered","channel":"proxy"}` + "\n"))
+	conn.Write([]byte(`{"time":"2024-03-01T12:00:01Z","level":"warning","msg":"upstream reset","channel":"proxy","status":503}` + "\n"))
+	conn.Close()
+
+	expected := `level=warning msg="upstream reset" channel=proxy sidecar=envoy status=503` + "\n"
+	deadline := time.Now().Add(2 * time.Second)
+	for b.String() == "" && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if b.String() != expected {
+		t.Errorf("expected %q got %q", expected, b.String())
+	}
+
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := net.Dial("unix", path); err == nil || strings.Contains(err.Error(), "in use") {
+		t.Errorf("expected the receiver to be closed")
+	}
+}
@@ -0,0 +1,66 @@
+package log
+
+import (
+	"context"
+	"fmt"
+
+	logrus "github.com/sirupsen/logrus"
+)
+
+// PanicKey is the field holding the value a recovered panic was raised with.
+const PanicKey = "panic"
+
+// RecoverAndLog recovers a panic of the calling goroutine and logs the panic
+// value and stack trace along with the fields of the logger in ctx, see
+// FromContext. It must be deferred directly:
+//
+//	defer log.RecoverAndLog(ctx, logrus.ErrorLevel, false)
+//
+// At FatalLevel the process exits once the entry is written. Otherwise
+// repanic raises the panic again with the recovered value after logging it.
+func RecoverAndLog(ctx context.Context, level logrus.Level, repanic bool) {
+	v := recover()
+	if v == nil {
+		return
+	}
+	LogPanic(ctx, v, level)
+	if repanic {
+		panic(v)
+	}
+}
+
+// LogPanic logs v, a value returned by recover, for callers that need to
+// recover the panic themselves. It doesn't panic at PanicLevel.
+func LogPanic(ctx context.Context, v interface{}, level logrus.Level) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	entry := FromContext(ctx).WithFields(logrus.Fields{
+		PanicKey: fmt.Sprint(v),
+		StackKey: captureStack(defaultStackFrames, nil),
+	})
+	if err, ok := v.(error); ok {
+		entry = entry.WithField(logrus.ErrorKey, err)
+	}
+	switch level {
+	case logrus.FatalLevel:
+		entry.Fatal("recovered panic")
+	case logrus.PanicLevel:
+		logWithoutPanic(entry)
+	default:
+		entry.Log(level, "recovered panic")
+	}
+}
+
+// logWithoutPanic writes entry at PanicLevel and recovers the panic logrus
+// raises with the entry afterwards.
+func logWithoutPanic(entry *logrus.Entry) {
+	defer func() {
+		if v := recover(); v != nil {
+			if _, ok := v.(*logrus.Entry); !ok {
+				panic(v)
+			}
+		}
+	}()
+	entry.Log(logrus.PanicLevel, "recovered panic")
+}
@@ -0,0 +1,74 @@
+package log
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	logrus "github.com/sirupsen/logrus"
+)
+
+// recovered runs f and returns the value it panicked with.
+func recovered(f func()) (v interface{}) {
+	defer func() {
+		v = recover()
+	}()
+	f()
+	return nil
+}
+
+func TestRecoverAndLog(t *testing.T) {
+	failure := errors.New("nil map")
+	for _, test := range []struct {
+		level   logrus.Level
+		repanic bool
+	}{
+		{logrus.ErrorLevel, false},
+		{logrus.ErrorLevel, true},
+		{logrus.PanicLevel, false},
+		{logrus.PanicLevel, true},
+	} {
+		w := &collectWriter{}
+		logger := logrus.New()
+		logger.Out = w
+		logger.Formatter = &ChannelJSONFormatter{}
+		ctx := NewContext(context.Background(), logrus.NewEntry(logger).WithField("request_id", "r1"))
+
+		v := recovered(func() {
+			defer RecoverAndLog(ctx, test.level, test.repanic)
+			panic(failure)
+		})
+		if test.repanic && v != failure {
+			t.Errorf("%v: expected the original value raised again, got %v", test.level, v)
+		}
+		if !test.repanic && v != nil {
+			t.Errorf("%v: expected the panic recovered, got %v", test.level, v)
+		}
+
+		if len(w.lines) != 1 {
+			t.Fatalf("%v: expected 1 entry, got %d", test.level, len(w.lines))
+		}
+		for _, expected := range []string{`"level":"` + test.level.String() + `"`, `"panic":"nil map"`, `"request_id":"r1"`, `"stack":`} {
+			if !strings.Contains(w.lines[0], expected) {
+				t.Errorf("%v: expected %s in %s", test.level, expected, w.lines[0])
+			}
+		}
+	}
+}
+
+func TestLogPanic(t *testing.T) {
+	w := &collectWriter{}
+	logger := logrus.New()
+	logger.Out = w
+	logger.Formatter = &ChannelJSONFormatter{}
+	ctx := NewContext(context.Background(), logrus.NewEntry(logger))
+
+	if v := recovered(func() { LogPanic(ctx, "boom", logrus.PanicLevel) }); v != nil {
+		t.Errorf("expected LogPanic not to panic at PanicLevel, got %v", v)
+	}
+	LogPanic(ctx, "boom", logrus.WarnLevel)
+	if len(w.lines) != 2 || !strings.Contains(w.lines[0], `"level":"panic"`) || !strings.Contains(w.lines[1], `"level":"warning"`) {
+		t.Errorf("expected both entries written, got %q", w.lines)
+	}
+}
@@ -0,0 +1,180 @@
+package log
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	logrus "github.com/sirupsen/logrus"
+)
+
+const RedactedPlaceholder = "[REDACTED]"
+
+var (
+	// CreditCardPattern matches 13 to 19 digit card numbers, optionally
+	// grouped with spaces or dashes. RedactHook only masks the matches
+	// passing the Luhn check, so timestamps and order IDs are kept.
+	CreditCardPattern = regexp.MustCompile(`\b(?:\d[ -]?){12,18}\d\b`)
+
+	EmailPattern = regexp.MustCompile(`[a-zA-Z0-9._%+\-]+@[a-zA-Z0-9.\-]+\.[a-zA-Z]{2,}`)
+)
+
+// RedactHook scrubs secrets and personal data from entries before they are
+// formatted. Fields named in Fields are replaced entirely, matches of Patterns
+// are replaced inside the message and string values, down into maps and
+// slices, which are copied rather than changed.
+type RedactHook struct {
+	// Fields whose values are always masked, matched case-insensitively
+	Fields []string
+
+	Patterns []*regexp.Regexp
+
+	// Placeholder replaces redacted values. Defaults to RedactedPlaceholder.
+	Placeholder string
+}
+
+// NewRedactHook returns a hook masking password, token and authorization
+// fields as well as card numbers and email addresses.
+func NewRedactHook() *RedactHook {
+	return &RedactHook{
+		Fields:   []string{"password", "token", "authorization"},
+		Patterns: []*regexp.Regexp{CreditCardPattern, EmailPattern},
+	}
+}
+
+func (h *RedactHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (h *RedactHook) Fire(entry *logrus.Entry) error {
+	entry.Message = h.redactString(entry.Message)
+	for k, v := range entry.Data {
+		if h.isRedactedField(k) {
+			entry.Data[k] = h.placeholder()
+			continue
+		}
+		entry.Data[k] = h.redactValue(v)
+	}
+	return nil
+}
+
+// redactValue returns v with its matches of Patterns masked, and the
+// redacted fields of maps.
+func (h *RedactHook) redactValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case string:
+		return h.redactString(v)
+	case *LazyValue:
+		// redact when a formatter computes it, not here
+		return Lazy(func() interface{} {
+			return h.redactValue(v.Value())
+		})
+	case error:
+		if s := h.redactString(v.Error()); s != v.Error() {
+			return s
+		}
+	case fmt.Stringer:
+		if s := h.redactString(v.String()); s != v.String() {
+			return s
+		}
+	case map[string]interface{}:
+		return h.redactMap(v)
+	case logrus.Fields:
+		return logrus.Fields(h.redactMap(v))
+	case map[string]string:
+		redacted := make(map[string]string, len(v))
+		for k, s := range v {
+			if h.isRedactedField(k) {
+				redacted[k] = h.placeholder()
+			} else {
+				redacted[k] = h.redactString(s)
+			}
+		}
+		return redacted
+	case []interface{}:
+		redacted := make([]interface{}, len(v))
+		for i, e := range v {
+			redacted[i] = h.redactValue(e)
+		}
+		return redacted
+	case []string:
+		redacted := make([]string, len(v))
+		for i, s := range v {
+			redacted[i] = h.redactString(s)
+		}
+		return redacted
+	}
+	return v
+}
+
+func (h *RedactHook) redactMap(m map[string]interface{}) map[string]interface{} {
+	redacted := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		if h.isRedactedField(k) {
+			redacted[k] = h.placeholder()
+		} else {
+			redacted[k] = h.redactValue(v)
+		}
+	}
+	return redacted
+}
+
+func (h *RedactHook) isRedactedField(key string) bool {
+	for _, f := range h.Fields {
+		if strings.EqualFold(f, key) {
+			return true
+		}
+	}
+	return false
+}
+
+// Redact returns s with the matches of Patterns masked, for values logged
+// outside of entry fields, such as query arguments.
+func (h *RedactHook) Redact(s string) string {
+	return h.redactString(s)
+}
+
+func (h *RedactHook) redactString(s string) string {
+	for _, p := range h.Patterns {
+		if p == CreditCardPattern {
+			s = p.ReplaceAllStringFunc(s, func(m string) string {
+				if luhnValid(m) {
+					return h.placeholder()
+				}
+				return m
+			})
+			continue
+		}
+		s = p.ReplaceAllLiteralString(s, h.placeholder())
+	}
+	return s
+}
+
+// luhnValid reports whether the digits of s pass the Luhn checksum of card
+// numbers, ignoring spaces and dashes.
+func luhnValid(s string) bool {
+	sum, double := 0, false
+	for i := len(s) - 1; i >= 0; i-- {
+		c := s[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+		d := int(c - '0')
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+	return sum%10 == 0
+}
+
+func (h *RedactHook) placeholder() string {
+	if h.Placeholder == "" {
+		return RedactedPlaceholder
+	}
+	return h.Placeholder
+}
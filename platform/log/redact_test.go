@@ -0,0 +1,99 @@
+package log
+
+import (
+	"errors"
+	"testing"
+
+	logrus "github.com/sirupsen/logrus"
+)
+
+func TestRedactHook(t *testing.T) {
+	h := NewRedactHook()
+	entry := logrus.NewEntry(logrus.StandardLogger()).WithFields(logrus.Fields{
+		"Password":   "hunter2",
+		"card":       "paid with 4111 1111 1111 1111",
+		"order":      "order 1234567890123 placed at 1500000000000",
+		"contact":    errors.New("mail to jane@example.com failed"),
+		"attempts":   3,
+		"request":    map[string]interface{}{"token": "abc", "email": "jane@example.com", "tags": []string{"5500-0000-0000-0004"}},
+		"headers":    map[string]string{"Authorization": "Bearer abc", "Accept": "*/*"},
+		"recipients": []interface{}{"jane@example.com", 42},
+	})
+	entry.Message = "charged 4111-1111-1111-1111 for jane@example.com"
+
+	nested := entry.Data["request"].(map[string]interface{})
+	if err := h.Fire(entry); err != nil {
+		t.Fatal(err)
+	}
+
+	if entry.Message != "charged [REDACTED] for [REDACTED]" {
+		t.Errorf("expected the message redacted, got %q", entry.Message)
+	}
+	expected := map[string]interface{}{
+		"Password": RedactedPlaceholder,
+		"card":     "paid with [REDACTED]",
+		"order":    "order 1234567890123 placed at 1500000000000",
+		"contact":  "mail to [REDACTED] failed",
+		"attempts": 3,
+	}
+	for k, v := range expected {
+		if entry.Data[k] != v {
+			t.Errorf("expected %s to be %v, got %v", k, v, entry.Data[k])
+		}
+	}
+
+	request := entry.Data["request"].(map[string]interface{})
+	if request["token"] != RedactedPlaceholder || request["email"] != RedactedPlaceholder {
+		t.Errorf("expected the nested fields redacted, got %v", request)
+	}
+	if tags := request["tags"].([]string); tags[0] != RedactedPlaceholder {
+		t.Errorf("expected the nested card number redacted, got %v", tags)
+	}
+	if nested["token"] != "abc" {
+		t.Errorf("expected the map of the caller left as it was, got %v", nested)
+	}
+	headers := entry.Data["headers"].(map[string]string)
+	if headers["Authorization"] != RedactedPlaceholder || headers["Accept"] != "*/*" {
+		t.Errorf("expected the authorization header redacted, got %v", headers)
+	}
+	recipients := entry.Data["recipients"].([]interface{})
+	if recipients[0] != RedactedPlaceholder || recipients[1] != 42 {
+		t.Errorf("expected the recipients redacted, got %v", recipients)
+	}
+}
+
+func TestRedactHookLazy(t *testing.T) {
+	calls := 0
+	entry := logrus.NewEntry(logrus.StandardLogger()).WithField("owner", Lazy(func() interface{} {
+		calls++
+		return "jane@example.com"
+	}))
+	if err := NewRedactHook().Fire(entry); err != nil {
+		t.Fatal(err)
+	}
+	if calls != 0 {
+		t.Errorf("expected the value not computed by the hook")
+	}
+
+	lazy, ok := entry.Data["owner"].(*LazyValue)
+	if !ok {
+		t.Fatalf("expected the field to stay lazy, got %T", entry.Data["owner"])
+	}
+	if lazy.Value() != "[REDACTED]" || calls != 1 {
+		t.Errorf("expected the computed value redacted, got %v", lazy.Value())
+	}
+}
+
+func TestLuhnValid(t *testing.T) {
+	for s, valid := range map[string]bool{
+		"4111111111111111":    true,
+		"4111 1111 1111 1111": true,
+		"5500-0000-0000-0004": true,
+		"4111111111111112":    false,
+		"1500000000000":       false,
+	} {
+		if luhnValid(s) != valid {
+			t.Errorf("expected luhnValid(%q) to be %v", s, valid)
+		}
+	}
+}
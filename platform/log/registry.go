@@ -0,0 +1,250 @@
+package log
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+
+	logrus "github.com/sirupsen/logrus"
+)
+
+// ChannelKey is the field every entry written through a named channel carries.
+const ChannelKey = "channel"
+
+// Logger is a named log channel with its own level, formatter and output.
+//
+// Names separated by dots form a tree: http.client and http.server are
+// children of http. A channel without a level of its own logs at the level
+// of its nearest ancestor that has one, the level of SetLevel otherwise, and
+// a new channel starts with the formatter, output and hooks of its nearest
+// registered ancestor or, after Config.Build, with the outputs configured
+// for it or its nearest configured ancestor.
+type Logger struct {
+	name  string
+	level logrus.Level
+
+	// levelSet is whether the channel has a level of its own rather than
+	// inheriting one
+	levelSet bool
+
+	logger *logrus.Logger
+}
+
+var (
+	channels   = map[string]*Logger{}
+	channelsMu sync.Mutex
+)
+
+// Channel returns the logger registered under name, creating it on first use.
+// A new channel starts with the level set by SetLevel and the formatter and
+// output of the standard logger, so call it after Init when the defaults matter.
+// After Config.Build it starts with the configured outputs.
+func Channel(name string) *Logger {
+	channelsMu.Lock()
+	defer channelsMu.Unlock()
+
+	if l, ok := channels[name]; ok {
+		return l
+	}
+	std := logrus.StandardLogger()
+	l := &Logger{
+		name: name,
+		logger: &logrus.Logger{
+			Out:       std.Out,
+			Formatter: std.Formatter,
+			Hooks:     make(logrus.LevelHooks),
+		},
+	}
+	if parent := parentChannel(name); parent != nil {
+		l.logger.Out = parent.logger.Out
+		l.logger.Formatter = parent.logger.Formatter
+		for level, hooks := range parent.logger.Hooks {
+			l.logger.Hooks[level] = append([]logrus.Hook(nil), hooks...)
+		}
+	}
+	if outputs := configOutputsFor(name); outputs != nil {
+		setOutputs(l.logger, outputs)
+	}
+	l.applyLevel()
+	channels[name] = l
+	return l
+}
+
+// parentName returns the name of the parent of the channel name, false for
+// top level channels.
+func parentName(name string) (string, bool) {
+	i := strings.LastIndexByte(name, '.')
+	if i < 0 {
+		return "", false
+	}
+	return name[:i], true
+}
+
+// parentChannel returns the nearest registered ancestor of the channel
+// name, nil when there is none. channelsMu must be held.
+func parentChannel(name string) *Logger {
+	for n, ok := parentName(name); ok; n, ok = parentName(n) {
+		if l, registered := channels[n]; registered {
+			return l
+		}
+	}
+	return nil
+}
+
+func lookupChannel(name string) (*Logger, bool) {
+	channelsMu.Lock()
+	defer channelsMu.Unlock()
+	l, ok := channels[name]
+	return l, ok
+}
+
+// Channels returns the names of all registered channels in sorted order.
+func Channels() []string {
+	channelsMu.Lock()
+	defer channelsMu.Unlock()
+
+	names := make([]string, 0, len(channels))
+	for name := range channels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (l *Logger) Name() string {
+	return l.name
+}
+
+// SetLevel sets the level of the channel and of its descendants that don't
+// have a level of their own.
+func (l *Logger) SetLevel(level logrus.Level) {
+	levelsMu.Lock()
+	l.level = level
+	l.levelSet = true
+	levelsMu.Unlock()
+	applyLevels()
+}
+
+// ResetLevel makes the channel inherit its level again.
+func (l *Logger) ResetLevel() {
+	levelsMu.Lock()
+	l.levelSet = false
+	levelsMu.Unlock()
+	applyLevels()
+}
+
+// GetLevel returns the level the channel logs at, taking SetLevelFor
+// overrides into account.
+func (l *Logger) GetLevel() logrus.Level {
+	return l.logger.GetLevel()
+}
+
+// applyLevel sets the level of the logrus logger, see channelLevel.
+// channelsMu must be held.
+func (l *Logger) applyLevel() {
+	levelsMu.RLock()
+	level := channelLevel(l.name)
+	levelsMu.RUnlock()
+	l.logger.SetLevel(level)
+}
+
+// channelLevel returns the level of the channel name: the level of the
+// longest SetLevelFor pattern matching it or the level set on it, then the
+// same for each of its ancestors, and the global level last. channelsMu and
+// levelsMu must be held.
+func channelLevel(name string) logrus.Level {
+	for n, ok := name, true; ok; n, ok = parentName(n) {
+		if level, matched := matchLevel(n); matched {
+			return level
+		}
+		if l, registered := channels[n]; registered && l.levelSet {
+			return l.level
+		}
+	}
+	return globalLevel
+}
+
+func (l *Logger) SetFormatter(formatter logrus.Formatter) {
+	l.logger.SetFormatter(formatter)
+}
+
+func (l *Logger) SetOutput(w io.Writer) {
+	l.logger.SetOutput(w)
+}
+
+func (l *Logger) AddHook(hook logrus.Hook) {
+	l.logger.AddHook(hook)
+}
+
+// RemoveHook removes a hook added with AddHook.
+func (l *Logger) RemoveHook(hook logrus.Hook) {
+	removeHook(l.logger, hook)
+}
+
+// RemoveHook removes a hook added to the standard logger.
+func RemoveHook(hook logrus.Hook) {
+	removeHook(logrus.StandardLogger(), hook)
+}
+
+// detachHook removes hook from the standard logger and every channel.
+func detachHook(hook logrus.Hook) {
+	RemoveHook(hook)
+	channelsMu.Lock()
+	defer channelsMu.Unlock()
+	for _, l := range channels {
+		l.RemoveHook(hook)
+	}
+}
+
+func removeHook(logger *logrus.Logger, hook logrus.Hook) {
+	hooks := make(logrus.LevelHooks)
+	for level, levelHooks := range logger.Hooks {
+		for _, h := range levelHooks {
+			if h != hook {
+				hooks[level] = append(hooks[level], h)
+			}
+		}
+	}
+	logger.ReplaceHooks(hooks)
+}
+
+// WithFields returns an entry on this channel carrying fields.
+func (l *Logger) WithFields(fields logrus.Fields) *logrus.Entry {
+	return stamp(l.logger.WithField(ChannelKey, l.name).WithFields(fields))
+}
+
+func (l *Logger) Info(format string, args ...interface{}) {
+	l.WithFields(logrus.Fields{}).Info(fmt.Sprintf(format, args...))
+}
+
+func (l *Logger) Warn(format string, args ...interface{}) {
+	l.WithFields(logrus.Fields{}).Warn(fmt.Sprintf(format, args...))
+}
+
+func (l *Logger) Error(err error) {
+	l.WithFields(logrus.Fields{"error": err}).Error()
+}
+
+func (l *Logger) Errorf(format string, args ...interface{}) {
+	l.WithFields(logrus.Fields{"error": fmt.Sprintf(format, args...)}).Error()
+}
+
+func (l *Logger) DebugMessage(format string, args ...interface{}) {
+	l.WithFields(logrus.Fields{"debug": fmt.Sprintf(format, args...)}).Debug()
+}
+
+// Trace logs wire-level detail below Debug, see the package level Trace.
+func (l *Logger) Trace(format string, args ...interface{}) {
+	l.WithFields(logrus.Fields{}).Trace(fmt.Sprintf(format, args...))
+}
+
+// TraceEnabled reports whether the channel logs at TraceLevel.
+func (l *Logger) TraceEnabled() bool {
+	return l.logger.IsLevelEnabled(logrus.TraceLevel)
+}
+
+func (l *Logger) Printf(format string, args ...interface{}) {
+	l.Info(format, args...)
+}
@@ -0,0 +1,93 @@
+package log
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	logrus "github.com/sirupsen/logrus"
+)
+
+func TestChannel(t *testing.T) {
+	payments := Channel("payments")
+	if Channel("payments") != payments {
+		t.Errorf("expected the same channel for the same name")
+	}
+
+	b := &bytes.Buffer{}
+	payments.SetOutput(b)
+	payments.SetFormatter(&ChannelTextFormatter{DisableTimestamp: true})
+	payments.SetLevel(logrus.WarnLevel)
+
+	payments.Info("not written")
+	payments.Warn("charge %v declined", "ch_1")
+	if strings.Contains(b.String(), "not written") {
+		t.Errorf("expected info to be filtered %q", b.String())
+	}
+	expected := `level=warning msg="charge ch_1 declined" channel=payments` + "\n"
+	if b.String() != expected {
+		t.Errorf("expected %q got %q", expected, b.String())
+	}
+
+	if Channel("http").GetLevel() == logrus.WarnLevel {
+		t.Errorf("expected http channel to keep its own level")
+	}
+}
+
+func TestChannelInheritance(t *testing.T) {
+	b := &bytes.Buffer{}
+	parent := Channel("shop")
+	parent.SetOutput(b)
+	parent.SetFormatter(&ChannelTextFormatter{DisableTimestamp: true})
+	parent.SetLevel(logrus.WarnLevel)
+
+	cart := Channel("shop.cart")
+	checkout := Channel("shop.checkout.payment")
+	checkout.SetLevel(logrus.DebugLevel)
+	if cart.GetLevel() != logrus.WarnLevel || checkout.GetLevel() != logrus.DebugLevel {
+		t.Errorf("expected warning and debug, got %v and %v", cart.GetLevel(), checkout.GetLevel())
+	}
+
+	parent.SetLevel(logrus.ErrorLevel)
+	if cart.GetLevel() != logrus.ErrorLevel || checkout.GetLevel() != logrus.DebugLevel {
+		t.Errorf("expected the change to reach cart only, got %v and %v", cart.GetLevel(), checkout.GetLevel())
+	}
+	checkout.ResetLevel()
+	if checkout.GetLevel() != logrus.ErrorLevel {
+		t.Errorf("expected checkout to inherit again, got %v", checkout.GetLevel())
+	}
+
+	cart.Errorf("cart %v expired", 7)
+	if !strings.Contains(b.String(), "channel=shop.cart") {
+		t.Errorf("expected the output of shop, got %q", b.String())
+	}
+}
+
+func TestChannelTrace(t *testing.T) {
+	wire := Channel("wire")
+	b := &bytes.Buffer{}
+	wire.SetOutput(b)
+	wire.SetFormatter(&ChannelTextFormatter{DisableTimestamp: true})
+	wire.SetLevel(logrus.DebugLevel)
+
+	if wire.TraceEnabled() {
+		t.Errorf("expected trace disabled at debug")
+	}
+	wire.Trace("not written")
+	if b.Len() != 0 {
+		t.Errorf("expected trace to be filtered %q", b.String())
+	}
+
+	if err := SetLevelFor("wire", logrus.TraceLevel); err != nil {
+		t.Fatal(err)
+	}
+	defer ResetLevelFor("wire")
+	wire.Trace("sent %d bytes", 42)
+	expected := `level=trace msg="sent 42 bytes" channel=wire` + "\n"
+	if b.String() != expected {
+		t.Errorf("expected %q got %q", expected, b.String())
+	}
+	if Channel("payments").TraceEnabled() {
+		t.Errorf("expected other channels to stay below trace")
+	}
+}
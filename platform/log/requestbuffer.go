@@ -0,0 +1,173 @@
+package log
+
+import (
+	"context"
+	"io/ioutil"
+	"sync"
+
+	logrus "github.com/sirupsen/logrus"
+)
+
+const defaultRequestBufferSize = 1000
+
+type bufferState int
+
+const (
+	buffering bufferState = iota
+	flushed
+	discarded
+)
+
+// RequestBuffer keeps the debug entries of one request that the logger in
+// its context doesn't log at its level, so they can be written once the
+// request turned out to fail or to be slow and dropped otherwise:
+//
+//	ctx, buf := log.NewRequestBuffer(r.Context(), 0)
+//	err := handle(ctx)
+//	if err != nil || buf.Failed() {
+//		buf.Flush()
+//	} else {
+//		buf.Discard()
+//	}
+//
+// Entries at the level of the logger and more severe are logged right away.
+// Flushed entries are written with the formatter, output and hooks of the
+// logger, so its output must be safe for concurrent writes, as files and the
+// writers of this package are.
+type RequestBuffer struct {
+	parent *logrus.Logger
+	fields logrus.Fields
+	size   int
+
+	mu      sync.Mutex
+	entries []*logrus.Entry
+	dropped int
+	failed  bool
+	state   bufferState
+}
+
+// NewRequestBuffer returns a copy of ctx whose FromContext entry buffers the
+// debug entries of the logger of the entry in ctx, along with the buffer.
+// The buffer keeps the last size entries, size defaults to 1000.
+func NewRequestBuffer(ctx context.Context, size int) (context.Context, *RequestBuffer) {
+	if size <= 0 {
+		size = defaultRequestBufferSize
+	}
+	entry := FromContext(ctx)
+	b := &RequestBuffer{parent: entry.Logger, fields: entry.Data, size: size}
+
+	proxy := logrus.New()
+	proxy.Out = ioutil.Discard
+	proxy.Formatter = discardFormatter{}
+	proxy.ExitFunc = entry.Logger.ExitFunc
+	proxy.SetLevel(logrus.DebugLevel)
+	proxy.AddHook(b)
+
+	e := logrus.NewEntry(proxy).WithFields(entry.Data)
+	if !entry.Time.IsZero() {
+		e = e.WithTime(entry.Time)
+	}
+	return NewContext(ctx, e), b
+}
+
+func (b *RequestBuffer) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+// Fire logs entry on the logger when it logs at the level of entry, or
+// keeps it until the buffer is flushed.
+func (b *RequestBuffer) Fire(entry *logrus.Entry) error {
+	if b.parent.IsLevelEnabled(entry.Level) {
+		if entry.Level <= logrus.ErrorLevel {
+			b.mu.Lock()
+			b.failed = true
+			b.mu.Unlock()
+		}
+		e := CopyEntry(entry)
+		e.Logger = b.parent
+		e.Log(entry.Level, entry.Message)
+		return nil
+	}
+
+	b.mu.Lock()
+	switch b.state {
+	case flushed:
+		b.mu.Unlock()
+		return b.write(CopyEntry(entry))
+	case discarded:
+		b.mu.Unlock()
+		return nil
+	}
+	if len(b.entries) == b.size {
+		b.entries = append(b.entries[:0], b.entries[1:]...)
+		b.dropped++
+	}
+	b.entries = append(b.entries, CopyEntry(entry))
+	b.mu.Unlock()
+	return nil
+}
+
+// Failed tells whether an entry at Error or more severe was logged through
+// the buffer.
+func (b *RequestBuffer) Failed() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.failed
+}
+
+// Len returns the number of buffered entries.
+func (b *RequestBuffer) Len() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.entries)
+}
+
+// Flush writes the buffered entries, oldest first, and the ones logged
+// afterwards right away. When the buffer was full, the oldest entries are
+// missing and a warning says how many.
+func (b *RequestBuffer) Flush() error {
+	b.mu.Lock()
+	entries, dropped := b.entries, b.dropped
+	b.entries, b.dropped = nil, 0
+	b.state = flushed
+	b.mu.Unlock()
+
+	if dropped > 0 {
+		recordDropped(uint64(dropped))
+		e := logrus.NewEntry(b.parent).WithFields(b.fields).WithField("dropped", dropped)
+		if len(entries) > 0 {
+			e = e.WithTime(entries[0].Time)
+		}
+		e.Warnf("request buffer dropped the first %d debug entries", dropped)
+	}
+	var firstErr error
+	for _, e := range entries {
+		if err := b.write(e); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// Discard drops the buffered entries and the ones logged afterwards.
+func (b *RequestBuffer) Discard() {
+	b.mu.Lock()
+	b.entries, b.dropped = nil, 0
+	b.state = discarded
+	b.mu.Unlock()
+}
+
+// write formats entry with the logger and fires its hooks, whatever the
+// level of the logger.
+func (b *RequestBuffer) write(entry *logrus.Entry) error {
+	entry.Logger = b.parent
+	if err := b.parent.Hooks.Fire(entry.Level, entry); err != nil {
+		reportf("Failed to fire hook because %+v", err)
+	}
+	line, err := b.parent.Formatter.Format(entry)
+	if err != nil {
+		return err
+	}
+	_, err = b.parent.Out.Write(line)
+	return err
+}
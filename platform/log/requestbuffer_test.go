@@ -0,0 +1,61 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	logrus "github.com/sirupsen/logrus"
+)
+
+func TestRequestBuffer(t *testing.T) {
+	b := &bytes.Buffer{}
+	l := Channel("request-buffer")
+	l.SetOutput(b)
+	l.SetFormatter(&ChannelTextFormatter{DisableTimestamp: true})
+	l.SetLevel(logrus.InfoLevel)
+
+	ctx := NewContext(context.Background(), l.WithFields(logrus.Fields{"request_id": "r1"}))
+	ctx, buf := NewRequestBuffer(ctx, 2)
+	entry := FromContext(ctx)
+	entry.Debug("one")
+	entry.Info("started")
+	entry.Debug("two")
+	entry.WithField("card", 7).Debug("three")
+	if strings.Contains(b.String(), "level=debug") || !strings.Contains(b.String(), "msg=started") {
+		t.Errorf("expected only the info entry before the flush, got %q", b.String())
+	}
+	if buf.Len() != 2 || buf.Failed() {
+		t.Errorf("expected 2 buffered entries and no failure, got %d %v", buf.Len(), buf.Failed())
+	}
+
+	entry.Error("declined")
+	if !buf.Failed() {
+		t.Errorf("expected the error to fail the request")
+	}
+	b.Reset()
+	if err := buf.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	entry.Debug("four")
+	lines := strings.Split(strings.TrimSpace(b.String()), "\n")
+	expected := []string{"dropped the first 1 debug entries", "msg=two", "msg=three card=7", "msg=four"}
+	if len(lines) != len(expected) {
+		t.Fatalf("expected %d lines got %q", len(expected), lines)
+	}
+	for i, s := range expected {
+		if !strings.Contains(lines[i], s) || !strings.Contains(lines[i], "request_id=r1") {
+			t.Errorf("expected %q with the request ID in %q", s, lines[i])
+		}
+	}
+
+	b.Reset()
+	ctx, buf = NewRequestBuffer(NewContext(context.Background(), l.WithFields(nil)), 0)
+	FromContext(ctx).Debug("one")
+	buf.Discard()
+	FromContext(ctx).Debug("two")
+	if b.Len() != 0 {
+		t.Errorf("expected discarded entries not to be written, got %q", b.String())
+	}
+}
@@ -0,0 +1,204 @@
+// Package requestid generates request IDs, carries them in contexts and HTTP
+// headers and adds them to every entry logged within a request.
+//
+//	logrus.AddHook(requestid.NewHook())
+//	http.HandleFunc("/charges", requestid.Middleware(charges))
+//	client := &http.Client{Transport: &requestid.Transport{}}
+package requestid
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/binary"
+	"encoding/hex"
+	"net/http"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/o3labs/openpoint/platform/log"
+	logrus "github.com/sirupsen/logrus"
+)
+
+const (
+	// Header carries the request ID between services
+	Header = "X-Request-ID"
+
+	// Key is the field holding the request ID in log entries
+	Key = "request_id"
+
+	// maxLength of IDs accepted from clients
+	maxLength = 128
+)
+
+// Generator returns a new request ID.
+type Generator func() string
+
+// New generates the IDs of requests that come without one. Defaults to
+// UUIDv7, whose IDs sort by time.
+var New Generator = UUIDv7
+
+// UUIDv7 returns a RFC 9562 version 7 UUID: a millisecond timestamp
+// followed by random bits.
+func UUIDv7() string {
+	b := make([]byte, 16)
+	rand.Read(b[6:])
+	ms := uint64(time.Now().UnixNano() / int64(time.Millisecond))
+	b[0] = byte(ms >> 40)
+	b[1] = byte(ms >> 32)
+	b[2] = byte(ms >> 24)
+	b[3] = byte(ms >> 16)
+	b[4] = byte(ms >> 8)
+	b[5] = byte(ms)
+	b[6] = b[6]&0x0f | 0x70
+	b[8] = b[8]&0x3f | 0x80
+
+	s := make([]byte, 36)
+	hex.Encode(s[0:8], b[0:4])
+	s[8] = '-'
+	hex.Encode(s[9:13], b[4:6])
+	s[13] = '-'
+	hex.Encode(s[14:18], b[6:8])
+	s[18] = '-'
+	hex.Encode(s[19:23], b[8:10])
+	s[23] = '-'
+	hex.Encode(s[24:], b[10:])
+	return string(s)
+}
+
+var (
+	xidEncoding = base32.NewEncoding("0123456789abcdefghijklmnopqrstuv").WithPadding(base32.NoPadding)
+	xidMachine  = machineID()
+	xidCounter  = randomCounter()
+)
+
+// XID returns a 20 character xid: a second timestamp, machine and process
+// IDs and a counter, shorter than a UUID and also sorting by time.
+func XID() string {
+	b := make([]byte, 12)
+	binary.BigEndian.PutUint32(b[0:], uint32(time.Now().Unix()))
+	copy(b[4:7], xidMachine)
+	pid := os.Getpid()
+	b[7] = byte(pid >> 8)
+	b[8] = byte(pid)
+	n := atomic.AddUint32(&xidCounter, 1)
+	b[9] = byte(n >> 16)
+	b[10] = byte(n >> 8)
+	b[11] = byte(n)
+	return xidEncoding.EncodeToString(b)
+}
+
+func machineID() []byte {
+	host, err := os.Hostname()
+	if err != nil {
+		b := make([]byte, 3)
+		rand.Read(b)
+		return b
+	}
+	sum := sha256.Sum256([]byte(host))
+	return sum[:3]
+}
+
+func randomCounter() uint32 {
+	b := make([]byte, 4)
+	rand.Read(b)
+	return binary.BigEndian.Uint32(b)
+}
+
+type contextKey struct{}
+
+// NewContext returns a copy of ctx carrying id.
+func NewContext(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// FromContext returns the request ID in ctx, or an empty string.
+func FromContext(ctx context.Context) string {
+	id, _ := ctx.Value(contextKey{}).(string)
+	return id
+}
+
+// FromRequest returns the ID the request came with in its Header, or a new
+// one when it has none or one that isn't a sensible ID.
+func FromRequest(r *http.Request) string {
+	if id := r.Header.Get(Header); Valid(id) {
+		return id
+	}
+	return New()
+}
+
+// Valid accepts IDs of printable ASCII without spaces, so clients can't
+// inject line breaks or huge values into the logs.
+func Valid(id string) bool {
+	if id == "" || len(id) > maxLength {
+		return false
+	}
+	return strings.IndexFunc(id, func(r rune) bool { return r <= ' ' || r > '~' }) < 0
+}
+
+// Middleware gives every request an ID, from its Header or generated, sets it
+// on the response and stores it in the request context, both for
+// FromContext and on the entry of log.FromContext.
+func Middleware(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := FromContext(r.Context())
+		if id == "" {
+			id = FromRequest(r)
+		}
+		w.Header().Set(Header, id)
+		ctx := NewContext(r.Context(), id)
+		ctx = log.WithContext(ctx, logrus.Fields{Key: id})
+		h(w, r.WithContext(ctx))
+	}
+}
+
+// Transport is an http.RoundTripper setting the Header of outgoing requests
+// to the ID in their context, so the ID follows calls to other services.
+type Transport struct {
+	// Base does the round trip. Defaults to http.DefaultTransport.
+	Base http.RoundTripper
+}
+
+func (t *Transport) RoundTrip(r *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	id := FromContext(r.Context())
+	if id == "" || r.Header.Get(Header) != "" {
+		return base.RoundTrip(r)
+	}
+	// a RoundTripper must not modify the request
+	r = r.Clone(r.Context())
+	r.Header.Set(Header, id)
+	return base.RoundTrip(r)
+}
+
+// Hook adds the request ID of the entry's context to entries that don't carry
+// one yet, e.g. those logged with logrus.WithContext(ctx) rather than
+// log.FromContext(ctx).
+type Hook struct{}
+
+func NewHook() *Hook {
+	return &Hook{}
+}
+
+func (h *Hook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (h *Hook) Fire(entry *logrus.Entry) error {
+	if entry.Context == nil {
+		return nil
+	}
+	if _, ok := entry.Data[Key]; ok {
+		return nil
+	}
+	if id := FromContext(entry.Context); id != "" {
+		entry.Data[Key] = id
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package requestid
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	"github.com/o3labs/openpoint/platform/log"
+	logrus "github.com/sirupsen/logrus"
+)
+
+func TestGenerators(t *testing.T) {
+	uuid := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-7[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+	if id := UUIDv7(); !uuid.MatchString(id) {
+		t.Errorf("expected a version 7 UUID, got %v", id)
+	}
+	a, b := XID(), XID()
+	if len(a) != 20 || a == b {
+		t.Errorf("expected distinct 20 character xids, got %v and %v", a, b)
+	}
+}
+
+func TestValid(t *testing.T) {
+	for id, expected := range map[string]bool{"r1": true, "": false, "a b": false, "a\nb": false, string(make([]byte, 200)): false} {
+		if Valid(id) != expected {
+			t.Errorf("expected Valid(%q) to be %v", id, expected)
+		}
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	handler := Middleware(func(w http.ResponseWriter, r *http.Request) {
+		if FromContext(r.Context()) != "r1" {
+			t.Errorf("expected the request ID in the context")
+		}
+		if log.FromContext(r.Context()).Data[Key] != "r1" {
+			t.Errorf("expected the request ID on the context logger")
+		}
+	})
+	r := httptest.NewRequest("GET", "/charges", nil)
+	r.Header.Set(Header, "r1")
+	w := httptest.NewRecorder()
+	handler(w, r)
+	if w.Header().Get(Header) != "r1" {
+		t.Errorf("expected the request ID on the response")
+	}
+
+	r = httptest.NewRequest("GET", "/charges", nil)
+	r.Header.Set(Header, "bad\tid")
+	w = httptest.NewRecorder()
+	Middleware(func(http.ResponseWriter, *http.Request) {})(w, r)
+	if id := w.Header().Get(Header); id == "" || id == "bad\tid" {
+		t.Errorf("expected a generated request ID, got %q", id)
+	}
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+func TestTransport(t *testing.T) {
+	var got string
+	transport := &Transport{Base: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		got = r.Header.Get(Header)
+		return &http.Response{StatusCode: http.StatusOK}, nil
+	})}
+	r := httptest.NewRequest("GET", "http://payments/charges", nil).WithContext(NewContext(context.Background(), "r1"))
+	if _, err := transport.RoundTrip(r); err != nil {
+		t.Fatal(err)
+	}
+	if got != "r1" {
+		t.Errorf("expected the request ID to be sent, got %q", got)
+	}
+	if r.Header.Get(Header) != "" {
+		t.Errorf("expected the original request to be left alone")
+	}
+}
+
+func TestHook(t *testing.T) {
+	entry := logrus.NewEntry(logrus.New()).WithContext(NewContext(context.Background(), "r1"))
+	if err := NewHook().Fire(entry); err != nil {
+		t.Fatal(err)
+	}
+	if entry.Data[Key] != "r1" {
+		t.Errorf("expected the hook to add the request ID, got %v", entry.Data)
+	}
+}
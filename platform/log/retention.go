@@ -0,0 +1,218 @@
+package log
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	logrus "github.com/sirupsen/logrus"
+)
+
+const defaultRetentionInterval = time.Hour
+
+// RetainedFile is a log file seen by a RetentionManager
+type RetainedFile struct {
+	Path    string
+	Size    int64
+	ModTime time.Time
+}
+
+// RetentionReport is what a RetentionManager did in a run
+type RetentionReport struct {
+	Time       time.Time
+	Removed    []RetainedFile
+	Compressed []RetainedFile
+
+	// Freed is the number of bytes removed and saved by compression
+	Freed int64
+
+	// Kept is the number of bytes of the files left
+	Kept int64
+}
+
+// RetentionManager keeps the log files matching Pattern within an age and a
+// size budget, compressing files as they get older and removing the oldest
+// first, so hosts don't need a cron job cleaning up after the file output.
+// Pattern shouldn't match the file being written, e.g. use
+// /var/log/api.log.* for the backups RotatingFileWriter keeps of
+// /var/log/api.log.
+//
+// Every run with files removed or compressed is reported on the internal
+// channel at Info, and the last run is kept by LastReport.
+type RetentionManager struct {
+	// Pattern of the files, see filepath.Match
+	Pattern string
+
+	// MaxAge removes files last modified longer ago. 0 keeps files of any
+	// age.
+	MaxAge time.Duration
+
+	// MaxTotalSize in bytes of the files, the oldest are removed until they
+	// fit. 0 disables the size budget.
+	MaxTotalSize int64
+
+	// CompressAfter gzips files last modified longer ago. 0 leaves files
+	// as they are.
+	CompressAfter time.Duration
+
+	mu   sync.Mutex
+	last RetentionReport
+
+	stop chan struct{}
+	done chan struct{}
+	once sync.Once
+}
+
+// Start runs the manager right away and then every interval, 1h by default,
+// until Close.
+func (m *RetentionManager) Start(interval time.Duration) {
+	if interval <= 0 {
+		interval = defaultRetentionInterval
+	}
+	m.stop = make(chan struct{})
+	m.done = make(chan struct{})
+	go func() {
+		defer close(m.done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			if _, err := m.Run(); err != nil {
+				reportf("Failed to apply the retention of %v because %+v", m.Pattern, err)
+			}
+			select {
+			case <-ticker.C:
+			case <-m.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Close stops the runs started by Start.
+func (m *RetentionManager) Close() error {
+	if m.stop == nil {
+		return nil
+	}
+	closed := false
+	m.once.Do(func() {
+		closed = true
+		close(m.stop)
+	})
+	if !closed {
+		return ErrWriterClosed
+	}
+	<-m.done
+	return nil
+}
+
+// LastReport returns what the last run did.
+func (m *RetentionManager) LastReport() RetentionReport {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.last
+}
+
+// Run compresses and removes files once. It goes on past files it fails to
+// compress or remove and returns the first error.
+func (m *RetentionManager) Run() (RetentionReport, error) {
+	t := now()
+	result := RetentionReport{Time: t}
+	files, err := m.files()
+	if err != nil {
+		return result, err
+	}
+
+	var firstErr error
+	fail := func(err error) {
+		if err != nil && !os.IsNotExist(err) && firstErr == nil {
+			firstErr = err
+		}
+	}
+	kept := files[:0]
+	for _, f := range files {
+		age := t.Sub(f.ModTime)
+		switch {
+		case m.MaxAge > 0 && age > m.MaxAge:
+			if err := os.Remove(f.Path); err != nil {
+				fail(err)
+				kept = append(kept, f)
+				continue
+			}
+			result.Removed = append(result.Removed, f)
+			result.Freed += f.Size
+		case m.CompressAfter > 0 && age > m.CompressAfter && !isCompressed(f.Path):
+			if err := compressFile(f.Path); err != nil {
+				fail(err)
+				kept = append(kept, f)
+				continue
+			}
+			result.Compressed = append(result.Compressed, f)
+			if info, err := os.Stat(f.Path + ".gz"); err == nil {
+				result.Freed += f.Size - info.Size()
+				f = RetainedFile{Path: f.Path + ".gz", Size: info.Size(), ModTime: f.ModTime}
+			}
+			kept = append(kept, f)
+		default:
+			kept = append(kept, f)
+		}
+	}
+
+	var total int64
+	for _, f := range kept {
+		total += f.Size
+	}
+	for m.MaxTotalSize > 0 && total > m.MaxTotalSize && len(kept) > 0 {
+		f := kept[0]
+		kept = kept[1:]
+		if err := os.Remove(f.Path); err != nil {
+			fail(err)
+			continue
+		}
+		result.Removed = append(result.Removed, f)
+		result.Freed += f.Size
+		total -= f.Size
+	}
+	result.Kept = total
+
+	m.mu.Lock()
+	m.last = result
+	m.mu.Unlock()
+	if len(result.Removed) > 0 || len(result.Compressed) > 0 {
+		removed := make([]string, len(result.Removed))
+		for i, f := range result.Removed {
+			removed[i] = f.Path
+		}
+		report(logrus.InfoLevel, "Applied log retention", logrus.Fields{
+			"pattern":    m.Pattern,
+			"removed":    removed,
+			"compressed": len(result.Compressed),
+			"freed":      Bytes(result.Freed),
+		})
+	}
+	return result, firstErr
+}
+
+// files returns the files matching Pattern, oldest first.
+func (m *RetentionManager) files() ([]RetainedFile, error) {
+	matches, err := filepath.Glob(m.Pattern)
+	if err != nil {
+		return nil, err
+	}
+	files := make([]RetainedFile, 0, len(matches))
+	for _, path := range matches {
+		info, err := os.Stat(path)
+		if err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+		files = append(files, RetainedFile{Path: path, Size: info.Size(), ModTime: info.ModTime()})
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].ModTime.Before(files[j].ModTime) })
+	return files, nil
+}
+
+func isCompressed(path string) bool {
+	return strings.HasSuffix(path, ".gz") || strings.HasSuffix(path, ".zst")
+}
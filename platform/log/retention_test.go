@@ -0,0 +1,71 @@
+package log
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRetentionManager(t *testing.T) {
+	dir, err := ioutil.TempDir("", "retention")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	start := time.Now()
+	SetClock(&fixedClock{t: start})
+	defer SetClock(nil)
+	write := func(name string, size int, age time.Duration) string {
+		path := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(path, []byte(strings.Repeat("a", size)), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, start.Add(-age), start.Add(-age)); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	write("api.log", 100, 0)
+	expired := write("api.log.1", 100, 40*24*time.Hour)
+	oldest := write("api.log.2", 1000, 10*24*time.Hour)
+	old := write("api.log.3", 1000, 2*24*time.Hour)
+	recent := write("api.log.4", 50, time.Hour)
+
+	m := &RetentionManager{
+		Pattern:       filepath.Join(dir, "api.log.*"),
+		MaxAge:        30 * 24 * time.Hour,
+		MaxTotalSize:  100,
+		CompressAfter: 24 * time.Hour,
+	}
+	report, err := m.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Compressed) != 2 {
+		t.Errorf("expected the files older than a day to be compressed, got %v", report.Compressed)
+	}
+	removed := []string{}
+	for _, f := range report.Removed {
+		removed = append(removed, f.Path)
+	}
+	if strings.Join(removed, ",") != expired+","+oldest+".gz" {
+		t.Errorf("expected the expired and then the oldest file to be removed, got %v", removed)
+	}
+	for _, path := range []string{filepath.Join(dir, "api.log"), old + ".gz", recent} {
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("expected %s to be kept: %v", path, err)
+		}
+	}
+	if report.Kept > 100 || m.LastReport().Kept != report.Kept {
+		t.Errorf("expected the files to fit the budget, kept %d", report.Kept)
+	}
+
+	m.Start(time.Hour)
+	if err := m.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
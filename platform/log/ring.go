@@ -0,0 +1,157 @@
+package log
+
+import (
+	"bytes"
+	"net/http"
+	"strconv"
+	"sync"
+
+	"github.com/o3labs/openpoint/platform/errors"
+	logrus "github.com/sirupsen/logrus"
+)
+
+const defaultRingSize = 1000
+
+// RingBuffer is a hook keeping the last entries in memory and an
+// http.Handler dumping them, e.g. mounted at /debug/logs, to see what a live
+// instance logged when its files are elsewhere.
+//
+// GET takes the query parameters
+//
+//	level=warning     entries at this level or more severe
+//	channel=payments  entries of one channel
+//	limit=100         the most recent entries only
+//	format=text       ChannelTextFormatter lines instead of a JSON array
+type RingBuffer struct {
+	// Token required as "Authorization: Bearer <token>", see LevelHandler
+	Token string
+
+	mu      sync.Mutex
+	entries []logrus.Entry
+	next    int
+	full    bool
+}
+
+// NewRingBuffer returns a buffer keeping the last size entries. Size
+// defaults to 1000.
+func NewRingBuffer(size int) *RingBuffer {
+	if size <= 0 {
+		size = defaultRingSize
+	}
+	return &RingBuffer{entries: make([]logrus.Entry, size)}
+}
+
+func (r *RingBuffer) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (r *RingBuffer) Fire(entry *logrus.Entry) error {
+	e := logrus.Entry{
+		Data:    make(logrus.Fields, len(entry.Data)),
+		Time:    entry.Time,
+		Level:   entry.Level,
+		Message: entry.Message,
+	}
+	for k, v := range entry.Data {
+		e.Data[k] = v
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.entries[r.next] = e
+	r.next = (r.next + 1) % len(r.entries)
+	if r.next == 0 {
+		r.full = true
+	}
+	return nil
+}
+
+// Entries returns the buffered entries, oldest first.
+func (r *RingBuffer) Entries() []logrus.Entry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.full {
+		return append([]logrus.Entry(nil), r.entries[:r.next]...)
+	}
+	entries := make([]logrus.Entry, 0, len(r.entries))
+	entries = append(entries, r.entries[r.next:]...)
+	return append(entries, r.entries[:r.next]...)
+}
+
+func (r *RingBuffer) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if !BearerAuthorized(req, r.Token) {
+		errors.Unauthorized().Write(w)
+		return
+	}
+	if req.Method != http.MethodGet {
+		errors.NewError(http.StatusMethodNotAllowed, "Use GET", http.StatusText(http.StatusMethodNotAllowed)).Write(w)
+		return
+	}
+
+	query := req.URL.Query()
+	level := logrus.TraceLevel
+	if v := query.Get("level"); v != "" {
+		l, err := ParseLevel(v)
+		if err != nil {
+			errors.BadRequest("%v", err).Write(w)
+			return
+		}
+		level = l
+	}
+	limit := 0
+	if v := query.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			errors.BadRequest("Invalid limit %v", v).Write(w)
+			return
+		}
+		limit = n
+	}
+	channel := query.Get("channel")
+
+	entries := []logrus.Entry{}
+	for _, e := range r.Entries() {
+		if e.Level > level {
+			continue
+		}
+		if channel != "" && e.Data[ChannelKey] != channel {
+			continue
+		}
+		entries = append(entries, e)
+	}
+	if limit > 0 && len(entries) > limit {
+		entries = entries[len(entries)-limit:]
+	}
+
+	text := query.Get("format") == "text"
+	var formatter logrus.Formatter = &ChannelJSONFormatter{}
+	if text {
+		formatter = &ChannelTextFormatter{DisableColors: true}
+	}
+	b := &bytes.Buffer{}
+	if !text {
+		b.WriteByte('[')
+	}
+	for i := range entries {
+		out, err := formatter.Format(&entries[i])
+		if err != nil {
+			errors.ServerError("%v", err).Write(w)
+			return
+		}
+		if !text {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			out = bytes.TrimRight(out, "\n")
+		}
+		b.Write(out)
+	}
+
+	if text {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	} else {
+		b.WriteString("]\n")
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	}
+	w.Write(b.Bytes())
+}
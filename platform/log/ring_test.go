@@ -0,0 +1,43 @@
+package log
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http/httptest"
+	"testing"
+
+	logrus "github.com/sirupsen/logrus"
+)
+
+func TestRingBuffer(t *testing.T) {
+	ring := NewRingBuffer(3)
+	logger := logrus.New()
+	logger.AddHook(ring)
+	logger.Out = ioutil.Discard
+	for i, level := range []logrus.Level{logrus.InfoLevel, logrus.WarnLevel, logrus.ErrorLevel, logrus.WarnLevel} {
+		logger.WithFields(logrus.Fields{ChannelKey: "payments", "n": i}).Log(level, "charge")
+	}
+	entries := ring.Entries()
+	if len(entries) != 3 || entries[0].Data["n"] != 1 || entries[2].Data["n"] != 3 {
+		t.Fatalf("expected the last 3 entries oldest first, got %+v", entries)
+	}
+
+	ring.Token = "secret"
+	r := httptest.NewRequest("GET", "/debug/logs?level=warning&limit=2&channel=payments", nil)
+	w := httptest.NewRecorder()
+	ring.ServeHTTP(w, r)
+	if w.Code != 401 {
+		t.Errorf("expected 401 without the token, got %v", w.Code)
+	}
+
+	r.Header.Set("Authorization", "Bearer secret")
+	w = httptest.NewRecorder()
+	ring.ServeHTTP(w, r)
+	out := []map[string]interface{}{}
+	if err := json.Unmarshal(w.Body.Bytes(), &out); err != nil {
+		t.Fatalf("%v %s", err, w.Body.Bytes())
+	}
+	if len(out) != 2 || out[0]["level"] != "error" || out[1]["n"] != float64(3) {
+		t.Errorf("expected the last 2 warnings and errors, got %+v", out)
+	}
+}
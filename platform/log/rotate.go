@@ -0,0 +1,344 @@
+package log
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const backupTimeFormat = "2006-01-02T15-04-05.000"
+
+// RotatingFileWriter is an io.WriteCloser that writes to Filename and moves it
+// aside once it grows past MaxSize or has been open longer than MaxAge. The
+// file is opened on the first Write.
+type RotatingFileWriter struct {
+	// Filename is the file to write to. Backups are kept next to it as
+	// Filename.<timestamp>, followed by -<n> when rotated more than once
+	// within a millisecond and a .gz suffix when compressed.
+	Filename string
+
+	// MaxSize in bytes before the file is rotated. 0 disables size rotation.
+	MaxSize int64
+
+	// MaxAge of the current file before it is rotated. 0 disables age rotation.
+	MaxAge time.Duration
+
+	// MaxBackups is the number of rotated files to keep. 0 keeps all of them.
+	MaxBackups int
+
+	// Compress rotated files with gzip.
+	Compress bool
+
+	// Archive uploads rotated files, once compressed, and removes them when
+	// uploaded, see Archiver. Uploads run on a goroutine of their own, which
+	// Close waits for after releasing the file.
+	Archive *Archiver
+
+	mu       sync.Mutex
+	file     *os.File
+	size     int64
+	openedAt time.Time
+
+	// cleanupMu serializes compression and removal of backups
+	cleanupMu sync.Mutex
+	wg        sync.WaitGroup
+
+	// uploads queues backups for Archive, uploaded is closed once the queue
+	// is done. pending are the backups queued, removeOldBackups leaves them
+	// to the upload.
+	uploads   chan string
+	uploaded  chan struct{}
+	pendingMu sync.Mutex
+	pending   map[string]bool
+
+	// unregister removes the open file from Shutdown
+	unregister func()
+}
+
+func (w *RotatingFileWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.file == nil {
+		if err := w.open(); err != nil {
+			return 0, err
+		}
+	}
+	if w.shouldRotate(int64(len(p))) {
+		if err := w.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := w.file.Write(p)
+	w.size += int64(n)
+	return n, err
+}
+
+// Rotate closes the current file, moves it aside and opens a new one.
+func (w *RotatingFileWriter) Rotate() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.rotate()
+}
+
+// Reopen closes the current file so the next Write opens Filename again,
+// for when an external tool such as logrotate has moved it aside.
+func (w *RotatingFileWriter) Reopen() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.close()
+}
+
+// Close closes the current file and waits for pending compression.
+func (w *RotatingFileWriter) Close() error {
+	w.mu.Lock()
+	if w.unregister != nil {
+		w.unregister()
+		w.unregister = nil
+	}
+	err := w.close()
+	uploads, uploaded := w.uploads, w.uploaded
+	w.uploads = nil
+	w.mu.Unlock()
+
+	// not under mu, the cleanup and the uploads report their failures,
+	// which may be logged to this writer
+	w.wg.Wait()
+	if uploads != nil {
+		close(uploads)
+	}
+	if uploaded != nil {
+		<-uploaded
+	}
+	return err
+}
+
+func (w *RotatingFileWriter) shouldRotate(n int64) bool {
+	if w.MaxSize > 0 && w.size > 0 && w.size+n > w.MaxSize {
+		return true
+	}
+	if w.MaxAge > 0 && now().Sub(w.openedAt) >= w.MaxAge {
+		return true
+	}
+	return false
+}
+
+func (w *RotatingFileWriter) open() error {
+	if err := os.MkdirAll(filepath.Dir(w.Filename), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(w.Filename, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	w.file = f
+	w.size = info.Size()
+	w.openedAt = now()
+	if w.unregister == nil {
+		w.unregister = RegisterSink(w, SinkOutput)
+	}
+	return nil
+}
+
+func (w *RotatingFileWriter) close() error {
+	if w.file == nil {
+		return nil
+	}
+	err := w.file.Close()
+	w.file = nil
+	return err
+}
+
+func (w *RotatingFileWriter) rotate() error {
+	if err := w.close(); err != nil {
+		return err
+	}
+	backup := w.backupName()
+	if err := os.Rename(w.Filename, backup); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err := w.open(); err != nil {
+		return err
+	}
+
+	if w.Archive != nil && w.uploads == nil {
+		w.uploads, w.uploaded = make(chan string, 16), make(chan struct{})
+		go w.upload(w.uploads, w.uploaded)
+	}
+	uploads := w.uploads
+
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		w.cleanupMu.Lock()
+		defer w.cleanupMu.Unlock()
+		if w.Compress {
+			if err := compressFile(backup); err != nil {
+				reportf("Failed to compress %v because %+v", backup, err)
+			} else {
+				backup += ".gz"
+			}
+		}
+		if uploads != nil {
+			w.pendingMu.Lock()
+			if w.pending == nil {
+				w.pending = map[string]bool{}
+			}
+			w.pending[backup] = true
+			w.pendingMu.Unlock()
+			uploads <- backup
+		}
+		if err := w.removeOldBackups(); err != nil {
+			reportf("Failed to remove old logs because %+v", err)
+		}
+	}()
+	return nil
+}
+
+// upload archives the backups queued on uploads until it is closed.
+func (w *RotatingFileWriter) upload(uploads <-chan string, uploaded chan<- struct{}) {
+	defer close(uploaded)
+	for backup := range uploads {
+		if err := w.Archive.UploadFile(backup); err != nil {
+			reportf("Failed to archive %v because %+v", backup, err)
+		}
+		w.pendingMu.Lock()
+		delete(w.pending, backup)
+		w.pendingMu.Unlock()
+	}
+}
+
+// backupName returns the name of the next backup, with a sequence number
+// when a backup of the same millisecond exists.
+func (w *RotatingFileWriter) backupName() string {
+	name := w.Filename + "." + now().Format(backupTimeFormat)
+	backup := name
+	for seq := 1; fileExists(backup) || fileExists(backup+".gz"); seq++ {
+		backup = fmt.Sprintf("%s-%d", name, seq)
+	}
+	return backup
+}
+
+func fileExists(path string) bool {
+	_, err := os.Lstat(path)
+	return err == nil
+}
+
+// backups returns the rotated files of Filename, oldest first.
+func (w *RotatingFileWriter) backups() ([]string, error) {
+	matches, err := filepath.Glob(w.Filename + ".*")
+	if err != nil {
+		return nil, err
+	}
+	type backup struct {
+		name string
+		time time.Time
+		seq  int
+	}
+	found := []backup{}
+	for _, m := range matches {
+		if t, seq, ok := parseBackupSuffix(strings.TrimPrefix(m, w.Filename+".")); ok {
+			found = append(found, backup{m, t, seq})
+		}
+	}
+	sort.Slice(found, func(i, j int) bool {
+		if !found[i].time.Equal(found[j].time) {
+			return found[i].time.Before(found[j].time)
+		}
+		return found[i].seq < found[j].seq
+	})
+	backups := make([]string, len(found))
+	for i, b := range found {
+		backups[i] = b.name
+	}
+	return backups, nil
+}
+
+// parseBackupSuffix returns the time and sequence number of a backup from
+// the suffix of its name, false for files that aren't backups.
+func parseBackupSuffix(suffix string) (time.Time, int, bool) {
+	suffix = strings.TrimSuffix(suffix, ".gz")
+	if len(suffix) < len(backupTimeFormat) {
+		return time.Time{}, 0, false
+	}
+	t, err := time.Parse(backupTimeFormat, suffix[:len(backupTimeFormat)])
+	if err != nil {
+		return time.Time{}, 0, false
+	}
+	seq := 0
+	if rest := suffix[len(backupTimeFormat):]; rest != "" {
+		if rest[0] != '-' {
+			return time.Time{}, 0, false
+		}
+		if seq, err = strconv.Atoi(rest[1:]); err != nil || seq <= 0 {
+			return time.Time{}, 0, false
+		}
+	}
+	return t, seq, true
+}
+
+func (w *RotatingFileWriter) removeOldBackups() error {
+	if w.MaxBackups <= 0 {
+		return nil
+	}
+	all, err := w.backups()
+	if err != nil {
+		return err
+	}
+	backups := all[:0]
+	w.pendingMu.Lock()
+	for _, b := range all {
+		if !w.pending[b] {
+			backups = append(backups, b)
+		}
+	}
+	w.pendingMu.Unlock()
+	for len(backups) > w.MaxBackups {
+		if err := os.Remove(backups[0]); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		backups = backups[1:]
+	}
+	return nil
+}
+
+func compressFile(path string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.OpenFile(path+".gz", os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	gz := gzip.NewWriter(dst)
+	if _, err := io.Copy(gz, src); err != nil {
+		dst.Close()
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		dst.Close()
+		return err
+	}
+	if err := dst.Close(); err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil {
+		return fmt.Errorf("remove %v: %v", path, err)
+	}
+	return nil
+}
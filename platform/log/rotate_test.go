@@ -0,0 +1,120 @@
+package log
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRotatingFileWriter(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rotate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	w := &RotatingFileWriter{
+		Filename:   filepath.Join(dir, "app.log"),
+		MaxSize:    10,
+		MaxBackups: 1,
+		Compress:   true,
+	}
+	for _, line := range []string{"first\n", "second\n", "third\n"} {
+		if _, err := w.Write([]byte(line)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := ioutil.ReadFile(w.Filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "third\n" {
+		t.Errorf("expected only the last line in the current file, got %q", b)
+	}
+	backups, err := w.backups()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(backups) != 1 || !strings.HasSuffix(backups[0], ".gz") {
+		t.Errorf("expected one compressed backup, got %v", backups)
+	}
+}
+
+func TestRotatingFileWriterSameMillisecond(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rotate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	clock := &fixedClock{t: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)}
+	SetClock(clock)
+	defer SetClock(nil)
+
+	w := &RotatingFileWriter{Filename: filepath.Join(dir, "app.log")}
+	defer w.Close()
+	for _, line := range []string{"first\n", "second\n", "third\n"} {
+		w.Write([]byte(line))
+		if err := w.Rotate(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	backups, err := w.backups()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stamp := "app.log." + clock.t.Format(backupTimeFormat)
+	expected := []string{stamp, stamp + "-1", stamp + "-2"}
+	if len(backups) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, backups)
+	}
+	for i, line := range []string{"first\n", "second\n", "third\n"} {
+		if filepath.Base(backups[i]) != expected[i] {
+			t.Errorf("expected %s, got %s", expected[i], filepath.Base(backups[i]))
+		}
+		if b, _ := ioutil.ReadFile(backups[i]); string(b) != line {
+			t.Errorf("expected %q in %s, got %q", line, backups[i], b)
+		}
+	}
+}
+
+func TestRotatingFileWriterCloseWhileReporting(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rotate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	w := &RotatingFileWriter{Filename: filepath.Join(dir, "app.log")}
+	w.Archive = NewArchiver(&memoryStore{fail: 1, objects: map[string]string{}}, "logs")
+	w.Archive.MaxRetries = 0
+
+	// the failed upload is reported to the writer being closed
+	internal := Channel(InternalChannel)
+	internal.SetOutput(w)
+	defer internal.SetOutput(os.Stderr)
+
+	w.Write([]byte("first\n"))
+	if err := w.Rotate(); err != nil {
+		t.Fatal(err)
+	}
+	closed := make(chan error, 1)
+	go func() { closed <- w.Close() }()
+	select {
+	case err := <-closed:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Close deadlocked with the cleanup reporting to the writer")
+	}
+	w.Close()
+}
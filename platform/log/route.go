@@ -0,0 +1,293 @@
+package log
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	logrus "github.com/sirupsen/logrus"
+)
+
+// Matcher selects the entries a Destination writes.
+type Matcher interface {
+	Match(entry *logrus.Entry) bool
+}
+
+// MatcherFunc adapts a function to a Matcher.
+type MatcherFunc func(entry *logrus.Entry) bool
+
+func (f MatcherFunc) Match(entry *logrus.Entry) bool {
+	return f(entry)
+}
+
+// ParseMatcher parses a routing expression such as
+//
+//	channel=payments AND level>=warn
+//	field:tenant=acme OR (user~^admin_ AND NOT msg="health check")
+//
+// Conditions compare a key with a value using =, !=, >, >=, <, <= or ~, a
+// regular expression match, and are combined with AND, OR, NOT and
+// parentheses. The keys are level, compared by severity so level>=warn
+// means warnings and worse, msg for the message and any field, optionally
+// written field:name. Values are quoted with double quotes when they hold
+// spaces or parentheses. Ordering operators on fields compare numbers.
+func ParseMatcher(expr string) (Matcher, error) {
+	tokens, err := tokenizeMatcher(expr)
+	if err != nil {
+		return nil, err
+	}
+	p := &matcherParser{tokens: tokens}
+	m, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("log: unexpected %q in %q", p.tokens[p.pos], expr)
+	}
+	return m, nil
+}
+
+// ParseRoute parses a rule of the form "expression -> target" as used by
+// Config.Routes.
+func ParseRoute(rule string) (Matcher, string, error) {
+	expr, target, err := splitRoute(rule)
+	if err != nil {
+		return nil, "", err
+	}
+	m, err := ParseMatcher(expr)
+	if err != nil {
+		return nil, "", err
+	}
+	return m, target, nil
+}
+
+func splitRoute(rule string) (string, string, error) {
+	i := strings.LastIndex(rule, "->")
+	if i < 0 || strings.TrimSpace(rule[i+2:]) == "" {
+		return "", "", fmt.Errorf("log: route %q has no target", rule)
+	}
+	return strings.TrimSpace(rule[:i]), strings.TrimSpace(rule[i+2:]), nil
+}
+
+func tokenizeMatcher(expr string) ([]string, error) {
+	var tokens []string
+	for i := 0; i < len(expr); {
+		switch c := expr[i]; {
+		case c == ' ' || c == '\t' || c == '\n':
+			i++
+		case c == '(' || c == ')':
+			tokens = append(tokens, expr[i:i+1])
+			i++
+		default:
+			start := i
+			quoted := false
+			for i < len(expr) && (quoted || !strings.ContainsRune(" \t\n()", rune(expr[i]))) {
+				switch expr[i] {
+				case '\\':
+					i++
+				case '"':
+					quoted = !quoted
+				}
+				i++
+			}
+			if quoted || i > len(expr) {
+				return nil, fmt.Errorf("log: unterminated quote in %q", expr)
+			}
+			tokens = append(tokens, expr[start:i])
+		}
+	}
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("log: empty expression")
+	}
+	return tokens, nil
+}
+
+type matcherParser struct {
+	tokens []string
+	pos    int
+}
+
+func (p *matcherParser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return ""
+}
+
+func (p *matcherParser) or() (Matcher, error) {
+	m, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+	for strings.EqualFold(p.peek(), "OR") {
+		p.pos++
+		right, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		left := m
+		m = MatcherFunc(func(e *logrus.Entry) bool { return left.Match(e) || right.Match(e) })
+	}
+	return m, nil
+}
+
+func (p *matcherParser) and() (Matcher, error) {
+	m, err := p.not()
+	if err != nil {
+		return nil, err
+	}
+	for strings.EqualFold(p.peek(), "AND") {
+		p.pos++
+		right, err := p.not()
+		if err != nil {
+			return nil, err
+		}
+		left := m
+		m = MatcherFunc(func(e *logrus.Entry) bool { return left.Match(e) && right.Match(e) })
+	}
+	return m, nil
+}
+
+func (p *matcherParser) not() (Matcher, error) {
+	switch t := p.peek(); {
+	case strings.EqualFold(t, "NOT"):
+		p.pos++
+		m, err := p.not()
+		if err != nil {
+			return nil, err
+		}
+		return MatcherFunc(func(e *logrus.Entry) bool { return !m.Match(e) }), nil
+	case t == "(":
+		p.pos++
+		m, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek() != ")" {
+			return nil, fmt.Errorf("log: missing )")
+		}
+		p.pos++
+		return m, nil
+	case t == "":
+		return nil, fmt.Errorf("log: unexpected end of expression")
+	default:
+		p.pos++
+		return parseCondition(t)
+	}
+}
+
+func parseCondition(s string) (Matcher, error) {
+	i := strings.IndexAny(s, "!=<>~")
+	if i <= 0 {
+		return nil, fmt.Errorf("log: not a condition %q", s)
+	}
+	op := s[i : i+1]
+	if i+1 < len(s) && s[i+1] == '=' && op != "=" && op != "~" {
+		op = s[i : i+2]
+	}
+	if op == "!" {
+		return nil, fmt.Errorf("log: not a condition %q", s)
+	}
+	key, value := s[:i], s[i+len(op):]
+	if strings.HasPrefix(value, `"`) {
+		v, err := strconv.Unquote(value)
+		if err != nil {
+			return nil, fmt.Errorf("log: bad value in %q, %v", s, err)
+		}
+		value = v
+	}
+
+	switch key {
+	case "level":
+		return levelCondition(op, value)
+	case "msg", "message":
+		return stringCondition(op, value, func(e *logrus.Entry) (string, bool) {
+			return e.Message, true
+		})
+	}
+	key = strings.TrimPrefix(key, "field:")
+	return stringCondition(op, value, func(e *logrus.Entry) (string, bool) {
+		v, ok := e.Data[key]
+		if !ok {
+			return "", false
+		}
+		return fmt.Sprint(resolveLazy(v)), true
+	})
+}
+
+func levelCondition(op, value string) (Matcher, error) {
+	want, err := LookupLevel(value)
+	if err != nil {
+		return nil, err
+	}
+	_, custom := customLevelByName(want.Name)
+	// more severe levels are lower in logrus
+	switch op {
+	case "=", "!=":
+		eq := op == "="
+		return MatcherFunc(func(e *logrus.Entry) bool {
+			if custom {
+				return (e.Data[LevelKey] == want.Name) == eq
+			}
+			return (e.Level == want.Level) == eq
+		}), nil
+	case ">=":
+		return MatcherFunc(func(e *logrus.Entry) bool { return e.Level <= want.Level }), nil
+	case ">":
+		return MatcherFunc(func(e *logrus.Entry) bool { return e.Level < want.Level }), nil
+	case "<=":
+		return MatcherFunc(func(e *logrus.Entry) bool { return e.Level >= want.Level }), nil
+	case "<":
+		return MatcherFunc(func(e *logrus.Entry) bool { return e.Level > want.Level }), nil
+	}
+	return nil, fmt.Errorf("log: cannot use %s on level", op)
+}
+
+func stringCondition(op, value string, get func(*logrus.Entry) (string, bool)) (Matcher, error) {
+	switch op {
+	case "=":
+		return MatcherFunc(func(e *logrus.Entry) bool {
+			v, ok := get(e)
+			return ok && v == value
+		}), nil
+	case "!=":
+		return MatcherFunc(func(e *logrus.Entry) bool {
+			v, ok := get(e)
+			return !ok || v != value
+		}), nil
+	case "~":
+		re, err := regexp.Compile(value)
+		if err != nil {
+			return nil, err
+		}
+		return MatcherFunc(func(e *logrus.Entry) bool {
+			v, ok := get(e)
+			return ok && re.MatchString(v)
+		}), nil
+	}
+
+	want, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return nil, fmt.Errorf("log: %s needs a number, got %q", op, value)
+	}
+	return MatcherFunc(func(e *logrus.Entry) bool {
+		v, ok := get(e)
+		if !ok {
+			return false
+		}
+		n, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return false
+		}
+		switch op {
+		case ">":
+			return n > want
+		case ">=":
+			return n >= want
+		case "<":
+			return n < want
+		}
+		return n <= want
+	}), nil
+}
@@ -0,0 +1,142 @@
+package log
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	logrus "github.com/sirupsen/logrus"
+)
+
+func TestParseMatcher(t *testing.T) {
+	entry := &logrus.Entry{
+		Level:   logrus.ErrorLevel,
+		Message: "charge failed",
+		Data:    logrus.Fields{ChannelKey: "payments", "tenant": "acme", "amount": 1000, "user": "admin_1"},
+	}
+	audit := &logrus.Entry{Level: logrus.InfoLevel, Data: logrus.Fields{LevelKey: "audit"}}
+
+	for expr, want := range map[string]bool{
+		"channel=payments AND level>=warn":           true,
+		"channel=payments AND level<warn":            false,
+		"field:tenant=acme":                          true,
+		"tenant!=acme OR amount>=1000":               true,
+		"amount>1000":                                false,
+		`msg="charge failed"`:                        true,
+		"missing!=x":                                 true,
+		"missing=x":                                  false,
+		"user~^admin_ AND NOT (channel=http OR x=y)": true,
+		"level=error":                                true,
+		"level=audit":                                false,
+	} {
+		m, err := ParseMatcher(expr)
+		if err != nil {
+			t.Errorf("%s: %v", expr, err)
+			continue
+		}
+		if m.Match(entry) != want {
+			t.Errorf("%s: expected %v", expr, want)
+		}
+	}
+
+	m, _ := ParseMatcher("level=audit")
+	if !m.Match(audit) {
+		t.Errorf("expected the custom level to match")
+	}
+
+	for _, expr := range []string{"", "channel", "a=b AND", "(a=b", "level=loud", "amount>many", `msg="open`, "a=b c=d"} {
+		if _, err := ParseMatcher(expr); err == nil {
+			t.Errorf("expected an error for %q", expr)
+		}
+	}
+}
+
+func TestConfigRoutes(t *testing.T) {
+	c := &Config{
+		Outputs: []OutputConfig{{Type: "stderr"}, {Name: "alerts", Type: "stdout", Match: "x=y"}},
+		Routes: []string{
+			"channel=payments AND level>=warn -> alerts",
+			"level>=error -> alerts",
+			"field:tenant=acme -> file:/var/log/acme.log",
+		},
+	}
+	outputs, err := c.routedOutputs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(outputs) != 3 {
+		t.Fatalf("expected an output added, got %+v", outputs)
+	}
+	if outputs[0].Match != "" {
+		t.Errorf("expected the unnamed output to get everything, got %q", outputs[0].Match)
+	}
+	if outputs[1].Match != "(x=y) AND ((channel=payments AND level>=warn) OR (level>=error))" {
+		t.Errorf("unexpected match %q", outputs[1].Match)
+	}
+	if o := outputs[2]; o.Type != "file" || o.Path != "/var/log/acme.log" || o.Match != "(field:tenant=acme)" {
+		t.Errorf("unexpected output %+v", o)
+	}
+
+	for _, rule := range []string{"level>=warn", "level>=warn ->", "level>>warn -> stdout"} {
+		c := &Config{Routes: []string{rule}}
+		if _, err := c.routedOutputs(); err == nil {
+			t.Errorf("expected an error for %q", rule)
+		}
+	}
+}
+
+func TestConfigRouteToSlack(t *testing.T) {
+	posted := make(chan string, 4)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]string
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Error(err)
+		}
+		posted <- body["text"]
+	}))
+	defer srv.Close()
+
+	dir, err := ioutil.TempDir("", "route")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	c := &Config{
+		Outputs: []OutputConfig{{Type: "file", Path: filepath.Join(dir, "all.log")}},
+		Routes:  []string{"channel=payments AND level>=warn -> slack:" + srv.URL},
+	}
+	closer, err := c.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resetConfig()
+	defer closer.Close()
+
+	Channel("payments").Info("charged")
+	Channel("http").Errorf("timeout")
+	Channel("payments").Warn("card declined")
+	select {
+	case text := <-posted:
+		if !strings.Contains(text, "*warning* [payments] card declined") {
+			t.Errorf("expected the warning of payments posted, got %q", text)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("nothing posted")
+	}
+	select {
+	case text := <-posted:
+		t.Errorf("expected only the routed entry posted, got %q", text)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	if _, err := (&Config{Outputs: []OutputConfig{{Type: "slack", Async: true, Address: srv.URL}}}).Build(); err == nil {
+		t.Errorf("expected an error for an async slack output")
+	}
+}
@@ -0,0 +1,186 @@
+package log
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	logrus "github.com/sirupsen/logrus"
+)
+
+type sampleKey struct {
+	level   logrus.Level
+	message string
+}
+
+type sampleCount struct {
+	start      time.Time
+	n          int
+	suppressed int
+	entry      *logrus.Entry
+}
+
+// SamplingFormatter wraps a formatter and, for each message and level, only
+// formats the First entries of every Interval. The rest are dropped and
+// summed up in a "suppressed N similar entries" entry, logged every Interval
+// through the logger of the dropped entries, or written ahead of the next
+// entry that gets through when that comes first.
+type SamplingFormatter struct {
+	Formatter logrus.Formatter
+
+	// First entries per message and level that are logged every Interval
+	First int
+
+	Interval time.Duration
+
+	mu     sync.Mutex
+	counts map[sampleKey]*sampleCount
+	ticker *time.Ticker
+}
+
+// sampleSummaryKey marks the context of the summaries Flush logs, with the
+// formatter as value
+type sampleSummaryKey struct{}
+
+// Format renders a single log entry, or nothing when it is sampled out
+func (f *SamplingFormatter) Format(entry *logrus.Entry) ([]byte, error) {
+	if entry.Context != nil && entry.Context.Value(sampleSummaryKey{}) == f {
+		return f.Formatter.Format(entry)
+	}
+
+	now := entry.Time
+	key := sampleKey{level: entry.Level, message: entry.Message}
+	if key.message == "" {
+		// Errorf and friends keep the text in the error field
+		key.message = fmt.Sprint(entry.Data["error"])
+	}
+
+	f.mu.Lock()
+	if f.counts == nil {
+		f.counts = map[sampleKey]*sampleCount{}
+	}
+	var summary *logrus.Entry
+	c, ok := f.counts[key]
+	if ok && now.Sub(c.start) >= f.Interval {
+		if c.suppressed > 0 {
+			summary = sampleSummary(c)
+		}
+		ok = false
+	}
+	if !ok {
+		c = &sampleCount{start: now}
+		f.counts[key] = c
+	}
+	c.n++
+	drop := c.n > f.First
+	if drop {
+		c.suppressed++
+		c.entry = entry
+		f.schedule()
+	}
+	f.mu.Unlock()
+
+	out := []byte{}
+	if summary != nil {
+		b, err := f.Formatter.Format(summary)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, b...)
+	}
+	if drop {
+		recordDropped(1)
+		return out, nil
+	}
+	b, err := f.Formatter.Format(entry)
+	if err != nil {
+		return nil, err
+	}
+	return append(out, b...), nil
+}
+
+// schedule starts the ticker logging the summaries, it stops once there is
+// nothing left to count. f.mu must be held.
+func (f *SamplingFormatter) schedule() {
+	if f.ticker != nil || f.Interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(f.Interval)
+	f.ticker = ticker
+	go func() {
+		for range ticker.C {
+			if !f.flush(ticker) {
+				return
+			}
+		}
+	}()
+}
+
+// Flush logs the summaries of the counts whose interval is over, through the
+// logger of the dropped entries, so they are written under the lock of the
+// logger like other entries.
+func (f *SamplingFormatter) Flush() error {
+	f.flush(nil)
+	return nil
+}
+
+// flush logs the summaries and, once nothing is counted anymore, stops
+// ticker when it is still the running one. It returns false then.
+func (f *SamplingFormatter) flush(ticker *time.Ticker) bool {
+	f.mu.Lock()
+	summaries := f.sweep(now())
+	running := true
+	if ticker != nil && len(f.counts) == 0 && f.ticker == ticker {
+		ticker.Stop()
+		f.ticker = nil
+		running = false
+	}
+	f.mu.Unlock()
+
+	for _, s := range summaries {
+		entry := logrus.NewEntry(s.Logger).
+			WithContext(context.WithValue(context.Background(), sampleSummaryKey{}, f)).
+			WithFields(s.Data).
+			WithTime(s.Time)
+		level := s.Level
+		if level == logrus.PanicLevel {
+			// Log panics at PanicLevel, Fatal doesn't exit
+			level = logrus.FatalLevel
+		}
+		entry.Log(level, s.Message)
+	}
+	return running
+}
+
+// sweep resets the counts whose interval is over and returns summary entries
+// for the ones that suppressed anything. f.mu must be held.
+func (f *SamplingFormatter) sweep(now time.Time) []*logrus.Entry {
+	summaries := []*logrus.Entry{}
+	for key, c := range f.counts {
+		if now.Sub(c.start) < f.Interval {
+			continue
+		}
+		if c.suppressed > 0 {
+			summaries = append(summaries, sampleSummary(c))
+		}
+		delete(f.counts, key)
+	}
+	return summaries
+}
+func sampleSummary(c *sampleCount) *logrus.Entry {
+	summary := &logrus.Entry{
+		Logger:  c.entry.Logger,
+		Data:    logrus.Fields{"sampled": c.entry.Message},
+		Time:    c.entry.Time,
+		Level:   c.entry.Level,
+		Message: fmt.Sprintf("suppressed %d similar entries", c.suppressed),
+	}
+	if v, ok := c.entry.Data["error"]; ok && c.entry.Message == "" {
+		summary.Data["sampled"] = fmt.Sprint(v)
+	}
+	if v, ok := c.entry.Data[ChannelKey]; ok {
+		summary.Data[ChannelKey] = v
+	}
+	return summary
+}
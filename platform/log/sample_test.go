@@ -0,0 +1,78 @@
+package log
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	logrus "github.com/sirupsen/logrus"
+)
+
+func TestSamplingFormatter(t *testing.T) {
+	f := &SamplingFormatter{
+		Formatter: &ChannelTextFormatter{DisableTimestamp: true},
+		First:     2,
+		Interval:  time.Minute,
+	}
+	start := time.Now()
+	out := ""
+	for i := 0; i < 5; i++ {
+		entry := logrus.NewEntry(logrus.StandardLogger())
+		entry.Time = start.Add(time.Duration(i) * time.Second)
+		entry.Level = logrus.WarnLevel
+		entry.Message = "node unreachable"
+		b, err := f.Format(entry)
+		if err != nil {
+			t.Fatal(err)
+		}
+		out += string(b)
+	}
+	if strings.Count(out, "node unreachable") != 2 {
+		t.Errorf("expected the first 2 entries, got %q", out)
+	}
+
+	entry := logrus.NewEntry(logrus.StandardLogger())
+	entry.Time = start.Add(2 * time.Minute)
+	entry.Level = logrus.WarnLevel
+	entry.Message = "node unreachable"
+	b, err := f.Format(entry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `level=warning msg="suppressed 3 similar entries" sampled="node unreachable"` + "\n" + `level=warning msg="node unreachable"` + "\n"
+	if string(b) != expected {
+		t.Errorf("expected %q got %q", expected, b)
+	}
+}
+
+func TestSamplingFormatterTicker(t *testing.T) {
+	out := &collectWriter{}
+	logger := logrus.New()
+	logger.Out = out
+	logger.Formatter = &SamplingFormatter{
+		Formatter: &ChannelTextFormatter{DisableTimestamp: true},
+		First:     1,
+		Interval:  50 * time.Millisecond,
+	}
+	for i := 0; i < 4; i++ {
+		logger.Warn("node unreachable")
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		out.mu.Lock()
+		lines := strings.Join(out.lines, "")
+		out.mu.Unlock()
+		if strings.Contains(lines, "suppressed") {
+			expected := `level=warning msg="node unreachable"` + "\n" + `level=warning msg="suppressed 3 similar entries" sampled="node unreachable"` + "\n"
+			if lines != expected {
+				t.Errorf("expected %q got %q", expected, lines)
+			}
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the summary to be logged without another entry, got %q", lines)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
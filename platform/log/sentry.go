@@ -0,0 +1,191 @@
+package log
+
+import (
+	"fmt"
+	"runtime"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+	"github.com/o3labs/openpoint/platform/config"
+	logrus "github.com/sirupsen/logrus"
+)
+
+const defaultSentryFlushTimeout = 2 * time.Second
+
+// SentryHook sends Error, Fatal and Panic entries to Sentry as events. The
+// error field becomes the exception, with its wrapped causes and a stack
+// trace, fields in TagKeys become tags and the other fields extra data.
+type SentryHook struct {
+	// TagKeys are fields sent as searchable tags. Defaults to the channel
+	// name.
+	TagKeys []string
+
+	// FlushTimeout is how long Fatal and Panic entries wait for the event to
+	// be sent before the process goes down. Defaults to 2s.
+	FlushTimeout time.Duration
+
+	client *sentry.Client
+}
+
+// NewSentryHook returns a hook sending to dsn. sampleRate is the fraction of
+// events sent, between 0 and 1; 0 sends all of them. The environment is the
+// config name.
+func NewSentryHook(dsn string, sampleRate float64) (*SentryHook, error) {
+	return newSentryHook(sentry.ClientOptions{
+		Dsn:         dsn,
+		Environment: config.Env.Name,
+		SampleRate:  sampleRate,
+	})
+}
+
+func newSentryHook(options sentry.ClientOptions) (*SentryHook, error) {
+	client, err := sentry.NewClient(options)
+	if err != nil {
+		return nil, err
+	}
+	return &SentryHook{
+		TagKeys:      []string{ChannelKey},
+		FlushTimeout: defaultSentryFlushTimeout,
+		client:       client,
+	}, nil
+}
+
+func (h *SentryHook) Levels() []logrus.Level {
+	return []logrus.Level{logrus.ErrorLevel, logrus.FatalLevel, logrus.PanicLevel}
+}
+
+func (h *SentryHook) Fire(entry *logrus.Entry) error {
+	event := sentry.NewEvent()
+	event.Level = sentryLevel(entry.Level)
+	event.Message = entry.Message
+	event.Timestamp = entry.Time
+
+	// Errorf and Panicf keep the text in the error field, Fatal and Panic
+	// keep the error in the fatal and panic fields
+	cause := ""
+	for _, k := range sentryErrorKeys {
+		if v, ok := entry.Data[k]; ok && v != nil {
+			cause = k
+			event.Exception = sentryValueExceptions(v)
+			break
+		}
+	}
+
+	for k, v := range entry.Data {
+		switch {
+		case k == cause:
+			continue
+		case k == StackKey:
+			// the exception carries the stack trace
+			continue
+		case k == HighlightKey || k == ColorKey:
+			continue
+		case k == ChannelKey:
+			event.Logger = fmt.Sprint(v)
+		}
+		if h.isTag(k) {
+			event.Tags[k] = fmt.Sprint(v)
+		} else if err, ok := v.(error); ok {
+			event.Extra[k] = err.Error()
+		} else {
+			event.Extra[k] = v
+		}
+	}
+	if event.Message == "" && len(event.Exception) > 0 {
+		event.Message = event.Exception[len(event.Exception)-1].Value
+	}
+
+	h.client.CaptureEvent(event, nil, nil)
+	if entry.Level <= logrus.FatalLevel {
+		h.client.Flush(h.FlushTimeout)
+	}
+	return nil
+}
+
+func (h *SentryHook) isTag(key string) bool {
+	for _, k := range h.TagKeys {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
+
+// Flush waits up to timeout for the events still being sent.
+func (h *SentryHook) Flush(timeout time.Duration) bool {
+	return h.client.Flush(timeout)
+}
+
+func sentryLevel(level logrus.Level) sentry.Level {
+	switch level {
+	case logrus.PanicLevel, logrus.FatalLevel:
+		return sentry.LevelFatal
+	case logrus.ErrorLevel:
+		return sentry.LevelError
+	case logrus.WarnLevel:
+		return sentry.LevelWarning
+	case logrus.InfoLevel:
+		return sentry.LevelInfo
+	default:
+		return sentry.LevelDebug
+	}
+}
+
+// sentryExceptions returns an exception per error of err's chain, innermost
+// first as Sentry expects. The outermost one gets the stack trace of err when
+// it has one, otherwise the stack of the code that logged.
+// sentryErrorKeys are the fields an exception is taken from, in order.
+var sentryErrorKeys = []string{logrus.ErrorKey, "fatal", "panic"}
+
+// sentryValueExceptions returns the exceptions for a value logged under one of
+// sentryErrorKeys, which is a plain string for Errorf and Panicf.
+func sentryValueExceptions(v interface{}) []sentry.Exception {
+	if err, ok := v.(error); ok {
+		return sentryExceptions(err)
+	}
+	return []sentry.Exception{{
+		Type:       fmt.Sprintf("%T", v),
+		Value:      fmt.Sprint(v),
+		Stacktrace: sentryStack(),
+	}}
+}
+
+func sentryExceptions(err error) []sentry.Exception {
+	chain := errorChain(err)
+	exceptions := make([]sentry.Exception, len(chain))
+	for i, e := range chain {
+		exceptions[len(chain)-1-i] = sentry.Exception{
+			Type:  fmt.Sprintf("%T", e),
+			Value: e.Error(),
+		}
+	}
+	stack := sentry.ExtractStacktrace(err)
+	if stack == nil {
+		stack = sentryStack()
+	}
+	exceptions[len(exceptions)-1].Stacktrace = stack
+	return exceptions
+}
+
+// sentryStack returns the calling goroutine's stack without logging frames,
+// oldest frame first.
+func sentryStack() *sentry.Stacktrace {
+	pc := make([]uintptr, defaultStackFrames+16)
+	n := runtime.Callers(2, pc)
+	frames := runtime.CallersFrames(pc[:n])
+
+	stack := []sentry.Frame{}
+	for len(stack) < defaultStackFrames {
+		frame, more := frames.Next()
+		if !isLogFrame(frame) && frame.Function != "runtime.goexit" {
+			stack = append(stack, sentry.NewFrame(frame))
+		}
+		if !more {
+			break
+		}
+	}
+	for i, j := 0, len(stack)-1; i < j; i, j = i+1, j-1 {
+		stack[i], stack[j] = stack[j], stack[i]
+	}
+	return &sentry.Stacktrace{Frames: stack}
+}
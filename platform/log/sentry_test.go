@@ -0,0 +1,115 @@
+package log
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+	logrus "github.com/sirupsen/logrus"
+)
+
+// sentryTransport keeps the events instead of sending them
+type sentryTransport struct {
+	mu     sync.Mutex
+	events []*sentry.Event
+}
+
+func (t *sentryTransport) Configure(sentry.ClientOptions) {}
+
+func (t *sentryTransport) SendEvent(event *sentry.Event) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.events = append(t.events, event)
+}
+
+func (t *sentryTransport) Flush(time.Duration) bool {
+	return true
+}
+
+func TestSentryHook(t *testing.T) {
+	transport := &sentryTransport{}
+	h, err := newSentryHook(sentry.ClientOptions{Transport: transport})
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger := logrus.New()
+	logger.Out = &collectWriter{}
+	logger.AddHook(h)
+
+	cause := errors.New("connection refused")
+	logger.WithFields(logrus.Fields{
+		logrus.ErrorKey: fmt.Errorf("charge failed: %w", cause),
+		ChannelKey:      "payments",
+		"invoice":       42,
+		StackKey:        "main.charge",
+	}).Error()
+	logger.Warn("retrying")
+
+	if len(transport.events) != 1 {
+		t.Fatalf("expected only the error sent, got %d events", len(transport.events))
+	}
+	event := transport.events[0]
+	if event.Level != sentry.LevelError || event.Logger != "payments" || event.Tags[ChannelKey] != "payments" {
+		t.Errorf("expected an error event for the payments channel, got %v %v %v", event.Level, event.Logger, event.Tags)
+	}
+	if event.Extra["invoice"] != 42 {
+		t.Errorf("expected the fields as extra data, got %v", event.Extra)
+	}
+	if _, ok := event.Extra[StackKey]; ok {
+		t.Errorf("expected the stack left to the exception, got %v", event.Extra)
+	}
+	if len(event.Exception) != 2 || event.Exception[0].Value != "connection refused" || event.Exception[1].Stacktrace == nil {
+		t.Fatalf("expected the chain innermost first with a stack trace, got %+v", event.Exception)
+	}
+	if event.Message != "charge failed: connection refused" {
+		t.Errorf("expected the error as message, got %q", event.Message)
+	}
+}
+
+func TestSentryHookPackageFunctions(t *testing.T) {
+	transport := &sentryTransport{}
+	h, err := newSentryHook(sentry.ClientOptions{Transport: transport})
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := logrus.StandardLogger().Out
+	logrus.SetOutput(&collectWriter{})
+	logrus.AddHook(h)
+	defer func() {
+		RemoveHook(h)
+		logrus.SetOutput(out)
+	}()
+
+	Errorf("charge failed for invoice %d", 42)
+	Panicf("ledger %s out of balance", "eu")
+	Fatal(errors.New("database unreachable"))
+	Panic(fmt.Errorf("replay: %w", errors.New("duplicate key")))
+
+	expected := []struct {
+		message    string
+		exceptions int
+	}{
+		{"charge failed for invoice 42", 1},
+		{"ledger eu out of balance", 1},
+		{"database unreachable", 1},
+		{"replay: duplicate key", 2},
+	}
+	if len(transport.events) != len(expected) {
+		t.Fatalf("expected %d events, got %d", len(expected), len(transport.events))
+	}
+	for i, e := range expected {
+		event := transport.events[i]
+		if event.Message != e.message {
+			t.Errorf("event %d: expected message %q, got %q", i, e.message, event.Message)
+		}
+		if len(event.Exception) != e.exceptions || event.Exception[len(event.Exception)-1].Stacktrace == nil {
+			t.Errorf("event %d: expected %d exceptions with a stack trace, got %+v", i, e.exceptions, event.Exception)
+		}
+		if len(event.Extra) != 0 {
+			t.Errorf("event %d: expected the error field left out of the extra data, got %v", i, event.Extra)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package log
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"time"
+
+	logrus "github.com/sirupsen/logrus"
+)
+
+// fatalShutdownTimeout bounds the exit hooks and the flush when a Fatal
+// entry exits the process, see SetExitTimeout
+const fatalShutdownTimeout = 5 * time.Second
+
+// SinkStage orders the sinks Shutdown closes.
+type SinkStage int
+
+const (
+	// SinkQueue sinks buffer entries, such as AsyncWriter and the hooks
+	// pushing batches, and are closed first so they flush into sinks of
+	// the next stage.
+	SinkQueue SinkStage = iota
+
+	// SinkOutput sinks are files and connections, closed last.
+	SinkOutput
+)
+
+type sinkEntry struct {
+	closer io.Closer
+	stage  SinkStage
+	seq    uint64
+}
+
+var (
+	sinksMu  sync.Mutex
+	sinks    = map[*sinkEntry]bool{}
+	sinksSeq uint64
+)
+
+func init() {
+	// Fatal exits the process, run the exit hooks and write what is still
+	// queued first
+	logrus.RegisterExitHandler(func() {
+		RunExitHooks()
+	})
+}
+
+// RegisterSink adds c to the sinks Shutdown closes and returns the function
+// removing it, for the Close of c to call. The sinks of this package
+// register themselves when they are created.
+func RegisterSink(c io.Closer, stage SinkStage) func() {
+	sinksMu.Lock()
+	sinksSeq++
+	e := &sinkEntry{closer: c, stage: stage, seq: sinksSeq}
+	sinks[e] = true
+	sinksMu.Unlock()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			sinksMu.Lock()
+			delete(sinks, e)
+			sinksMu.Unlock()
+		})
+	}
+}
+
+// Shutdown flushes and closes every registered sink, queues before outputs
+// and the most recently created first, so the last entries are written
+// before the process exits. Hooks are removed from the standard logger and
+// the channels before they are closed, entries logged afterwards only reach
+// the outputs, which fail writes once closed. It returns the first error, or
+// ctx's error when it is done before all sinks are closed.
+func Shutdown(ctx context.Context) error {
+	done := make(chan error, 1)
+	go func() {
+		var firstErr error
+		// outputs are listed once the queues are closed, files are opened
+		// on the first entry a queue flushes into them
+		for _, stage := range []SinkStage{SinkQueue, SinkOutput} {
+			for _, c := range registeredSinks(stage) {
+				if hook, ok := c.(logrus.Hook); ok {
+					detachHook(hook)
+				}
+				if err := c.Close(); err != nil && err != ErrWriterClosed && firstErr == nil {
+					firstErr = err
+				}
+			}
+		}
+		done <- firstErr
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return fmt.Errorf("log: shutdown didn't finish, %v", ctx.Err())
+	}
+}
+
+// registeredSinks returns the sinks of stage, the most recent first.
+func registeredSinks(stage SinkStage) []io.Closer {
+	sinksMu.Lock()
+	entries := make([]*sinkEntry, 0, len(sinks))
+	for e := range sinks {
+		if e.stage == stage {
+			entries = append(entries, e)
+		}
+	}
+	sinksMu.Unlock()
+	sort.Slice(entries, func(i, j int) bool { return entries[i].seq > entries[j].seq })
+
+	closers := make([]io.Closer, len(entries))
+	for i, e := range entries {
+		closers[i] = e.closer
+	}
+	return closers
+}
+
+// sinkRegistration is embedded by the sinks of this package, register is
+// called by their constructor and release by their Close.
+type sinkRegistration struct {
+	unregister func()
+}
+
+func (r *sinkRegistration) register(c io.Closer, stage SinkStage) {
+	r.unregister = RegisterSink(c, stage)
+}
+
+func (r *sinkRegistration) release() {
+	if r.unregister != nil {
+		r.unregister()
+	}
+}
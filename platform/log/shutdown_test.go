@@ -0,0 +1,108 @@
+package log
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	logrus "github.com/sirupsen/logrus"
+)
+
+type slowWriter struct {
+	delay time.Duration
+	w     *RotatingFileWriter
+}
+
+func (s slowWriter) Write(p []byte) (int, error) {
+	time.Sleep(s.delay)
+	return s.w.Write(p)
+}
+
+type blockingCloser chan struct{}
+
+func (c blockingCloser) Close() error {
+	<-c
+	return nil
+}
+
+func TestShutdown(t *testing.T) {
+	dir, err := ioutil.TempDir("", "shutdown")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	f := &RotatingFileWriter{Filename: filepath.Join(dir, "app.log")}
+	a := NewAsyncWriter(slowWriter{time.Millisecond, f}, 16)
+	for i := 0; i < 10; i++ {
+		a.Write([]byte("entry\n"))
+	}
+	if err := Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	b, _ := ioutil.ReadFile(f.Filename)
+	if n := strings.Count(string(b), "entry\n"); n != 10 {
+		t.Errorf("expected every queued entry written, got %d", n)
+	}
+	f.mu.Lock()
+	open := f.file != nil
+	f.mu.Unlock()
+	if open {
+		t.Error("expected the file closed after the queue was flushed into it")
+	}
+	sinksMu.Lock()
+	left := len(sinks)
+	sinksMu.Unlock()
+	if left != 0 {
+		t.Errorf("expected closed sinks to unregister, %d left", left)
+	}
+}
+
+func TestShutdownDeadline(t *testing.T) {
+	c := blockingCloser(make(chan struct{}))
+	defer close(c)
+	defer RegisterSink(c, SinkOutput)()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := Shutdown(ctx); err == nil {
+		t.Error("expected a sink that doesn't close in time to fail the shutdown")
+	}
+}
+
+func TestLogAfterShutdown(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	h := NewLokiHook(server.URL, nil)
+	l := Channel("shutdown.after")
+	l.SetOutput(NewAsyncWriter(ioutil.Discard, 16))
+	l.AddHook(h)
+	logrus.AddHook(h)
+	defer RemoveHook(h)
+
+	if err := Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	l.Info("logged after shutdown")
+	logrus.Info("logged after shutdown")
+
+	for _, logger := range []*logrus.Logger{l.logger, logrus.StandardLogger()} {
+		for _, hooks := range logger.Hooks {
+			for _, hook := range hooks {
+				if hook == logrus.Hook(h) {
+					t.Fatal("expected the closed hook detached")
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package log
+
+import (
+	"os"
+	"os/signal"
+	"sync"
+
+	logrus "github.com/sirupsen/logrus"
+)
+
+// Reopener is a writer that can reopen its file, see RotatingFileWriter.
+type Reopener interface {
+	Reopen() error
+}
+
+var (
+	reopenersMu sync.Mutex
+	reopeners   = map[*reopenerEntry]bool{}
+)
+
+type reopenerEntry struct {
+	Reopener
+}
+
+// RegisterReopener adds r to the writers ReopenFiles reopens. Call the
+// returned function once r is closed. Config.Build registers the files it
+// opens.
+func RegisterReopener(r Reopener) func() {
+	e := &reopenerEntry{r}
+	reopenersMu.Lock()
+	reopeners[e] = true
+	reopenersMu.Unlock()
+	return func() {
+		reopenersMu.Lock()
+		delete(reopeners, e)
+		reopenersMu.Unlock()
+	}
+}
+
+// ReopenFiles reopens every registered writer and returns the first error,
+// after trying all of them.
+func ReopenFiles() error {
+	reopenersMu.Lock()
+	defer reopenersMu.Unlock()
+	var firstErr error
+	for e := range reopeners {
+		if err := e.Reopen(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// HandleSignals lets operators control logging with signals until the
+// returned function is called:
+//
+//	SIGHUP   reopens the log files, after logrotate moved them aside
+//	SIGUSR1  sets the level to debug
+//	SIGUSR2  sets the level back to what it was before SIGUSR1
+//
+// On Windows, which has none of these signals, it does nothing.
+func HandleSignals() func() {
+	signals := make(chan os.Signal, 1)
+	all := append(append(append([]os.Signal{}, reopenSignals...), debugOnSignals...), debugOffSignals...)
+	if len(all) == 0 {
+		return func() {}
+	}
+	signal.Notify(signals, all...)
+
+	done := make(chan struct{})
+	exited := make(chan struct{})
+	go func() {
+		defer close(exited)
+		var saved *logrus.Level
+		for {
+			select {
+			case s := <-signals:
+				switch {
+				case containsSignal(reopenSignals, s):
+					if err := ReopenFiles(); err != nil {
+						reportf("Failed to reopen log files because %+v", err)
+					} else {
+						Info("Reopened log files on %v", s)
+					}
+				case containsSignal(debugOnSignals, s):
+					if saved == nil {
+						level := GetLevel()
+						saved = &level
+					}
+					SetLevel(logrus.DebugLevel)
+					Info("Log level set to debug on %v", s)
+				case containsSignal(debugOffSignals, s):
+					if saved != nil {
+						SetLevel(*saved)
+						Info("Log level set back to %v on %v", *saved, s)
+						saved = nil
+					}
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			signal.Stop(signals)
+			close(done)
+			<-exited
+		})
+	}
+}
+
+func containsSignal(signals []os.Signal, s os.Signal) bool {
+	for _, c := range signals {
+		if c == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,62 @@
+//go:build !windows
+// +build !windows
+
+package log
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	logrus "github.com/sirupsen/logrus"
+)
+
+func TestHandleSignals(t *testing.T) {
+	dir, err := ioutil.TempDir("", "signal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	w := &RotatingFileWriter{Filename: filepath.Join(dir, "app.log")}
+	defer w.Close()
+	defer RegisterReopener(w)()
+	defer SetLevel(GetLevel())
+	SetLevel(logrus.InfoLevel)
+
+	stop := HandleSignals()
+	defer stop()
+
+	w.Write([]byte("before\n"))
+	if err := os.Rename(w.Filename, w.Filename+".1"); err != nil {
+		t.Fatal(err)
+	}
+	syscall.Kill(os.Getpid(), syscall.SIGHUP)
+	waitFor(t, func() bool {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		return w.file == nil
+	})
+	w.Write([]byte("after\n"))
+	if b, _ := ioutil.ReadFile(w.Filename); string(b) != "after\n" {
+		t.Errorf("expected a new file after SIGHUP, got %q", b)
+	}
+
+	syscall.Kill(os.Getpid(), syscall.SIGUSR1)
+	waitFor(t, func() bool { return GetLevel() == logrus.DebugLevel })
+	syscall.Kill(os.Getpid(), syscall.SIGUSR2)
+	waitFor(t, func() bool { return GetLevel() == logrus.InfoLevel })
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(5 * time.Millisecond) {
+		if cond() {
+			return
+		}
+	}
+	t.Fatal("timed out")
+}
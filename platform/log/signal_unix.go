@@ -0,0 +1,15 @@
+//go:build !windows
+// +build !windows
+
+package log
+
+import (
+	"os"
+	"syscall"
+)
+
+var (
+	reopenSignals   = []os.Signal{syscall.SIGHUP}
+	debugOnSignals  = []os.Signal{syscall.SIGUSR1}
+	debugOffSignals = []os.Signal{syscall.SIGUSR2}
+)
@@ -0,0 +1,9 @@
+package log
+
+import "os"
+
+var (
+	reopenSignals   []os.Signal
+	debugOnSignals  []os.Signal
+	debugOffSignals []os.Signal
+)
@@ -0,0 +1,101 @@
+package log
+
+import (
+	"context"
+	"log/slog"
+
+	logrus "github.com/sirupsen/logrus"
+)
+
+// SlogHandler is a slog.Handler writing through a channel or the standard
+// logger, so code using log/slog shares the same formatters and outputs.
+type SlogHandler struct {
+	logger *logrus.Logger
+	fields logrus.Fields
+	prefix string
+}
+
+// NewSlogHandler returns a handler for channel l, or for the standard logger
+// when l is nil.
+//
+//	logger := slog.New(log.NewSlogHandler(log.Channel("payments")))
+func NewSlogHandler(l *Logger) *SlogHandler {
+	if l == nil {
+		return &SlogHandler{logger: logrus.StandardLogger(), fields: logrus.Fields{}}
+	}
+	return &SlogHandler{logger: l.logger, fields: logrus.Fields{ChannelKey: l.name}}
+}
+
+// SlogLevel maps a slog level to a logrus level.
+func SlogLevel(level slog.Level) logrus.Level {
+	switch {
+	case level >= slog.LevelError:
+		return logrus.ErrorLevel
+	case level >= slog.LevelWarn:
+		return logrus.WarnLevel
+	case level >= slog.LevelInfo:
+		return logrus.InfoLevel
+	case level >= slog.LevelDebug:
+		return logrus.DebugLevel
+	default:
+		return logrus.TraceLevel
+	}
+}
+
+func (h *SlogHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.logger.IsLevelEnabled(SlogLevel(level))
+}
+
+func (h *SlogHandler) Handle(ctx context.Context, r slog.Record) error {
+	fields := make(logrus.Fields, len(h.fields)+r.NumAttrs())
+	for k, v := range h.fields {
+		fields[k] = v
+	}
+	r.Attrs(func(a slog.Attr) bool {
+		addSlogAttr(fields, h.prefix, a)
+		return true
+	})
+
+	entry := h.logger.WithFields(fields).WithTime(r.Time)
+	if ctx != nil {
+		entry = entry.WithContext(ctx)
+	}
+	entry.Log(SlogLevel(r.Level), r.Message)
+	return nil
+}
+
+func (h *SlogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	fields := make(logrus.Fields, len(h.fields)+len(attrs))
+	for k, v := range h.fields {
+		fields[k] = v
+	}
+	for _, a := range attrs {
+		addSlogAttr(fields, h.prefix, a)
+	}
+	return &SlogHandler{logger: h.logger, fields: fields, prefix: h.prefix}
+}
+
+func (h *SlogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	return &SlogHandler{logger: h.logger, fields: h.fields, prefix: h.prefix + name + "."}
+}
+
+// addSlogAttr adds a to fields, flattening groups into dotted keys.
+func addSlogAttr(fields logrus.Fields, prefix string, a slog.Attr) {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return
+	}
+	if a.Value.Kind() == slog.KindGroup {
+		if a.Key != "" {
+			prefix += a.Key + "."
+		}
+		for _, ga := range a.Value.Group() {
+			addSlogAttr(fields, prefix, ga)
+		}
+		return
+	}
+	fields[prefix+a.Key] = a.Value.Any()
+}
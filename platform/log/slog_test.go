@@ -0,0 +1,45 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"testing/slogtest"
+
+	logrus "github.com/sirupsen/logrus"
+)
+
+func TestSlogHandler(t *testing.T) {
+	b := &bytes.Buffer{}
+	logger := logrus.New()
+	logger.Out = b
+	logger.Formatter = &ChannelJSONFormatter{NestFields: true, FieldMap: FieldMap{FieldKeyTime: "time", FieldKeyMsg: "msg"}}
+	h := &SlogHandler{logger: logger, fields: logrus.Fields{}}
+
+	results := func() []map[string]interface{} {
+		ms := []map[string]interface{}{}
+		for _, line := range bytes.Split(bytes.TrimSpace(b.Bytes()), []byte("\n")) {
+			m := map[string]interface{}{}
+			if err := json.Unmarshal(line, &m); err != nil {
+				t.Fatalf("%v %s", err, line)
+			}
+			ms = append(ms, m)
+		}
+		return ms
+	}
+	err := slogtest.TestHandler(h, results)
+	if err == nil {
+		return
+	}
+	errs := []error{err}
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		errs = joined.Unwrap()
+	}
+	for _, err := range errs {
+		// logrus stamps every entry, a zero time included
+		if !strings.Contains(err.Error(), "zero Record.Time") {
+			t.Error(err)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package log
+
+import (
+	"io"
+	"os"
+
+	logrus "github.com/sirupsen/logrus"
+)
+
+// LevelWriter is a writer choosing where to write an entry by its level.
+// Destinations pass the level of the entries they write to writers
+// implementing it.
+type LevelWriter interface {
+	io.Writer
+	WriteLevel(level logrus.Level, p []byte) (int, error)
+}
+
+// SplitWriter writes entries to a writer picked by their level, e.g. warnings
+// and errors to stderr and the rest to stdout, for container runtimes that
+// tell severities apart by stream:
+//
+//	hook := log.NewMultiHook(&log.Destination{
+//		Writer:    log.NewStdSplitWriter(),
+//		Formatter: &log.ChannelJSONFormatter{},
+//		Level:     logrus.InfoLevel,
+//	})
+//
+// It only sees the level of entries written through a Destination, see
+// MultiHook and Config. Used as the output of a logger it writes everything
+// to Default.
+type SplitWriter struct {
+	// Writers are the writers of levels, levels without a writer are
+	// written to Default
+	Writers map[logrus.Level]io.Writer
+	Default io.Writer
+}
+
+// NewSplitWriter returns a writer writing entries at level or more severe
+// to high and the others to low.
+func NewSplitWriter(level logrus.Level, low, high io.Writer) *SplitWriter {
+	w := &SplitWriter{Writers: map[logrus.Level]io.Writer{}, Default: low}
+	for _, l := range logrus.AllLevels {
+		if l <= level {
+			w.Writers[l] = high
+		}
+	}
+	return w
+}
+
+// NewStdSplitWriter returns a writer writing warnings and more severe
+// entries to stderr and the others to stdout.
+func NewStdSplitWriter() *SplitWriter {
+	return NewSplitWriter(logrus.WarnLevel, os.Stdout, os.Stderr)
+}
+
+// Write writes p to Default.
+func (w *SplitWriter) Write(p []byte) (int, error) {
+	return w.Default.Write(p)
+}
+
+// WriteLevel writes p to the writer of level.
+func (w *SplitWriter) WriteLevel(level logrus.Level, p []byte) (int, error) {
+	if lw, ok := w.Writers[level]; ok {
+		return lw.Write(p)
+	}
+	return w.Default.Write(p)
+}
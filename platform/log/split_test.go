@@ -0,0 +1,36 @@
+package log
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+
+	logrus "github.com/sirupsen/logrus"
+)
+
+func TestSplitWriter(t *testing.T) {
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	l := logrus.New()
+	l.Out = ioutil.Discard
+	l.Level = logrus.DebugLevel
+	l.AddHook(NewMultiHook(&Destination{
+		Writer:    NewSplitWriter(logrus.WarnLevel, stdout, stderr),
+		Formatter: &ChannelTextFormatter{DisableColors: true, DisableTimestamp: true},
+		Level:     logrus.DebugLevel,
+	}))
+
+	l.Debug("cache miss")
+	l.Info("charge succeeded")
+	l.Warn("retrying")
+	l.Error("charge failed")
+	if stdout.String() != "level=debug msg=\"cache miss\"\nlevel=info msg=\"charge succeeded\"\n" {
+		t.Errorf("unexpected stdout %q", stdout.String())
+	}
+	if stderr.String() != "level=warning msg=retrying\nlevel=error msg=\"charge failed\"\n" {
+		t.Errorf("unexpected stderr %q", stderr.String())
+	}
+
+	if _, err := (&Config{Outputs: []OutputConfig{{Type: "split", Async: true}}}).Build(); err == nil {
+		t.Errorf("expected an error for an async split output")
+	}
+}
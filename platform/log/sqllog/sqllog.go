@@ -0,0 +1,412 @@
+// Package sqllog wraps database/sql drivers to log every query with its
+// arguments, the rows it affected or returned and its latency:
+//
+//	sqllog.Register("postgres-logged", &pq.Driver{}, sqllog.Options{SlowThreshold: 200 * time.Millisecond})
+//	db, err := sql.Open("postgres-logged", dsn)
+//
+// Queries are logged at Debug, slow ones at Warn with slow=true and failed
+// ones at Error.
+package sqllog
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/o3labs/openpoint/platform/log"
+	"github.com/o3labs/openpoint/platform/log/requestid"
+	logrus "github.com/sirupsen/logrus"
+)
+
+const DefaultChannel = "sql"
+
+type Options struct {
+	// Channel the queries are logged to. Defaults to "sql".
+	Channel string
+
+	// SlowThreshold logs queries taking at least this long at Warn. Zero
+	// disables it.
+	SlowThreshold time.Duration
+
+	// Redact masks the matches of its patterns in queries and string
+	// arguments. Defaults to log.NewRedactHook().
+	Redact *log.RedactHook
+
+	// HideArgs leaves the arguments out entirely
+	HideArgs bool
+}
+
+func (o Options) withDefaults() Options {
+	if o.Channel == "" {
+		o.Channel = DefaultChannel
+	}
+	if o.Redact == nil {
+		o.Redact = log.NewRedactHook()
+	}
+	return o
+}
+
+// Register makes d available to sql.Open under name, logging its queries.
+func Register(name string, d driver.Driver, o Options) {
+	sql.Register(name, Wrap(d, o))
+}
+
+// Wrap returns a driver logging the queries of d.
+func Wrap(d driver.Driver, o Options) driver.Driver {
+	return &wrappedDriver{Driver: d, logger: newLogger(o)}
+}
+
+// WrapConnector returns a connector logging the queries of c, for
+// sql.OpenDB.
+func WrapConnector(c driver.Connector, o Options) driver.Connector {
+	return &connector{Connector: c, logger: newLogger(o)}
+}
+
+type logger struct {
+	Options
+	channel *log.Logger
+}
+
+func newLogger(o Options) *logger {
+	o = o.withDefaults()
+	return &logger{Options: o, channel: log.Channel(o.Channel)}
+}
+
+// log logs a finished query. rows is -1 when the driver doesn't tell.
+func (l *logger) log(ctx context.Context, query string, args []driver.NamedValue, start time.Time, rows int64, err error) {
+	if err == driver.ErrSkip {
+		return
+	}
+	latency := time.Since(start)
+	fields := logrus.Fields{
+		"query":   l.Redact.Redact(strings.Join(strings.Fields(query), " ")),
+		"latency": latency.String(),
+	}
+	if len(args) > 0 && !l.HideArgs {
+		fields["args"] = l.args(args)
+	}
+	if rows >= 0 {
+		fields["rows"] = rows
+	}
+	if id := requestid.FromContext(ctx); id != "" {
+		fields[requestid.Key] = id
+	}
+	entry := l.channel.WithFields(fields).WithContext(ctx)
+
+	switch {
+	case err != nil:
+		entry.WithField(logrus.ErrorKey, err).Error("query")
+	case l.SlowThreshold > 0 && latency >= l.SlowThreshold:
+		entry.WithField("slow", true).Warn("query")
+	default:
+		entry.Debug("query")
+	}
+}
+
+// args returns the arguments as logged, strings redacted and binary values
+// as their length.
+func (l *logger) args(args []driver.NamedValue) []interface{} {
+	logged := make([]interface{}, len(args))
+	for i, a := range args {
+		switch v := a.Value.(type) {
+		case nil:
+			logged[i] = "NULL"
+		case string:
+			logged[i] = l.Redact.Redact(v)
+		case []byte:
+			logged[i] = fmt.Sprintf("<%d bytes>", len(v))
+		case time.Time:
+			logged[i] = v.Format(time.RFC3339Nano)
+		default:
+			logged[i] = v
+		}
+	}
+	return logged
+}
+
+type wrappedDriver struct {
+	driver.Driver
+	logger *logger
+}
+
+func (d *wrappedDriver) Open(name string) (driver.Conn, error) {
+	c, err := d.Driver.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return &conn{Conn: c, logger: d.logger}, nil
+}
+
+type connector struct {
+	driver.Connector
+	logger *logger
+}
+
+func (c *connector) Connect(ctx context.Context) (driver.Conn, error) {
+	cn, err := c.Connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &conn{Conn: cn, logger: c.logger}, nil
+}
+
+func (c *connector) Driver() driver.Driver {
+	return &wrappedDriver{Driver: c.Connector.Driver(), logger: c.logger}
+}
+
+// conn implements the optional interfaces of database/sql, falling back to
+// what database/sql does itself when the driver doesn't.
+type conn struct {
+	driver.Conn
+	logger *logger
+}
+
+func (c *conn) Prepare(query string) (driver.Stmt, error) {
+	s, err := c.Conn.Prepare(query)
+	if err != nil {
+		return nil, err
+	}
+	return &stmt{Stmt: s, conn: c.Conn, query: query, logger: c.logger}, nil
+}
+
+func (c *conn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	pc, ok := c.Conn.(driver.ConnPrepareContext)
+	if !ok {
+		return c.Prepare(query)
+	}
+	s, err := pc.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	return &stmt{Stmt: s, conn: c.Conn, query: query, logger: c.logger}, nil
+}
+
+func (c *conn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if b, ok := c.Conn.(driver.ConnBeginTx); ok {
+		return b.BeginTx(ctx, opts)
+	}
+	if opts.Isolation != driver.IsolationLevel(sql.LevelDefault) {
+		return nil, errors.New("sqllog: driver does not support non-default isolation level")
+	}
+	if opts.ReadOnly {
+		return nil, errors.New("sqllog: driver does not support read-only transactions")
+	}
+	return c.Conn.Begin()
+}
+
+// ExecContext returns driver.ErrSkip when the driver can't execute without
+// preparing, database/sql then prepares a statement, which is logged.
+func (c *conn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	e, ok := c.Conn.(driver.ExecerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	start := time.Now()
+	res, err := e.ExecContext(ctx, query, args)
+	c.logger.log(ctx, query, args, start, rowsAffected(res, err), err)
+	return res, err
+}
+
+func (c *conn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	q, ok := c.Conn.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	start := time.Now()
+	r, err := q.QueryContext(ctx, query, args)
+	if err != nil {
+		c.logger.log(ctx, query, args, start, -1, err)
+		return nil, err
+	}
+	return &rows{Rows: r, ctx: ctx, query: query, args: args, start: start, logger: c.logger}, nil
+}
+
+func (c *conn) Ping(ctx context.Context) error {
+	if p, ok := c.Conn.(driver.Pinger); ok {
+		return p.Ping(ctx)
+	}
+	return nil
+}
+
+func (c *conn) ResetSession(ctx context.Context) error {
+	if r, ok := c.Conn.(driver.SessionResetter); ok {
+		return r.ResetSession(ctx)
+	}
+	return nil
+}
+
+func (c *conn) IsValid() bool {
+	if v, ok := c.Conn.(driver.Validator); ok {
+		return v.IsValid()
+	}
+	return true
+}
+
+func (c *conn) CheckNamedValue(nv *driver.NamedValue) error {
+	if n, ok := c.Conn.(driver.NamedValueChecker); ok {
+		return n.CheckNamedValue(nv)
+	}
+	return driver.ErrSkip
+}
+
+type stmt struct {
+	driver.Stmt
+	conn   driver.Conn
+	query  string
+	logger *logger
+}
+
+func (s *stmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	start := time.Now()
+	var res driver.Result
+	var err error
+	if e, ok := s.Stmt.(driver.StmtExecContext); ok {
+		res, err = e.ExecContext(ctx, args)
+	} else {
+		var values []driver.Value
+		if values, err = namedValues(args); err == nil {
+			res, err = s.Stmt.Exec(values)
+		}
+	}
+	s.logger.log(ctx, s.query, args, start, rowsAffected(res, err), err)
+	return res, err
+}
+
+func (s *stmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	start := time.Now()
+	var r driver.Rows
+	var err error
+	if q, ok := s.Stmt.(driver.StmtQueryContext); ok {
+		r, err = q.QueryContext(ctx, args)
+	} else {
+		var values []driver.Value
+		if values, err = namedValues(args); err == nil {
+			r, err = s.Stmt.Query(values)
+		}
+	}
+	if err != nil {
+		s.logger.log(ctx, s.query, args, start, -1, err)
+		return nil, err
+	}
+	return &rows{Rows: r, ctx: ctx, query: s.query, args: args, start: start, logger: s.logger}, nil
+}
+
+// CheckNamedValue asks the statement, then the connection, the way
+// database/sql does.
+func (s *stmt) CheckNamedValue(nv *driver.NamedValue) error {
+	if n, ok := s.Stmt.(driver.NamedValueChecker); ok {
+		return n.CheckNamedValue(nv)
+	}
+	if n, ok := s.conn.(driver.NamedValueChecker); ok {
+		return n.CheckNamedValue(nv)
+	}
+	return driver.ErrSkip
+}
+
+// rows counts the rows read and logs the query once closed, so the latency
+// includes reading the results.
+type rows struct {
+	driver.Rows
+	ctx    context.Context
+	query  string
+	args   []driver.NamedValue
+	start  time.Time
+	n      int64
+	err    error
+	logger *logger
+}
+
+func (r *rows) Next(dest []driver.Value) error {
+	err := r.Rows.Next(dest)
+	switch err {
+	case nil:
+		r.n++
+	case io.EOF:
+	default:
+		r.err = err
+	}
+	return err
+}
+
+func (r *rows) Close() error {
+	err := r.Rows.Close()
+	r.logger.log(r.ctx, r.query, r.args, r.start, r.n, r.err)
+	return err
+}
+
+func (r *rows) HasNextResultSet() bool {
+	if n, ok := r.Rows.(driver.RowsNextResultSet); ok {
+		return n.HasNextResultSet()
+	}
+	return false
+}
+
+func (r *rows) NextResultSet() error {
+	if n, ok := r.Rows.(driver.RowsNextResultSet); ok {
+		return n.NextResultSet()
+	}
+	return io.EOF
+}
+
+func (r *rows) ColumnTypeScanType(index int) reflect.Type {
+	if c, ok := r.Rows.(driver.RowsColumnTypeScanType); ok {
+		return c.ColumnTypeScanType(index)
+	}
+	return reflect.TypeOf(new(interface{})).Elem()
+}
+
+func (r *rows) ColumnTypeDatabaseTypeName(index int) string {
+	if c, ok := r.Rows.(driver.RowsColumnTypeDatabaseTypeName); ok {
+		return c.ColumnTypeDatabaseTypeName(index)
+	}
+	return ""
+}
+
+func (r *rows) ColumnTypeLength(index int) (int64, bool) {
+	if c, ok := r.Rows.(driver.RowsColumnTypeLength); ok {
+		return c.ColumnTypeLength(index)
+	}
+	return 0, false
+}
+
+func (r *rows) ColumnTypeNullable(index int) (bool, bool) {
+	if c, ok := r.Rows.(driver.RowsColumnTypeNullable); ok {
+		return c.ColumnTypeNullable(index)
+	}
+	return false, false
+}
+
+func (r *rows) ColumnTypePrecisionScale(index int) (int64, int64, bool) {
+	if c, ok := r.Rows.(driver.RowsColumnTypePrecisionScale); ok {
+		return c.ColumnTypePrecisionScale(index)
+	}
+	return 0, 0, false
+}
+
+func rowsAffected(res driver.Result, err error) int64 {
+	if err != nil || res == nil {
+		return -1
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return -1
+	}
+	return n
+}
+
+func namedValues(args []driver.NamedValue) ([]driver.Value, error) {
+	values := make([]driver.Value, len(args))
+	for i, a := range args {
+		if a.Name != "" {
+			return nil, errors.New("sqllog: driver does not support the use of Named Parameters")
+		}
+		values[i] = a.Value
+	}
+	return values, nil
+}
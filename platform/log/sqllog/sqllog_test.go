@@ -0,0 +1,158 @@
+package sqllog
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/o3labs/openpoint/platform/log"
+	"github.com/o3labs/openpoint/platform/log/requestid"
+	logrus "github.com/sirupsen/logrus"
+)
+
+// fakeDriver answers every query with two rows and fails the ones
+// mentioning missing_table. It doesn't execute without preparing, so the
+// statement path is used.
+type fakeDriver struct {
+	delay time.Duration
+}
+
+func (d *fakeDriver) Open(name string) (driver.Conn, error) {
+	return &fakeConn{delay: d.delay}, nil
+}
+
+type fakeConn struct {
+	delay time.Duration
+}
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return &fakeStmt{query: query, delay: c.delay}, nil
+}
+
+func (c *fakeConn) Close() error              { return nil }
+func (c *fakeConn) Begin() (driver.Tx, error) { return nil, errors.New("no transactions") }
+
+type fakeStmt struct {
+	query string
+	delay time.Duration
+}
+
+func (s *fakeStmt) Close() error  { return nil }
+func (s *fakeStmt) NumInput() int { return -1 }
+
+func (s *fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	time.Sleep(s.delay)
+	if strings.Contains(s.query, "missing_table") {
+		return nil, errors.New(`relation "missing_table" does not exist`)
+	}
+	return driver.RowsAffected(3), nil
+}
+
+func (s *fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	time.Sleep(s.delay)
+	return &fakeRows{n: 2}, nil
+}
+
+type fakeRows struct {
+	n int
+}
+
+func (r *fakeRows) Columns() []string { return []string{"id"} }
+func (r *fakeRows) Close() error      { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.n == 0 {
+		return io.EOF
+	}
+	r.n--
+	dest[0] = int64(r.n)
+	return nil
+}
+
+func TestDriver(t *testing.T) {
+	b := &bytes.Buffer{}
+	channel := log.Channel("sqllog")
+	channel.SetOutput(b)
+	channel.SetFormatter(&log.ChannelTextFormatter{DisableTimestamp: true})
+	channel.SetLevel(logrus.DebugLevel)
+
+	Register("sqllog-fake", &fakeDriver{}, Options{Channel: "sqllog"})
+	db, err := sql.Open("sqllog-fake", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	ctx := requestid.NewContext(context.Background(), "r1")
+	if _, err := db.ExecContext(ctx, "UPDATE users\n\tSET email = $1, avatar = $2 WHERE id = $3", "alice@example.com", []byte("png"), 7); err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{"level=debug", `query="UPDATE users SET email = $1, avatar = $2 WHERE id = $3"`, "[REDACTED] <3 bytes> 7", "rows=3", "request_id=r1"} {
+		if !strings.Contains(b.String(), s) {
+			t.Errorf("expected %v in %q", s, b.String())
+		}
+	}
+	if strings.Contains(b.String(), "alice") {
+		t.Errorf("expected the email to be redacted, got %q", b.String())
+	}
+
+	b.Reset()
+	rows, err := db.Query("SELECT id FROM users")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for rows.Next() {
+	}
+	rows.Close()
+	if !strings.Contains(b.String(), "rows=2") {
+		t.Errorf("expected the rows read in %q", b.String())
+	}
+
+	b.Reset()
+	if _, err := db.Exec("DELETE FROM missing_table"); err == nil {
+		t.Fatal("expected the query to fail")
+	}
+	if !strings.Contains(b.String(), "level=error") || !strings.Contains(b.String(), "does not exist") {
+		t.Errorf("expected the failure at error, got %q", b.String())
+	}
+}
+
+func TestSlowQuery(t *testing.T) {
+	b := &bytes.Buffer{}
+	channel := log.Channel("sqllog-slow")
+	channel.SetOutput(b)
+	channel.SetFormatter(&log.ChannelTextFormatter{DisableTimestamp: true})
+	channel.SetLevel(logrus.InfoLevel)
+
+	c := WrapConnector(&fakeConnector{&fakeDriver{delay: 20 * time.Millisecond}}, Options{Channel: "sqllog-slow", SlowThreshold: 10 * time.Millisecond, HideArgs: true})
+	db := sql.OpenDB(c)
+	defer db.Close()
+
+	if _, err := db.Exec("UPDATE cards SET pan = $1", "4111 1111 1111 1111"); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(b.String(), "level=warning") || !strings.Contains(b.String(), "slow=true") {
+		t.Errorf("expected a slow query warning, got %q", b.String())
+	}
+	if strings.Contains(b.String(), "args") {
+		t.Errorf("expected no args, got %q", b.String())
+	}
+}
+
+type fakeConnector struct {
+	d *fakeDriver
+}
+
+func (c *fakeConnector) Connect(context.Context) (driver.Conn, error) {
+	return c.d.Open("")
+}
+
+func (c *fakeConnector) Driver() driver.Driver {
+	return c.d
+}
@@ -0,0 +1,71 @@
+package log
+
+import (
+	"bytes"
+	"fmt"
+	"runtime"
+
+	logrus "github.com/sirupsen/logrus"
+)
+
+// StackKey is the field holding the stack trace added by StackHook.
+const StackKey = "stack"
+
+const defaultStackFrames = 32
+
+// StackHook adds the goroutine's stack trace to Error, Fatal and Panic
+// entries. Frames of logrus and this package are left out so the trace starts
+// at the code that logged.
+type StackHook struct {
+	// MaxFrames kept in the trace. Defaults to 32.
+	MaxFrames int
+
+	// TrimPrefixes are stripped from file paths, see
+	// ChannelTextFormatter.CallerTrimPrefixes.
+	TrimPrefixes []string
+}
+
+func NewStackHook() *StackHook {
+	return &StackHook{MaxFrames: defaultStackFrames}
+}
+
+func (h *StackHook) Levels() []logrus.Level {
+	return []logrus.Level{logrus.ErrorLevel, logrus.FatalLevel, logrus.PanicLevel}
+}
+
+func (h *StackHook) Fire(entry *logrus.Entry) error {
+	if _, ok := entry.Data[StackKey]; ok {
+		return nil
+	}
+	entry.Data[StackKey] = captureStack(h.MaxFrames, h.TrimPrefixes)
+	return nil
+}
+
+// captureStack returns up to max frames of the calling goroutine, one
+// "function\n\tfile:line" pair per frame, without logging frames.
+func captureStack(max int, prefixes []string) string {
+	if max <= 0 {
+		max = defaultStackFrames
+	}
+	pc := make([]uintptr, max+16)
+	n := runtime.Callers(2, pc)
+	frames := runtime.CallersFrames(pc[:n])
+
+	b := &bytes.Buffer{}
+	count := 0
+	for count < max {
+		frame, more := frames.Next()
+		if !isLogFrame(frame) && frame.Function != "runtime.goexit" {
+			caller, function := formatCaller(frame, prefixes)
+			if b.Len() > 0 {
+				b.WriteByte('\n')
+			}
+			fmt.Fprintf(b, "%s\n\t%s", function, caller)
+			count++
+		}
+		if !more {
+			break
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,54 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	logrus "github.com/sirupsen/logrus"
+)
+
+func TestStackHook(t *testing.T) {
+	b := &bytes.Buffer{}
+	logger := logrus.New()
+	logger.Out = b
+	logger.Formatter = &ChannelJSONFormatter{}
+	h := NewStackHook()
+	logger.AddHook(h)
+
+	stack := func() interface{} {
+		defer b.Reset()
+		out := map[string]interface{}{}
+		if err := json.Unmarshal(b.Bytes(), &out); err != nil {
+			t.Fatalf("%v %s", err, b)
+		}
+		return out[StackKey]
+	}
+
+	logger.Error("failed")
+	s, _ := stack().(string)
+	lines := strings.Split(s, "\n")
+	if !strings.HasSuffix(lines[0], "log.TestStackHook") || !strings.Contains(lines[1], "stack_test.go:") {
+		t.Errorf("expected the trace to start at the test, got %q", s)
+	}
+	if strings.Contains(s, "sirupsen/logrus") {
+		t.Errorf("expected no logrus frames, got %q", s)
+	}
+
+	h.MaxFrames = 1
+	logger.Error("failed")
+	if s, _ := stack().(string); strings.Count(s, "\n") != 1 {
+		t.Errorf("expected a single frame, got %q", s)
+	}
+
+	logger.WithField(StackKey, "kept").Error("failed")
+	if s := stack(); s != "kept" {
+		t.Errorf("expected an existing stack to be kept, got %q", s)
+	}
+
+	logger.Info("done")
+	if s := stack(); s != nil {
+		t.Errorf("expected no stack on info entries, got %q", s)
+	}
+}
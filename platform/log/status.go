@@ -0,0 +1,100 @@
+package log
+
+import (
+	"encoding/json"
+	"expvar"
+	"net/http"
+	"sync/atomic"
+
+	"github.com/o3labs/openpoint/platform/errors"
+	"github.com/o3labs/openpoint/platform/models"
+)
+
+var (
+	formattedEntries uint64
+	writtenBytes     uint64
+	sinkErrors       uint64
+)
+
+// QueueLen is implemented by sinks queueing entries, such as AsyncWriter
+// and the hooks pushing batches, to report how many are waiting.
+type QueueLen interface {
+	QueueLen() int
+}
+
+// Stats is the state of the logging pipeline since startup. Entries, Bytes
+// and SinkErrors count what is written through destinations, see
+// MultiHook and Config.
+type Stats struct {
+	// Entries formatted and Bytes written
+	Entries uint64 `json:"entries"`
+	Bytes   uint64 `json:"bytes"`
+
+	// Queued entries waiting in the registered sinks
+	Queued int `json:"queued"`
+
+	// Dropped entries, see Dropped
+	Dropped uint64 `json:"dropped"`
+
+	// SinkErrors are failed writes and the problems reported on the
+	// internal channel
+	SinkErrors uint64 `json:"sinkErrors"`
+
+	// Sinks is the number of registered sinks, see RegisterSink
+	Sinks int `json:"sinks"`
+}
+
+func init() {
+	expvar.Publish("log", expvar.Func(func() interface{} {
+		return PipelineStats()
+	}))
+}
+
+// PipelineStats returns the state of the logging pipeline, which is also
+// published as the expvar log.
+func PipelineStats() Stats {
+	s := Stats{
+		Entries:    atomic.LoadUint64(&formattedEntries),
+		Bytes:      atomic.LoadUint64(&writtenBytes),
+		Dropped:    Dropped(),
+		SinkErrors: atomic.LoadUint64(&sinkErrors),
+	}
+	for _, stage := range []SinkStage{SinkQueue, SinkOutput} {
+		for _, c := range registeredSinks(stage) {
+			s.Sinks++
+			if q, ok := c.(QueueLen); ok {
+				s.Queued += q.QueueLen()
+			}
+		}
+	}
+	return s
+}
+
+func recordWritten(n int) {
+	atomic.AddUint64(&formattedEntries, 1)
+	atomic.AddUint64(&writtenBytes, uint64(n))
+}
+
+func recordSinkError() {
+	atomic.AddUint64(&sinkErrors, 1)
+}
+
+// StatusHandler returns the PipelineStats as JSON, e.g. mounted at
+// /debug/logstatus next to LevelHandler.
+type StatusHandler struct {
+	// Token required as "Authorization: Bearer <token>", see LevelHandler
+	Token string
+}
+
+func (h *StatusHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !BearerAuthorized(r, h.Token) {
+		errors.Unauthorized().Write(w)
+		return
+	}
+	if r.Method != http.MethodGet {
+		errors.NewError(http.StatusMethodNotAllowed, "Use GET", http.StatusText(http.StatusMethodNotAllowed)).Write(w)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	json.NewEncoder(w).Encode(models.NewSuccessResponse(PipelineStats()))
+}
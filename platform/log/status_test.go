@@ -0,0 +1,79 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"expvar"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	logrus "github.com/sirupsen/logrus"
+)
+
+type writerFunc func(p []byte) (int, error)
+
+func (f writerFunc) Write(p []byte) (int, error) {
+	return f(p)
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("disk full")
+}
+
+func TestPipelineStats(t *testing.T) {
+	before := PipelineStats()
+
+	blocked := make(chan struct{})
+	queue := NewAsyncWriter(writerFunc(func(p []byte) (int, error) {
+		<-blocked
+		return len(p), nil
+	}), 10)
+	hook := NewMultiHook(
+		&Destination{Writer: &bytes.Buffer{}, Formatter: &LogfmtFormatter{DisableTimestamp: true}, Level: logrus.InfoLevel},
+		&Destination{Writer: failingWriter{}, Formatter: &LogfmtFormatter{DisableTimestamp: true}, Level: logrus.InfoLevel},
+		&Destination{Writer: queue, Formatter: &LogfmtFormatter{DisableTimestamp: true}, Level: logrus.InfoLevel},
+	)
+	entry := &logrus.Entry{Level: logrus.InfoLevel, Time: time.Now(), Message: "ok", Data: logrus.Fields{}}
+	for i := 0; i < 3; i++ {
+		hook.Fire(entry)
+	}
+
+	stats := PipelineStats()
+	if stats.Entries-before.Entries != 6 || stats.Bytes-before.Bytes != 6*uint64(len("level=info msg=ok\n")) {
+		t.Errorf("expected 6 entries written, got %+v", stats)
+	}
+	if stats.SinkErrors-before.SinkErrors < 3 {
+		t.Errorf("expected 3 sink errors, got %+v", stats)
+	}
+	if stats.Queued < 2 {
+		t.Errorf("expected the entries waiting in the queue, got %+v", stats)
+	}
+	close(blocked)
+	queue.Close()
+
+	var published Stats
+	if err := json.Unmarshal([]byte(expvar.Get("log").String()), &published); err != nil {
+		t.Fatal(err)
+	}
+	if published.Entries < stats.Entries {
+		t.Errorf("expected the stats published as an expvar, got %+v", published)
+	}
+
+	w := httptest.NewRecorder()
+	(&StatusHandler{Token: "secret"}).ServeHTTP(w, httptest.NewRequest("GET", "/debug/logstatus", nil))
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("expected the token to be required, got %v", w.Code)
+	}
+	w = httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/debug/logstatus", nil)
+	r.Header.Set("Authorization", "Bearer secret")
+	(&StatusHandler{Token: "secret"}).ServeHTTP(w, r)
+	if w.Code != http.StatusOK || !bytes.Contains(w.Body.Bytes(), []byte(`"sinkErrors"`)) {
+		t.Errorf("unexpected status response %v %s", w.Code, w.Body)
+	}
+}
@@ -0,0 +1,187 @@
+package log
+
+import (
+	"bytes"
+	stdlog "log"
+	"strings"
+	"sync"
+	"unicode"
+
+	logrus "github.com/sirupsen/logrus"
+)
+
+// StdWriter is an io.Writer for libraries logging with the standard log
+// package or writing lines to a writer. Each line is logged as an entry at
+// the level of its leading level token, such as "ERROR:", "[WARN]" or
+// "DEBUG ", which is removed from the message:
+//
+//	client.ErrorLog = log.NewStdLogger(log.Channel("vault"))
+//
+// The date and time the standard log package writes with its default flags
+// are removed as well. Lines without a level token are logged at Level.
+// Fatal and panic lines are logged at FatalLevel without exiting, the
+// standard log package exits or panics itself.
+type StdWriter struct {
+	// Level of lines without a level token, InfoLevel for writers returned
+	// by NewStdWriter
+	Level logrus.Level
+
+	logger *logrus.Logger
+	fields logrus.Fields
+
+	mu  sync.Mutex
+	buf []byte
+}
+
+// NewStdWriter returns a writer logging to channel l, or to the standard
+// logger when l is nil.
+func NewStdWriter(l *Logger) *StdWriter {
+	if l == nil {
+		return &StdWriter{Level: logrus.InfoLevel, logger: logrus.StandardLogger(), fields: logrus.Fields{}}
+	}
+	return &StdWriter{Level: logrus.InfoLevel, logger: l.logger, fields: logrus.Fields{ChannelKey: l.name}}
+}
+
+// NewStdLogger returns a standard library logger writing through a
+// StdWriter for channel l, for libraries taking a *log.Logger.
+func NewStdLogger(l *Logger) *stdlog.Logger {
+	return stdlog.New(NewStdWriter(l), "", 0)
+}
+
+// RedirectStdLog makes the standard log package write to channel l, or the
+// standard logger when l is nil, and returns the function restoring its
+// output and flags.
+func RedirectStdLog(l *Logger) func() {
+	flags, prefix, out := stdlog.Flags(), stdlog.Prefix(), stdlog.Writer()
+	stdlog.SetFlags(0)
+	stdlog.SetPrefix("")
+	stdlog.SetOutput(NewStdWriter(l))
+	return func() {
+		stdlog.SetFlags(flags)
+		stdlog.SetPrefix(prefix)
+		stdlog.SetOutput(out)
+	}
+}
+
+// Write logs every complete line of p. The rest is kept until the line is
+// completed or Flush is called.
+func (w *StdWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			break
+		}
+		w.log(string(w.buf[:i]))
+		w.buf = w.buf[i+1:]
+	}
+	if len(w.buf) == 0 {
+		w.buf = nil
+	}
+	return len(p), nil
+}
+
+// Flush logs what is left of an incomplete line.
+func (w *StdWriter) Flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if len(w.buf) > 0 {
+		w.log(string(w.buf))
+		w.buf = nil
+	}
+}
+
+// Close flushes the writer.
+func (w *StdWriter) Close() error {
+	w.Flush()
+	return nil
+}
+
+func (w *StdWriter) log(line string) {
+	line = strings.TrimRight(line, " \t\r")
+	if line == "" {
+		return
+	}
+	level, msg := detectLevel(trimStdTimestamp(line))
+	if level.Name == "" {
+		level = logrusLevel(w.Level)
+	}
+	if !w.logger.IsLevelEnabled(level.Level) {
+		return
+	}
+	entry := stamp(w.logger.WithFields(w.fields))
+	if _, ok := customLevelByName(level.Name); ok {
+		entry = entry.WithField(LevelKey, level.Name)
+	}
+	if level.Level < logrus.FatalLevel {
+		level.Level = logrus.FatalLevel
+	}
+	entry.Log(level.Level, msg)
+}
+
+// trimStdTimestamp removes the date, time and microseconds the standard log
+// package writes, e.g. "2009/01/23 01:23:23.123123 ".
+func trimStdTimestamp(line string) string {
+	rest := line
+	if len(rest) > 11 && isDigits(rest[:4]) && rest[4] == '/' && isDigits(rest[5:7]) && rest[7] == '/' && isDigits(rest[8:10]) && rest[10] == ' ' {
+		rest = rest[11:]
+	}
+	if len(rest) > 9 && isDigits(rest[:2]) && rest[2] == ':' && isDigits(rest[3:5]) && rest[5] == ':' && isDigits(rest[6:8]) {
+		i := 8
+		if rest[i] == '.' {
+			i++
+			for i < len(rest) && rest[i] >= '0' && rest[i] <= '9' {
+				i++
+			}
+		}
+		if i < len(rest) && rest[i] == ' ' {
+			rest = rest[i+1:]
+		}
+	}
+	return rest
+}
+
+// detectLevel returns the level of the leading level token of line and the
+// line without it, or an empty Level and line when there is none. Tokens
+// are level names in brackets, followed by a colon or, in upper case,
+// followed by a space.
+func detectLevel(line string) (Level, string) {
+	var name, rest string
+	switch {
+	case strings.HasPrefix(line, "[") || strings.HasPrefix(line, "<"):
+		end := strings.IndexAny(line, "]>")
+		if end < 0 {
+			return Level{}, line
+		}
+		name, rest = line[1:end], line[end+1:]
+	default:
+		end := strings.IndexFunc(line, func(r rune) bool { return !unicode.IsLetter(r) })
+		if end <= 0 {
+			return Level{}, line
+		}
+		name, rest = line[:end], line[end:]
+		switch {
+		case strings.HasPrefix(rest, ":"):
+			rest = rest[1:]
+		case strings.HasPrefix(rest, " ") && name == strings.ToUpper(name):
+		default:
+			return Level{}, line
+		}
+	}
+	level, err := LookupLevel(strings.TrimSpace(name))
+	if err != nil {
+		return Level{}, line
+	}
+	return level, strings.TrimLeft(rest, " \t:")
+}
+
+func isDigits(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,41 @@
+package log
+
+import (
+	"bytes"
+	stdlog "log"
+	"testing"
+
+	logrus "github.com/sirupsen/logrus"
+)
+
+func TestStdWriter(t *testing.T) {
+	b := &bytes.Buffer{}
+	channel := Channel("vault")
+	channel.SetOutput(b)
+	channel.SetFormatter(&ChannelTextFormatter{DisableColors: true, DisableTimestamp: true})
+	channel.SetLevel(logrus.InfoLevel)
+
+	w := NewStdWriter(channel)
+	logger := stdlog.New(w, "", stdlog.LstdFlags|stdlog.Lmicroseconds)
+	logger.Print("ERROR: lease renewal failed")
+	logger.Print("[WARN] token expires soon")
+	logger.Print("DEBUG not written")
+	logger.Print("notice: sealed")
+	logger.Print("FATAL: out of memory")
+	logger.Print("Error connecting, retrying")
+	logger.Print("[main] started")
+	w.Write([]byte("partial "))
+	w.Write([]byte("line"))
+	w.Flush()
+
+	expected := `level=error msg="lease renewal failed" channel=vault` + "\n" +
+		`level=warning msg="token expires soon" channel=vault` + "\n" +
+		`level=notice msg=sealed channel=vault` + "\n" +
+		`level=fatal msg="out of memory" channel=vault` + "\n" +
+		`level=info msg="Error connecting, retrying" channel=vault` + "\n" +
+		`level=info msg="[main] started" channel=vault` + "\n" +
+		`level=info msg="partial line" channel=vault` + "\n"
+	if b.String() != expected {
+		t.Errorf("expected %q got %q", expected, b.String())
+	}
+}
@@ -0,0 +1,195 @@
+package log
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	logrus "github.com/sirupsen/logrus"
+)
+
+// SuppressedKey marks entries a SuppressRule downgraded with the rule name
+const SuppressedKey = "suppressed_by"
+
+// TimeWindow is a daily window of time, such as 02:00-03:00 UTC. It may
+// wrap past midnight, 22:00-06:00 covers the night.
+type TimeWindow struct {
+	// Start and End since midnight
+	Start time.Duration
+	End   time.Duration
+
+	// Location of the times. Defaults to UTC.
+	Location *time.Location
+}
+
+// ParseTimeWindow parses a window written as "15:04-15:04", optionally
+// followed by a time zone name: "02:00-03:00 UTC", "22:00-06:00
+// Europe/Paris".
+func ParseTimeWindow(s string) (TimeWindow, error) {
+	w := TimeWindow{Location: time.UTC}
+	fields := strings.Fields(s)
+	if len(fields) == 0 || len(fields) > 2 {
+		return w, fmt.Errorf("log: bad time window %q", s)
+	}
+	if len(fields) == 2 {
+		loc, err := time.LoadLocation(fields[1])
+		if err != nil {
+			return w, fmt.Errorf("log: bad time window %q, %v", s, err)
+		}
+		w.Location = loc
+	}
+	bounds := strings.Split(fields[0], "-")
+	if len(bounds) != 2 {
+		return w, fmt.Errorf("log: bad time window %q", s)
+	}
+	for i, bound := range bounds {
+		t, err := time.Parse("15:04", bound)
+		if err != nil {
+			return w, fmt.Errorf("log: bad time window %q, %v", s, err)
+		}
+		d := time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute
+		if i == 0 {
+			w.Start = d
+		} else {
+			w.End = d
+		}
+	}
+	return w, nil
+}
+
+// Contains reports whether t falls in the window.
+func (w TimeWindow) Contains(t time.Time) bool {
+	loc := w.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+	t = t.In(loc)
+	d := time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute + time.Duration(t.Second())*time.Second
+	if w.Start <= w.End {
+		return d >= w.Start && d < w.End
+	}
+	return d >= w.Start || d < w.End
+}
+
+// SuppressRule silences entries known to be noise, e.g. the timeouts of a
+// flaky dependency during its nightly maintenance.
+type SuppressRule struct {
+	// Name identifies the rule in SuppressionPolicy.Stats and the
+	// suppressed_by field.
+	Name string
+
+	// Match selects the entries of the rule, see ParseMatcher
+	Match Matcher
+
+	// Windows the rule applies in. None means always.
+	Windows []TimeWindow
+
+	// Downgrade, when set, logs the entries at this level with a
+	// suppressed_by field instead of dropping them
+	Downgrade *logrus.Level
+}
+
+// SuppressStats counts what a rule suppressed.
+type SuppressStats struct {
+	Rule       string
+	Dropped    uint64
+	Downgraded uint64
+
+	// Last is when the rule last suppressed an entry
+	Last time.Time
+}
+
+// SuppressionPolicy drops or downgrades the entries of its rules, the first
+// matching rule wins. Add its Middleware to a MultiHook, Config.Suppress
+// does so for the configured outputs.
+type SuppressionPolicy struct {
+	Rules []*SuppressRule
+
+	mu    sync.Mutex
+	stats map[string]*SuppressStats
+}
+
+// Middleware returns the middleware applying the policy.
+func (p *SuppressionPolicy) Middleware() Middleware {
+	return func(entry *logrus.Entry) (*logrus.Entry, bool) {
+		rule := p.match(entry)
+		if rule == nil {
+			return entry, true
+		}
+		if rule.Downgrade == nil {
+			p.record(rule.Name, entry.Time, false)
+			recordDropped(1)
+			return nil, false
+		}
+		p.record(rule.Name, entry.Time, true)
+		entry = CopyEntry(entry)
+		entry.Level = *rule.Downgrade
+		delete(entry.Data, LevelKey)
+		entry.Data[SuppressedKey] = rule.Name
+		return entry, true
+	}
+}
+
+func (p *SuppressionPolicy) match(entry *logrus.Entry) *SuppressRule {
+	for _, r := range p.Rules {
+		if r.Match != nil && !r.Match.Match(entry) {
+			continue
+		}
+		if len(r.Windows) == 0 {
+			return r
+		}
+		for _, w := range r.Windows {
+			if w.Contains(entry.Time) {
+				return r
+			}
+		}
+	}
+	return nil
+}
+
+func (p *SuppressionPolicy) record(rule string, t time.Time, downgraded bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.stats == nil {
+		p.stats = map[string]*SuppressStats{}
+	}
+	s, ok := p.stats[rule]
+	if !ok {
+		s = &SuppressStats{Rule: rule}
+		p.stats[rule] = s
+	}
+	if downgraded {
+		s.Downgraded++
+	} else {
+		s.Dropped++
+	}
+	s.Last = t
+}
+
+// Stats returns what every rule suppressed so far, in the order of Rules.
+func (p *SuppressionPolicy) Stats() []SuppressStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	stats := make([]SuppressStats, 0, len(p.Rules))
+	for _, r := range p.Rules {
+		if s, ok := p.stats[r.Name]; ok {
+			stats = append(stats, *s)
+		} else {
+			stats = append(stats, SuppressStats{Rule: r.Name})
+		}
+	}
+	return stats
+}
+
+// SuppressionStats returns what the rules of the config applied by the last
+// Config.Build suppressed, nil when it has none.
+func SuppressionStats() []SuppressStats {
+	outputsMu.Lock()
+	policy := configPolicy
+	outputsMu.Unlock()
+	if policy == nil {
+		return nil
+	}
+	return policy.Stats()
+}
@@ -0,0 +1,80 @@
+package log
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	logrus "github.com/sirupsen/logrus"
+)
+
+func TestTimeWindow(t *testing.T) {
+	w, err := ParseTimeWindow("22:00-06:00 UTC")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for clock, want := range map[string]bool{
+		"21:59": false,
+		"22:00": true,
+		"03:30": true,
+		"06:00": false,
+		"12:00": false,
+	} {
+		at, _ := time.Parse("15:04", clock)
+		if got := w.Contains(at); got != want {
+			t.Errorf("%s: expected %v, got %v", clock, want, got)
+		}
+	}
+
+	paris, err := ParseTimeWindow("02:00-03:00 Europe/Paris")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !paris.Contains(time.Date(2018, 2, 26, 1, 30, 0, 0, time.UTC)) {
+		t.Error("expected 01:30 UTC to be 02:30 in Paris")
+	}
+
+	for _, bad := range []string{"", "02:00", "02:00-25:00", "02:00-03:00 Mars/Olympus"} {
+		if _, err := ParseTimeWindow(bad); err == nil {
+			t.Errorf("%q: expected an error", bad)
+		}
+	}
+}
+
+func TestSuppressionPolicy(t *testing.T) {
+	dependency, _ := ParseMatcher("field:dependency=billing AND msg~timeout")
+	maintenance, _ := ParseTimeWindow("02:00-03:00")
+	debug := logrus.DebugLevel
+	policy := &SuppressionPolicy{Rules: []*SuppressRule{
+		{Name: "billing-maintenance", Match: dependency, Windows: []TimeWindow{maintenance}},
+		{Name: "healthz", Match: MatcherFunc(func(e *logrus.Entry) bool { return e.Message == "health check" }), Downgrade: &debug},
+	}}
+
+	out := &bytes.Buffer{}
+	hook := &MultiHook{
+		Destinations: []*Destination{{Writer: out, Formatter: &LogfmtFormatter{DisableTimestamp: true}, Level: logrus.TraceLevel}},
+		Middleware:   policy.Middleware(),
+	}
+	at := func(clock string) time.Time {
+		t, _ := time.Parse("15:04", clock)
+		return t
+	}
+	for _, e := range []*logrus.Entry{
+		{Level: logrus.ErrorLevel, Time: at("02:15"), Message: "billing timeout", Data: logrus.Fields{"dependency": "billing"}},
+		{Level: logrus.ErrorLevel, Time: at("04:00"), Message: "billing timeout", Data: logrus.Fields{"dependency": "billing"}},
+		{Level: logrus.InfoLevel, Time: at("02:15"), Message: "health check", Data: logrus.Fields{}},
+	} {
+		if err := hook.Fire(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want := "level=error msg=\"billing timeout\" dependency=billing\nlevel=debug msg=\"health check\" suppressed_by=healthz\n"
+	if out.String() != want {
+		t.Errorf("expected %q, got %q", want, out.String())
+	}
+	stats := policy.Stats()
+	if len(stats) != 2 || stats[0].Dropped != 1 || stats[1].Downgraded != 1 || !stats[0].Last.Equal(at("02:15")) {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+}
@@ -0,0 +1,271 @@
+package log
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	logrus "github.com/sirupsen/logrus"
+)
+
+// Facility is a syslog facility code.
+type Facility int
+
+const (
+	FacilityKern Facility = iota
+	FacilityUser
+	FacilityMail
+	FacilityDaemon
+	FacilityAuth
+	FacilitySyslog
+	FacilityLPR
+	FacilityNews
+	FacilityUUCP
+	FacilityCron
+	FacilityAuthPriv
+	FacilityFTP
+)
+
+const (
+	FacilityLocal0 Facility = iota + 16
+	FacilityLocal1
+	FacilityLocal2
+	FacilityLocal3
+	FacilityLocal4
+	FacilityLocal5
+	FacilityLocal6
+	FacilityLocal7
+)
+
+const (
+	syslogNilValue        = "-"
+	syslogTimestampFormat = "2006-01-02T15:04:05.000000Z07:00"
+	defaultSyslogSDID     = "fields@32473"
+)
+
+// SyslogSeverity maps a logrus level to a syslog severity.
+func SyslogSeverity(level logrus.Level) int {
+	switch level {
+	case logrus.PanicLevel:
+		return 0 // emergency
+	case logrus.FatalLevel:
+		return 2 // critical
+	case logrus.ErrorLevel:
+		return 3 // error
+	case logrus.WarnLevel:
+		return 4 // warning
+	case logrus.InfoLevel:
+		return 6 // informational
+	default:
+		return 7 // debug
+	}
+}
+
+// SyslogFormatter formats logs as RFC 5424 syslog messages. Entry fields are
+// written as a single structured data element.
+type SyslogFormatter struct {
+	// Facility the messages are logged under. Defaults to FacilityUser,
+	// FacilityKern is left to the kernel.
+	Facility Facility
+
+	// Hostname defaults to os.Hostname()
+	Hostname string
+
+	// AppName identifies the service, e.g. "openpoint"
+	AppName string
+
+	// ProcID defaults to the process ID
+	ProcID string
+
+	// MsgIDKey is the field used as MSGID, e.g. ChannelKey. Empty means "-".
+	MsgIDKey string
+
+	// StructuredDataID of the element holding the fields. Defaults to
+	// "fields@32473", the private enterprise number reserved for examples.
+	StructuredDataID string
+
+	sync.Once
+}
+
+func (f *SyslogFormatter) init() {
+	if f.Hostname == "" {
+		f.Hostname, _ = os.Hostname()
+	}
+	if f.ProcID == "" {
+		f.ProcID = strconv.Itoa(os.Getpid())
+	}
+	if f.StructuredDataID == "" {
+		f.StructuredDataID = defaultSyslogSDID
+	}
+}
+
+// Format renders a single log entry
+func (f *SyslogFormatter) Format(entry *logrus.Entry) ([]byte, error) {
+	f.Do(f.init)
+	level, entry := prepareEntry(entry)
+
+	b := &bytes.Buffer{}
+	facility := f.Facility
+	if facility == FacilityKern {
+		facility = FacilityUser
+	}
+	pri := int(facility)*8 + level.Severity
+
+	msgID := ""
+	if f.MsgIDKey != "" {
+		if v, ok := entry.Data[f.MsgIDKey]; ok {
+			msgID = fmt.Sprint(v)
+		}
+	}
+
+	fmt.Fprintf(b, "<%d>1 %s %s %s %s %s ",
+		pri,
+		entryTime(entry).Format(syslogTimestampFormat),
+		syslogHeaderField(f.Hostname, 255),
+		syslogHeaderField(f.AppName, 48),
+		syslogHeaderField(f.ProcID, 128),
+		syslogHeaderField(msgID, 32),
+	)
+
+	keys := make([]string, 0, len(entry.Data))
+	for k := range entry.Data {
+		if k != f.MsgIDKey {
+			keys = append(keys, k)
+		}
+	}
+	if len(keys) == 0 {
+		b.WriteString(syslogNilValue)
+	} else {
+		sort.Strings(keys)
+		b.WriteByte('[')
+		b.WriteString(f.StructuredDataID)
+		for _, k := range keys {
+			fmt.Fprintf(b, " %s=\"%s\"", syslogParamName(k), syslogParamValue(entry.Data[k]))
+		}
+		b.WriteByte(']')
+	}
+
+	if entry.Message != "" {
+		b.WriteByte(' ')
+		b.WriteString(entry.Message)
+	}
+	b.WriteByte('\n')
+	return b.Bytes(), nil
+}
+
+// syslogHeaderField returns s as printable ASCII without spaces, or "-".
+func syslogHeaderField(s string, max int) string {
+	clean := strings.Map(func(r rune) rune {
+		if r < 33 || r > 126 {
+			return -1
+		}
+		return r
+	}, s)
+	if clean == "" {
+		return syslogNilValue
+	}
+	if len(clean) > max {
+		clean = clean[:max]
+	}
+	return clean
+}
+
+func syslogParamName(s string) string {
+	clean := strings.Map(func(r rune) rune {
+		if r < 33 || r > 126 || r == '=' || r == ']' || r == '"' {
+			return '_'
+		}
+		return r
+	}, s)
+	if len(clean) > 32 {
+		clean = clean[:32]
+	}
+	return clean
+}
+
+var syslogParamEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, `]`, `\]`)
+
+func syslogParamValue(v interface{}) string {
+	s, ok := v.(string)
+	if !ok {
+		s = fmt.Sprint(v)
+	}
+	return syslogParamEscaper.Replace(s)
+}
+
+// SyslogWriter sends formatted syslog messages to a server over "udp", "tcp",
+// "unix" or "unixgram". TCP uses RFC 6587 octet-counting framing, the other
+// transports send one message per datagram.
+type SyslogWriter struct {
+	network string
+	addr    string
+
+	mu   sync.Mutex
+	conn net.Conn
+
+	registration sinkRegistration
+}
+
+// NewSyslogWriter connects to the syslog server at addr.
+func NewSyslogWriter(network, addr string) (*SyslogWriter, error) {
+	w := &SyslogWriter{network: network, addr: addr}
+	if err := w.connect(); err != nil {
+		return nil, err
+	}
+	w.registration.register(w, SinkOutput)
+	return w, nil
+}
+
+func (w *SyslogWriter) connect() error {
+	conn, err := net.Dial(w.network, w.addr)
+	if err != nil {
+		return err
+	}
+	w.conn = conn
+	return nil
+}
+
+// Write sends one message, reconnecting once if the connection was lost.
+func (w *SyslogWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	msg := bytes.TrimRight(p, "\n")
+	if w.network == "tcp" || w.network == "tcp4" || w.network == "tcp6" {
+		msg = append([]byte(strconv.Itoa(len(msg))+" "), msg...)
+	}
+
+	if w.conn == nil {
+		if err := w.connect(); err != nil {
+			return 0, err
+		}
+	}
+	if _, err := w.conn.Write(msg); err != nil {
+		w.conn.Close()
+		if err := w.connect(); err != nil {
+			w.conn = nil
+			return 0, err
+		}
+		if _, err := w.conn.Write(msg); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+func (w *SyslogWriter) Close() error {
+	w.registration.release()
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.conn == nil {
+		return nil
+	}
+	err := w.conn.Close()
+	w.conn = nil
+	return err
+}
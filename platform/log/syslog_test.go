@@ -0,0 +1,29 @@
+package log
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	logrus "github.com/sirupsen/logrus"
+)
+
+func TestSyslogFacility(t *testing.T) {
+	entry := &logrus.Entry{Level: logrus.ErrorLevel, Time: time.Now(), Message: "failed", Data: logrus.Fields{}}
+	for _, tt := range []struct {
+		facility Facility
+		pri      string
+	}{
+		{0, "<11>"},
+		{FacilityUser, "<11>"},
+		{FacilityLocal0, "<131>"},
+	} {
+		b, err := (&SyslogFormatter{Facility: tt.facility}).Format(entry)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasPrefix(string(b), tt.pri+"1 ") {
+			t.Errorf("expected %v for facility %v, got %q", tt.pri, tt.facility, b)
+		}
+	}
+}
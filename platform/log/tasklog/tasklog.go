@@ -0,0 +1,110 @@
+// Package tasklog starts background goroutines with a logger carrying the
+// request ID of the context they are started from and an ID of their own,
+// so entries of asynchronous work can be traced back to the request.
+//
+//	tasklog.Go(ctx, "send receipt", func(ctx context.Context) {
+//		log.FromContext(ctx).Info("receipt sent")
+//	})
+package tasklog
+
+import (
+	"context"
+	"time"
+
+	"github.com/o3labs/openpoint/platform/log"
+	"github.com/o3labs/openpoint/platform/log/requestid"
+	logrus "github.com/sirupsen/logrus"
+)
+
+const (
+	// Key is the field holding the task ID in log entries
+	Key = "task_id"
+
+	// NameKey holds the name of the task
+	NameKey = "task"
+
+	// ParentKey holds the ID of the task that started the task
+	ParentKey = "parent_task_id"
+)
+
+// New generates task IDs. Defaults to requestid.XID.
+var New requestid.Generator = requestid.XID
+
+type task struct {
+	id   string
+	name string
+}
+
+type contextKey struct{}
+
+// Go runs fn in a new goroutine and returns the ID of the task. fn gets ctx
+// carrying the task and a logger for log.FromContext with the request ID,
+// task name, task ID and the ID of the task Go was called from, if any. A
+// panic of fn is logged at Error rather than crashing the process.
+//
+// ctx is passed on as it is, so work that must outlive a request should be
+// started with context.WithoutCancel(ctx).
+func Go(ctx context.Context, name string, fn func(ctx context.Context)) string {
+	ctx, id := start(ctx, name)
+	go func() {
+		defer func() {
+			if v := recover(); v != nil {
+				log.LogPanic(ctx, v, logrus.ErrorLevel)
+			}
+		}()
+		begin := time.Now()
+		fn(ctx)
+		log.FromContext(ctx).WithField("latency", time.Since(begin).String()).Debug("task finished")
+	}()
+	return id
+}
+
+// start returns ctx carrying a new task named name and its logger.
+func start(ctx context.Context, name string) (context.Context, string) {
+	t := &task{id: New(), name: name}
+	fields := logrus.Fields{Key: t.id, NameKey: name}
+	if parent, ok := ctx.Value(contextKey{}).(*task); ok {
+		fields[ParentKey] = parent.id
+	}
+	if id := requestid.FromContext(ctx); id != "" {
+		fields[requestid.Key] = id
+	}
+	ctx = context.WithValue(ctx, contextKey{}, t)
+	return log.WithContext(ctx, fields), t.id
+}
+
+// FromContext returns the ID of the task running with ctx, or an empty
+// string.
+func FromContext(ctx context.Context) string {
+	if t, ok := ctx.Value(contextKey{}).(*task); ok {
+		return t.id
+	}
+	return ""
+}
+
+// Hook adds the task of the entry's context to entries that don't carry it
+// yet, e.g. those logged with logrus.WithContext(ctx) rather than
+// log.FromContext(ctx).
+type Hook struct{}
+
+func NewHook() *Hook {
+	return &Hook{}
+}
+
+func (h *Hook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (h *Hook) Fire(entry *logrus.Entry) error {
+	if entry.Context == nil {
+		return nil
+	}
+	if _, ok := entry.Data[Key]; ok {
+		return nil
+	}
+	if t, ok := entry.Context.Value(contextKey{}).(*task); ok {
+		entry.Data[Key] = t.id
+		entry.Data[NameKey] = t.name
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package tasklog
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/o3labs/openpoint/platform/log"
+	"github.com/o3labs/openpoint/platform/log/requestid"
+	logrus "github.com/sirupsen/logrus"
+)
+
+// syncBuffer is written by the task goroutines
+type syncBuffer struct {
+	mu sync.Mutex
+	b  bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.b.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.b.String()
+}
+
+func TestGo(t *testing.T) {
+	b := &syncBuffer{}
+	channel := log.Channel("tasklog")
+	channel.SetOutput(b)
+	channel.SetFormatter(&log.ChannelTextFormatter{DisableTimestamp: true})
+	channel.SetLevel(logrus.InfoLevel)
+
+	ctx := requestid.NewContext(context.Background(), "r1")
+	ctx = log.NewContext(ctx, channel.WithFields(logrus.Fields{}))
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	var child string
+	parent := Go(ctx, "send receipt", func(ctx context.Context) {
+		defer wg.Done()
+		log.FromContext(ctx).Info("receipt sent")
+		child = Go(ctx, "notify", func(ctx context.Context) {
+			defer wg.Done()
+			panic("mail server down")
+		})
+	})
+	wg.Wait()
+	if parent == "" || child == "" || parent == child {
+		t.Fatalf("expected distinct task IDs, got %q and %q", parent, child)
+	}
+
+	// the panic is logged after the task is done
+	for i := 0; i < 100 && !strings.Contains(b.String(), "recovered panic"); i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	for _, s := range []string{
+		`msg="receipt sent" channel=tasklog request_id=r1 task="send receipt" task_id=` + parent,
+		`msg="recovered panic" channel=tasklog panic="mail server down" parent_task_id=` + parent + ` request_id=r1`,
+		"task=notify task_id=" + child,
+	} {
+		if !strings.Contains(b.String(), s) {
+			t.Errorf("expected %v in %q", s, b.String())
+		}
+	}
+}
+
+func TestHook(t *testing.T) {
+	ctx, id := start(context.Background(), "export")
+	entry := logrus.NewEntry(logrus.New()).WithContext(ctx)
+	NewHook().Fire(entry)
+	if entry.Data[Key] != id || entry.Data[NameKey] != "export" || FromContext(ctx) != id {
+		t.Errorf("expected the task on the entry, got %v", entry.Data)
+	}
+}
@@ -0,0 +1,109 @@
+package log
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+
+	logrus "github.com/sirupsen/logrus"
+)
+
+// TemplateFormatter lays out lines with a text/template, to match the layout
+// of an existing log pipeline without forking a formatter. The template is
+// executed with a TemplateEntry, e.g.
+//
+//	{{.Timestamp}} [{{.Level | upper | pad 7}}] {{.Field "channel"}}: {{.Message}} {{.Logfmt "channel"}}
+//
+// Besides the text/template builtins it can call upper, lower and pad. A
+// newline is added when the template doesn't end with one.
+type TemplateFormatter struct {
+	// TimestampFormat of TemplateEntry.Timestamp
+	TimestampFormat string
+
+	tmpl *template.Template
+}
+
+// TemplateEntry is what the template of a TemplateFormatter is executed with.
+type TemplateEntry struct {
+	Time      time.Time
+	Timestamp string
+	Level     Level
+	Message   string
+	Fields    logrus.Fields
+}
+
+// Field returns the value of the field key, or an empty string when the entry
+// doesn't have it.
+func (e TemplateEntry) Field(key string) interface{} {
+	if v, ok := e.Fields[key]; ok {
+		return v
+	}
+	return ""
+}
+
+// Logfmt returns the fields as sorted logfmt pairs, leaving out except, which
+// usually are the fields the template already shows.
+func (e TemplateEntry) Logfmt(except ...string) string {
+	keys := make([]string, 0, len(e.Fields))
+next:
+	for k := range e.Fields {
+		for _, x := range except {
+			if k == x {
+				continue next
+			}
+		}
+		keys = append(keys, k)
+	}
+	sortKeys(keys)
+
+	b := &bytes.Buffer{}
+	for _, k := range keys {
+		appendLogfmt(b, k, e.Fields[k])
+	}
+	return b.String()
+}
+
+var templateFuncs = template.FuncMap{
+	"upper": func(v interface{}) string { return strings.ToUpper(fmt.Sprint(v)) },
+	"lower": func(v interface{}) string { return strings.ToLower(fmt.Sprint(v)) },
+	"pad":   func(width int, v interface{}) string { return fmt.Sprintf("%-*v", width, v) },
+}
+
+// NewTemplateFormatter parses layout, see TemplateFormatter.
+func NewTemplateFormatter(layout string) (*TemplateFormatter, error) {
+	tmpl, err := template.New("log").Funcs(templateFuncs).Parse(layout)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to parse log template, %v", err)
+	}
+	return &TemplateFormatter{TimestampFormat: defaultTimestampFormat, tmpl: tmpl}, nil
+}
+
+// Format renders a single log entry
+func (f *TemplateFormatter) Format(entry *logrus.Entry) ([]byte, error) {
+	level, entry := prepareEntry(entry)
+	timestampFormat := f.TimestampFormat
+	if timestampFormat == "" {
+		timestampFormat = defaultTimestampFormat
+	}
+
+	b := bufferPool.Get().(*bytes.Buffer)
+	b.Reset()
+	defer bufferPool.Put(b)
+
+	err := f.tmpl.Execute(b, TemplateEntry{
+		Time:      entryTime(entry),
+		Timestamp: entryTime(entry).Format(timestampFormat),
+		Level:     level,
+		Message:   entry.Message,
+		Fields:    entry.Data,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Failed to execute log template, %v", err)
+	}
+	if b.Len() == 0 || b.Bytes()[b.Len()-1] != '\n' {
+		b.WriteByte('\n')
+	}
+	return append([]byte(nil), b.Bytes()...), nil
+}
@@ -0,0 +1,43 @@
+package log
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	logrus "github.com/sirupsen/logrus"
+)
+
+func TestTemplateFormatter(t *testing.T) {
+	f, err := NewTemplateFormatter(`{{.Timestamp}} [{{.Level | upper | pad 5}}] {{.Field "channel"}}{{.Field "missing"}}: {{.Message}} {{.Logfmt "channel"}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry := &logrus.Entry{
+		Time:    time.Date(2018, 2, 26, 10, 4, 5, 0, time.UTC),
+		Level:   logrus.WarnLevel,
+		Message: "charge failed",
+		Data:    logrus.Fields{ChannelKey: "payments", "amount": 1000, "error": errors.New("card declined")},
+	}
+	b, err := f.Format(entry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "2018-02-26T10:04:05Z [WARNING] payments: charge failed amount=1000 error=\"card declined\"\n"
+	if string(b) != expected {
+		t.Errorf("expected %q got %q", expected, b)
+	}
+}
+
+func TestTemplateFormatterInvalid(t *testing.T) {
+	if _, err := NewTemplateFormatter("{{.Message"); err == nil {
+		t.Error("expected a parse error")
+	}
+	f, err := NewTemplateFormatter("{{.Missing}}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Format(&logrus.Entry{Data: logrus.Fields{}}); err == nil {
+		t.Error("expected an execute error")
+	}
+}
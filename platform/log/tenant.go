@@ -0,0 +1,212 @@
+package log
+
+import (
+	"container/list"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	logrus "github.com/sirupsen/logrus"
+)
+
+const (
+	defaultTenantKey     = "tenant"
+	defaultTenantMaxOpen = 64
+
+	// unknownTenant is the shard of entries without a tenant field
+	unknownTenant = "_unknown"
+)
+
+// TenantHook keeps the logs of every tenant apart, sending each entry to a
+// hook of its own picked by the tenant field, e.g. a file per customer or a
+// LokiHook with a tenant label:
+//
+//	hook := log.NewTenantHook("tenant", func(tenant string) (logrus.Hook, error) {
+//		return &log.LokiHook{URL: url, Labels: map[string]string{"tenant": tenant}}, nil
+//	})
+//
+// Hooks are created on the first entry of a tenant. At most MaxOpen are kept,
+// the least recently used one is closed, when it is an io.Closer, to make
+// room for a new one and created again when its tenant logs next.
+type TenantHook struct {
+	// Key is the field naming the tenant. Defaults to "tenant".
+	Key string
+
+	// New returns the hook of a tenant. Entries without the Key field go to
+	// the tenant "_unknown".
+	New func(tenant string) (logrus.Hook, error)
+
+	// MaxOpen is the number of tenant hooks kept open. Defaults to 64.
+	MaxOpen int
+
+	mu      sync.Mutex
+	lru     list.List
+	tenants map[string]*list.Element
+}
+
+type tenantShard struct {
+	tenant string
+	hook   logrus.Hook
+}
+
+func NewTenantHook(key string, newHook func(tenant string) (logrus.Hook, error)) *TenantHook {
+	return &TenantHook{Key: key, New: newHook, MaxOpen: defaultTenantMaxOpen}
+}
+
+// NewTenantFileHook returns a TenantHook writing every tenant to its own
+// RotatingFileWriter, dir/<tenant>.log, using formatter.
+func NewTenantFileHook(key, dir string, formatter logrus.Formatter) *TenantHook {
+	return NewTenantHook(key, func(tenant string) (logrus.Hook, error) {
+		w := &RotatingFileWriter{Filename: filepath.Join(dir, tenantFileName(tenant)+".log")}
+		return &writerHook{Destination{Writer: w, Formatter: formatter, Level: logrus.TraceLevel}}, nil
+	})
+}
+
+func (h *TenantHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+// Fire passes entry to the hook of its tenant. The hooks run one at a time,
+// so one being closed is never written to.
+func (h *TenantHook) Fire(entry *logrus.Entry) error {
+	tenant := unknownTenant
+	if v, ok := entry.Data[h.key()]; ok && v != nil {
+		if s := fmt.Sprint(resolveLazy(v)); s != "" {
+			tenant = s
+		}
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	hook, err := h.hook(tenant)
+	if err != nil {
+		recordDropped(1)
+		return fmt.Errorf("Failed to open log of tenant %s, %v", tenant, err)
+	}
+	for _, l := range hook.Levels() {
+		if l == entry.Level {
+			return hook.Fire(entry)
+		}
+	}
+	return nil
+}
+
+// hook returns the hook of tenant, creating it and closing the least
+// recently used one when needed. h.mu must be held.
+func (h *TenantHook) hook(tenant string) (logrus.Hook, error) {
+	if h.tenants == nil {
+		h.tenants = map[string]*list.Element{}
+	}
+	if e, ok := h.tenants[tenant]; ok {
+		h.lru.MoveToFront(e)
+		return e.Value.(*tenantShard).hook, nil
+	}
+
+	hook, err := h.New(tenant)
+	if err != nil {
+		return nil, err
+	}
+	max := h.MaxOpen
+	if max <= 0 {
+		max = defaultTenantMaxOpen
+	}
+	for h.lru.Len() >= max {
+		h.evict(h.lru.Back())
+	}
+	h.tenants[tenant] = h.lru.PushFront(&tenantShard{tenant: tenant, hook: hook})
+	return hook, nil
+}
+
+func (h *TenantHook) evict(e *list.Element) {
+	shard := h.lru.Remove(e).(*tenantShard)
+	delete(h.tenants, shard.tenant)
+	if c, ok := shard.hook.(io.Closer); ok {
+		if err := c.Close(); err != nil {
+			// not while h.mu is held, the report may go through h
+			go reportf("Failed to close log of tenant %s, %v", shard.tenant, err)
+		}
+	}
+}
+
+// Open returns the tenants with an open hook, the most recently used first.
+func (h *TenantHook) Open() []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	tenants := make([]string, 0, h.lru.Len())
+	for e := h.lru.Front(); e != nil; e = e.Next() {
+		tenants = append(tenants, e.Value.(*tenantShard).tenant)
+	}
+	return tenants
+}
+
+// Reopen closes the hooks of all tenants, which reopens their files when
+// they are created again, see ReopenFiles.
+func (h *TenantHook) Reopen() error {
+	return h.Close()
+}
+
+// Close closes the hooks of all tenants and returns the first error.
+func (h *TenantHook) Close() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	var firstErr error
+	for h.lru.Len() > 0 {
+		shard := h.lru.Remove(h.lru.Back()).(*tenantShard)
+		delete(h.tenants, shard.tenant)
+		if c, ok := shard.hook.(io.Closer); ok {
+			if err := c.Close(); err != nil && firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	return firstErr
+}
+
+func (h *TenantHook) key() string {
+	if h.Key == "" {
+		return defaultTenantKey
+	}
+	return h.Key
+}
+
+// writerHook is a hook writing to a single Destination, closing its writer
+// on Close.
+type writerHook struct {
+	d Destination
+}
+
+func (w *writerHook) Levels() []logrus.Level {
+	return logrus.AllLevels[:w.d.Level+1]
+}
+
+func (w *writerHook) Fire(entry *logrus.Entry) error {
+	return w.d.write(entry)
+}
+
+func (w *writerHook) Close() error {
+	if c, ok := w.d.Writer.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+// tenantFileName makes tenant safe to use as a file name, so a tenant can't
+// write outside of the log directory. Other bytes than letters, digits, -, _
+// and . are percent-encoded, as are names of dots only, so two tenants never
+// share a file.
+func tenantFileName(tenant string) string {
+	dots := strings.Trim(tenant, ".") == ""
+	b := &strings.Builder{}
+	for i := 0; i < len(tenant); i++ {
+		c := tenant[i]
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9', c == '-', c == '_', c == '.' && !dots:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,79 @@
+package log
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	logrus "github.com/sirupsen/logrus"
+)
+
+func TestTenantFileHook(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tenant")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	hook := NewTenantFileHook("tenant", dir, &LogfmtFormatter{DisableTimestamp: true})
+	hook.MaxOpen = 2
+	l := logrus.New()
+	l.SetOutput(ioutil.Discard)
+	l.AddHook(hook)
+
+	l.WithField("tenant", "acme").Info("one")
+	l.WithField("tenant", "globex").Info("two")
+	l.WithField("tenant", "../initech").Info("three")
+	l.WithField("tenant", "acme").Info("four")
+	l.Info("five")
+
+	if got := hook.Open(); !reflect.DeepEqual(got, []string{unknownTenant, "acme"}) {
+		t.Errorf("expected the two most recent tenants to stay open, got %v", got)
+	}
+	if err := hook.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	for file, want := range map[string]string{
+		"acme.log":         "level=info msg=one tenant=acme\nlevel=info msg=four tenant=acme\n",
+		"globex.log":       "level=info msg=two tenant=globex\n",
+		"..%2Finitech.log": "level=info msg=three tenant=../initech\n",
+		"_unknown.log":     "level=info msg=five\n",
+	} {
+		b, err := ioutil.ReadFile(filepath.Join(dir, file))
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		if string(b) != want {
+			t.Errorf("%s: expected %q, got %q", file, want, b)
+		}
+	}
+}
+
+func TestTenantFileName(t *testing.T) {
+	for tenant, want := range map[string]string{
+		"acme":        "acme",
+		"acme.eu-1":   "acme.eu-1",
+		"..":          "%2E%2E",
+		"a/b\\c d":    "a%2Fb%5Cc%20d",
+		"café":        "caf%C3%A9",
+		"/etc/passwd": "%2Fetc%2Fpasswd",
+		"acme%2Feu":   "acme%252Feu",
+	} {
+		if got := tenantFileName(tenant); got != want {
+			t.Errorf("%q: expected %q, got %q", tenant, want, got)
+		}
+	}
+
+	names := map[string]string{}
+	for _, tenant := range []string{"acme/eu", "acme eu", "acme_eu", "acme%2Feu", ".", "%2E"} {
+		name := tenantFileName(tenant)
+		if other, ok := names[name]; ok {
+			t.Errorf("expected %q and %q in different files, both got %q", tenant, other, name)
+		}
+		names[name] = tenant
+	}
+}
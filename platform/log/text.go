@@ -6,9 +6,11 @@ import (
 	"io"
 	"os"
 	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
+	"unicode/utf8"
 
 	"golang.org/x/crypto/ssh/terminal"
 
@@ -17,12 +19,13 @@ import (
 )
 
 const (
-	nocolor = 0
-	red     = 31
-	green   = 32
-	yellow  = 33
-	blue    = 36
-	gray    = 37
+	defaultMessageWidth = 44
+
+	// MessageWidthAuto pads messages to half the width of the terminal
+	MessageWidthAuto = -1
+
+	// MessageWidthNone writes the fields right after the message
+	MessageWidthNone = -2
 )
 
 var (
@@ -33,14 +36,24 @@ func init() {
 	baseTimestamp = time.Now()
 }
 
-// ChannelFormatter formats logs into text
+// ChannelFormatter formats logs into text. Create it with New and options
+// or as a struct literal.
 type ChannelTextFormatter struct {
 	// Set to true to bypass checking for a TTY before outputting colors.
+	// Colors are also on with CLICOLOR_FORCE set or a WriterHint, and off
+	// with NO_COLOR or CLICOLOR=0 set, unless forced.
 	ForceColors bool
 
 	// Force disabling colors.
 	DisableColors bool
 
+	// Theme sets the colors. Defaults to DefaultTheme.
+	Theme *Theme
+
+	// Glyphs puts a level marker such as EmojiGlyphs in front of colored
+	// entries. Nil leaves it out.
+	Glyphs *Glyphs
+
 	// Disable timestamp logging. useful when output is redirected to logging
 	// system that already adds timestamps.
 	DisableTimestamp bool
@@ -52,28 +65,132 @@ type ChannelTextFormatter struct {
 	// TimestampFormat to use for display when a full timestamp is printed
 	TimestampFormat string
 
+	// ElapsedFormat and ElapsedWidth write the time passed when
+	// FullTimestamp is off, see ElapsedFormat. ElapsedWidth is the number
+	// of digits the seconds, or the hours of the clock formats, are zero
+	// padded to.
+	ElapsedFormat ElapsedFormat
+	ElapsedWidth  int
+
+	// MessageWidth is the number of terminal cells colored messages are
+	// padded to, so the fields line up. Zero means 44, MessageWidthAuto
+	// half the width of the terminal and MessageWidthNone no padding.
+	MessageWidth int
+
 	// The fields are sorted by default for a consistent output. For applications
 	// that log extremely frequently and don't use the JSON formatter this may not
 	// be desired.
 	DisableSorting bool
 
+	// KeyOrder lists fields that always come first and in this order, e.g.
+	// request_id and user_id. The other fields follow, sorted unless
+	// DisableSorting is set.
+	KeyOrder []string
+
 	// QuoteEmptyFields will wrap empty fields in quotes if true
 	QuoteEmptyFields bool
 
+	// Escaping of values that need quoting. Defaults to EscapeGo.
+	Escaping Escaping
+
+	// ReportCaller adds the file:line and function that logged the entry.
+	ReportCaller bool
+
+	// CallerSkip is the number of frames to skip above the first caller
+	// outside this package, for code that wraps the log functions.
+	CallerSkip int
+
+	// CallerTrimPrefixes are stripped from caller file paths. By default
+	// paths are trimmed up to the GOPATH src directory.
+	CallerTrimPrefixes []string
+
+	// ErrorChain renders the causes of error values, see ErrorChainMode.
+	ErrorChain ErrorChainMode
+
+	// FlattenFields writes map and struct values as a field per entry with
+	// dotted keys, http.method=GET http.status=200, down to MaxDepth levels,
+	// which defaults to 5.
+	FlattenFields bool
+	MaxDepth      int
+
+	// FieldMap renames the time, level and msg keys. Fields with the same
+	// names as these keys are renamed fields.<key>.
+	FieldMap FieldMap
+
 	// Whether the logger's out is to a terminal
 	isTerminal bool
 
+	// Whether NO_COLOR is set
+	noColor bool
+
+	// MessageWidth resolved
+	messageWidth int
+
 	sync.Once
 }
 
 func (f *ChannelTextFormatter) init(entry *log.Entry) {
 	if entry.Logger != nil {
-		f.isTerminal = f.checkIfTerminal(entry.Logger.Out)
+		f.isTerminal = f.checkIfTerminal(entry.Logger.Out) || forceColor()
+	}
+	f.noColor = noColor()
+
+	f.messageWidth = f.MessageWidth
+	switch f.MessageWidth {
+	case 0:
+		f.messageWidth = defaultMessageWidth
+	case MessageWidthAuto:
+		f.messageWidth = defaultMessageWidth
+		if entry.Logger != nil {
+			if width := terminalWidth(entry.Logger.Out); width > 0 {
+				f.messageWidth = width / 2
+			}
+		}
 	}
 }
 
 func (f *ChannelTextFormatter) checkIfTerminal(w io.Writer) bool {
+	return isTerminal(w)
+}
+
+// terminalWidth returns the number of columns of the terminal w writes to,
+// zero when it isn't one.
+func terminalWidth(w io.Writer) int {
 	switch v := w.(type) {
+	case WriterHint:
+		return v.Width
+	case *WriterHint:
+		return v.Width
+	}
+	if v, ok := w.(*os.File); ok && terminal.IsTerminal(int(v.Fd())) {
+		if width, _, err := terminal.GetSize(int(v.Fd())); err == nil {
+			return width
+		}
+	}
+	return 0
+}
+
+// WriterHint tells the text, dev and diff formatters whether the writer it
+// wraps shows colors, for writers that aren't terminals themselves, such as
+// a pipe into less -R or an io.MultiWriter teeing to a terminal and a file:
+//
+//	logrus.SetOutput(log.WriterHint{Writer: pager, Terminal: true})
+type WriterHint struct {
+	io.Writer
+
+	// Terminal turns colors on, as ForceColors does
+	Terminal bool
+
+	// Width is the number of columns, for MessageWidthAuto
+	Width int
+}
+
+func isTerminal(w io.Writer) bool {
+	switch v := w.(type) {
+	case WriterHint:
+		return v.Terminal
+	case *WriterHint:
+		return v.Terminal
 	case *os.File:
 		return terminal.IsTerminal(int(v.Fd()))
 	default:
@@ -83,91 +200,193 @@ func (f *ChannelTextFormatter) checkIfTerminal(w io.Writer) bool {
 
 // Format renders a single log entry
 func (f *ChannelTextFormatter) Format(entry *log.Entry) ([]byte, error) {
-	var b *bytes.Buffer
-	keys := make([]string, 0, len(entry.Data))
+	styled := entry
+	if f.ErrorChain == ErrorChainFields {
+		expanded := *entry
+		expanded.Data = expandErrorFields(entry.Data)
+		entry = &expanded
+	}
+	level, entry := prepareEntry(entry)
+	if f.FlattenFields {
+		flat := *entry
+		flat.Data = flattenFields(entry.Data, f.MaxDepth)
+		entry = &flat
+	}
+	timeKey, levelKey, msgKey := f.FieldMap.resolve(FieldKeyTime, "time"), f.FieldMap.resolve(FieldKeyLevel, "level"), f.FieldMap.resolve(FieldKeyMsg, "msg")
+	entry = prefixEntryClashes(entry, timeKey, levelKey, msgKey)
+
+	kp := keysPool.Get().(*[]string)
+	keys := (*kp)[:0]
 	for k := range entry.Data {
 		keys = append(keys, k)
 	}
-
-	if !f.DisableSorting {
-		sort.Strings(keys)
+	defer func() {
+		*kp = keys[:0]
+		keysPool.Put(kp)
+	}()
+
+	orderKeys(keys, f.KeyOrder, !f.DisableSorting)
+
+	// logrus hands out a pooled buffer that it releases after writing; when
+	// there is none, use our own and copy the result out of it
+	b := entry.Buffer
+	pooled := b == nil
+	if pooled {
+		b = bufferPool.Get().(*bytes.Buffer)
+		b.Reset()
+		defer bufferPool.Put(b)
 	}
 
-	b = &bytes.Buffer{}
-
-	// prefixFieldClashes(entry.Data)
-
 	f.Do(func() { f.init(entry) })
 
-	isColored := (f.ForceColors || f.isTerminal) && !f.DisableColors
+	isColored := (f.ForceColors || (f.isTerminal && !f.noColor)) && !f.DisableColors
 
 	timestampFormat := f.TimestampFormat
 	if timestampFormat == "" {
 		timestampFormat = defaultTimestampFormat
 	}
+
+	caller, function := "", ""
+	if f.ReportCaller {
+		if frame, ok := callerFrame(f.CallerSkip); ok {
+			caller, function = formatCaller(frame, f.CallerTrimPrefixes)
+		}
+	}
+
 	if isColored {
-		f.printColored(b, entry, keys, timestampFormat)
+		theme := themeOrDefault(f.Theme)
+		f.printColored(b, entry, styled, level, keys, timestampFormat, theme)
+		if caller != "" {
+			b.WriteByte(' ')
+			theme.Caller.write(b, "caller")
+			b.WriteByte('=')
+			b.WriteString(caller)
+			b.WriteByte(' ')
+			theme.Caller.write(b, "func")
+			b.WriteByte('=')
+			b.WriteString(function)
+		}
 	} else {
 		if !f.DisableTimestamp {
-			f.appendKeyValue(b, "time", entry.Time.Format(timestampFormat))
+			f.appendTime(b, timeKey, entryTime(entry), timestampFormat)
 		}
-		f.appendKeyValue(b, "level", entry.Level.String())
+		f.appendKeyString(b, levelKey, level.Name)
 		if entry.Message != "" {
-			f.appendKeyValue(b, "msg", entry.Message)
+			f.appendKeyString(b, msgKey, entry.Message)
 		}
 		for _, key := range keys {
 			f.appendKeyValue(b, key, entry.Data[key])
 		}
+		if caller != "" {
+			f.appendKeyString(b, "caller", caller)
+			f.appendKeyString(b, "func", function)
+		}
 	}
 
 	// f.appendKeyValue(b, "test", "tesssssst")
 	b.WriteByte('\n')
+	if pooled {
+		return append([]byte(nil), b.Bytes()...), nil
+	}
 	return b.Bytes(), nil
 }
 
-func (f *ChannelTextFormatter) printColored(b *bytes.Buffer, entry *log.Entry, keys []string, timestampFormat string) {
-	var levelColor int
-	switch entry.Level {
-	case log.DebugLevel:
-		levelColor = gray
-	case log.WarnLevel:
-		levelColor = yellow
-	case log.ErrorLevel, log.FatalLevel, log.PanicLevel:
-		levelColor = red
-	default:
-		levelColor = blue
+var (
+	bufferPool = sync.Pool{
+		New: func() interface{} {
+			return new(bytes.Buffer)
+		},
 	}
 
-	levelText := strings.ToUpper(entry.Level.String())[0:4]
+	// keysPool holds the slices field keys are sorted in
+	keysPool = sync.Pool{
+		New: func() interface{} {
+			keys := make([]string, 0, 16)
+			return &keys
+		},
+	}
+)
 
-	if entry.Level <= log.WarnLevel {
-		if f.DisableTimestamp {
-			fmt.Fprintf(b, "\x1b[%dm%s\x1b[0m ", levelColor, levelText)
-		} else if !f.FullTimestamp {
-			fmt.Fprintf(b, "\x1b[%dm%s\x1b[0m[%04d] ", levelColor, levelText, int(entry.Time.Sub(baseTimestamp)/time.Second))
-		} else {
-			fmt.Fprintf(b, "\x1b[%dm%s\x1b[0m[%s] ", levelColor, levelText, entry.Time.Format(timestampFormat))
+// sortKeys sorts small key sets with an insertion sort, which unlike
+// sort.Strings doesn't allocate.
+func sortKeys(keys []string) {
+	if len(keys) > 12 {
+		sort.Strings(keys)
+		return
+	}
+	for i := 1; i < len(keys); i++ {
+		for j := i; j > 0 && keys[j] < keys[j-1]; j-- {
+			keys[j], keys[j-1] = keys[j-1], keys[j]
 		}
-		for _, k := range keys {
-			v := entry.Data[k]
-			fmt.Fprintf(b, " \x1b[%dm%s\x1b[0m=", levelColor, k)
-			f.appendValue(b, v)
+	}
+}
+
+// orderKeys moves the keys listed in order to the front, in that order, after
+// sorting the keys if sorted is set. Without sorting the rest are left in map
+// order since logrus fields don't keep the order they were added in.
+func orderKeys(keys []string, order []string, sorted bool) {
+	if sorted {
+		sortKeys(keys)
+	}
+	next := 0
+	for _, k := range order {
+		for i := next; i < len(keys); i++ {
+			if keys[i] == k {
+				copy(keys[next+1:i+1], keys[next:i])
+				keys[next] = k
+				next++
+				break
+			}
 		}
-	} else {
-		if f.DisableTimestamp {
-			fmt.Fprintf(b, "\x1b[%dm%s\x1b[0m %-44s ", levelColor, levelText, entry.Message)
-		} else if !f.FullTimestamp {
-			fmt.Fprintf(b, "\x1b[%dm%s\x1b[0m[%04d] %-44s ", levelColor, levelText, int(entry.Time.Sub(baseTimestamp)/time.Second), entry.Message)
-		} else {
-			fmt.Fprintf(b, "\x1b[%dm%s\x1b[0m[%s] %-44s ", levelColor, levelText, entry.Time.Format(timestampFormat), entry.Message)
+	}
+}
+
+// printColored writes entry, styled is the entry as logged, carrying the
+// fields of Highlight and EntryColor.
+func (f *ChannelTextFormatter) printColored(b *bytes.Buffer, entry, styled *log.Entry, level Level, keys []string, timestampFormat string, theme *Theme) {
+	highlighted, messageColor := entryStyle(styled)
+	levelColor, keyColor := theme.Level(entry.Level), theme.key(entry.Level)
+	if messageColor != "" {
+		levelColor = messageColor
+		if theme.Key == "" {
+			keyColor = messageColor
 		}
-		for _, k := range keys {
-			v := entry.Data[k]
-			fmt.Fprintf(b, " \x1b[%dm%s\x1b[0m=", levelColor, k)
-			f.appendValue(b, v)
+	}
+
+	if f.Glyphs != nil {
+		f.Glyphs.write(b, entry.Level, levelColor)
+	}
+	levelText := fmt.Sprintf("%-4.4s", strings.ToUpper(level.Name))
+	levelColor.write(b, levelText)
+
+	if !f.DisableTimestamp {
+		b.WriteByte('[')
+		if !f.FullTimestamp {
+			theme.Timestamp.write(b, formatElapsed(elapsed(entry), f.ElapsedFormat, f.ElapsedWidth))
+		} else {
+			theme.Timestamp.write(b, entryTime(entry).Format(timestampFormat))
 		}
+		b.WriteByte(']')
 	}
+	b.WriteByte(' ')
 
+	if f.messageWidth > 0 {
+		// pad by terminal cells, wide characters take two
+		padDisplay(b, entry.Message, f.messageWidth, messageColor)
+		b.WriteByte(' ')
+	} else {
+		messageColor.write(b, entry.Message)
+	}
+	for _, k := range keys {
+		b.WriteByte(' ')
+		keyColor.write(b, k)
+		b.WriteByte('=')
+		if c := highlighted.color(k, theme); c != "" {
+			c.writeValue(b, func() { f.appendValue(b, entry.Data[k]) })
+		} else {
+			f.appendValue(b, entry.Data[k])
+		}
+	}
 }
 
 func (f *ChannelTextFormatter) needsQuoting(text string) bool {
@@ -194,15 +413,124 @@ func (f *ChannelTextFormatter) appendKeyValue(b *bytes.Buffer, key string, value
 	f.appendValue(b, value)
 }
 
+// appendKeyString is appendKeyValue for strings, which would be allocated
+// converting them to interface{}.
+func (f *ChannelTextFormatter) appendKeyString(b *bytes.Buffer, key string, value string) {
+	if b.Len() > 0 {
+		b.WriteByte(' ')
+	}
+	b.WriteString(key)
+	b.WriteByte('=')
+	f.appendString(b, value)
+}
+
+// appendTime writes the time field formatting into a stack buffer rather
+// than a new string.
+func (f *ChannelTextFormatter) appendTime(b *bytes.Buffer, key string, t time.Time, layout string) {
+	var tmp [64]byte
+	ts := t.AppendFormat(tmp[:0], layout)
+	for _, c := range ts {
+		if c < 0x20 || c >= 0x7f || c == '"' || c == '\\' {
+			// needs escaping, rare enough to take the slow path
+			f.appendKeyString(b, key, string(ts))
+			return
+		}
+	}
+	if b.Len() > 0 {
+		b.WriteByte(' ')
+	}
+	b.WriteString(key)
+	b.WriteByte('=')
+	quote := f.needsQuoting(string(ts))
+	if quote {
+		b.WriteByte('"')
+	}
+	b.Write(ts)
+	if quote {
+		b.WriteByte('"')
+	}
+}
+
+// appendValue writes strings, numbers and bools without going through fmt,
+// everything else as fmt.Sprint prints it.
 func (f *ChannelTextFormatter) appendValue(b *bytes.Buffer, value interface{}) {
-	stringVal, ok := value.(string)
-	if !ok {
-		stringVal = fmt.Sprint(value)
+	var tmp [64]byte
+	switch v := resolveLazy(value).(type) {
+	case string:
+		f.appendString(b, v)
+	case int:
+		b.Write(strconv.AppendInt(tmp[:0], int64(v), 10))
+	case int64:
+		b.Write(strconv.AppendInt(tmp[:0], v, 10))
+	case int32:
+		b.Write(strconv.AppendInt(tmp[:0], int64(v), 10))
+	case uint:
+		b.Write(strconv.AppendUint(tmp[:0], uint64(v), 10))
+	case uint64:
+		b.Write(strconv.AppendUint(tmp[:0], v, 10))
+	case uint32:
+		b.Write(strconv.AppendUint(tmp[:0], uint64(v), 10))
+	case float64:
+		b.Write(strconv.AppendFloat(tmp[:0], v, 'g', -1, 64))
+	case float32:
+		b.Write(strconv.AppendFloat(tmp[:0], float64(v), 'g', -1, 32))
+	case bool:
+		b.WriteString(strconv.FormatBool(v))
+	case error:
+		if f.ErrorChain == ErrorChainInline {
+			f.appendString(b, inlineErrorChain(v))
+		} else {
+			f.appendString(b, v.Error())
+		}
+	default:
+		f.appendString(b, fmt.Sprint(v))
 	}
+}
 
-	if !f.needsQuoting(stringVal) {
-		b.WriteString(stringVal)
-	} else {
-		b.WriteString(fmt.Sprintf("%q", stringVal))
+func (f *ChannelTextFormatter) appendString(b *bytes.Buffer, s string) {
+	if !f.needsQuoting(s) {
+		b.WriteString(s)
+		return
+	}
+	f.Escaping.or(EscapeGo).appendQuoted(b, s)
+}
+
+// appendQuoted writes s as strconv.Quote does, straight into b.
+func appendQuoted(b *bytes.Buffer, s string) {
+	b.WriteByte('"')
+	start := 0
+	for i := 0; i < len(s); {
+		c := s[i]
+		if c >= 0x20 && c < utf8.RuneSelf && c != 0x7f && c != '"' && c != '\\' {
+			i++
+			continue
+		}
+		r, width := rune(c), 1
+		if c >= utf8.RuneSelf {
+			r, width = utf8.DecodeRuneInString(s[i:])
+			if (r != utf8.RuneError || width > 1) && strconv.IsPrint(r) {
+				i += width
+				continue
+			}
+		}
+
+		b.WriteString(s[start:i])
+		switch {
+		case c == '"' || c == '\\':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case r == utf8.RuneError && width == 1:
+			b.WriteString(`\x`)
+			b.WriteByte(hexDigits[c>>4])
+			b.WriteByte(hexDigits[c&0xf])
+		default:
+			var tmp [16]byte
+			q := strconv.AppendQuoteRune(tmp[:0], r)
+			b.Write(q[1 : len(q)-1])
+		}
+		i += width
+		start = i
 	}
+	b.WriteString(s[start:])
+	b.WriteByte('"')
 }
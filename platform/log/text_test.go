@@ -0,0 +1,165 @@
+package log
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strconv"
+	"testing"
+	"time"
+
+	logrus "github.com/sirupsen/logrus"
+)
+
+func benchmarkEntry(fields logrus.Fields) *logrus.Entry {
+	entry := logrus.NewEntry(logrus.StandardLogger())
+	entry.Time = time.Now()
+	entry.Level = logrus.InfoLevel
+	entry.Message = "charge succeeded"
+	entry.Data = fields
+	return entry
+}
+
+func TestTextFormatterKeyOrder(t *testing.T) {
+	entry := benchmarkEntry(logrus.Fields{"amount": 1000, "user_id": 7, "currency": "usd", "request_id": "r1"})
+	f := &ChannelTextFormatter{DisableColors: true, DisableTimestamp: true, KeyOrder: []string{"request_id", "missing", "user_id"}}
+	b, err := f.Format(entry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "level=info msg=\"charge succeeded\" request_id=r1 user_id=7 amount=1000 currency=usd\n"
+	if string(b) != expected {
+		t.Errorf("expected %q got %q", expected, b)
+	}
+}
+
+func TestTextFormatterTheme(t *testing.T) {
+	entry := benchmarkEntry(logrus.Fields{"amount": 1000})
+	f := &ChannelTextFormatter{ForceColors: true, DisableTimestamp: true, Theme: &Theme{Info: Color256(39), Key: TrueColor(42, 161, 152)}}
+	b, err := f.Format(entry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "\x1b[38;5;39mINFO\x1b[0m charge succeeded                              \x1b[38;2;42;161;152mamount\x1b[0m=1000\n"
+	if string(b) != expected {
+		t.Errorf("expected %q got %q", expected, b)
+	}
+}
+
+func TestTextFormatterMessageWidth(t *testing.T) {
+	entry := benchmarkEntry(logrus.Fields{"amount": 1000})
+	entry.Level = logrus.WarnLevel
+	for _, tt := range []struct {
+		width    int
+		expected string
+	}{
+		{20, "\x1b[33mWARN\x1b[0m charge succeeded      \x1b[33mamount\x1b[0m=1000\n"},
+		{MessageWidthNone, "\x1b[33mWARN\x1b[0m charge succeeded \x1b[33mamount\x1b[0m=1000\n"},
+		{MessageWidthAuto, "\x1b[33mWARN\x1b[0m charge succeeded                              \x1b[33mamount\x1b[0m=1000\n"},
+	} {
+		f := &ChannelTextFormatter{ForceColors: true, DisableTimestamp: true, MessageWidth: tt.width}
+		b, err := f.Format(entry)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != tt.expected {
+			t.Errorf("width %d: expected %q got %q", tt.width, tt.expected, b)
+		}
+	}
+}
+
+func TestTextFormatterQuoting(t *testing.T) {
+	f := &ChannelTextFormatter{DisableColors: true}
+	for _, s := range []string{"plain", "with space", `quote " backslash \`, "newline\n tab\t bell\a del\x7f", "unicode ü 日本", "zero width \u200b", "invalid \xff\xfe", "replacement \ufffd"} {
+		b := &bytes.Buffer{}
+		f.appendString(b, s)
+		expected := strconv.Quote(s)
+		if !f.needsQuoting(s) {
+			expected = s
+		}
+		if b.String() != expected {
+			t.Errorf("expected %s got %s", expected, b.String())
+		}
+	}
+
+	b := &bytes.Buffer{}
+	for _, v := range []interface{}{-42, int64(1) << 40, uint32(7), 0.25, 1e21, float32(0.1), true, errors.New("card declined"), time.Second} {
+		b.Reset()
+		f.appendValue(b, v)
+		b2 := &bytes.Buffer{}
+		f.appendString(b2, fmt.Sprint(v))
+		if b.String() != b2.String() {
+			t.Errorf("expected %s got %s", b2.String(), b.String())
+		}
+	}
+}
+
+func TestTextFormatterAllocs(t *testing.T) {
+	if raceEnabled {
+		t.Skip("sync.Pool drops items under the race detector")
+	}
+	f := &ChannelTextFormatter{DisableColors: true}
+	entry := benchmarkEntry(logrus.Fields{"channel": "payments", "charge": "ch_1", "note": "two words"})
+	entry.Buffer = &bytes.Buffer{}
+	allocs := testing.AllocsPerRun(100, func() {
+		entry.Buffer.Reset()
+		f.Format(entry)
+	})
+	if allocs != 0 {
+		t.Errorf("expected no allocations for string fields, got %v", allocs)
+	}
+}
+
+func BenchmarkTextFormatterFewFields(b *testing.B) {
+	f := &ChannelTextFormatter{DisableColors: true}
+	entry := benchmarkEntry(logrus.Fields{"channel": "payments", "amount": 1000})
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := f.Format(entry); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkTextFormatterManyFields(b *testing.B) {
+	f := &ChannelTextFormatter{DisableColors: true}
+	entry := benchmarkEntry(logrus.Fields{
+		"channel": "payments", "amount": 1000, "currency": "usd", "neoAddress": "AM8pnu1yK7ViMt7Sw2nPpbtPQXTwjjkykn",
+		"charge": "ch_1", "email": "user@example.com", "tokens": 10, "txid": "b487a1d1", "error": errors.New("card declined"),
+		"duration": time.Second,
+	})
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := f.Format(entry); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkTextFormatterStringFields(b *testing.B) {
+	f := &ChannelTextFormatter{DisableColors: true}
+	entry := benchmarkEntry(logrus.Fields{"channel": "payments", "charge": "ch_1", "note": "two words"})
+	entry.Buffer = &bytes.Buffer{}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		entry.Buffer.Reset()
+		if _, err := f.Format(entry); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkTextFormatterColored(b *testing.B) {
+	f := &ChannelTextFormatter{ForceColors: true}
+	entry := benchmarkEntry(logrus.Fields{"channel": "payments", "amount": 1000})
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := f.Format(entry); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
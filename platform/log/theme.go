@@ -0,0 +1,186 @@
+package log
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+
+	logrus "github.com/sirupsen/logrus"
+)
+
+// Color is the SGR parameter of an ANSI escape sequence, e.g. "31" for red
+// or "1;33" for bold yellow. An empty Color leaves text uncolored.
+type Color string
+
+// ANSIColor returns one of the 16 basic colors, 30-37 and 90-97.
+func ANSIColor(code int) Color {
+	return Color(fmt.Sprint(code))
+}
+
+// Color256 returns a color of the 256-color palette.
+func Color256(n uint8) Color {
+	return Color(fmt.Sprintf("38;5;%d", n))
+}
+
+// TrueColor returns a 24-bit color, shown by most recent terminals.
+func TrueColor(r, g, b uint8) Color {
+	return Color(fmt.Sprintf("38;2;%d;%d;%d", r, g, b))
+}
+
+// write writes s in color c to b.
+func (c Color) write(b *bytes.Buffer, s string) {
+	if c == "" {
+		b.WriteString(s)
+		return
+	}
+	b.WriteString("\x1b[")
+	b.WriteString(string(c))
+	b.WriteByte('m')
+	b.WriteString(s)
+	b.WriteString("\x1b[0m")
+}
+
+// Theme sets the colors of ChannelTextFormatter and DevFormatter.
+type Theme struct {
+	Trace Color
+	Debug Color
+	Info  Color
+	Warn  Color
+	Error Color
+	Fatal Color
+	Panic Color
+
+	// Key colors field names. Empty uses the level color.
+	Key Color
+
+	// Timestamp colors the time. Empty leaves it uncolored.
+	Timestamp Color
+
+	// Caller colors the caller and func keys.
+	Caller Color
+
+	// Highlight colors the values of fields marked with Highlight. Empty
+	// makes them bold.
+	Highlight Color
+}
+
+// Built-in themes. DefaultTheme uses the basic colors every terminal shows,
+// the others the 256-color palette or 24-bit colors.
+var (
+	DefaultTheme = &Theme{
+		Trace:     ANSIColor(36),
+		Debug:     ANSIColor(37),
+		Info:      ANSIColor(36),
+		Warn:      ANSIColor(33),
+		Error:     ANSIColor(31),
+		Fatal:     ANSIColor(31),
+		Panic:     ANSIColor(31),
+		Caller:    ANSIColor(37),
+		Highlight: "1;" + ANSIColor(35),
+	}
+
+	// DarkTheme dims fields and timestamps on dark backgrounds
+	DarkTheme = &Theme{
+		Trace:     Color256(244),
+		Debug:     Color256(250),
+		Info:      Color256(39),
+		Warn:      Color256(214),
+		Error:     Color256(196),
+		Fatal:     "1;" + Color256(196),
+		Panic:     "1;" + Color256(201),
+		Key:       Color256(109),
+		Timestamp: Color256(242),
+		Caller:    Color256(242),
+		Highlight: "1;" + Color256(213),
+	}
+
+	// LightTheme keeps contrast on light backgrounds
+	LightTheme = &Theme{
+		Trace:     Color256(245),
+		Debug:     Color256(240),
+		Info:      Color256(25),
+		Warn:      Color256(130),
+		Error:     Color256(124),
+		Fatal:     "1;" + Color256(124),
+		Panic:     "1;" + Color256(90),
+		Key:       Color256(30),
+		Timestamp: Color256(246),
+		Caller:    Color256(246),
+		Highlight: "1;" + Color256(127),
+	}
+
+	// SolarizedTheme uses the Solarized accent colors
+	SolarizedTheme = &Theme{
+		Trace:     TrueColor(88, 110, 117),
+		Debug:     TrueColor(147, 161, 161),
+		Info:      TrueColor(38, 139, 210),
+		Warn:      TrueColor(181, 137, 0),
+		Error:     TrueColor(220, 50, 47),
+		Fatal:     "1;" + TrueColor(220, 50, 47),
+		Panic:     "1;" + TrueColor(211, 54, 130),
+		Key:       TrueColor(42, 161, 152),
+		Timestamp: TrueColor(101, 123, 131),
+		Caller:    TrueColor(101, 123, 131),
+		Highlight: "1;" + TrueColor(108, 113, 196),
+	}
+)
+
+// Level returns the color of level.
+func (t *Theme) Level(level logrus.Level) Color {
+	switch level {
+	case logrus.TraceLevel:
+		return t.Trace
+	case logrus.DebugLevel:
+		return t.Debug
+	case logrus.InfoLevel:
+		return t.Info
+	case logrus.WarnLevel:
+		return t.Warn
+	case logrus.ErrorLevel:
+		return t.Error
+	case logrus.FatalLevel:
+		return t.Fatal
+	default:
+		return t.Panic
+	}
+}
+
+// key returns the color of field names at level.
+func (t *Theme) key(level logrus.Level) Color {
+	if t.Key == "" {
+		return t.Level(level)
+	}
+	return t.Key
+}
+
+// highlight returns the color of highlighted values.
+func (t *Theme) highlight() Color {
+	if t.Highlight == "" {
+		return "1"
+	}
+	return t.Highlight
+}
+
+func themeOrDefault(t *Theme) *Theme {
+	if t == nil {
+		return DefaultTheme
+	}
+	return t
+}
+
+// noColor reports whether the environment asks for output without colors,
+// with NO_COLOR, see https://no-color.org, or CLICOLOR=0 without
+// CLICOLOR_FORCE, see https://bixense.com/clicolors.
+func noColor() bool {
+	if os.Getenv("NO_COLOR") != "" {
+		return true
+	}
+	return os.Getenv("CLICOLOR") == "0" && !forceColor()
+}
+
+// forceColor reports whether CLICOLOR_FORCE asks for colors on writers that
+// aren't terminals, e.g. when piping through less -R.
+func forceColor() bool {
+	v := os.Getenv("CLICOLOR_FORCE")
+	return v != "" && v != "0"
+}
@@ -0,0 +1,67 @@
+package log
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// TraceParentHeader and TraceStateHeader carry the W3C trace context
+	TraceParentHeader = "traceparent"
+	TraceStateHeader  = "tracestate"
+)
+
+// ParseTraceParent parses W3C traceparent and tracestate header values,
+// e.g. 00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01, into the
+// remote span context of the caller, so the trace ID can be logged without
+// the OpenTelemetry SDK. An invalid tracestate is ignored, as the spec asks.
+func ParseTraceParent(traceparent, tracestate string) (trace.SpanContext, error) {
+	parts := strings.Split(strings.TrimSpace(traceparent), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || !isLowerHex(parts[0]) || parts[0] == "ff" {
+		return trace.SpanContext{}, fmt.Errorf("log: invalid traceparent %q", traceparent)
+	}
+	// version 00 has exactly four parts, later versions may add more
+	if parts[0] == "00" && len(parts) != 4 {
+		return trace.SpanContext{}, fmt.Errorf("log: invalid traceparent %q", traceparent)
+	}
+
+	var c trace.SpanContextConfig
+	var flags [1]byte
+	if !decodeTraceHex(c.TraceID[:], parts[1]) || !decodeTraceHex(c.SpanID[:], parts[2]) || !decodeTraceHex(flags[:], parts[3]) {
+		return trace.SpanContext{}, fmt.Errorf("log: invalid traceparent %q", traceparent)
+	}
+	c.TraceFlags = trace.TraceFlags(flags[0])
+	c.Remote = true
+	if tracestate != "" {
+		if ts, err := trace.ParseTraceState(tracestate); err == nil {
+			c.TraceState = ts
+		}
+	}
+	sc := trace.NewSpanContext(c)
+	if !sc.IsValid() {
+		return trace.SpanContext{}, fmt.Errorf("log: invalid traceparent %q", traceparent)
+	}
+	return sc, nil
+}
+
+// decodeTraceHex decodes s, which must be lower case hex of exactly
+// len(dst) bytes.
+func decodeTraceHex(dst []byte, s string) bool {
+	if len(s) != 2*len(dst) || !isLowerHex(s) {
+		return false
+	}
+	_, err := hex.Decode(dst, []byte(s))
+	return err == nil
+}
+
+func isLowerHex(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if !(s[i] >= '0' && s[i] <= '9') && !(s[i] >= 'a' && s[i] <= 'f') {
+			return false
+		}
+	}
+	return true
+}
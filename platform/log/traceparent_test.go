@@ -0,0 +1,33 @@
+package log
+
+import "testing"
+
+func TestParseTraceParent(t *testing.T) {
+	sc, err := ParseTraceParent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", "congo=t61rcWkgMzE")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sc.TraceID().String() != "4bf92f3577b34da6a3ce929d0e0e4736" || sc.SpanID().String() != "00f067aa0ba902b7" {
+		t.Errorf("unexpected span context %v %v", sc.TraceID(), sc.SpanID())
+	}
+	if !sc.IsSampled() || !sc.IsRemote() || sc.TraceState().String() != "congo=t61rcWkgMzE" {
+		t.Errorf("expected a sampled remote span with its trace state")
+	}
+	if _, err := ParseTraceParent("01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00-future", ""); err != nil {
+		t.Errorf("expected later versions to be parsed, got %v", err)
+	}
+
+	for _, header := range []string{
+		"",
+		"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e47-00f067aa0ba902b7-01",
+	} {
+		if _, err := ParseTraceParent(header, ""); err == nil {
+			t.Errorf("expected an error for %q", header)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package log
+
+import (
+	"fmt"
+	"unicode/utf8"
+
+	logrus "github.com/sirupsen/logrus"
+)
+
+const (
+	// TruncatedKey is set to true on entries whose message or fields were
+	// shortened by TruncateHook.
+	TruncatedKey = "truncated"
+
+	truncationMarker = "..."
+)
+
+// TruncateHook shortens the message and the string values of an entry
+// beyond a maximum length, ending them with "..." and flagging the entry with
+// TruncatedKey, so a dumped payload of megabytes doesn't break the parsers
+// downstream. Strings, byte slices, errors and fmt.Stringers are truncated,
+// other values are left as they are. Add it after a RedactHook so patterns
+// still match whole values.
+type TruncateHook struct {
+	// MaxMessage length of the message in bytes. 0 means no limit.
+	MaxMessage int
+
+	// MaxField length of every field value in bytes. 0 means no limit.
+	MaxField int
+}
+
+func NewTruncateHook(maxMessage, maxField int) *TruncateHook {
+	return &TruncateHook{MaxMessage: maxMessage, MaxField: maxField}
+}
+
+func (h *TruncateHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (h *TruncateHook) Fire(entry *logrus.Entry) error {
+	truncated := false
+	if s, ok := truncateString(entry.Message, h.MaxMessage); ok {
+		entry.Message = s
+		truncated = true
+	}
+	if h.MaxField <= 0 {
+		if truncated {
+			entry.Data[TruncatedKey] = true
+		}
+		return nil
+	}
+
+	for k, v := range entry.Data {
+		var s string
+		switch v := v.(type) {
+		case string:
+			s = v
+		case []byte:
+			if len(v) <= h.MaxField {
+				continue
+			}
+			s = string(v[:h.MaxField+1])
+		case *LazyValue:
+			// computing it here would defeat the point
+			continue
+		case error:
+			s = v.Error()
+		case fmt.Stringer:
+			s = v.String()
+		default:
+			continue
+		}
+		if s, ok := truncateString(s, h.MaxField); ok {
+			entry.Data[k] = s
+			truncated = true
+		}
+	}
+	if truncated {
+		entry.Data[TruncatedKey] = true
+	}
+	return nil
+}
+
+// truncateString cuts s to at most max bytes including the marker, on a
+// rune boundary, and reports whether it did.
+func truncateString(s string, max int) (string, bool) {
+	if max <= 0 || len(s) <= max {
+		return s, false
+	}
+	cut := max - len(truncationMarker)
+	if cut < 0 {
+		cut = 0
+	}
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return s[:cut] + truncationMarker, true
+}
@@ -0,0 +1,47 @@
+package log
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	logrus "github.com/sirupsen/logrus"
+)
+
+func TestTruncateHook(t *testing.T) {
+	entry := &logrus.Entry{
+		Message: strings.Repeat("m", 30),
+		Data: logrus.Fields{
+			"body":   strings.Repeat("ü", 10),
+			"raw":    []byte(strings.Repeat("b", 20)),
+			"error":  errors.New(strings.Repeat("e", 20)),
+			"short":  "ok",
+			"amount": 1000,
+		},
+	}
+	if err := NewTruncateHook(10, 8).Fire(entry); err != nil {
+		t.Fatal(err)
+	}
+	expected := logrus.Fields{
+		"body":       "üü...",
+		"raw":        "bbbbb...",
+		"error":      "eeeee...",
+		"short":      "ok",
+		"amount":     1000,
+		TruncatedKey: true,
+	}
+	if entry.Message != "mmmmmmm..." {
+		t.Errorf("unexpected message %q", entry.Message)
+	}
+	for k, v := range expected {
+		if entry.Data[k] != v {
+			t.Errorf("expected %v=%v got %v", k, v, entry.Data[k])
+		}
+	}
+
+	entry = &logrus.Entry{Message: "short", Data: logrus.Fields{"short": "ok"}}
+	NewTruncateHook(10, 8).Fire(entry)
+	if _, ok := entry.Data[TruncatedKey]; ok {
+		t.Error("expected entries within the limits to be left alone")
+	}
+}
@@ -0,0 +1,67 @@
+package log
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+)
+
+// Typed field values that text formatters render for people and JSON
+// formatters keep as plain numbers for queries:
+//
+//	logrus.WithFields(logrus.Fields{
+//		"took": log.Duration(time.Since(start)), // took=1.23s, "took":1234567890
+//		"size": log.Bytes(n),                    // size="3.4 MiB", "size":3565158
+//		"hits": log.Percent(hits / total),       // hits=25.3%, "hits":0.253
+//	}).Info("export")
+type (
+	// Duration renders rounded to about three digits, JSON holds
+	// nanoseconds.
+	Duration time.Duration
+
+	// Bytes renders in binary units, KiB, MiB and so on.
+	Bytes int64
+
+	// Percent is a ratio, 1 being 100%, rendered to one decimal.
+	Percent float64
+)
+
+func (d Duration) String() string {
+	v := time.Duration(d)
+	abs := v
+	if abs < 0 {
+		abs = -abs
+	}
+	switch {
+	case abs >= time.Minute:
+		return v.Round(time.Second).String()
+	case abs >= time.Second:
+		return v.Round(10 * time.Millisecond).String()
+	case abs >= time.Millisecond:
+		return v.Round(10 * time.Microsecond).String()
+	case abs >= time.Microsecond:
+		return v.Round(10 * time.Nanosecond).String()
+	}
+	return v.String()
+}
+
+const byteUnits = "KMGTPE"
+
+func (b Bytes) String() string {
+	n := int64(b)
+	if n < 1024 && n > -1024 {
+		return fmt.Sprintf("%d B", n)
+	}
+	v := float64(n)
+	i := -1
+	for (v >= 1024 || v <= -1024) && i < len(byteUnits)-1 {
+		v /= 1024
+		i++
+	}
+	return fmt.Sprintf("%.1f %ciB", v, byteUnits[i])
+}
+
+func (p Percent) String() string {
+	return strconv.FormatFloat(math.Round(float64(p)*1000)/10, 'f', -1, 64) + "%"
+}
@@ -0,0 +1,54 @@
+package log
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	logrus "github.com/sirupsen/logrus"
+)
+
+func TestUnits(t *testing.T) {
+	for v, expected := range map[interface{}]string{
+		Duration(1234567890 * time.Nanosecond): "1.23s",
+		Duration(90500 * time.Millisecond):     "1m31s",
+		Duration(12345 * time.Microsecond):     "12.35ms",
+		Duration(-1500 * time.Microsecond):     "-1.5ms",
+		Duration(999):                          "999ns",
+		Bytes(512):                             "512 B",
+		Bytes(3565158):                         "3.4 MiB",
+		Bytes(5 << 40):                         "5.0 TiB",
+		Percent(0.253):                         "25.3%",
+		Percent(1):                             "100%",
+	} {
+		if s := v.(interface{ String() string }).String(); s != expected {
+			t.Errorf("expected %v got %v", expected, s)
+		}
+	}
+}
+
+func TestUnitsFormatting(t *testing.T) {
+	entry := &logrus.Entry{Logger: logrus.StandardLogger(), Level: logrus.InfoLevel, Data: logrus.Fields{
+		"took": Duration(1234567890),
+		"size": Bytes(3565158),
+		"hits": Percent(0.25),
+	}}
+
+	text, err := (&ChannelTextFormatter{DisableColors: true, DisableTimestamp: true}).Format(entry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(text), `hits="25%" size="3.4 MiB" took=1.23s`) {
+		t.Errorf("expected human readable values in %q", text)
+	}
+
+	json, err := (&ChannelJSONFormatter{}).Format(entry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{`"took":1234567890`, `"size":3565158`, `"hits":0.25`} {
+		if !strings.Contains(string(json), s) {
+			t.Errorf("expected %v in %q", s, json)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package log
+
+import (
+	"io"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+const configReloadDelay = 100 * time.Millisecond
+
+// ConfigWatcher applies a logging config file and applies it again whenever
+// the file changes, without restarting the service. A config that fails to
+// load or build is logged and the running one is kept.
+type ConfigWatcher struct {
+	file    string
+	watcher *fsnotify.Watcher
+
+	mu      sync.Mutex
+	current io.Closer
+
+	done chan struct{}
+}
+
+// WatchConfig builds the config in file, see LoadConfig, and rebuilds it on
+// every change. Close the watcher on shutdown.
+func WatchConfig(file string) (*ConfigWatcher, error) {
+	c, err := LoadConfig(file)
+	if err != nil {
+		return nil, err
+	}
+	current, err := c.Build()
+	if err != nil {
+		return nil, err
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		current.Close()
+		return nil, err
+	}
+	// watch the directory, editors and Kubernetes config maps replace the
+	// file rather than writing to it
+	if err := watcher.Add(filepath.Dir(file)); err != nil {
+		watcher.Close()
+		current.Close()
+		return nil, err
+	}
+
+	w := &ConfigWatcher{
+		file:    filepath.Clean(file),
+		watcher: watcher,
+		current: current,
+		done:    make(chan struct{}),
+	}
+	go w.run()
+	return w, nil
+}
+
+func (w *ConfigWatcher) run() {
+	defer close(w.done)
+
+	// an editor saving a file triggers several events, reload once they
+	// settle
+	var timer <-chan time.Time
+	for {
+		select {
+		case e, ok := <-w.watcher.Events:
+			if !ok {
+				return
+			}
+			if filepath.Clean(e.Name) != w.file && filepath.Base(e.Name) != "..data" {
+				continue
+			}
+			if e.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Rename) != 0 {
+				timer = time.After(configReloadDelay)
+			}
+		case err, ok := <-w.watcher.Errors:
+			if !ok {
+				return
+			}
+			reportf("Failed to watch log config because %+v", err)
+		case <-timer:
+			timer = nil
+			if err := w.Reload(); err != nil {
+				reportf("Failed to reload log config because %+v", err)
+			}
+		}
+	}
+}
+
+// Reload builds the config file again. The outputs of the previous config
+// are closed once the new ones are in place and the entries still being
+// written to them are done, which flushes what they still buffer.
+func (w *ConfigWatcher) Reload() error {
+	c, err := LoadConfig(w.file)
+	if err != nil {
+		return err
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	next, err := c.Build()
+	if err != nil {
+		return err
+	}
+	previous := w.current
+	w.current = next
+	return previous.Close()
+}
+
+// Close stops watching and closes the outputs of the current config.
+func (w *ConfigWatcher) Close() error {
+	err := w.watcher.Close()
+	<-w.done
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if cerr := w.current.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
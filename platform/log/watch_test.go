@@ -0,0 +1,163 @@
+package log
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	logrus "github.com/sirupsen/logrus"
+)
+
+func writeConfig(t *testing.T, file, out string) {
+	config := `{
+		"level": "info",
+		"outputs": [
+			{"type": "file", "path": "` + out + `", "formatter": "logfmt", "async": true},
+			{"type": "file", "path": "` + out + `.errors", "formatter": "logfmt", "level": "error"}
+		]
+	}`
+	if err := ioutil.WriteFile(file, []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestConfigWatcher(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logwatch")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer resetConfig()
+
+	file := filepath.Join(dir, "log.json")
+	first, second := filepath.Join(dir, "first.log"), filepath.Join(dir, "second.log")
+	writeConfig(t, file, first)
+	w, err := WatchConfig(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	Info("before")
+
+	writeConfig(t, file, second)
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		Info("after")
+		if b, _ := ioutil.ReadFile(second); strings.Contains(string(b), "msg=after") {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("expected the config to be reloaded on change")
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	// a broken config keeps the running one
+	ioutil.WriteFile(file, []byte(`{"level": "loud"}`), 0644)
+	if err := w.Reload(); err == nil {
+		t.Error("expected an error for an invalid level")
+	}
+	Info("kept")
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := ioutil.ReadFile(first)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(b), "msg=before") || strings.Contains(string(b), "msg=kept") {
+		t.Errorf("expected only the entries before the reload in the first file, got %q", b)
+	}
+	if b, _ := ioutil.ReadFile(second); !strings.Contains(string(b), "msg=kept") {
+		t.Errorf("expected the entries after a failed reload in the second file, got %q", b)
+	}
+}
+
+func TestConfigWatcherReloadWhileLogging(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logwatch")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer resetConfig()
+
+	file, out := filepath.Join(dir, "log.json"), filepath.Join(dir, "out.log")
+	writeConfig(t, file, out)
+	w, err := WatchConfig(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dropped := Dropped()
+	const loggers, entries = 4, 2000
+	wg := sync.WaitGroup{}
+	for i := 0; i < loggers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < entries; j++ {
+				Info("paid")
+			}
+		}()
+	}
+	logged := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(logged)
+	}()
+	for reloading := true; reloading; {
+		if err := w.Reload(); err != nil {
+			t.Fatal(err)
+		}
+		select {
+		case <-logged:
+			reloading = false
+		default:
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := ioutil.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// every entry is written or counted as dropped, none hits a closed output
+	if written := strings.Count(string(b), "msg=paid"); uint64(written)+Dropped()-dropped != loggers*entries {
+		t.Errorf("expected %d entries written or dropped, got %d written and %d dropped", loggers*entries, written, Dropped()-dropped)
+	}
+}
+
+func TestConfigWatcherRemovesChannelLevel(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logwatch")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer resetConfig()
+
+	file := filepath.Join(dir, "log.json")
+	ioutil.WriteFile(file, []byte(`{"level": "info", "channels": {"watch.payments": {"level": "debug"}}}`), 0644)
+	w, err := WatchConfig(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	payments := Channel("watch.payments")
+	if payments.GetLevel() != logrus.DebugLevel {
+		t.Fatalf("expected the configured level, got %v", payments.GetLevel())
+	}
+
+	ioutil.WriteFile(file, []byte(`{"level": "warning"}`), 0644)
+	if err := w.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if payments.GetLevel() != logrus.WarnLevel {
+		t.Errorf("expected the channel to inherit the global level once its level is removed, got %v", payments.GetLevel())
+	}
+}
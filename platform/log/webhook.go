@@ -0,0 +1,187 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"text/template"
+	"time"
+
+	logrus "github.com/sirupsen/logrus"
+)
+
+// WebhookKind is the chat service a WebhookHook posts to.
+type WebhookKind int
+
+const (
+	WebhookSlack WebhookKind = iota
+	WebhookTeams
+)
+
+const defaultWebhookThrottle = time.Minute
+
+var defaultWebhookTemplate = template.Must(template.New("webhook").Parse(
+	"*{{.Level}}*{{with .Channel}} [{{.}}]{{end}} {{.Message}}" +
+		"{{range .Fields}}\n{{.Key}}: {{.Value}}{{end}}" +
+		"{{with .Suppressed}}\n({{.}} more suppressed){{end}}"))
+
+// WebhookHook posts entries to a Slack or Microsoft Teams incoming webhook,
+// so on-call engineers see fatal errors without a full alerting stack. At
+// most one message is posted per Throttle; entries in between are counted
+// and the count is added to the next message.
+type WebhookHook struct {
+	URL  string
+	Kind WebhookKind
+
+	// Level is the least severe level posted. Defaults to Error.
+	Level logrus.Level
+
+	// Template renders the message text from a WebhookMessage
+	Template *template.Template
+
+	// Throttle is the minimum time between two messages. Defaults to a minute.
+	Throttle time.Duration
+
+	Client *http.Client
+
+	mu         sync.Mutex
+	last       time.Time
+	suppressed int
+}
+
+// WebhookMessage is the data the template of a WebhookHook is executed with.
+type WebhookMessage struct {
+	Level      string
+	Channel    string
+	Message    string
+	Time       time.Time
+	Fields     []WebhookField
+	Suppressed int
+}
+
+type WebhookField struct {
+	Key   string
+	Value interface{}
+}
+
+func NewSlackHook(url string) *WebhookHook {
+	return newWebhookHook(url, WebhookSlack)
+}
+
+func NewTeamsHook(url string) *WebhookHook {
+	return newWebhookHook(url, WebhookTeams)
+}
+
+func newWebhookHook(url string, kind WebhookKind) *WebhookHook {
+	return &WebhookHook{
+		URL:      url,
+		Kind:     kind,
+		Level:    logrus.ErrorLevel,
+		Template: defaultWebhookTemplate,
+		Throttle: defaultWebhookThrottle,
+		Client:   &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (h *WebhookHook) Levels() []logrus.Level {
+	levels := []logrus.Level{}
+	for _, l := range logrus.AllLevels {
+		if l <= h.Level {
+			levels = append(levels, l)
+		}
+	}
+	return levels
+}
+
+func (h *WebhookHook) Fire(entry *logrus.Entry) error {
+	h.mu.Lock()
+	if entry.Time.Sub(h.last) < h.Throttle {
+		h.suppressed++
+		h.mu.Unlock()
+		return nil
+	}
+	h.last = entry.Time
+	suppressed := h.suppressed
+	h.suppressed = 0
+	h.mu.Unlock()
+
+	msg := WebhookMessage{
+		Level:      entry.Level.String(),
+		Message:    entry.Message,
+		Time:       entry.Time,
+		Suppressed: suppressed,
+	}
+	keys := make([]string, 0, len(entry.Data))
+	for k := range entry.Data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		v := entry.Data[k]
+		if k == ChannelKey {
+			msg.Channel = fmt.Sprint(v)
+			continue
+		}
+		if k == HighlightKey || k == ColorKey {
+			continue
+		}
+		if err, ok := v.(error); ok {
+			v = err.Error()
+		}
+		msg.Fields = append(msg.Fields, WebhookField{Key: k, Value: v})
+	}
+
+	text := &bytes.Buffer{}
+	if err := h.Template.Execute(text, msg); err != nil {
+		return err
+	}
+	body, err := json.Marshal(h.payload(entry.Level, text.String()))
+	if err != nil {
+		return err
+	}
+
+	// the process goes down after Fatal and Panic, so wait for those
+	if entry.Level <= logrus.FatalLevel {
+		return h.post(body)
+	}
+	go func() {
+		if err := h.post(body); err != nil {
+			reportf("Failed to post log entry to webhook because %+v", err)
+		}
+	}()
+	return nil
+}
+
+func (h *WebhookHook) payload(level logrus.Level, text string) interface{} {
+	if h.Kind == WebhookTeams {
+		color := "0078D7"
+		if level <= logrus.ErrorLevel {
+			color = "D70000"
+		} else if level == logrus.WarnLevel {
+			color = "FFA500"
+		}
+		return map[string]interface{}{
+			"@type":      "MessageCard",
+			"@context":   "http://schema.org/extensions",
+			"summary":    level.String(),
+			"themeColor": color,
+			"text":       text,
+		}
+	}
+	return map[string]interface{}{"text": text}
+}
+
+func (h *WebhookHook) post(body []byte) error {
+	resp, err := h.Client.Post(h.URL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("webhook responded %v", resp.Status)
+	}
+	return nil
+}
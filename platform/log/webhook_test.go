@@ -0,0 +1,73 @@
+package log
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	logrus "github.com/sirupsen/logrus"
+)
+
+func webhookEntry(level logrus.Level, t time.Time) *logrus.Entry {
+	entry := logrus.NewEntry(logrus.StandardLogger()).WithFields(logrus.Fields{
+		ChannelKey:      "payments",
+		logrus.ErrorKey: errors.New("card declined"),
+		"invoice":       42,
+	})
+	entry.Level = level
+	entry.Time = t
+	entry.Message = "charge failed"
+	return entry
+}
+
+func TestWebhookHook(t *testing.T) {
+	posted := make(chan map[string]interface{}, 4)
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body := map[string]interface{}{}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Error(err)
+		}
+		posted <- body
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	h := NewSlackHook(server.URL)
+	if len(h.Levels()) != 3 {
+		t.Errorf("expected error and above only, got %v", h.Levels())
+	}
+	start := time.Now()
+	// fatal entries are posted before Fire returns
+	if err := h.Fire(webhookEntry(logrus.FatalLevel, start)); err != nil {
+		t.Fatal(err)
+	}
+	expected := "*fatal* [payments] charge failed\nerror: card declined\ninvoice: 42"
+	if body := <-posted; body["text"] != expected {
+		t.Errorf("expected %q got %q", expected, body["text"])
+	}
+
+	h.Fire(webhookEntry(logrus.FatalLevel, start.Add(time.Second)))
+	status = http.StatusInternalServerError
+	if err := h.Fire(webhookEntry(logrus.FatalLevel, start.Add(time.Minute))); err == nil {
+		t.Error("expected the error status to be returned")
+	}
+	if body := <-posted; body["text"] != expected+"\n(1 more suppressed)" {
+		t.Errorf("expected the throttled entry counted, got %q", body["text"])
+	}
+	status = http.StatusOK
+
+	teams := NewTeamsHook(server.URL)
+	teams.Fire(webhookEntry(logrus.ErrorLevel, start))
+	select {
+	case body := <-posted:
+		if body["@type"] != "MessageCard" || body["themeColor"] != "D70000" || body["summary"] != "error" {
+			t.Errorf("expected a red Teams card, got %v", body)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("nothing posted")
+	}
+}
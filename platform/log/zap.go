@@ -0,0 +1,109 @@
+package log
+
+import (
+	logrus "github.com/sirupsen/logrus"
+	"go.uber.org/zap/zapcore"
+)
+
+// ZapCore is a zapcore.Core writing through a channel or the standard
+// logger, so libraries logging with zap share the same formatters and
+// outputs.
+type ZapCore struct {
+	logger *logrus.Logger
+	fields logrus.Fields
+}
+
+// NewZapCore returns a core for channel l, or for the standard logger when l
+// is nil.
+//
+//	logger := zap.New(log.NewZapCore(log.Channel("payments")))
+func NewZapCore(l *Logger) *ZapCore {
+	if l == nil {
+		return &ZapCore{logger: logrus.StandardLogger(), fields: logrus.Fields{}}
+	}
+	return &ZapCore{logger: l.logger, fields: logrus.Fields{ChannelKey: l.name}}
+}
+
+// ZapLevel maps a zap level to a logrus level.
+func ZapLevel(level zapcore.Level) logrus.Level {
+	switch {
+	case level >= zapcore.FatalLevel:
+		return logrus.FatalLevel
+	case level >= zapcore.PanicLevel:
+		return logrus.PanicLevel
+	case level >= zapcore.ErrorLevel:
+		return logrus.ErrorLevel
+	case level >= zapcore.WarnLevel:
+		return logrus.WarnLevel
+	case level >= zapcore.InfoLevel:
+		return logrus.InfoLevel
+	case level >= zapcore.DebugLevel:
+		return logrus.DebugLevel
+	default:
+		return logrus.TraceLevel
+	}
+}
+
+func (c *ZapCore) Enabled(level zapcore.Level) bool {
+	return c.logger.IsLevelEnabled(ZapLevel(level))
+}
+
+func (c *ZapCore) With(fields []zapcore.Field) zapcore.Core {
+	return &ZapCore{logger: c.logger, fields: zapFields(c.fields, fields)}
+}
+
+func (c *ZapCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(entry.Level) {
+		return checked.AddCore(entry, c)
+	}
+	return checked
+}
+
+// Write logs the entry. zap exits or panics itself after Fatal and Panic
+// entries, so they are only logged.
+func (c *ZapCore) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	data := zapFields(c.fields, fields)
+	if entry.LoggerName != "" {
+		data["logger"] = entry.LoggerName
+	}
+	if entry.Caller.Defined {
+		data["caller"] = entry.Caller.TrimmedPath()
+	}
+	if entry.Stack != "" {
+		data[StackKey] = entry.Stack
+	}
+	logWithoutExit(c.logger.WithFields(data).WithTime(entry.Time), ZapLevel(entry.Level), entry.Message)
+	return nil
+}
+
+func (c *ZapCore) Sync() error {
+	return nil
+}
+
+// zapFields returns base with fields added, encoded the way zap's map
+// encoder does.
+func zapFields(base logrus.Fields, fields []zapcore.Field) logrus.Fields {
+	enc := zapcore.NewMapObjectEncoder()
+	for _, f := range fields {
+		f.AddTo(enc)
+	}
+	data := make(logrus.Fields, len(base)+len(enc.Fields))
+	for k, v := range base {
+		data[k] = v
+	}
+	for k, v := range enc.Fields {
+		data[k] = v
+	}
+	return data
+}
+
+// logWithoutExit logs at level without the panic logrus raises after Panic
+// entries, for adapters whose library panics or exits on its own.
+func logWithoutExit(entry *logrus.Entry, level logrus.Level, msg string) {
+	if level == logrus.PanicLevel {
+		defer func() {
+			recover()
+		}()
+	}
+	entry.Log(level, msg)
+}
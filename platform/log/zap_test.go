@@ -0,0 +1,31 @@
+package log
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	logrus "github.com/sirupsen/logrus"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestZapCore(t *testing.T) {
+	b := &bytes.Buffer{}
+	channel := Channel("zap")
+	channel.SetOutput(b)
+	channel.SetFormatter(&ChannelTextFormatter{DisableColors: true, DisableTimestamp: true})
+	channel.SetLevel(logrus.InfoLevel)
+
+	core := NewZapCore(channel).With([]zapcore.Field{{Key: "order", Type: zapcore.StringType, String: "o_1"}})
+	if core.Enabled(zapcore.DebugLevel) {
+		t.Error("expected debug to follow the channel level")
+	}
+	entry := zapcore.Entry{Level: zapcore.ErrorLevel, Time: time.Now(), LoggerName: "billing", Message: "charge failed"}
+	core.Check(entry, nil).Write(zapcore.Field{Key: "amount", Type: zapcore.Int64Type, Integer: 1000})
+
+	expected := `level=error msg="charge failed" amount=1000 channel=zap logger=billing order=o_1`
+	if !strings.Contains(b.String(), expected) {
+		t.Errorf("expected %q in %q", expected, b.String())
+	}
+}
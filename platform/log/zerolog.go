@@ -0,0 +1,101 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"time"
+
+	logrus "github.com/sirupsen/logrus"
+)
+
+// ZerologWriter is an io.Writer taking the JSON lines of a zerolog logger and
+// writing them through a channel or the standard logger, so libraries logging
+// with zerolog share the same formatters and outputs.
+//
+//	logger := zerolog.New(log.NewZerologWriter(log.Channel("payments")))
+type ZerologWriter struct {
+	// LevelKey, MessageKey and TimeKey are the zerolog field names. Default
+	// to "level", "message" and "time".
+	LevelKey   string
+	MessageKey string
+	TimeKey    string
+
+	logger *logrus.Logger
+	fields logrus.Fields
+}
+
+// NewZerologWriter returns a writer for channel l, or for the standard logger
+// when l is nil.
+func NewZerologWriter(l *Logger) *ZerologWriter {
+	w := &ZerologWriter{
+		LevelKey:   "level",
+		MessageKey: "message",
+		TimeKey:    "time",
+		logger:     logrus.StandardLogger(),
+		fields:     logrus.Fields{},
+	}
+	if l != nil {
+		w.logger = l.logger
+		w.fields = logrus.Fields{ChannelKey: l.name}
+	}
+	return w
+}
+
+// Write logs one zerolog event. Lines that aren't JSON are logged at Info as
+// they are. zerolog exits or panics itself after Fatal and Panic events, so
+// they are only logged.
+func (w *ZerologWriter) Write(p []byte) (int, error) {
+	line := bytes.TrimSpace(p)
+	if len(line) == 0 {
+		return len(p), nil
+	}
+
+	data := map[string]interface{}{}
+	d := json.NewDecoder(bytes.NewReader(line))
+	d.UseNumber()
+	if err := d.Decode(&data); err != nil {
+		w.logger.WithFields(w.fields).Info(string(line))
+		return len(p), nil
+	}
+
+	level := logrus.InfoLevel
+	if v, ok := data[w.LevelKey].(string); ok {
+		if l, err := logrus.ParseLevel(v); err == nil {
+			level = l
+		}
+	}
+	message, _ := data[w.MessageKey].(string)
+	t := zerologTime(data[w.TimeKey])
+	delete(data, w.LevelKey)
+	delete(data, w.MessageKey)
+	delete(data, w.TimeKey)
+
+	fields := make(logrus.Fields, len(w.fields)+len(data))
+	for k, v := range w.fields {
+		fields[k] = v
+	}
+	for k, v := range data {
+		fields[k] = v
+	}
+	logWithoutExit(w.logger.WithFields(fields).WithTime(t), level, message)
+	return len(p), nil
+}
+
+// zerologTime parses an RFC 3339 or Unix timestamp, the zerolog time formats,
+// falling back to now.
+func zerologTime(v interface{}) time.Time {
+	switch v := v.(type) {
+	case string:
+		if t, err := time.Parse(time.RFC3339Nano, v); err == nil {
+			return t
+		}
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return time.Unix(n, 0)
+		}
+		if f, err := v.Float64(); err == nil {
+			return time.Unix(0, int64(f*float64(time.Second)))
+		}
+	}
+	return time.Now()
+}
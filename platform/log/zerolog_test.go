@@ -0,0 +1,40 @@
+package log
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestZerologWriter(t *testing.T) {
+	b := &bytes.Buffer{}
+	channel := Channel("zerolog")
+	channel.SetOutput(b)
+	channel.SetFormatter(&ChannelTextFormatter{DisableColors: true})
+
+	w := NewZerologWriter(channel)
+	lines := []string{
+		`{"level":"warn","time":"2018-02-26T10:04:05Z","amount":1000,"message":"charge retried"}` + "\n",
+		`{"level":"panic","message":"out of cards"}` + "\n",
+		"not json\n",
+	}
+	for _, line := range lines {
+		if _, err := w.Write([]byte(line)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	out := strings.Split(strings.TrimSpace(b.String()), "\n")
+	if len(out) != 3 {
+		t.Fatalf("expected 3 entries, got %q", b.String())
+	}
+	for i, expected := range []string{
+		`time="2018-02-26T10:04:05Z" level=warning msg="charge retried" amount=1000 channel=zerolog`,
+		`level=panic msg="out of cards" channel=zerolog`,
+		`level=info msg="not json" channel=zerolog`,
+	} {
+		if !strings.Contains(out[i], expected) {
+			t.Errorf("expected %q in %q", expected, out[i])
+		}
+	}
+}